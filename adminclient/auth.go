@@ -0,0 +1,44 @@
+package adminclient
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+
+	"RobinPenn974/OpenAI-mocker/api"
+)
+
+// ListKeys 列出所有API密钥，密钥以掩码形式返回
+func (c *Client) ListKeys(ctx context.Context) ([]api.ApiKey, error) {
+	var resp struct {
+		Keys []api.ApiKey `json:"keys"`
+	}
+	err := c.do(ctx, http.MethodGet, "/admin/auth/keys", nil, &resp)
+	return resp.Keys, err
+}
+
+// CreateKey 创建API密钥，返回值的Key字段为完整密钥，只在创建时返回
+func (c *Client) CreateKey(ctx context.Context, req api.CreateKeyRequest) (api.ApiKey, error) {
+	var key api.ApiKey
+	err := c.do(ctx, http.MethodPost, "/admin/auth/keys", req, &key)
+	return key, err
+}
+
+// RevokeKey 吊销API密钥，keyID可以是密钥ID或完整密钥
+func (c *Client) RevokeKey(ctx context.Context, keyID string) (api.ApiKey, error) {
+	var resp struct {
+		Key api.ApiKey `json:"key"`
+	}
+	err := c.do(ctx, http.MethodPost, "/admin/auth/keys/"+url.PathEscape(keyID)+"/revoke", nil, &resp)
+	return resp.Key, err
+}
+
+// DeleteKey 删除API密钥，keyID可以是密钥ID或完整密钥
+func (c *Client) DeleteKey(ctx context.Context, keyID string) error {
+	return c.do(ctx, http.MethodDelete, "/admin/auth/keys/"+url.PathEscape(keyID), nil, nil)
+}
+
+// DeleteAllKeys 删除所有API密钥
+func (c *Client) DeleteAllKeys(ctx context.Context) error {
+	return c.do(ctx, http.MethodDelete, "/admin/auth/keys", nil, nil)
+}
@@ -0,0 +1,201 @@
+// Package adminclient 管理API的Go客户端，用于在集成测试中控制远程的模拟服务
+//
+//	client := adminclient.New("http://localhost:8080", adminclient.WithToken(token))
+//	if err := client.WaitUntilReady(ctx, 200*time.Millisecond); err != nil {
+//		t.Fatal(err)
+//	}
+//	model, err := client.LoadModel(ctx, api.ModelLoadRequest{ModelID: "my-model", ModelType: "llm"})
+package adminclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// 选择工作区的请求头，与 middleware.HeaderWorkspace 一致
+const headerWorkspace = "X-Mock-Workspace"
+
+// Client 管理API客户端
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+	token      string
+	workspace  string
+}
+
+// Option 配置客户端的选项
+type Option func(*Client)
+
+// WithHTTPClient 使用指定的HTTP客户端
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithToken 设置管理员令牌，以 Authorization: Bearer 头发送
+func WithToken(token string) Option {
+	return func(c *Client) {
+		c.token = token
+	}
+}
+
+// WithWorkspace 设置操作的工作区，以 X-Mock-Workspace 头发送
+func WithWorkspace(id string) Option {
+	return func(c *Client) {
+		c.workspace = id
+	}
+}
+
+// New 创建管理API客户端，baseURL为服务地址，如 http://localhost:8080
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		httpClient: http.DefaultClient,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// InWorkspace 返回操作指定工作区的客户端副本
+func (c *Client) InWorkspace(id string) *Client {
+	copied := *c
+	copied.workspace = id
+	return &copied
+}
+
+// Error 服务端返回的错误
+type Error struct {
+	StatusCode int    `json:"-"`
+	Message    string `json:"message"`
+	Type       string `json:"type"`
+	Code       string `json:"code,omitempty"`
+}
+
+// Error 实现error接口
+func (e *Error) Error() string {
+	if e.Code != "" {
+		return fmt.Sprintf("mocker: %d %s (%s): %s", e.StatusCode, e.Type, e.Code, e.Message)
+	}
+	return fmt.Sprintf("mocker: %d %s: %s", e.StatusCode, e.Type, e.Message)
+}
+
+// WaitUntilReady 轮询 /v1/healthz 直到服务就绪或ctx结束
+func (c *Client) WaitUntilReady(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		err := c.do(ctx, http.MethodGet, "/v1/healthz", nil, nil)
+		if err == nil {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("mocker not ready: %v", err)
+		case <-ticker.C:
+		}
+	}
+}
+
+// do 发送请求并将成功的响应解码到out，非2xx响应解码为*Error
+func (c *Client) do(ctx context.Context, method, path string, body, out interface{}) error {
+	_, err := c.doWithHeader(ctx, method, path, nil, body, out)
+	return err
+}
+
+// doWithHeader 与do相同，附加请求头并返回响应头
+func (c *Client) doWithHeader(ctx context.Context, method, path string, header http.Header, body, out interface{}) (http.Header, error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("error encoding request: %v", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reader)
+	if err != nil {
+		return nil, err
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	if c.workspace != "" {
+		req.Header.Set(headerWorkspace, c.workspace)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return resp.Header, fmt.Errorf("error reading response: %v", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.Header, decodeError(resp.StatusCode, data)
+	}
+	if out == nil {
+		return resp.Header, nil
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return resp.Header, fmt.Errorf("error decoding response: %v", err)
+	}
+	return resp.Header, nil
+}
+
+// decodeError 解码管理API和OpenAI风格的错误响应，无法解析时使用响应体作为错误信息
+func decodeError(status int, data []byte) error {
+	var body struct {
+		Error struct {
+			Message string      `json:"message"`
+			Type    string      `json:"type"`
+			Code    interface{} `json:"code"` // OpenAI错误中为字符串，Azure错误中可能为数字字符串
+		} `json:"error"`
+	}
+	apiErr := &Error{StatusCode: status}
+	if err := json.Unmarshal(data, &body); err != nil || body.Error.Message == "" {
+		apiErr.Message = strings.TrimSpace(string(data))
+		if apiErr.Message == "" {
+			apiErr.Message = http.StatusText(status)
+		}
+		return apiErr
+	}
+
+	apiErr.Message = body.Error.Message
+	apiErr.Type = body.Error.Type
+	if body.Error.Code != nil {
+		apiErr.Code = fmt.Sprint(body.Error.Code)
+	}
+	return apiErr
+}
+
+// escapePath 转义路径中的各段，保留斜杠，用于可能包含斜杠的模型名
+func escapePath(value string) string {
+	segments := strings.Split(value, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
@@ -0,0 +1,186 @@
+package adminclient
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"RobinPenn974/OpenAI-mocker/api"
+	"RobinPenn974/OpenAI-mocker/models"
+	"RobinPenn974/OpenAI-mocker/provider"
+	"RobinPenn974/OpenAI-mocker/templates"
+)
+
+// LoadModel 加载模型，可同时注册响应模板，返回补全默认值后的模型信息
+func (c *Client) LoadModel(ctx context.Context, req api.ModelLoadRequest) (models.ModelInfo, error) {
+	var resp struct {
+		Model models.ModelInfo `json:"model"`
+	}
+	err := c.do(ctx, http.MethodPost, "/admin/models/load", req, &resp)
+	return resp.Model, err
+}
+
+// UnloadModel 卸载模型，服务端会清理其关联的模板、别名、部署和规则
+func (c *Client) UnloadModel(ctx context.Context, modelID string) error {
+	return c.do(ctx, http.MethodPost, "/admin/models/unload", api.UnloadModelRequest{ModelID: modelID}, nil)
+}
+
+// UnloadAllModels 卸载所有模型
+func (c *Client) UnloadAllModels(ctx context.Context) error {
+	return c.do(ctx, http.MethodPost, "/admin/models/unload_all", nil, nil)
+}
+
+// GetModelRouting 获取模型别名、匹配模式和自动注册设置
+func (c *Client) GetModelRouting(ctx context.Context) (models.RoutingRules, error) {
+	var rules models.RoutingRules
+	err := c.do(ctx, http.MethodGet, "/admin/models/routing", nil, &rules)
+	return rules, err
+}
+
+// SetModelAlias 创建或更新模型别名
+func (c *Client) SetModelAlias(ctx context.Context, alias, target string) error {
+	return c.do(ctx, http.MethodPost, "/admin/models/aliases", api.ModelAliasRequest{Alias: alias, Target: target}, nil)
+}
+
+// DeleteModelAlias 删除模型别名
+func (c *Client) DeleteModelAlias(ctx context.Context, alias string) error {
+	return c.do(ctx, http.MethodDelete, "/admin/models/aliases/"+escapePath(alias), nil, nil)
+}
+
+// CreateModelPattern 创建模型名匹配模式
+func (c *Client) CreateModelPattern(ctx context.Context, pattern models.ModelPattern) (models.ModelPattern, error) {
+	var resp struct {
+		Pattern models.ModelPattern `json:"pattern"`
+	}
+	err := c.do(ctx, http.MethodPost, "/admin/models/patterns", pattern, &resp)
+	return resp.Pattern, err
+}
+
+// DeleteModelPattern 删除模型名匹配模式
+func (c *Client) DeleteModelPattern(ctx context.Context, patternID string) error {
+	return c.do(ctx, http.MethodDelete, "/admin/models/patterns/"+url.PathEscape(patternID), nil, nil)
+}
+
+// SetAutoRegister 设置是否自动注册未知模型
+func (c *Client) SetAutoRegister(ctx context.Context, config models.AutoRegisterConfig) error {
+	return c.do(ctx, http.MethodPut, "/admin/models/auto_register", config, nil)
+}
+
+// ListProviderProfiles 列出所有服务商兼容配置及实例默认使用的配置名
+func (c *Client) ListProviderProfiles(ctx context.Context) ([]provider.Profile, string, error) {
+	var resp struct {
+		Profiles []provider.Profile `json:"profiles"`
+		Default  string             `json:"default"`
+	}
+	err := c.do(ctx, http.MethodGet, "/admin/provider_profiles", nil, &resp)
+	return resp.Profiles, resp.Default, err
+}
+
+// ListTemplates 列出所有响应模板
+func (c *Client) ListTemplates(ctx context.Context) ([]templates.ResponseTemplate, error) {
+	var resp struct {
+		Templates []templates.ResponseTemplate `json:"templates"`
+	}
+	err := c.do(ctx, http.MethodGet, "/admin/templates", nil, &resp)
+	return resp.Templates, err
+}
+
+// GetTemplate 获取模型的生效模板，已合并父模板、匹配的模式模板和默认模板
+func (c *Client) GetTemplate(ctx context.Context, modelID string) (templates.ResponseTemplate, error) {
+	var template templates.ResponseTemplate
+	err := c.do(ctx, http.MethodGet, "/admin/templates/"+url.PathEscape(modelID), nil, &template)
+	return template, err
+}
+
+// UpdateTemplate 创建或替换 template.ModelID 对应的响应模板
+func (c *Client) UpdateTemplate(ctx context.Context, template templates.ResponseTemplate) (templates.ResponseTemplate, error) {
+	var resp struct {
+		Template templates.ResponseTemplate `json:"template"`
+	}
+	err := c.do(ctx, http.MethodPut, "/admin/templates/"+url.PathEscape(template.ModelID), template, &resp)
+	return resp.Template, err
+}
+
+// GetStoredTemplate 获取保存的模板本身，不合并父模板和默认模板，模板不存在时返回状态码为404的*Error
+func (c *Client) GetStoredTemplate(ctx context.Context, modelID string) (templates.ResponseTemplate, error) {
+	var template templates.ResponseTemplate
+	err := c.do(ctx, http.MethodGet, "/admin/templates/"+url.PathEscape(modelID)+"?stored=true", nil, &template)
+	return template, err
+}
+
+// PatchTemplate 只修改patch中的字段，值为nil的字段恢复为未设置，返回修改后保存的模板
+func (c *Client) PatchTemplate(ctx context.Context, modelID string, patch map[string]interface{}) (templates.ResponseTemplate, error) {
+	var resp struct {
+		Template templates.ResponseTemplate `json:"template"`
+	}
+	err := c.do(ctx, http.MethodPatch, "/admin/templates/"+url.PathEscape(modelID), patch, &resp)
+	return resp.Template, err
+}
+
+// GetTemplateWithETag 获取保存的模板本身和当前版本的ETag，ETag可用于 UpdateTemplateIfMatch，模板不存在时返回状态码为404的*Error
+func (c *Client) GetTemplateWithETag(ctx context.Context, modelID string) (templates.ResponseTemplate, string, error) {
+	var template templates.ResponseTemplate
+	header, err := c.doWithHeader(ctx, http.MethodGet, "/admin/templates/"+url.PathEscape(modelID)+"?stored=true", nil, nil, &template)
+	return template, header.Get("ETag"), err
+}
+
+// UpdateTemplateIfMatch 仅在模板仍为etag对应的版本时更新，否则返回状态码为412的*Error，返回更新后的ETag
+func (c *Client) UpdateTemplateIfMatch(ctx context.Context, template templates.ResponseTemplate, etag string) (templates.ResponseTemplate, string, error) {
+	var resp struct {
+		Template templates.ResponseTemplate `json:"template"`
+	}
+	header, err := c.doWithHeader(ctx, http.MethodPut, "/admin/templates/"+url.PathEscape(template.ModelID), http.Header{"If-Match": {etag}}, template, &resp)
+	return resp.Template, header.Get("ETag"), err
+}
+
+// GetTemplateHistory 获取模板的历史版本，按版本号从旧到新排列
+func (c *Client) GetTemplateHistory(ctx context.Context, modelID string) ([]templates.Version, error) {
+	var resp struct {
+		Versions []templates.Version `json:"versions"`
+	}
+	err := c.do(ctx, http.MethodGet, "/admin/templates/"+url.PathEscape(modelID)+"/history", nil, &resp)
+	return resp.Versions, err
+}
+
+// RollbackTemplate 将模板恢复为指定历史版本的内容
+func (c *Client) RollbackTemplate(ctx context.Context, modelID string, version int) (templates.ResponseTemplate, error) {
+	var resp struct {
+		Template templates.ResponseTemplate `json:"template"`
+	}
+	err := c.do(ctx, http.MethodPost, "/admin/templates/"+url.PathEscape(modelID)+"/rollback/"+strconv.Itoa(version), nil, &resp)
+	return resp.Template, err
+}
+
+// TemplatePreview 模板的预览结果，response 和 stream 中的数据块与实际接口返回的JSON一致
+type TemplatePreview struct {
+	ModelID          string                  `json:"model_id"`
+	TemplateSource   string                  `json:"template_source"` // draft 或 current
+	Endpoint         string                  `json:"endpoint"`
+	Generator        string                  `json:"generator"`    // chat、completion 或 reasoning
+	MatchedRule      string                  `json:"matched_rule"` // 命中的模板字段
+	Language         string                  `json:"language"`     // 检测到的输入语言
+	Content          string                  `json:"content"`
+	ReasoningContent *string                 `json:"reasoning_content"`
+	FinishReason     string                  `json:"finish_reason"`
+	Usage            api.ChatCompletionUsage `json:"usage"`
+	Response         json.RawMessage         `json:"response"`
+	Stream           []struct {
+		Data    json.RawMessage `json:"data"`
+		DelayMs int64           `json:"delay_ms"`
+	} `json:"stream"`
+	Warnings []string `json:"warnings"`
+}
+
+// PreviewTemplate 使用模板草稿（为空时使用当前模板）渲染示例请求的响应，不保存任何修改
+func (c *Client) PreviewTemplate(ctx context.Context, modelID string, req api.TemplatePreviewRequest) (TemplatePreview, error) {
+	var preview TemplatePreview
+	err := c.do(ctx, http.MethodPost, "/admin/templates/"+url.PathEscape(modelID)+"/preview", req, &preview)
+	return preview, err
+}
+
+// DeleteTemplate 删除模型的响应模板
+func (c *Client) DeleteTemplate(ctx context.Context, modelID string) error {
+	return c.do(ctx, http.MethodDelete, "/admin/templates/"+url.PathEscape(modelID), nil, nil)
+}
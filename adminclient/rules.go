@@ -0,0 +1,120 @@
+package adminclient
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+
+	"RobinPenn974/OpenAI-mocker/api"
+	"RobinPenn974/OpenAI-mocker/azure"
+	"RobinPenn974/OpenAI-mocker/embeddings"
+	"RobinPenn974/OpenAI-mocker/faults"
+	"RobinPenn974/OpenAI-mocker/models"
+)
+
+// ListFaults 列出所有故障注入规则
+func (c *Client) ListFaults(ctx context.Context) ([]faults.Rule, error) {
+	var resp struct {
+		Faults []faults.Rule `json:"faults"`
+	}
+	err := c.do(ctx, http.MethodGet, "/admin/faults", nil, &resp)
+	return resp.Faults, err
+}
+
+// CreateFault 创建故障注入规则
+func (c *Client) CreateFault(ctx context.Context, rule faults.Rule) (faults.Rule, error) {
+	var resp struct {
+		Fault faults.Rule `json:"fault"`
+	}
+	err := c.do(ctx, http.MethodPost, "/admin/faults", rule, &resp)
+	return resp.Fault, err
+}
+
+// DeleteFault 删除故障注入规则
+func (c *Client) DeleteFault(ctx context.Context, faultID string) error {
+	return c.do(ctx, http.MethodDelete, "/admin/faults/"+url.PathEscape(faultID), nil, nil)
+}
+
+// DeleteAllFaults 删除所有故障注入规则
+func (c *Client) DeleteAllFaults(ctx context.Context) error {
+	return c.do(ctx, http.MethodDelete, "/admin/faults", nil, nil)
+}
+
+// ListEmbeddingPins 列出所有固定向量
+func (c *Client) ListEmbeddingPins(ctx context.Context) ([]embeddings.Pin, error) {
+	var resp struct {
+		Pins []embeddings.Pin `json:"pins"`
+	}
+	err := c.do(ctx, http.MethodGet, "/admin/embeddings/pins", nil, &resp)
+	return resp.Pins, err
+}
+
+// CreateEmbeddingPin 为指定模型和输入固定向量
+func (c *Client) CreateEmbeddingPin(ctx context.Context, pin embeddings.Pin) (embeddings.Pin, error) {
+	var resp struct {
+		Pin embeddings.Pin `json:"pin"`
+	}
+	err := c.do(ctx, http.MethodPost, "/admin/embeddings/pins", pin, &resp)
+	return resp.Pin, err
+}
+
+// DeleteEmbeddingPin 删除固定向量
+func (c *Client) DeleteEmbeddingPin(ctx context.Context, pinID string) error {
+	return c.do(ctx, http.MethodDelete, "/admin/embeddings/pins/"+url.PathEscape(pinID), nil, nil)
+}
+
+// DeleteAllEmbeddingPins 删除所有固定向量
+func (c *Client) DeleteAllEmbeddingPins(ctx context.Context) error {
+	return c.do(ctx, http.MethodDelete, "/admin/embeddings/pins", nil, nil)
+}
+
+// ListDeployments 列出所有Azure部署
+func (c *Client) ListDeployments(ctx context.Context) ([]models.Deployment, error) {
+	var resp struct {
+		Deployments []models.Deployment `json:"deployments"`
+	}
+	err := c.do(ctx, http.MethodGet, "/admin/azure/deployments", nil, &resp)
+	return resp.Deployments, err
+}
+
+// CreateDeployment 创建或更新Azure部署
+func (c *Client) CreateDeployment(ctx context.Context, req api.CreateDeploymentRequest) (models.Deployment, error) {
+	var resp struct {
+		Deployment models.Deployment `json:"deployment"`
+	}
+	err := c.do(ctx, http.MethodPost, "/admin/azure/deployments", req, &resp)
+	return resp.Deployment, err
+}
+
+// DeleteDeployment 删除Azure部署
+func (c *Client) DeleteDeployment(ctx context.Context, name string) error {
+	return c.do(ctx, http.MethodDelete, "/admin/azure/deployments/"+url.PathEscape(name), nil, nil)
+}
+
+// ListContentFilterRules 列出所有Azure内容过滤规则
+func (c *Client) ListContentFilterRules(ctx context.Context) ([]azure.ContentFilterRule, error) {
+	var resp struct {
+		Rules []azure.ContentFilterRule `json:"rules"`
+	}
+	err := c.do(ctx, http.MethodGet, "/admin/azure/content_filter/rules", nil, &resp)
+	return resp.Rules, err
+}
+
+// CreateContentFilterRule 创建Azure内容过滤规则
+func (c *Client) CreateContentFilterRule(ctx context.Context, rule azure.ContentFilterRule) (azure.ContentFilterRule, error) {
+	var resp struct {
+		Rule azure.ContentFilterRule `json:"rule"`
+	}
+	err := c.do(ctx, http.MethodPost, "/admin/azure/content_filter/rules", rule, &resp)
+	return resp.Rule, err
+}
+
+// DeleteContentFilterRule 删除Azure内容过滤规则
+func (c *Client) DeleteContentFilterRule(ctx context.Context, ruleID string) error {
+	return c.do(ctx, http.MethodDelete, "/admin/azure/content_filter/rules/"+url.PathEscape(ruleID), nil, nil)
+}
+
+// DeleteAllContentFilterRules 删除所有Azure内容过滤规则
+func (c *Client) DeleteAllContentFilterRules(ctx context.Context) error {
+	return c.do(ctx, http.MethodDelete, "/admin/azure/content_filter/rules", nil, nil)
+}
@@ -0,0 +1,33 @@
+package adminclient
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"RobinPenn974/OpenAI-mocker/instance"
+)
+
+// Status 服务状态
+type Status struct {
+	StartedAt        time.Time               `json:"started_at"`
+	UptimeSeconds    int64                   `json:"uptime_seconds"`
+	ReloadIntervalMs int                     `json:"reload_interval_ms"`
+	Reload           []instance.ReloadStatus `json:"reload"`
+}
+
+// GetStatus 获取服务状态，包括配置文件和模板文件最近一次重新加载的结果
+func (c *Client) GetStatus(ctx context.Context) (Status, error) {
+	var status Status
+	err := c.do(ctx, http.MethodGet, "/admin/status", nil, &status)
+	return status, err
+}
+
+// Reload 重新加载配置文件和模板文件，有文件加载失败时返回*Error，该文件继续使用上一个有效版本
+func (c *Client) Reload(ctx context.Context) ([]instance.ReloadStatus, error) {
+	var resp struct {
+		Reload []instance.ReloadStatus `json:"reload"`
+	}
+	err := c.do(ctx, http.MethodPost, "/admin/reload", nil, &resp)
+	return resp.Reload, err
+}
@@ -0,0 +1,65 @@
+package adminclient
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"RobinPenn974/OpenAI-mocker/api"
+	"RobinPenn974/OpenAI-mocker/audit"
+	"RobinPenn974/OpenAI-mocker/workspace"
+)
+
+// ListWorkspaces 列出所有工作区
+func (c *Client) ListWorkspaces(ctx context.Context) ([]workspace.Info, error) {
+	var resp struct {
+		Workspaces []workspace.Info `json:"workspaces"`
+	}
+	err := c.do(ctx, http.MethodGet, "/admin/workspaces", nil, &resp)
+	return resp.Workspaces, err
+}
+
+// CreateWorkspace 创建工作区，新工作区复制基础工作区的状态
+func (c *Client) CreateWorkspace(ctx context.Context, req api.CreateWorkspaceRequest) (workspace.Info, error) {
+	var resp struct {
+		Workspace workspace.Info `json:"workspace"`
+	}
+	err := c.do(ctx, http.MethodPost, "/admin/workspaces", req, &resp)
+	return resp.Workspace, err
+}
+
+// GetWorkspace 获取工作区信息和用量计数
+func (c *Client) GetWorkspace(ctx context.Context, id string) (workspace.Info, error) {
+	var info workspace.Info
+	err := c.do(ctx, http.MethodGet, "/admin/workspaces/"+url.PathEscape(id), nil, &info)
+	return info, err
+}
+
+// DeleteWorkspace 删除工作区
+func (c *Client) DeleteWorkspace(ctx context.Context, id string) error {
+	return c.do(ctx, http.MethodDelete, "/admin/workspaces/"+url.PathEscape(id), nil, nil)
+}
+
+// GetJournal 获取工作区最近的API请求日志
+func (c *Client) GetJournal(ctx context.Context, id string) ([]workspace.JournalEntry, error) {
+	var resp struct {
+		Entries []workspace.JournalEntry `json:"entries"`
+	}
+	err := c.do(ctx, http.MethodGet, "/admin/workspaces/"+url.PathEscape(id)+"/journal", nil, &resp)
+	return resp.Entries, err
+}
+
+// ResetJournal 清空工作区的请求日志和用量计数
+func (c *Client) ResetJournal(ctx context.Context, id string) error {
+	return c.do(ctx, http.MethodDelete, "/admin/workspaces/"+url.PathEscape(id)+"/journal", nil, nil)
+}
+
+// ListAuditLog 按时间倒序获取最近limit条管理操作审计记录
+func (c *Client) ListAuditLog(ctx context.Context, limit int) ([]audit.Entry, error) {
+	var resp struct {
+		Entries []audit.Entry `json:"entries"`
+	}
+	err := c.do(ctx, http.MethodGet, "/admin/audit?limit="+strconv.Itoa(limit), nil, &resp)
+	return resp.Entries, err
+}
@@ -0,0 +1,86 @@
+package api
+
+import "encoding/json"
+
+// Assistants API v2 相关类型定义
+
+// AssistantTool 助手和运行可使用的工具：code_interpreter、file_search 或 function
+type AssistantTool struct {
+	Type     string        `json:"type"`
+	Function *ToolFunction `json:"function,omitempty"`
+}
+
+// AssistantRequest 创建或修改助手的请求，修改时只更新传入的字段
+type AssistantRequest struct {
+	Model          *string                `json:"model,omitempty"`
+	Name           *string                `json:"name,omitempty"`
+	Description    *string                `json:"description,omitempty"`
+	Instructions   *string                `json:"instructions,omitempty"`
+	Tools          *[]AssistantTool       `json:"tools,omitempty"`
+	ToolResources  map[string]interface{} `json:"tool_resources,omitempty"`
+	Metadata       map[string]string      `json:"metadata,omitempty"`
+	Temperature    *float64               `json:"temperature,omitempty"`
+	TopP           *float64               `json:"top_p,omitempty"`
+	ResponseFormat interface{}            `json:"response_format,omitempty"`
+}
+
+// ThreadRequest 创建或修改线程的请求，messages 仅在创建时使用
+type ThreadRequest struct {
+	Messages      []AssistantMessageRequest `json:"messages,omitempty"`
+	ToolResources map[string]interface{}    `json:"tool_resources,omitempty"`
+	Metadata      map[string]string         `json:"metadata,omitempty"`
+}
+
+// AssistantMessageRequest 向线程添加消息的请求，content 为字符串或内容片段数组
+type AssistantMessageRequest struct {
+	Role        string            `json:"role"`
+	Content     json.RawMessage   `json:"content"`
+	Attachments []interface{}     `json:"attachments,omitempty"`
+	Metadata    map[string]string `json:"metadata,omitempty"`
+}
+
+// RunRequest 创建运行的请求，未设置的模型、指令和工具使用助手的设置
+// thread 仅用于同时创建线程和运行
+type RunRequest struct {
+	AssistantID            string                    `json:"assistant_id"`
+	Thread                 *ThreadRequest            `json:"thread,omitempty"`
+	Model                  *string                   `json:"model,omitempty"`
+	Instructions           *string                   `json:"instructions,omitempty"`
+	AdditionalInstructions *string                   `json:"additional_instructions,omitempty"`
+	AdditionalMessages     []AssistantMessageRequest `json:"additional_messages,omitempty"`
+	Tools                  *[]AssistantTool          `json:"tools,omitempty"`
+	Metadata               map[string]string         `json:"metadata,omitempty"`
+	Temperature            *float64                  `json:"temperature,omitempty"`
+	TopP                   *float64                  `json:"top_p,omitempty"`
+	Stream                 bool                      `json:"stream,omitempty"`
+	MaxPromptTokens        *int                      `json:"max_prompt_tokens,omitempty"`
+	MaxCompletionTokens    *int                      `json:"max_completion_tokens,omitempty"`
+	ToolChoice             interface{}               `json:"tool_choice,omitempty"` // none、auto、required 或指定的工具
+	ParallelToolCalls      *bool                     `json:"parallel_tool_calls,omitempty"`
+	ResponseFormat         interface{}               `json:"response_format,omitempty"`
+	TruncationStrategy     interface{}               `json:"truncation_strategy,omitempty"`
+}
+
+// ToolOutput 函数工具调用的输出
+type ToolOutput struct {
+	ToolCallID string `json:"tool_call_id"`
+	Output     string `json:"output"`
+}
+
+// SubmitToolOutputsRequest 提交工具输出的请求
+type SubmitToolOutputsRequest struct {
+	ToolOutputs []ToolOutput `json:"tool_outputs"`
+	Stream      bool         `json:"stream,omitempty"`
+}
+
+// MetadataRequest 只修改元数据的请求，用于消息和运行
+type MetadataRequest struct {
+	Metadata map[string]string `json:"metadata"`
+}
+
+// DeletedResponse 删除对象的响应
+type DeletedResponse struct {
+	ID      string `json:"id"`
+	Object  string `json:"object"`
+	Deleted bool   `json:"deleted"`
+}
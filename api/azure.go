@@ -0,0 +1,92 @@
+package api
+
+// Azure OpenAI 相关类型定义
+
+// ContentFilterSeverityResult 按严重程度分级的内容过滤结果
+type ContentFilterSeverityResult struct {
+	Filtered bool   `json:"filtered"`
+	Severity string `json:"severity"` // safe, low, medium, high
+}
+
+// ContentFilterDetectedResult 按是否检测到进行判定的内容过滤结果
+type ContentFilterDetectedResult struct {
+	Filtered bool `json:"filtered"`
+	Detected bool `json:"detected"`
+}
+
+// ContentFilterResults 各类别的内容过滤结果
+type ContentFilterResults struct {
+	Hate      *ContentFilterSeverityResult `json:"hate,omitempty"`
+	SelfHarm  *ContentFilterSeverityResult `json:"self_harm,omitempty"`
+	Sexual    *ContentFilterSeverityResult `json:"sexual,omitempty"`
+	Violence  *ContentFilterSeverityResult `json:"violence,omitempty"`
+	Jailbreak *ContentFilterDetectedResult `json:"jailbreak,omitempty"`
+}
+
+// PromptFilterResult 单个提示词的内容过滤结果
+type PromptFilterResult struct {
+	PromptIndex          int                  `json:"prompt_index"`
+	ContentFilterResults ContentFilterResults `json:"content_filter_results"`
+}
+
+// AzureChatCompletionChoice 带内容过滤结果的Chat选项
+type AzureChatCompletionChoice struct {
+	ChatCompletionChoice
+	ContentFilterResults ContentFilterResults `json:"content_filter_results"`
+}
+
+// AzureChatCompletionResponse Azure风格的Chat响应
+type AzureChatCompletionResponse struct {
+	ID                  string                      `json:"id"`
+	Object              string                      `json:"object"`
+	Created             int64                       `json:"created"`
+	Model               string                      `json:"model"`
+	PromptFilterResults []PromptFilterResult        `json:"prompt_filter_results"`
+	Choices             []AzureChatCompletionChoice `json:"choices"`
+	Usage               ChatCompletionUsage         `json:"usage"`
+}
+
+// AzureCompletionChoice 带内容过滤结果的文本补全选项
+type AzureCompletionChoice struct {
+	CompletionChoice
+	ContentFilterResults ContentFilterResults `json:"content_filter_results"`
+}
+
+// AzureCompletionResponse Azure风格的文本补全响应
+type AzureCompletionResponse struct {
+	ID                  string                  `json:"id"`
+	Object              string                  `json:"object"`
+	Created             int64                   `json:"created"`
+	Model               string                  `json:"model"`
+	PromptFilterResults []PromptFilterResult    `json:"prompt_filter_results"`
+	Choices             []AzureCompletionChoice `json:"choices"`
+	Usage               ChatCompletionUsage     `json:"usage"`
+}
+
+// AzurePromptFilterChunk 流式响应中携带提示词过滤结果的首个数据块
+type AzurePromptFilterChunk struct {
+	ID                  string               `json:"id"`
+	Object              string               `json:"object"`
+	Created             int64                `json:"created"`
+	Model               string               `json:"model"`
+	PromptFilterResults []PromptFilterResult `json:"prompt_filter_results"`
+	Choices             []interface{}        `json:"choices"`
+}
+
+// AzureInnerError Azure错误中的内部错误信息
+type AzureInnerError struct {
+	Code                string               `json:"code"`
+	ContentFilterResult ContentFilterResults `json:"content_filter_result"`
+}
+
+// AzureErrorResponse Azure风格的错误响应
+type AzureErrorResponse struct {
+	Error struct {
+		Message    string           `json:"message"`
+		Type       *string          `json:"type"`
+		Param      *string          `json:"param,omitempty"`
+		Code       string           `json:"code"`
+		Status     int              `json:"status,omitempty"`
+		InnerError *AzureInnerError `json:"innererror,omitempty"`
+	} `json:"error"`
+}
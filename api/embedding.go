@@ -0,0 +1,58 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+// EmbeddingInput Embeddings请求的输入，兼容OpenAI支持的四种形式：
+// 字符串、字符串数组、token ID数组以及token ID数组的数组
+type EmbeddingInput struct {
+	Texts  []string // 文本输入
+	Tokens [][]int  // token输入，与Texts互斥
+}
+
+// UnmarshalJSON 解析任意一种输入形式
+func (e *EmbeddingInput) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err == nil {
+		e.Texts = []string{text}
+		return nil
+	}
+
+	var texts []string
+	if err := json.Unmarshal(data, &texts); err == nil {
+		e.Texts = texts
+		return nil
+	}
+
+	var tokens []int
+	if err := json.Unmarshal(data, &tokens); err == nil {
+		e.Tokens = [][]int{tokens}
+		return nil
+	}
+
+	var tokenArrays [][]int
+	if err := json.Unmarshal(data, &tokenArrays); err == nil {
+		e.Tokens = tokenArrays
+		return nil
+	}
+
+	return errors.New("'input' must be a string, an array of strings, an array of integers, or an array of integer arrays")
+}
+
+// MarshalJSON 按原始形式输出
+func (e EmbeddingInput) MarshalJSON() ([]byte, error) {
+	if e.Tokens != nil {
+		return json.Marshal(e.Tokens)
+	}
+	return json.Marshal(e.Texts)
+}
+
+// Len 返回输入的条数
+func (e EmbeddingInput) Len() int {
+	if e.Tokens != nil {
+		return len(e.Tokens)
+	}
+	return len(e.Texts)
+}
@@ -0,0 +1,41 @@
+package api
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestEmbeddingInputUnmarshal(t *testing.T) {
+	cases := []struct {
+		input   string
+		texts   []string
+		tokens  [][]int
+		wantErr bool
+	}{
+		{`"hello"`, []string{"hello"}, nil, false},
+		{`["a","b"]`, []string{"a", "b"}, nil, false},
+		{`[1,2,3]`, nil, [][]int{{1, 2, 3}}, false},
+		{`[[1,2],[3]]`, nil, [][]int{{1, 2}, {3}}, false},
+		{`42`, nil, nil, true},
+		{`[1,"a"]`, nil, nil, true},
+		{`{"text":"a"}`, nil, nil, true},
+	}
+	for _, tc := range cases {
+		var input EmbeddingInput
+		err := json.Unmarshal([]byte(tc.input), &input)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("%s: error %v, want error %v", tc.input, err, tc.wantErr)
+			continue
+		}
+		if tc.wantErr {
+			continue
+		}
+		if !reflect.DeepEqual(input.Texts, tc.texts) || !reflect.DeepEqual(input.Tokens, tc.tokens) {
+			t.Errorf("%s: texts %v, tokens %v", tc.input, input.Texts, input.Tokens)
+		}
+		if want := len(tc.texts) + len(tc.tokens); input.Len() != want {
+			t.Errorf("%s: Len %d, want %d", tc.input, input.Len(), want)
+		}
+	}
+}
@@ -0,0 +1,30 @@
+package api
+
+import "encoding/json"
+
+// FineTuningHyperparameters 微调的超参数，每项为 "auto" 或数值
+type FineTuningHyperparameters struct {
+	NEpochs                json.RawMessage `json:"n_epochs"`
+	BatchSize              json.RawMessage `json:"batch_size"`
+	LearningRateMultiplier json.RawMessage `json:"learning_rate_multiplier"`
+}
+
+// FineTuningMethod 微调方法，目前只支持 supervised
+type FineTuningMethod struct {
+	Type       string `json:"type"`
+	Supervised *struct {
+		Hyperparameters *FineTuningHyperparameters `json:"hyperparameters"`
+	} `json:"supervised"`
+}
+
+// CreateFineTuningJobRequest 创建微调任务的请求，hyperparameters 已被 method 取代但仍然支持
+type CreateFineTuningJobRequest struct {
+	Model           string                     `json:"model"`
+	TrainingFile    string                     `json:"training_file"`
+	ValidationFile  string                     `json:"validation_file"`
+	Hyperparameters *FineTuningHyperparameters `json:"hyperparameters"`
+	Method          *FineTuningMethod          `json:"method"`
+	Suffix          *string                    `json:"suffix"`
+	Seed            *int64                     `json:"seed"`
+	Metadata        map[string]string          `json:"metadata"`
+}
@@ -0,0 +1,101 @@
+package api
+
+// 分页列表的排序方向
+const (
+	OrderAsc  = "asc"
+	OrderDesc = "desc"
+)
+
+// 分页列表每页数量的默认值和上限
+const (
+	DefaultListLimit = 20
+	MaxListLimit     = 100
+)
+
+// ListParams 分页列表的查询参数，after和before为对象ID游标
+type ListParams struct {
+	After  string
+	Before string
+	Limit  int
+	Order  string
+}
+
+// ListResponse OpenAI风格的分页列表响应
+type ListResponse struct {
+	Object  string      `json:"object"`
+	Data    interface{} `json:"data"`
+	FirstID *string     `json:"first_id"`
+	LastID  *string     `json:"last_id"`
+	HasMore bool        `json:"has_more"`
+}
+
+// NewListResponse 构造分页列表响应，ids为data中按顺序排列的对象ID
+func NewListResponse(data interface{}, ids []string, hasMore bool) ListResponse {
+	response := ListResponse{
+		Object:  "list",
+		Data:    data,
+		HasMore: hasMore,
+	}
+	if len(ids) > 0 {
+		response.FirstID = &ids[0]
+		response.LastID = &ids[len(ids)-1]
+	}
+	return response
+}
+
+// Paginate 按分页参数截取按创建顺序排列的items，id返回对象的ID，游标对应的对象不存在时返回空列表
+// 返回当前页的对象和按排序方向是否还有更多对象
+func Paginate[T any](items []T, id func(T) string, params ListParams) ([]T, bool) {
+	ordered := make([]T, len(items))
+	copy(ordered, items)
+	if params.Order != OrderAsc {
+		for i, j := 0, len(ordered)-1; i < j; i, j = i+1, j-1 {
+			ordered[i], ordered[j] = ordered[j], ordered[i]
+		}
+	}
+
+	indexOf := func(cursor string) int {
+		for i, item := range ordered {
+			if id(item) == cursor {
+				return i
+			}
+		}
+		return -1
+	}
+
+	limit := params.Limit
+	if limit <= 0 {
+		limit = DefaultListLimit
+	}
+
+	start, end := 0, len(ordered)
+	if params.After != "" {
+		index := indexOf(params.After)
+		if index < 0 {
+			return []T{}, false
+		}
+		start = index + 1
+	}
+	if params.Before != "" {
+		index := indexOf(params.Before)
+		if index < 0 {
+			return []T{}, false
+		}
+		end = index
+	}
+	if start >= end {
+		return []T{}, false
+	}
+
+	// before 游标取最靠近游标的一页，has_more 表示游标前还有对象
+	if params.Before != "" && params.After == "" {
+		if end-start > limit {
+			return ordered[end-limit : end], true
+		}
+		return ordered[start:end], false
+	}
+	if end-start > limit {
+		return ordered[start : start+limit], true
+	}
+	return ordered[start:end], false
+}
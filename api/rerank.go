@@ -0,0 +1,164 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// RerankDocument 待排序的文档，可以是字符串，也可以是包含多个字段的对象
+type RerankDocument struct {
+	Text   string                 // 字符串文档的内容，或对象文档的text字段
+	Fields map[string]interface{} // 对象文档的全部字段，字符串文档为nil
+}
+
+// UnmarshalJSON 解析字符串或对象形式的文档
+func (d *RerankDocument) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err == nil {
+		d.Text = text
+		return nil
+	}
+
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil || fields == nil {
+		return errors.New("each document must be a string or an object")
+	}
+	d.Fields = fields
+	if text, ok := fields["text"].(string); ok {
+		d.Text = text
+	}
+	return nil
+}
+
+// MarshalJSON 输出为对象形式，字符串文档输出为 {"text": ...}
+func (d RerankDocument) MarshalJSON() ([]byte, error) {
+	if d.Fields != nil {
+		return json.Marshal(d.Fields)
+	}
+	return json.Marshal(map[string]string{"text": d.Text})
+}
+
+// RankText 返回参与排序的文本，rankFields为空时使用text字段
+func (d RerankDocument) RankText(rankFields []string) (string, error) {
+	if d.Fields == nil {
+		return d.Text, nil
+	}
+
+	if len(rankFields) == 0 {
+		if _, ok := d.Fields["text"]; ok {
+			rankFields = []string{"text"}
+		} else {
+			// 没有text字段时使用所有字段，按字段名排序保证结果稳定
+			for name := range d.Fields {
+				rankFields = append(rankFields, name)
+			}
+			sort.Strings(rankFields)
+		}
+	}
+
+	// 只有一个字符串字段时直接使用其内容
+	if len(rankFields) == 1 {
+		if value, ok := d.Fields[rankFields[0]].(string); ok {
+			return value, nil
+		}
+	}
+
+	parts := make([]string, 0, len(rankFields))
+	for _, name := range rankFields {
+		value, ok := d.Fields[name]
+		if !ok {
+			return "", fmt.Errorf("rank field '%s' is missing from a document", name)
+		}
+		switch v := value.(type) {
+		case string:
+			parts = append(parts, name+": "+v)
+		default:
+			encoded, _ := json.Marshal(v)
+			parts = append(parts, name+": "+string(encoded))
+		}
+	}
+	return strings.Join(parts, "\n"), nil
+}
+
+// Cohere v2 风格的重排序响应
+type CohereRerankResult struct {
+	Index          int             `json:"index"`
+	RelevanceScore float64         `json:"relevance_score"`
+	Document       *RerankDocument `json:"document,omitempty"`
+}
+
+type CohereRerankMeta struct {
+	APIVersion struct {
+		Version string `json:"version"`
+	} `json:"api_version"`
+	BilledUnits struct {
+		SearchUnits int `json:"search_units"`
+	} `json:"billed_units"`
+}
+
+type CohereRerankResponse struct {
+	ID      string               `json:"id"`
+	Results []CohereRerankResult `json:"results"`
+	Meta    CohereRerankMeta     `json:"meta"`
+}
+
+// Jina / vLLM 风格的重排序响应
+type JinaRerankResult struct {
+	Index          int             `json:"index"`
+	Document       *RerankDocument `json:"document,omitempty"`
+	RelevanceScore float64         `json:"relevance_score"`
+}
+
+type JinaRerankUsage struct {
+	TotalTokens  int `json:"total_tokens"`
+	PromptTokens int `json:"prompt_tokens,omitempty"`
+}
+
+type JinaRerankResponse struct {
+	ID      string             `json:"id,omitempty"`
+	Model   string             `json:"model"`
+	Usage   JinaRerankUsage    `json:"usage"`
+	Results []JinaRerankResult `json:"results"`
+}
+
+// vLLM /score 接口
+type ScoreText []string
+
+// UnmarshalJSON 兼容字符串和字符串数组
+func (t *ScoreText) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err == nil {
+		*t = ScoreText{text}
+		return nil
+	}
+	var texts []string
+	if err := json.Unmarshal(data, &texts); err != nil {
+		return errors.New("must be a string or an array of strings")
+	}
+	*t = texts
+	return nil
+}
+
+type ScoreRequest struct {
+	Model string    `json:"model"`
+	Text1 ScoreText `json:"text_1"`
+	Text2 ScoreText `json:"text_2"`
+}
+
+type ScoreData struct {
+	Index  int     `json:"index"`
+	Object string  `json:"object"`
+	Score  float64 `json:"score"`
+}
+
+type ScoreResponse struct {
+	ID      string              `json:"id"`
+	Object  string              `json:"object"`
+	Created int64               `json:"created"`
+	Model   string              `json:"model"`
+	Data    []ScoreData         `json:"data"`
+	Usage   ChatCompletionUsage `json:"usage"`
+}
@@ -1,14 +1,40 @@
 package api
 
-import "time"
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"RobinPenn974/OpenAI-mocker/templates"
+)
 
 // 通用错误响应格式
 type ErrorResponse struct {
-	Error struct {
-		Message string `json:"message"`
-		Type    string `json:"type"`
-		Code    string `json:"code,omitempty"`
-	} `json:"error"`
+	Error ErrorDetail `json:"error"`
+}
+
+// ErrorDetail 错误详情，param和code为空时与OpenAI一样输出null
+type ErrorDetail struct {
+	Message string  `json:"message"`
+	Type    string  `json:"type"`
+	Param   *string `json:"param"`
+	Code    *string `json:"code"`
+}
+
+// NewErrorResponse 构造OpenAI格式的错误响应
+func NewErrorResponse(message, errType, param, code string) ErrorResponse {
+	detail := ErrorDetail{
+		Message: message,
+		Type:    errType,
+	}
+	if param != "" {
+		detail.Param = &param
+	}
+	if code != "" {
+		detail.Code = &code
+	}
+	return ErrorResponse{Error: detail}
 }
 
 // Chat相关类型定义
@@ -17,14 +43,97 @@ type ChatCompletionMessage struct {
 	Content          string  `json:"content"`
 	Name             *string `json:"name,omitempty"`
 	ReasoningContent *string `json:"reasoning_content,omitempty"`
+	Reasoning        *string `json:"reasoning,omitempty"` // 模板的推理格式为 reasoning 时推理内容使用的字段
+
+	// 请求中以内容片段数组形式传入的非文本片段类型（image_url, input_audio等），仅用于校验
+	PartTypes []string `json:"-"`
+}
+
+// ContentPart 多模态消息中的内容片段
+type ContentPart struct {
+	Type string `json:"type"`
+	Text string `json:"text,omitempty"`
+}
+
+// UnmarshalJSON 兼容字符串和内容片段数组两种消息内容，文本片段拼接后存入Content
+func (m *ChatCompletionMessage) UnmarshalJSON(data []byte) error {
+	type plainMessage ChatCompletionMessage
+	var raw struct {
+		plainMessage
+		Content json.RawMessage `json:"content"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*m = ChatCompletionMessage(raw.plainMessage)
+
+	if len(raw.Content) == 0 || string(raw.Content) == "null" {
+		return nil
+	}
+	if err := json.Unmarshal(raw.Content, &m.Content); err == nil {
+		return nil
+	}
+
+	var parts []ContentPart
+	if err := json.Unmarshal(raw.Content, &parts); err != nil {
+		return errors.New("message content must be a string or an array of content parts")
+	}
+	texts := make([]string, 0, len(parts))
+	for _, part := range parts {
+		if part.Type == "text" {
+			texts = append(texts, part.Text)
+		} else {
+			m.PartTypes = append(m.PartTypes, part.Type)
+		}
+	}
+	m.Content = strings.Join(texts, "\n")
+	return nil
 }
 
 type ChatCompletionRequest struct {
-	Model       string                  `json:"model"`
-	Messages    []ChatCompletionMessage `json:"messages"`
-	Temperature float64                 `json:"temperature,omitempty"`
-	MaxTokens   int                     `json:"max_tokens,omitempty"`
-	Stream      bool                    `json:"stream,omitempty"`
+	Model               string                  `json:"model"`
+	Messages            []ChatCompletionMessage `json:"messages"`
+	Temperature         float64                 `json:"temperature,omitempty"`
+	MaxTokens           int                     `json:"max_tokens,omitempty"`
+	MaxCompletionTokens int                     `json:"max_completion_tokens,omitempty"`
+	Stream              bool                    `json:"stream,omitempty"`
+	Tools               []Tool                  `json:"tools,omitempty"`
+	ToolChoice          interface{}             `json:"tool_choice,omitempty"`
+	ResponseFormat      *ResponseFormat         `json:"response_format,omitempty"`
+	ReasoningEffort     string                  `json:"reasoning_effort,omitempty"` // low、medium 或 high，调整推理内容的长度
+	ChatTemplateKwargs  *ChatTemplateKwargs     `json:"chat_template_kwargs,omitempty"`
+	Thinking            *ThinkingConfig         `json:"thinking,omitempty"`
+	Seed                *int64                  `json:"seed,omitempty"` // 设置后相同输入得到相同的候选回复和生成文本
+}
+
+// ChatTemplateKwargs vLLM 风格的对话模板参数，enable_thinking 开启或关闭 Qwen 等模型的思考
+type ChatTemplateKwargs struct {
+	EnableThinking *bool `json:"enable_thinking,omitempty"`
+}
+
+// ThinkingConfig 思考配置，type 为 enabled 或 disabled，budget_tokens 限制推理内容的token数
+type ThinkingConfig struct {
+	Type         string `json:"type"`
+	BudgetTokens int    `json:"budget_tokens,omitempty"`
+}
+
+// Tool 工具定义
+type Tool struct {
+	Type     string       `json:"type"`
+	Function ToolFunction `json:"function"`
+}
+
+// ToolFunction 函数工具的定义
+type ToolFunction struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	Parameters  json.RawMessage `json:"parameters,omitempty"`
+}
+
+// ResponseFormat 结构化输出格式
+type ResponseFormat struct {
+	Type       string          `json:"type"` // text, json_object, json_schema
+	JSONSchema json.RawMessage `json:"json_schema,omitempty"`
 }
 
 type ChatCompletionChoice struct {
@@ -34,9 +143,15 @@ type ChatCompletionChoice struct {
 }
 
 type ChatCompletionUsage struct {
-	PromptTokens     int `json:"prompt_tokens"`
-	CompletionTokens int `json:"completion_tokens"`
-	TotalTokens      int `json:"total_tokens"`
+	PromptTokens            int                      `json:"prompt_tokens"`
+	CompletionTokens        int                      `json:"completion_tokens"`
+	TotalTokens             int                      `json:"total_tokens"`
+	CompletionTokensDetails *CompletionTokensDetails `json:"completion_tokens_details,omitempty"` // 生成了推理内容时返回
+}
+
+// CompletionTokensDetails 生成token数的明细，推理内容的token数包含在 completion_tokens 中
+type CompletionTokensDetails struct {
+	ReasoningTokens int `json:"reasoning_tokens"`
 }
 
 type ChatCompletionResponse struct {
@@ -53,6 +168,7 @@ type ChatCompletionChunkDelta struct {
 	Role             *string `json:"role,omitempty"`
 	Content          *string `json:"content,omitempty"`
 	ReasoningContent *string `json:"reasoning_content,omitempty"`
+	Reasoning        *string `json:"reasoning,omitempty"`
 }
 
 type ChatCompletionChunkChoice struct {
@@ -77,6 +193,7 @@ type CompletionRequest struct {
 	Temperature float64  `json:"temperature,omitempty"`
 	Stop        []string `json:"stop,omitempty"`
 	Stream      bool     `json:"stream,omitempty"`
+	Seed        *int64   `json:"seed,omitempty"`
 }
 
 type CompletionChoice struct {
@@ -111,14 +228,17 @@ type CompletionChunkResponse struct {
 
 // Embedding相关类型定义
 type EmbeddingRequest struct {
-	Model string   `json:"model"`
-	Input []string `json:"input"`
+	Model          string         `json:"model"`
+	Input          EmbeddingInput `json:"input"`
+	Dimensions     *int           `json:"dimensions,omitempty"`
+	EncodingFormat string         `json:"encoding_format,omitempty"` // float 或 base64
+	User           string         `json:"user,omitempty"`
 }
 
 type EmbeddingData struct {
-	Object    string    `json:"object"`
-	Embedding []float64 `json:"embedding"`
-	Index     int       `json:"index"`
+	Object    string      `json:"object"`
+	Embedding interface{} `json:"embedding"` // float格式为[]float64，base64格式为字符串
+	Index     int         `json:"index"`
 }
 
 type EmbeddingResponse struct {
@@ -133,15 +253,18 @@ type EmbeddingResponse struct {
 
 // Rerank相关类型定义
 type RerankRequest struct {
-	Model     string   `json:"model"`
-	Query     string   `json:"query"`
-	Documents []string `json:"documents"`
-	TopN      int      `json:"top_n,omitempty"`
+	Model           string           `json:"model"`
+	Query           string           `json:"query"`
+	Documents       []RerankDocument `json:"documents"`
+	TopN            int              `json:"top_n,omitempty"`
+	ReturnDocuments *bool            `json:"return_documents,omitempty"`
+	RankFields      []string         `json:"rank_fields,omitempty"`
+	MaxChunksPerDoc int              `json:"max_chunks_per_doc,omitempty"`
 }
 
 type RerankResult struct {
 	Index          int     `json:"index"`
-	Document       string  `json:"document"`
+	Document       *string `json:"document,omitempty"`
 	Score          float64 `json:"score"`
 	RelevanceScore float64 `json:"relevance_score"`
 }
@@ -167,20 +290,130 @@ type ModelData struct {
 	OwnedBy string `json:"owned_by"`
 }
 
+// ModelDeleteResponse 删除模型的响应
+type ModelDeleteResponse struct {
+	ID      string `json:"id"`
+	Object  string `json:"object"`
+	Deleted bool   `json:"deleted"`
+}
+
 // API密钥管理相关
+
+// ApiKey API密钥信息，列表中只展示掩码后的密钥
 type ApiKey struct {
-	ID        string    `json:"id"`
-	Key       string    `json:"key"`
-	Name      string    `json:"name"`
-	CreatedAt time.Time `json:"created_at"`
+	ID              string          `json:"id"`
+	Key             string          `json:"key,omitempty"` // 完整密钥，只在创建时返回
+	Name            string          `json:"name"`
+	MaskedKey       string          `json:"masked_key"`
+	CreatedAt       time.Time       `json:"created_at"`
+	ExpiresAt       *time.Time      `json:"expires_at"`
+	LastUsedAt      *time.Time      `json:"last_used_at"`
+	Revoked         bool            `json:"revoked"`
+	Expired         bool            `json:"expired"`
+	Scopes          []string        `json:"scopes"`
+	AllowedModels   []string        `json:"allowed_models"`
+	RateLimit       ApiKeyRateLimit `json:"rate_limit"`
+	Workspace       string          `json:"workspace,omitempty"`
+	ProviderProfile string          `json:"provider_profile,omitempty"`
+	UsageCount      int64           `json:"usage_count"`
+}
+
+// ApiKeyRateLimit 单个密钥的速率限制，0表示不限制
+type ApiKeyRateLimit struct {
+	RequestsPerMinute int `json:"requests_per_minute,omitempty"`
+}
+
+// ModelLoadRequest 加载模型的请求结构
+type ModelLoadRequest struct {
+	ModelID      string `json:"model_id" binding:"required"`
+	ModelType    string `json:"model_type" binding:"required"`
+	OwnedBy      string `json:"owned_by,omitempty"`
+	ResponseType string `json:"response_type,omitempty"` // chat, completion, reasoning
+
+	// Embedding模型的向量生成算法：hash 或 random
+	EmbeddingAlgorithm string `json:"embedding_algorithm,omitempty"`
+
+	// Rerank模型的响应格式：default, cohere, jina, vllm
+	RerankProfile string `json:"rerank_profile,omitempty"`
+	// Chat和文本补全模仿的服务商
+	ProviderProfile string `json:"provider_profile,omitempty"`
+
+	// 能力元数据，未设置时按模型类型使用默认值
+	ContextWindow       int      `json:"context_window,omitempty"`
+	MaxOutputTokens     int      `json:"max_output_tokens,omitempty"`
+	Endpoints           []string `json:"endpoints,omitempty"`
+	Modalities          []string `json:"modalities,omitempty"`
+	SupportsTools       bool     `json:"supports_tools,omitempty"`
+	SupportsJSONSchema  bool     `json:"supports_json_schema,omitempty"`
+	SupportsReasoning   bool     `json:"supports_reasoning,omitempty"`
+	EmbeddingDimensions int      `json:"embedding_dimensions,omitempty"`
+	SupportsDimensions  bool     `json:"supports_dimensions,omitempty"`
+
+	// 可选的响应模板
+	Template *TemplateConfig `json:"template,omitempty"`
 }
 
-// 模型管理API的请求类型
-type LoadModelRequest struct {
+// TemplateConfig 模型响应模板配置，未设置的字段使用父模板、匹配的模式模板或内置默认模板的值
+type TemplateConfig struct {
+	Extends           string `json:"extends,omitempty"`
+	Prefix            string `json:"prefix,omitempty"`
+	Greeting          string `json:"greeting,omitempty"`
+	Question          string `json:"question,omitempty"`
+	HelpRequest       string `json:"help_request,omitempty"`
+	Default           string `json:"default,omitempty"`
+	SupportReasoning  *bool  `json:"support_reasoning,omitempty"`
+	ReasoningPrefix   string `json:"reasoning_prefix,omitempty"`
+	ReasoningTemplate string `json:"reasoning_template,omitempty"`
+	ReasoningFormat   string `json:"reasoning_format,omitempty"`
+	CompletionPrefix  string `json:"completion_prefix,omitempty"`
+
+	// 各字段的候选回复，格式与 templates.ResponseTemplate 的 variants 相同
+	Variants map[string][]templates.Variant `json:"variants,omitempty"`
+	// 各字段的本地化文本，键为字段名加语言后缀，如 greeting.zh
+	Localized map[string]string `json:"localized,omitempty"`
+}
+
+// TemplatePreviewRequest 预览模板渲染结果的请求，chat、completion 二选一
+type TemplatePreviewRequest struct {
+	Template   *templates.ResponseTemplate `json:"template,omitempty"` // 模板草稿，为空时使用当前生效的模板
+	Chat       *ChatCompletionRequest      `json:"chat,omitempty"`
+	Completion *CompletionRequest          `json:"completion,omitempty"`
+	Responses  json.RawMessage             `json:"responses,omitempty"` // 服务未实现Responses API，不支持预览，传入时返回400
+}
+
+// UnloadModelRequest 卸载模型的请求
+type UnloadModelRequest struct {
+	ModelID string `json:"model_id" binding:"required"`
+}
+
+// ModelAliasRequest 创建或更新模型别名的请求
+type ModelAliasRequest struct {
+	Alias  string `json:"alias" binding:"required"`
+	Target string `json:"target" binding:"required"`
+}
+
+// CreateDeploymentRequest 创建或更新Azure部署的请求
+type CreateDeploymentRequest struct {
+	Name    string `json:"name" binding:"required"`
 	ModelID string `json:"model_id" binding:"required"`
-	Path    string `json:"path,omitempty"`
 }
 
+// CreateWorkspaceRequest 创建工作区的请求
+type CreateWorkspaceRequest struct {
+	ID   string `json:"id" binding:"required"`
+	Base string `json:"base,omitempty"` // 复制状态的基础工作区，为空时使用默认工作区
+}
+
+// CreateKeyRequest 创建API密钥的请求
 type CreateKeyRequest struct {
-	Name string `json:"name" binding:"required"`
+	Name          string          `json:"name" binding:"required"`
+	Key           string          `json:"key,omitempty"`        // 指定密钥值，为空时自动生成
+	ExpiresAt     *time.Time      `json:"expires_at,omitempty"` // 过期时间
+	ExpiresIn     int64           `json:"expires_in,omitempty"` // 有效期（秒），未设置expires_at时使用
+	Scopes        []string        `json:"scopes,omitempty"`
+	AllowedModels []string        `json:"allowed_models,omitempty"`
+	RateLimit     ApiKeyRateLimit `json:"rate_limit,omitempty"`
+	Workspace     string          `json:"workspace,omitempty"` // 绑定的工作区
+	// 模仿的服务商兼容配置
+	ProviderProfile string `json:"provider_profile,omitempty"`
 }
@@ -0,0 +1,135 @@
+package apikeys
+
+import (
+	"path"
+	"time"
+
+	"RobinPenn974/OpenAI-mocker/api"
+)
+
+// API密钥的权限范围
+const (
+	ScopeModelsRead       = "models:read"
+	ScopeModelsWrite      = "models:write"
+	ScopeChatWrite        = "chat:write"
+	ScopeCompletionsWrite = "completions:write"
+	ScopeEmbeddingsWrite  = "embeddings:write"
+	ScopeRerankWrite      = "rerank:write"
+	ScopeAssistantsRead   = "assistants:read"
+	ScopeAssistantsWrite  = "assistants:write"
+	ScopeFilesRead        = "files:read"
+	ScopeFilesWrite       = "files:write"
+	ScopeBatchesRead      = "batches:read"
+	ScopeBatchesWrite     = "batches:write"
+	ScopeFineTuningRead   = "fine_tuning:read"
+	ScopeFineTuningWrite  = "fine_tuning:write"
+)
+
+// 所有支持的权限范围
+var supportedScopes = []string{
+	ScopeModelsRead,
+	ScopeModelsWrite,
+	ScopeChatWrite,
+	ScopeCompletionsWrite,
+	ScopeEmbeddingsWrite,
+	ScopeRerankWrite,
+	ScopeAssistantsRead,
+	ScopeAssistantsWrite,
+	ScopeFilesRead,
+	ScopeFilesWrite,
+	ScopeBatchesRead,
+	ScopeBatchesWrite,
+	ScopeFineTuningRead,
+	ScopeFineTuningWrite,
+}
+
+// IsSupportedScope 检查是否为支持的权限范围
+func IsSupportedScope(scope string) bool {
+	for _, supported := range supportedScopes {
+		if scope == supported {
+			return true
+		}
+	}
+	return false
+}
+
+// Key API密钥记录，密钥本身只保存哈希值
+type Key struct {
+	ID            string              `json:"id"`
+	Name          string              `json:"name"`
+	Hash          string              `json:"hash"`       // 密钥的SHA-256哈希
+	MaskedKey     string              `json:"masked_key"` // 用于展示的掩码密钥
+	CreatedAt     time.Time           `json:"created_at"`
+	ExpiresAt     *time.Time          `json:"expires_at,omitempty"`
+	LastUsedAt    *time.Time          `json:"last_used_at,omitempty"`
+	Revoked       bool                `json:"revoked"`
+	Scopes        []string            `json:"scopes,omitempty"`         // 为空时拥有所有权限
+	AllowedModels []string            `json:"allowed_models,omitempty"` // 为空时可访问所有模型，支持glob
+	RateLimit     api.ApiKeyRateLimit `json:"rate_limit"`
+	Workspace     string              `json:"workspace,omitempty"` // 绑定的工作区，为空时可通过请求头选择
+	// 模仿的服务商，优先于模型和配置中的设置
+	ProviderProfile string `json:"provider_profile,omitempty"`
+	UsageCount      int64  `json:"usage_count"`
+}
+
+// IsExpired 检查密钥是否已过期
+func (k Key) IsExpired(now time.Time) bool {
+	return k.ExpiresAt != nil && !now.Before(*k.ExpiresAt)
+}
+
+// HasScope 检查密钥是否拥有指定权限
+func (k Key) HasScope(scope string) bool {
+	if len(k.Scopes) == 0 || scope == "" {
+		return true
+	}
+	for _, s := range k.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsModel 检查密钥是否可以访问指定模型，设置了白名单时不允许空的模型ID
+func (k Key) AllowsModel(modelID string) bool {
+	if len(k.AllowedModels) == 0 {
+		return true
+	}
+	if modelID == "" {
+		return false
+	}
+	for _, pattern := range k.AllowedModels {
+		if matched, _ := path.Match(pattern, modelID); matched || pattern == modelID {
+			return true
+		}
+	}
+	return false
+}
+
+// maskKey 生成用于展示的掩码密钥，只保留开头和末尾几位
+func maskKey(secret string) string {
+	if len(secret) <= 15 {
+		return "***"
+	}
+	return secret[:11] + "..." + secret[len(secret)-4:]
+}
+
+// Info 返回用于展示的密钥信息，不包含完整密钥
+func (k Key) Info() api.ApiKey {
+	return api.ApiKey{
+		ID:              k.ID,
+		Name:            k.Name,
+		MaskedKey:       k.MaskedKey,
+		CreatedAt:       k.CreatedAt,
+		ExpiresAt:       k.ExpiresAt,
+		LastUsedAt:      k.LastUsedAt,
+		Revoked:         k.Revoked,
+		Expired:         k.IsExpired(time.Now()),
+		Scopes:          k.Scopes,
+		AllowedModels:   k.AllowedModels,
+		RateLimit:       k.RateLimit,
+		Workspace:       k.Workspace,
+		ProviderProfile: k.ProviderProfile,
+		UsageCount:      k.UsageCount,
+	}
+}
@@ -0,0 +1,459 @@
+package apikeys
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"RobinPenn974/OpenAI-mocker/api"
+	"RobinPenn974/OpenAI-mocker/atomicfile"
+	"RobinPenn974/OpenAI-mocker/provider"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// 默认密钥存储目录
+	defaultKeyDir = "auth_data"
+	// 默认密钥存储文件名
+	defaultKeyFile = "keys.json"
+)
+
+var (
+	ErrKeyNotFound = errors.New("API key not found")
+	ErrKeyRevoked  = errors.New("API key has been revoked")
+	ErrKeyExpired  = errors.New("API key has expired")
+)
+
+// KeyOptions 创建API密钥的选项
+type KeyOptions struct {
+	Name          string
+	Key           string // 指定密钥值，为空时自动生成
+	ExpiresAt     *time.Time
+	Scopes        []string
+	AllowedModels []string
+	RateLimit     api.ApiKeyRateLimit
+	Workspace     string
+	// 模仿的服务商兼容配置
+	ProviderProfile string
+}
+
+// OptionsFromRequest 将创建密钥的请求转换为选项，未指定过期时间时按有效期计算
+func OptionsFromRequest(req api.CreateKeyRequest) KeyOptions {
+	expiresAt := req.ExpiresAt
+	if expiresAt == nil && req.ExpiresIn > 0 {
+		t := time.Now().UTC().Add(time.Duration(req.ExpiresIn) * time.Second)
+		expiresAt = &t
+	}
+
+	return KeyOptions{
+		Name:            req.Name,
+		Key:             req.Key,
+		ExpiresAt:       expiresAt,
+		Scopes:          req.Scopes,
+		AllowedModels:   req.AllowedModels,
+		RateLimit:       req.RateLimit,
+		Workspace:       req.Workspace,
+		ProviderProfile: req.ProviderProfile,
+	}
+}
+
+// rateWindow 固定一分钟窗口的请求计数
+type rateWindow struct {
+	start time.Time
+	count int
+}
+
+// keyUsage 尚未合并到密钥记录中的使用情况
+type keyUsage struct {
+	lastUsedAt time.Time
+	count      int64
+}
+
+// KeyStore 管理API密钥，提供持久化存储能力
+// 密钥的使用情况只在内存中累计，由 Flush 或下一次修改密钥时写入文件，记录使用情况不会等待磁盘写入
+type KeyStore struct {
+	keys       map[string]Key // 以密钥哈希为键
+	windows    map[string]*rateWindow
+	rateLimit  api.ApiKeyRateLimit // 未设置速率限制的密钥使用的默认限制
+	dirty      bool                // 内存中的使用情况尚未写入文件
+	mu         sync.RWMutex
+	usage      map[string]keyUsage // 以密钥哈希为键
+	usageMu    sync.Mutex
+	storageDir string
+	filename   string
+}
+
+// NewKeyStore 创建一个新的密钥存储
+func NewKeyStore(storageDir, filename string) *KeyStore {
+	if storageDir == "" {
+		storageDir = defaultKeyDir
+	}
+	if filename == "" {
+		filename = defaultKeyFile
+	}
+
+	store := &KeyStore{
+		keys:       make(map[string]Key),
+		windows:    make(map[string]*rateWindow),
+		usage:      make(map[string]keyUsage),
+		storageDir: storageDir,
+		filename:   filename,
+	}
+
+	// 加载密钥数据
+	if err := store.loadKeys(); err != nil {
+		fmt.Printf("Error loading API keys: %v\n", err)
+	}
+
+	return store
+}
+
+// GenerateKey 生成一个新的密钥值
+func GenerateKey() (string, error) {
+	bytes := make([]byte, 16)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return "sk-mock-" + hex.EncodeToString(bytes), nil
+}
+
+// CreateKey 创建API密钥，返回密钥记录和完整的密钥值（只在创建时返回）
+func (s *KeyStore) CreateKey(opts KeyOptions) (Key, string, error) {
+	for _, scope := range opts.Scopes {
+		if !IsSupportedScope(scope) {
+			return Key{}, "", fmt.Errorf("invalid scope '%s'", scope)
+		}
+	}
+	for _, pattern := range opts.AllowedModels {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return Key{}, "", fmt.Errorf("invalid model pattern '%s'", pattern)
+		}
+	}
+	if opts.RateLimit.RequestsPerMinute < 0 {
+		return Key{}, "", errors.New("requests_per_minute must not be negative")
+	}
+	if opts.ProviderProfile != "" && !provider.IsSupportedProfile(opts.ProviderProfile) {
+		return Key{}, "", fmt.Errorf("invalid provider_profile '%s'", opts.ProviderProfile)
+	}
+
+	secret := opts.Key
+	if secret == "" {
+		generated, err := GenerateKey()
+		if err != nil {
+			return Key{}, "", err
+		}
+		secret = generated
+	}
+
+	key := Key{
+		ID:              "key-" + uuid.New().String()[:8],
+		Name:            opts.Name,
+		Hash:            hashKey(secret),
+		MaskedKey:       maskKey(secret),
+		CreatedAt:       time.Now().UTC(),
+		ExpiresAt:       opts.ExpiresAt,
+		Scopes:          opts.Scopes,
+		AllowedModels:   opts.AllowedModels,
+		RateLimit:       opts.RateLimit,
+		Workspace:       opts.Workspace,
+		ProviderProfile: opts.ProviderProfile,
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.keys[key.Hash]; exists {
+		return Key{}, "", errors.New("API key already exists")
+	}
+
+	keys := s.copyKeys()
+	keys[key.Hash] = key
+	if err := s.writeKeysToFile(keys); err != nil {
+		return Key{}, "", err
+	}
+	s.keys = keys
+	s.dirty = false
+
+	return key, secret, nil
+}
+
+// Authenticate 校验密钥值，返回密钥记录；已吊销或过期的密钥返回错误
+func (s *KeyStore) Authenticate(secret string) (Key, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	key, exists := s.keys[hashKey(secret)]
+	if !exists {
+		return Key{}, ErrKeyNotFound
+	}
+	if key.Revoked {
+		return key, ErrKeyRevoked
+	}
+	if key.IsExpired(time.Now()) {
+		return key, ErrKeyExpired
+	}
+	return key, nil
+}
+
+// SetDefaultRateLimit 设置未配置速率限制的密钥使用的默认限制
+func (s *KeyStore) SetDefaultRateLimit(limit api.ApiKeyRateLimit) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rateLimit = limit
+}
+
+// RateLimit 返回密钥生效的速率限制，密钥未设置时使用默认限制
+func (s *KeyStore) RateLimit(key Key) api.ApiKeyRateLimit {
+	if key.RateLimit.RequestsPerMinute > 0 {
+		return key.RateLimit
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.rateLimit
+}
+
+// AllowRequest 按密钥生效的速率限制计数，返回是否允许、剩余请求数和窗口重置时间
+func (s *KeyStore) AllowRequest(key Key) (bool, int, time.Duration) {
+	limit := s.RateLimit(key).RequestsPerMinute
+	if limit <= 0 {
+		return true, 0, 0
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	window, exists := s.windows[key.ID]
+	if !exists || now.Sub(window.start) >= time.Minute {
+		window = &rateWindow{start: now}
+		s.windows[key.ID] = window
+	}
+	reset := window.start.Add(time.Minute).Sub(now)
+
+	if window.count >= limit {
+		return false, 0, reset
+	}
+	window.count++
+	return true, limit - window.count, reset
+}
+
+// RecordUsage 在内存中记录密钥的最近使用时间和使用次数
+func (s *KeyStore) RecordUsage(key Key) {
+	s.usageMu.Lock()
+	defer s.usageMu.Unlock()
+
+	usage := s.usage[key.Hash]
+	usage.lastUsedAt = time.Now().UTC()
+	usage.count++
+	s.usage[key.Hash] = usage
+}
+
+// Flush 将内存中累计的使用情况写入文件，没有新的使用情况时不写入
+func (s *KeyStore) Flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.mergeUsage()
+	if !s.dirty {
+		return nil
+	}
+	if err := s.writeKeysToFile(s.keys); err != nil {
+		return err
+	}
+	s.dirty = false
+	return nil
+}
+
+// mergeUsage 将累计的使用情况合并到内存中的密钥记录，已删除的密钥的使用情况被丢弃，调用方需持有写锁
+func (s *KeyStore) mergeUsage() {
+	s.usageMu.Lock()
+	pending := s.usage
+	s.usage = make(map[string]keyUsage)
+	s.usageMu.Unlock()
+
+	if len(pending) == 0 {
+		return
+	}
+	keys := s.copyKeys()
+	for hash, usage := range pending {
+		key, exists := keys[hash]
+		if !exists {
+			continue
+		}
+		lastUsedAt := usage.lastUsedAt
+		key.LastUsedAt = &lastUsedAt
+		key.UsageCount += usage.count
+		keys[hash] = key
+		s.dirty = true
+	}
+	s.keys = keys
+}
+
+// RevokeKey 吊销密钥，参数可以是密钥ID或完整的密钥值
+func (s *KeyStore) RevokeKey(idOrKey string) (Key, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	hash, exists := s.findHash(idOrKey)
+	if !exists {
+		return Key{}, ErrKeyNotFound
+	}
+
+	s.mergeUsage()
+	keys := s.copyKeys()
+	key := keys[hash]
+	key.Revoked = true
+	keys[hash] = key
+	if err := s.writeKeysToFile(keys); err != nil {
+		return Key{}, err
+	}
+	s.keys = keys
+	s.dirty = false
+	return key, nil
+}
+
+// DeleteKey 删除密钥，参数可以是密钥ID或完整的密钥值
+func (s *KeyStore) DeleteKey(idOrKey string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	hash, exists := s.findHash(idOrKey)
+	if !exists {
+		return ErrKeyNotFound
+	}
+
+	s.mergeUsage()
+	keys := s.copyKeys()
+	delete(s.windows, keys[hash].ID)
+	delete(keys, hash)
+	if err := s.writeKeysToFile(keys); err != nil {
+		return err
+	}
+	s.keys = keys
+	s.dirty = false
+	return nil
+}
+
+// RemoveAllKeys 删除所有密钥
+func (s *KeyStore) RemoveAllKeys() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	keys := make(map[string]Key)
+	if err := s.writeKeysToFile(keys); err != nil {
+		return err
+	}
+	s.mergeUsage()
+	s.keys = keys
+	s.windows = make(map[string]*rateWindow)
+	s.dirty = false
+	return nil
+}
+
+// ListKeys 按创建时间列出所有密钥记录，包含尚未写入文件的使用情况
+func (s *KeyStore) ListKeys() []Key {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.mergeUsage()
+	result := make([]Key, 0, len(s.keys))
+	for _, key := range s.keys {
+		result = append(result, key)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].CreatedAt.Before(result[j].CreatedAt)
+	})
+	return result
+}
+
+// HasKeys 检查是否存在任何密钥（包括已吊销的密钥）
+func (s *KeyStore) HasKeys() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.keys) > 0
+}
+
+// findHash 按密钥ID或完整密钥值查找密钥哈希，调用方需持有锁
+func (s *KeyStore) findHash(idOrKey string) (string, bool) {
+	if _, exists := s.keys[hashKey(idOrKey)]; exists {
+		return hashKey(idOrKey), true
+	}
+	for hash, key := range s.keys {
+		if key.ID == idOrKey {
+			return hash, true
+		}
+	}
+	return "", false
+}
+
+// copyKeys 复制当前的密钥映射，调用方需持有锁
+func (s *KeyStore) copyKeys() map[string]Key {
+	keys := make(map[string]Key, len(s.keys))
+	for hash, key := range s.keys {
+		keys[hash] = key
+	}
+	return keys
+}
+
+// loadKeys 从文件加载密钥，文件不存在时使用空存储
+func (s *KeyStore) loadKeys() error {
+	filePath := filepath.Join(s.storageDir, s.filename)
+	data, err := os.ReadFile(filePath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error reading key file: %v", err)
+	}
+
+	var keys []Key
+	if err := json.Unmarshal(data, &keys); err != nil {
+		return fmt.Errorf("error parsing key file: %v", err)
+	}
+	for _, key := range keys {
+		s.keys[key.Hash] = key
+	}
+	return nil
+}
+
+// writeKeysToFile 将密钥记录写入文件
+func (s *KeyStore) writeKeysToFile(keys map[string]Key) error {
+	list := make([]Key, 0, len(keys))
+	for _, key := range keys {
+		list = append(list, key)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].CreatedAt.Before(list[j].CreatedAt)
+	})
+
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding API keys: %v", err)
+	}
+
+	if err := os.MkdirAll(s.storageDir, 0700); err != nil {
+		return fmt.Errorf("error creating key directory: %v", err)
+	}
+	filePath := filepath.Join(s.storageDir, s.filename)
+	if err := atomicfile.WriteFile(filePath, data, 0600); err != nil {
+		return fmt.Errorf("error writing key file: %v", err)
+	}
+	return nil
+}
+
+// hashKey 计算密钥值的SHA-256哈希
+func hashKey(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,406 @@
+package assistants
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"RobinPenn974/OpenAI-mocker/api"
+)
+
+// Plan 运行的生成结果和模拟耗时，由调用方用模板生成器预先生成
+type Plan struct {
+	ToolCalls []ToolCall    // 不为空时运行进入 requires_action 等待调用方提交工具输出
+	Chunks    []string      // 助手消息内容的流式分块，拼接后为完整内容
+	Usage     Usage         // 本阶段的token使用量，运行结束时累加到运行的用量中
+	QueuedFor time.Duration // 保持 queued 状态的时间
+	RunFor    time.Duration // 保持 in_progress 状态的时间
+}
+
+// runState 运行及其步骤、生成结果和状态切换时间
+type runState struct {
+	Run
+	steps    []*RunStep
+	plan     Plan
+	usage    Usage    // 已结束阶段累计的token使用量
+	message  *Message // 运行正在创建的助手消息
+	startAt  time.Time
+	finishAt time.Time
+}
+
+// schedule 按生成结果的模拟耗时安排状态切换时间
+func (r *runState) schedule(plan Plan, now time.Time) {
+	r.plan = plan
+	r.startAt = now.Add(plan.QueuedFor)
+	r.finishAt = r.startAt.Add(plan.RunFor)
+}
+
+// earlier 返回两个时间中较早的一个，流式返回时立即切换状态
+func earlier(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
+
+// advance 按经过的时间推进运行状态：queued → in_progress → requires_action 或 completed
+// force为true时不等待模拟耗时，直接推进到需要调用方操作或结束，返回状态变化产生的事件
+func (r *runState) advance(t *threadState, now time.Time, force bool) []Event {
+	var events []Event
+	for {
+		switch r.Status {
+		case StatusQueued:
+			if !force && now.Before(r.startAt) {
+				return events
+			}
+			events = append(events, r.start(t, earlier(r.startAt, now))...)
+		case StatusInProgress:
+			if !force && now.Before(r.finishAt) {
+				return events
+			}
+			events = append(events, r.finish(earlier(r.finishAt, now))...)
+		case StatusRequiresAction:
+			if force || r.ExpiresAt == nil || now.Unix() < *r.ExpiresAt {
+				return events
+			}
+			events = append(events, r.end(StatusExpired, time.Unix(*r.ExpiresAt, 0)))
+		case StatusCancelling:
+			events = append(events, r.end(StatusCancelled, now))
+		default:
+			return events
+		}
+	}
+}
+
+// start 开始运行，创建工具调用步骤，或创建助手消息及其消息创建步骤
+func (r *runState) start(t *threadState, at time.Time) []Event {
+	r.Status = StatusInProgress
+	r.StartedAt = unix(at)
+	events := []Event{{Name: "thread.run.in_progress", Data: r.Run}}
+
+	step := &RunStep{
+		ID:          newID("step_"),
+		Object:      "thread.run.step",
+		CreatedAt:   at.Unix(),
+		AssistantID: r.AssistantID,
+		ThreadID:    r.ThreadID,
+		RunID:       r.ID,
+		Status:      StatusInProgress,
+		Metadata:    map[string]string{},
+	}
+	r.steps = append(r.steps, step)
+
+	if len(r.plan.ToolCalls) > 0 {
+		step.Type = StepToolCalls
+		step.StepDetails = StepDetails{Type: StepToolCalls, ToolCalls: r.plan.ToolCalls}
+		return append(events,
+			Event{Name: "thread.run.step.created", Data: *step},
+			Event{Name: "thread.run.step.in_progress", Data: *step},
+		)
+	}
+
+	assistantID, runID := r.AssistantID, r.ID
+	r.message = t.addMessage(Message{
+		Status:      StatusInProgress,
+		Role:        "assistant",
+		Content:     []MessageContent{},
+		AssistantID: &assistantID,
+		RunID:       &runID,
+	}, at)
+	step.Type = StepMessageCreation
+	step.StepDetails = StepDetails{Type: StepMessageCreation, MessageCreation: &MessageCreation{MessageID: r.message.ID}}
+	return append(events,
+		Event{Name: "thread.run.step.created", Data: *step},
+		Event{Name: "thread.run.step.in_progress", Data: *step},
+		Event{Name: "thread.message.created", Data: *r.message},
+		Event{Name: "thread.message.in_progress", Data: *r.message},
+	)
+}
+
+// finish 结束当前阶段：有工具调用时等待调用方提交工具输出，否则写入助手消息并完成运行
+func (r *runState) finish(at time.Time) []Event {
+	if len(r.plan.ToolCalls) > 0 {
+		action := &RequiredAction{Type: "submit_tool_outputs"}
+		action.SubmitToolOutputs.ToolCalls = r.plan.ToolCalls
+		r.RequiredAction = action
+		r.Status = StatusRequiresAction
+		return []Event{{Name: "thread.run.requires_action", Data: r.Run}}
+	}
+
+	var events []Event
+	for _, chunk := range r.plan.Chunks {
+		delta := MessageDelta{ID: r.message.ID, Object: "thread.message.delta"}
+		delta.Delta.Content = []MessageDeltaContent{{Index: 0, Type: "text", Text: MessageText{Value: chunk, Annotations: []interface{}{}}}}
+		events = append(events, Event{Name: "thread.message.delta", Data: delta})
+	}
+
+	r.message.Content = []MessageContent{textContent(strings.Join(r.plan.Chunks, ""))}
+	r.message.Status = StatusCompleted
+	r.message.CompletedAt = unix(at)
+	events = append(events, Event{Name: "thread.message.completed", Data: *r.message})
+
+	step := r.steps[len(r.steps)-1]
+	stepUsage := r.plan.Usage
+	step.Status = StatusCompleted
+	step.CompletedAt = unix(at)
+	step.Usage = &stepUsage
+	events = append(events, Event{Name: "thread.run.step.completed", Data: *step})
+
+	r.usage = r.usage.add(r.plan.Usage)
+	usage := r.usage
+	r.Status = StatusCompleted
+	r.CompletedAt = unix(at)
+	r.ExpiresAt = nil
+	r.Usage = &usage
+	return append(events, Event{Name: "thread.run.completed", Data: r.Run})
+}
+
+// end 以取消或过期结束运行，进行中的步骤和消息一并结束
+func (r *runState) end(status string, at time.Time) Event {
+	r.Status = status
+	r.RequiredAction = nil
+	r.ExpiresAt = nil
+	for _, step := range r.steps {
+		if step.Status != StatusInProgress {
+			continue
+		}
+		step.Status = status
+		if status == StatusExpired {
+			step.ExpiredAt = unix(at)
+		} else {
+			step.CancelledAt = unix(at)
+		}
+	}
+	if r.message != nil && r.message.Status == StatusInProgress {
+		r.message.Status = StatusIncomplete
+		r.message.IncompleteAt = unix(at)
+		r.message.IncompleteDetails = &IncompleteDetails{Reason: "run_" + status}
+	}
+	if status == StatusExpired {
+		return Event{Name: "thread.run.expired", Data: r.Run}
+	}
+	r.CancelledAt = unix(at)
+	return Event{Name: "thread.run.cancelled", Data: r.Run}
+}
+
+// CreateRun 在线程上创建运行，先追加运行的附加消息，线程有进行中的运行时返回错误
+func (s *Store) CreateRun(threadID string, run Run, additionalMessages []Message, plan Plan) (Run, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, err := s.thread(threadID)
+	if err != nil {
+		return Run{}, err
+	}
+	if active := t.activeRun(); active != nil {
+		return Run{}, fmt.Errorf("Thread %s already has an active run %s.", t.ID, active.ID)
+	}
+
+	now := time.Now()
+	for _, message := range additionalMessages {
+		t.addMessage(message, now)
+	}
+
+	run.ID = newID("run_")
+	run.Object = "thread.run"
+	run.CreatedAt = now.Unix()
+	run.ThreadID = t.ID
+	run.Status = StatusQueued
+	run.ExpiresAt = unix(now.Add(runExpiresIn * time.Second))
+	run.Metadata = emptyMetadata(run.Metadata)
+	if run.Tools == nil {
+		run.Tools = []api.AssistantTool{}
+	}
+	state := &runState{Run: run}
+	state.schedule(plan, now)
+	t.runs = append(t.runs, state)
+	return state.Run, nil
+}
+
+// GetRun 获取线程中指定ID的运行
+func (s *Store) GetRun(threadID, runID string) (Run, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, r, err := s.run(threadID, runID)
+	if err != nil {
+		return Run{}, err
+	}
+	return r.Run, nil
+}
+
+// UpdateRun 修改运行的元数据
+func (s *Store) UpdateRun(threadID, runID string, metadata map[string]string) (Run, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, r, err := s.run(threadID, runID)
+	if err != nil {
+		return Run{}, err
+	}
+	r.Metadata = emptyMetadata(metadata)
+	return r.Run, nil
+}
+
+// ListRuns 分页列出线程中的运行
+func (s *Store) ListRuns(threadID string, params api.ListParams) ([]Run, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, err := s.thread(threadID)
+	if err != nil {
+		return nil, false, err
+	}
+	all := make([]Run, 0, len(t.runs))
+	for _, r := range t.runs {
+		all = append(all, r.Run)
+	}
+	data, hasMore := api.Paginate(all, func(r Run) string { return r.ID }, params)
+	return data, hasMore, nil
+}
+
+// CancelRun 取消进行中的运行，运行先进入 cancelling 状态，下次查询时变为 cancelled
+func (s *Store) CancelRun(threadID, runID string) (Run, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, r, err := s.run(threadID, runID)
+	if err != nil {
+		return Run{}, err
+	}
+	if !r.Active() || r.Status == StatusCancelling {
+		return Run{}, fmt.Errorf("Cannot cancel run with status '%s'.", r.Status)
+	}
+	r.Status = StatusCancelling
+	return r.Run, nil
+}
+
+// SubmitToolOutputs 提交运行等待的全部工具输出，运行以新的生成结果重新排队，返回产生的事件
+func (s *Store) SubmitToolOutputs(threadID, runID string, outputs []api.ToolOutput, plan Plan) (Run, []Event, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, r, err := s.run(threadID, runID)
+	if err != nil {
+		return Run{}, nil, err
+	}
+	if r.Status != StatusRequiresAction {
+		return Run{}, nil, fmt.Errorf("Runs in status \"%s\" do not accept tool outputs.", r.Status)
+	}
+
+	submitted := make(map[string]string, len(outputs))
+	got := make([]string, 0, len(outputs))
+	for _, output := range outputs {
+		submitted[output.ToolCallID] = output.Output
+		got = append(got, output.ToolCallID)
+	}
+	expected := make([]string, 0, len(r.plan.ToolCalls))
+	missing := false
+	for _, call := range r.plan.ToolCalls {
+		expected = append(expected, call.ID)
+		if _, ok := submitted[call.ID]; !ok {
+			missing = true
+		}
+	}
+	if missing || len(submitted) != len(expected) {
+		return Run{}, nil, fmt.Errorf("Expected tool outputs for call_ids %s, got %s", formatIDs(expected), formatIDs(got))
+	}
+
+	// 工具调用步骤写入输出后完成
+	now := time.Now()
+	step := r.steps[len(r.steps)-1]
+	calls := make([]ToolCall, len(r.plan.ToolCalls))
+	for i, call := range r.plan.ToolCalls {
+		output := submitted[call.ID]
+		call.Function.Output = &output
+		calls[i] = call
+	}
+	stepUsage := r.plan.Usage
+	step.StepDetails = StepDetails{Type: StepToolCalls, ToolCalls: calls}
+	step.Status = StatusCompleted
+	step.CompletedAt = unix(now)
+	step.Usage = &stepUsage
+	events := []Event{{Name: "thread.run.step.completed", Data: *step}}
+
+	r.usage = r.usage.add(r.plan.Usage)
+	r.RequiredAction = nil
+	r.Status = StatusQueued
+	r.schedule(plan, now)
+	events = append(events, Event{Name: "thread.run.queued", Data: r.Run})
+	return r.Run, events, nil
+}
+
+// StreamRun 不等待模拟耗时，将运行推进到需要调用方操作或结束，返回状态变化产生的事件
+func (s *Store) StreamRun(threadID, runID string) ([]Event, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// 不按时间推进，避免在返回事件前已切换状态
+	t, err := s.findThread(threadID)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range t.runs {
+		if r.ID == runID {
+			return r.advance(t, time.Now(), true), nil
+		}
+	}
+	return nil, &NotFoundError{Object: "run", ID: runID}
+}
+
+// GetRunStep 获取运行中指定ID的步骤
+func (s *Store) GetRunStep(threadID, runID, stepID string) (RunStep, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, r, err := s.run(threadID, runID)
+	if err != nil {
+		return RunStep{}, err
+	}
+	for _, step := range r.steps {
+		if step.ID == stepID {
+			return *step, nil
+		}
+	}
+	return RunStep{}, &NotFoundError{Object: "run step", ID: stepID}
+}
+
+// ListRunSteps 分页列出运行的步骤
+func (s *Store) ListRunSteps(threadID, runID string, params api.ListParams) ([]RunStep, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, r, err := s.run(threadID, runID)
+	if err != nil {
+		return nil, false, err
+	}
+	all := make([]RunStep, 0, len(r.steps))
+	for _, step := range r.steps {
+		all = append(all, *step)
+	}
+	data, hasMore := api.Paginate(all, func(step RunStep) string { return step.ID }, params)
+	return data, hasMore, nil
+}
+
+// run 查找线程中的运行并推进其状态，调用方需持有锁
+func (s *Store) run(threadID, runID string) (*threadState, *runState, error) {
+	t, err := s.thread(threadID)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, r := range t.runs {
+		if r.ID == runID {
+			return t, r, nil
+		}
+	}
+	return nil, nil, &NotFoundError{Object: "run", ID: runID}
+}
+
+// formatIDs 按OpenAI错误信息的格式输出ID列表
+func formatIDs(ids []string) string {
+	quoted := make([]string, len(ids))
+	for i, id := range ids {
+		quoted[i] = "'" + id + "'"
+	}
+	return "[" + strings.Join(quoted, ", ") + "]"
+}
@@ -0,0 +1,360 @@
+package assistants
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"RobinPenn974/OpenAI-mocker/api"
+
+	"github.com/google/uuid"
+)
+
+// NotFoundError 请求的对象不存在
+type NotFoundError struct {
+	Object string // assistant, thread, message, run, run step
+	ID     string
+}
+
+// Error 返回与OpenAI一致的错误信息
+func (e *NotFoundError) Error() string {
+	return fmt.Sprintf("No %s found with id '%s'.", e.Object, e.ID)
+}
+
+// Store 在内存中保存一个工作区的助手、线程、消息、运行和运行步骤
+type Store struct {
+	assistants []*Assistant // 按创建顺序排列
+	threads    map[string]*threadState
+	mu         sync.Mutex
+}
+
+// threadState 线程及其消息和运行，均按创建顺序排列
+type threadState struct {
+	Thread
+	messages []*Message
+	runs     []*runState
+}
+
+// NewStore 创建一个新的存储
+func NewStore() *Store {
+	return &Store{
+		threads: make(map[string]*threadState),
+	}
+}
+
+// newID 生成带前缀的对象ID，如 asst_、thread_、msg_
+func newID(prefix string) string {
+	return prefix + strings.ReplaceAll(uuid.NewString(), "-", "")[:24]
+}
+
+// unix 返回时间的Unix时间戳指针
+func unix(t time.Time) *int64 {
+	timestamp := t.Unix()
+	return &timestamp
+}
+
+// emptyMetadata 未设置元数据时返回空对象，与OpenAI一致
+func emptyMetadata(metadata map[string]string) map[string]string {
+	if metadata == nil {
+		return map[string]string{}
+	}
+	return metadata
+}
+
+// CreateAssistant 保存新的助手并分配ID
+func (s *Store) CreateAssistant(assistant Assistant) Assistant {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	assistant.ID = newID("asst_")
+	assistant.Object = "assistant"
+	assistant.CreatedAt = time.Now().Unix()
+	assistant.Metadata = emptyMetadata(assistant.Metadata)
+	if assistant.Tools == nil {
+		assistant.Tools = []api.AssistantTool{}
+	}
+	if assistant.ToolResources == nil {
+		assistant.ToolResources = map[string]interface{}{}
+	}
+	if assistant.ResponseFormat == nil {
+		assistant.ResponseFormat = "auto"
+	}
+	s.assistants = append(s.assistants, &assistant)
+	return assistant
+}
+
+// GetAssistant 获取指定ID的助手
+func (s *Store) GetAssistant(id string) (Assistant, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	assistant, err := s.findAssistant(id)
+	if err != nil {
+		return Assistant{}, err
+	}
+	return *assistant, nil
+}
+
+// UpdateAssistant 修改指定ID的助手
+func (s *Store) UpdateAssistant(id string, update func(*Assistant)) (Assistant, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	assistant, err := s.findAssistant(id)
+	if err != nil {
+		return Assistant{}, err
+	}
+	update(assistant)
+	return *assistant, nil
+}
+
+// DeleteAssistant 删除指定ID的助手，已有的运行不受影响
+func (s *Store) DeleteAssistant(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, assistant := range s.assistants {
+		if assistant.ID == id {
+			s.assistants = append(s.assistants[:i], s.assistants[i+1:]...)
+			return nil
+		}
+	}
+	return &NotFoundError{Object: "assistant", ID: id}
+}
+
+// ListAssistants 分页列出助手
+func (s *Store) ListAssistants(params api.ListParams) ([]Assistant, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	all := make([]Assistant, 0, len(s.assistants))
+	for _, assistant := range s.assistants {
+		all = append(all, *assistant)
+	}
+	return api.Paginate(all, func(a Assistant) string { return a.ID }, params)
+}
+
+// findAssistant 查找助手，调用方需持有锁
+func (s *Store) findAssistant(id string) (*Assistant, error) {
+	for _, assistant := range s.assistants {
+		if assistant.ID == id {
+			return assistant, nil
+		}
+	}
+	return nil, &NotFoundError{Object: "assistant", ID: id}
+}
+
+// CreateThread 保存新的线程及其初始消息
+func (s *Store) CreateThread(thread Thread, messages []Message) Thread {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	thread.ID = newID("thread_")
+	thread.Object = "thread"
+	thread.CreatedAt = now.Unix()
+	thread.Metadata = emptyMetadata(thread.Metadata)
+	if thread.ToolResources == nil {
+		thread.ToolResources = map[string]interface{}{}
+	}
+	state := &threadState{Thread: thread}
+	for _, message := range messages {
+		state.addMessage(message, now)
+	}
+	s.threads[thread.ID] = state
+	return thread
+}
+
+// GetThread 获取指定ID的线程
+func (s *Store) GetThread(id string) (Thread, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, err := s.thread(id)
+	if err != nil {
+		return Thread{}, err
+	}
+	return t.Thread, nil
+}
+
+// UpdateThread 修改指定ID线程的元数据和工具资源
+func (s *Store) UpdateThread(id string, update func(*Thread)) (Thread, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, err := s.thread(id)
+	if err != nil {
+		return Thread{}, err
+	}
+	update(&t.Thread)
+	return t.Thread, nil
+}
+
+// DeleteThread 删除指定ID的线程及其消息和运行
+func (s *Store) DeleteThread(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.threads[id]; !exists {
+		return &NotFoundError{Object: "thread", ID: id}
+	}
+	delete(s.threads, id)
+	return nil
+}
+
+// CreateMessage 向线程添加消息，线程有进行中的运行时返回错误
+func (s *Store) CreateMessage(threadID string, message Message) (Message, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, err := s.thread(threadID)
+	if err != nil {
+		return Message{}, err
+	}
+	if active := t.activeRun(); active != nil {
+		return Message{}, fmt.Errorf("Can't add messages to %s while a run %s is active.", t.ID, active.ID)
+	}
+	return *t.addMessage(message, time.Now()), nil
+}
+
+// GetMessage 获取线程中指定ID的消息
+func (s *Store) GetMessage(threadID, messageID string) (Message, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	message, err := s.message(threadID, messageID)
+	if err != nil {
+		return Message{}, err
+	}
+	return *message, nil
+}
+
+// UpdateMessage 修改消息的元数据
+func (s *Store) UpdateMessage(threadID, messageID string, metadata map[string]string) (Message, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	message, err := s.message(threadID, messageID)
+	if err != nil {
+		return Message{}, err
+	}
+	message.Metadata = emptyMetadata(metadata)
+	return *message, nil
+}
+
+// DeleteMessage 删除线程中指定ID的消息
+func (s *Store) DeleteMessage(threadID, messageID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, err := s.thread(threadID)
+	if err != nil {
+		return err
+	}
+	for i, message := range t.messages {
+		if message.ID == messageID {
+			t.messages = append(t.messages[:i], t.messages[i+1:]...)
+			return nil
+		}
+	}
+	return &NotFoundError{Object: "message", ID: messageID}
+}
+
+// ListMessages 分页列出线程中的消息，runID不为空时只列出该运行创建的消息
+func (s *Store) ListMessages(threadID, runID string, params api.ListParams) ([]Message, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, err := s.thread(threadID)
+	if err != nil {
+		return nil, false, err
+	}
+	all := make([]Message, 0, len(t.messages))
+	for _, message := range t.messages {
+		if runID == "" || (message.RunID != nil && *message.RunID == runID) {
+			all = append(all, *message)
+		}
+	}
+	data, hasMore := api.Paginate(all, func(m Message) string { return m.ID }, params)
+	return data, hasMore, nil
+}
+
+// ThreadMessages 按创建顺序返回线程中的所有消息，用于生成运行的回复
+func (s *Store) ThreadMessages(threadID string) ([]Message, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, err := s.thread(threadID)
+	if err != nil {
+		return nil, err
+	}
+	messages := make([]Message, 0, len(t.messages))
+	for _, message := range t.messages {
+		messages = append(messages, *message)
+	}
+	return messages, nil
+}
+
+// findThread 查找线程，不推进运行状态，调用方需持有锁
+func (s *Store) findThread(id string) (*threadState, error) {
+	t, exists := s.threads[id]
+	if !exists {
+		return nil, &NotFoundError{Object: "thread", ID: id}
+	}
+	return t, nil
+}
+
+// thread 查找线程并按经过的时间推进其运行的状态，调用方需持有锁
+func (s *Store) thread(id string) (*threadState, error) {
+	t, err := s.findThread(id)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	for _, r := range t.runs {
+		r.advance(t, now, false)
+	}
+	return t, nil
+}
+
+// message 查找线程中的消息，调用方需持有锁
+func (s *Store) message(threadID, messageID string) (*Message, error) {
+	t, err := s.thread(threadID)
+	if err != nil {
+		return nil, err
+	}
+	for _, message := range t.messages {
+		if message.ID == messageID {
+			return message, nil
+		}
+	}
+	return nil, &NotFoundError{Object: "message", ID: messageID}
+}
+
+// addMessage 为消息分配ID并追加到线程
+func (t *threadState) addMessage(message Message, now time.Time) *Message {
+	message.ID = newID("msg_")
+	message.Object = "thread.message"
+	message.CreatedAt = now.Unix()
+	message.ThreadID = t.ID
+	message.Metadata = emptyMetadata(message.Metadata)
+	if message.Status == "" {
+		message.Status = StatusCompleted
+	}
+	if message.Attachments == nil {
+		message.Attachments = []interface{}{}
+	}
+	t.messages = append(t.messages, &message)
+	return &message
+}
+
+// activeRun 返回线程中进行中的运行
+func (t *threadState) activeRun() *runState {
+	for _, r := range t.runs {
+		if r.Active() {
+			return r
+		}
+	}
+	return nil
+}
@@ -0,0 +1,192 @@
+package assistants
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+
+	"RobinPenn974/OpenAI-mocker/api"
+)
+
+// tool_choice 的字符串取值
+const (
+	ToolChoiceNone     = "none"
+	ToolChoiceAuto     = "auto"
+	ToolChoiceRequired = "required"
+)
+
+// 生成参数时嵌套对象和数组的最大深度，避免递归的schema无限展开
+const maxArgumentDepth = 5
+
+// ValidateTools 校验工具类型和函数工具的定义，返回出错的参数名和错误
+func ValidateTools(tools []api.AssistantTool) (string, error) {
+	for i, tool := range tools {
+		if !IsSupportedTool(tool.Type) {
+			return fmt.Sprintf("tools[%d].type", i), fmt.Errorf("Invalid value: '%s'. Supported values are: '%s', '%s', and '%s'.", tool.Type, ToolCodeInterpreter, ToolFileSearch, ToolFunction)
+		}
+		if tool.Type == ToolFunction && (tool.Function == nil || tool.Function.Name == "") {
+			return fmt.Sprintf("tools[%d].function", i), fmt.Errorf("Missing required parameter: 'tools[%d].function'.", i)
+		}
+	}
+	return "", nil
+}
+
+// ValidateToolChoice 校验 tool_choice：none、auto、required，或指定 tools 中已定义的工具
+func ValidateToolChoice(tools []api.AssistantTool, toolChoice interface{}) error {
+	switch choice := toolChoice.(type) {
+	case nil:
+		return nil
+	case string:
+		if choice == ToolChoiceNone || choice == ToolChoiceAuto || choice == ToolChoiceRequired {
+			return nil
+		}
+		return fmt.Errorf("Invalid value: '%s'. Supported values are: '%s', '%s', and '%s'.", choice, ToolChoiceNone, ToolChoiceAuto, ToolChoiceRequired)
+	case map[string]interface{}:
+		choiceType, _ := choice["type"].(string)
+		if choiceType != ToolFunction {
+			if !IsSupportedTool(choiceType) {
+				return fmt.Errorf("Invalid value: '%s'. Supported values are: '%s', '%s', and '%s'.", choiceType, ToolCodeInterpreter, ToolFileSearch, ToolFunction)
+			}
+			return nil
+		}
+		name := choiceFunctionName(choice)
+		for _, tool := range tools {
+			if tool.Type == ToolFunction && tool.Function != nil && tool.Function.Name == name {
+				return nil
+			}
+		}
+		return fmt.Errorf("Invalid 'tool_choice': function '%s' is not defined in tools.", name)
+	}
+	return errors.New("Invalid 'tool_choice': expected a string or an object.")
+}
+
+// choiceFunctionName 返回 tool_choice 指定的函数名
+func choiceFunctionName(choice map[string]interface{}) string {
+	function, _ := choice["function"].(map[string]interface{})
+	name, _ := function["name"].(string)
+	return name
+}
+
+// FunctionCalls 返回运行应调用的函数工具：tool_choice 指定函数时调用该函数，为 none 或指定其他工具时不调用，
+// 否则调用第一个函数工具，参数按函数的参数定义生成
+func FunctionCalls(tools []api.AssistantTool, toolChoice interface{}) []ToolCall {
+	var selected *api.ToolFunction
+	for _, tool := range tools {
+		if tool.Type == ToolFunction && tool.Function != nil {
+			selected = tool.Function
+			break
+		}
+	}
+
+	switch choice := toolChoice.(type) {
+	case string:
+		if choice == ToolChoiceNone {
+			return nil
+		}
+	case map[string]interface{}:
+		if choiceType, _ := choice["type"].(string); choiceType != ToolFunction {
+			return nil
+		}
+		name := choiceFunctionName(choice)
+		for _, tool := range tools {
+			if tool.Type == ToolFunction && tool.Function != nil && tool.Function.Name == name {
+				selected = tool.Function
+			}
+		}
+	}
+	if selected == nil {
+		return nil
+	}
+
+	return []ToolCall{{
+		ID:   newID("call_"),
+		Type: ToolFunction,
+		Function: FunctionCall{
+			Name:      selected.Name,
+			Arguments: MockArguments(selected.Parameters),
+		},
+	}}
+}
+
+// MockArguments 按函数参数的JSON Schema生成确定性的模拟参数，
+// 包含所有属性，枚举取第一个值，无法解析的schema返回空对象
+func MockArguments(parameters json.RawMessage) string {
+	var schema map[string]interface{}
+	if len(parameters) == 0 || json.Unmarshal(parameters, &schema) != nil {
+		return "{}"
+	}
+	value := mockValue("value", schema, 0)
+	if _, ok := value.(map[string]interface{}); !ok {
+		return "{}"
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return "{}"
+	}
+	return string(data)
+}
+
+// mockValue 按schema生成属性name的模拟值
+func mockValue(name string, schema map[string]interface{}, depth int) interface{} {
+	if values, ok := schema["enum"].([]interface{}); ok && len(values) > 0 {
+		return values[0]
+	}
+	if value, ok := schema["const"]; ok {
+		return value
+	}
+	if value, ok := schema["default"]; ok {
+		return value
+	}
+
+	schemaType, _ := schema["type"].(string)
+	if types, ok := schema["type"].([]interface{}); ok {
+		// 联合类型取第一个非null的类型
+		for _, t := range types {
+			if s, ok := t.(string); ok && s != "null" {
+				schemaType = s
+				break
+			}
+		}
+	}
+	if schemaType == "" {
+		if _, ok := schema["properties"]; ok {
+			schemaType = "object"
+		}
+	}
+
+	switch schemaType {
+	case "object":
+		result := map[string]interface{}{}
+		if depth >= maxArgumentDepth {
+			return result
+		}
+		properties, _ := schema["properties"].(map[string]interface{})
+		names := make([]string, 0, len(properties))
+		for property := range properties {
+			names = append(names, property)
+		}
+		sort.Strings(names)
+		for _, property := range names {
+			propertySchema, _ := properties[property].(map[string]interface{})
+			result[property] = mockValue(property, propertySchema, depth+1)
+		}
+		return result
+	case "array":
+		if depth >= maxArgumentDepth {
+			return []interface{}{}
+		}
+		items, _ := schema["items"].(map[string]interface{})
+		return []interface{}{mockValue(name, items, depth+1)}
+	case "integer":
+		return 1
+	case "number":
+		return 1.5
+	case "boolean":
+		return true
+	case "null":
+		return nil
+	default:
+		return "mock_" + name
+	}
+}
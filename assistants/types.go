@@ -0,0 +1,302 @@
+package assistants
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"RobinPenn974/OpenAI-mocker/api"
+)
+
+// 运行和运行步骤的状态
+const (
+	StatusQueued         = "queued"
+	StatusInProgress     = "in_progress"
+	StatusRequiresAction = "requires_action"
+	StatusCancelling     = "cancelling"
+	StatusCancelled      = "cancelled"
+	StatusCompleted      = "completed"
+	StatusFailed         = "failed"
+	StatusExpired        = "expired"
+	StatusIncomplete     = "incomplete"
+)
+
+// 工具类型
+const (
+	ToolCodeInterpreter = "code_interpreter"
+	ToolFileSearch      = "file_search"
+	ToolFunction        = "function"
+)
+
+// 运行步骤类型
+const (
+	StepMessageCreation = "message_creation"
+	StepToolCalls       = "tool_calls"
+)
+
+// 运行在创建后的有效期（秒），与OpenAI一致
+const runExpiresIn = 600
+
+// IsSupportedTool 检查是否为支持的工具类型
+func IsSupportedTool(toolType string) bool {
+	switch toolType {
+	case ToolCodeInterpreter, ToolFileSearch, ToolFunction:
+		return true
+	}
+	return false
+}
+
+// Assistant 助手
+type Assistant struct {
+	ID             string                 `json:"id"`
+	Object         string                 `json:"object"`
+	CreatedAt      int64                  `json:"created_at"`
+	Name           *string                `json:"name"`
+	Description    *string                `json:"description"`
+	Model          string                 `json:"model"`
+	Instructions   *string                `json:"instructions"`
+	Tools          []api.AssistantTool    `json:"tools"`
+	ToolResources  map[string]interface{} `json:"tool_resources"`
+	Metadata       map[string]string      `json:"metadata"`
+	Temperature    *float64               `json:"temperature"`
+	TopP           *float64               `json:"top_p"`
+	ResponseFormat interface{}            `json:"response_format"`
+}
+
+// Thread 线程
+type Thread struct {
+	ID            string                 `json:"id"`
+	Object        string                 `json:"object"`
+	CreatedAt     int64                  `json:"created_at"`
+	ToolResources map[string]interface{} `json:"tool_resources"`
+	Metadata      map[string]string      `json:"metadata"`
+}
+
+// MessageText 消息中的文本内容
+type MessageText struct {
+	Value       string        `json:"value"`
+	Annotations []interface{} `json:"annotations"`
+}
+
+// MessageContent 消息的内容片段，图片片段原样保存
+type MessageContent struct {
+	Type      string          `json:"type"` // text, image_file, image_url
+	Text      *MessageText    `json:"text,omitempty"`
+	ImageFile json.RawMessage `json:"image_file,omitempty"`
+	ImageURL  json.RawMessage `json:"image_url,omitempty"`
+}
+
+// IncompleteDetails 消息或运行未完成的原因
+type IncompleteDetails struct {
+	Reason string `json:"reason"`
+}
+
+// Message 线程中的消息
+type Message struct {
+	ID                string             `json:"id"`
+	Object            string             `json:"object"`
+	CreatedAt         int64              `json:"created_at"`
+	ThreadID          string             `json:"thread_id"`
+	Status            string             `json:"status"`
+	IncompleteDetails *IncompleteDetails `json:"incomplete_details"`
+	CompletedAt       *int64             `json:"completed_at"`
+	IncompleteAt      *int64             `json:"incomplete_at"`
+	Role              string             `json:"role"`
+	Content           []MessageContent   `json:"content"`
+	AssistantID       *string            `json:"assistant_id"`
+	RunID             *string            `json:"run_id"`
+	Attachments       []interface{}      `json:"attachments"`
+	Metadata          map[string]string  `json:"metadata"`
+}
+
+// Text 返回消息中所有文本片段拼接后的内容
+func (m Message) Text() string {
+	texts := make([]string, 0, len(m.Content))
+	for _, content := range m.Content {
+		if content.Text != nil {
+			texts = append(texts, content.Text.Value)
+		}
+	}
+	return strings.Join(texts, "\n")
+}
+
+// ParseMessageContent 解析请求中的消息内容，支持字符串和 text、image_file、image_url 内容片段数组
+func ParseMessageContent(raw json.RawMessage) ([]MessageContent, error) {
+	var text string
+	if err := json.Unmarshal(raw, &text); err == nil {
+		return []MessageContent{textContent(text)}, nil
+	}
+
+	var parts []struct {
+		Type      string          `json:"type"`
+		Text      string          `json:"text"`
+		ImageFile json.RawMessage `json:"image_file"`
+		ImageURL  json.RawMessage `json:"image_url"`
+	}
+	if err := json.Unmarshal(raw, &parts); err != nil || len(parts) == 0 {
+		return nil, errors.New("content must be a string or a non-empty array of content parts")
+	}
+	contents := make([]MessageContent, 0, len(parts))
+	for _, part := range parts {
+		switch part.Type {
+		case "text":
+			contents = append(contents, textContent(part.Text))
+		case "image_file":
+			contents = append(contents, MessageContent{Type: part.Type, ImageFile: part.ImageFile})
+		case "image_url":
+			contents = append(contents, MessageContent{Type: part.Type, ImageURL: part.ImageURL})
+		default:
+			return nil, errors.New("unsupported content part type '" + part.Type + "'")
+		}
+	}
+	return contents, nil
+}
+
+// textContent 构造文本内容片段
+func textContent(value string) MessageContent {
+	return MessageContent{Type: "text", Text: &MessageText{Value: value, Annotations: []interface{}{}}}
+}
+
+// FunctionCall 函数工具调用，output 只在运行步骤中提交工具输出后出现
+type FunctionCall struct {
+	Name      string  `json:"name"`
+	Arguments string  `json:"arguments"`
+	Output    *string `json:"output,omitempty"`
+}
+
+// ToolCall 运行请求调用的工具
+type ToolCall struct {
+	ID       string       `json:"id"`
+	Type     string       `json:"type"`
+	Function FunctionCall `json:"function"`
+}
+
+// RequiredAction 运行等待调用方执行的操作
+type RequiredAction struct {
+	Type              string `json:"type"` // submit_tool_outputs
+	SubmitToolOutputs struct {
+		ToolCalls []ToolCall `json:"tool_calls"`
+	} `json:"submit_tool_outputs"`
+}
+
+// RunError 运行失败的原因
+type RunError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Usage 运行和运行步骤的token使用量
+type Usage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+// add 累加token使用量
+func (u Usage) add(other Usage) Usage {
+	return Usage{
+		PromptTokens:     u.PromptTokens + other.PromptTokens,
+		CompletionTokens: u.CompletionTokens + other.CompletionTokens,
+		TotalTokens:      u.TotalTokens + other.TotalTokens,
+	}
+}
+
+// TruncationStrategy 运行构建上下文时截断线程的策略
+type TruncationStrategy struct {
+	Type         string `json:"type"`
+	LastMessages *int   `json:"last_messages"`
+}
+
+// Run 线程上的一次运行
+type Run struct {
+	ID                  string              `json:"id"`
+	Object              string              `json:"object"`
+	CreatedAt           int64               `json:"created_at"`
+	AssistantID         string              `json:"assistant_id"`
+	ThreadID            string              `json:"thread_id"`
+	Status              string              `json:"status"`
+	StartedAt           *int64              `json:"started_at"`
+	ExpiresAt           *int64              `json:"expires_at"`
+	CancelledAt         *int64              `json:"cancelled_at"`
+	FailedAt            *int64              `json:"failed_at"`
+	CompletedAt         *int64              `json:"completed_at"`
+	RequiredAction      *RequiredAction     `json:"required_action"`
+	LastError           *RunError           `json:"last_error"`
+	IncompleteDetails   *IncompleteDetails  `json:"incomplete_details"`
+	Model               string              `json:"model"`
+	Instructions        string              `json:"instructions"`
+	Tools               []api.AssistantTool `json:"tools"`
+	Metadata            map[string]string   `json:"metadata"`
+	Usage               *Usage              `json:"usage"`
+	Temperature         *float64            `json:"temperature"`
+	TopP                *float64            `json:"top_p"`
+	MaxPromptTokens     *int                `json:"max_prompt_tokens"`
+	MaxCompletionTokens *int                `json:"max_completion_tokens"`
+	TruncationStrategy  interface{}         `json:"truncation_strategy"`
+	ToolChoice          interface{}         `json:"tool_choice"`
+	ParallelToolCalls   bool                `json:"parallel_tool_calls"`
+	ResponseFormat      interface{}         `json:"response_format"`
+}
+
+// Active 判断运行是否仍在进行，进行中的运行阻止向线程添加消息和创建新的运行
+func (r Run) Active() bool {
+	switch r.Status {
+	case StatusQueued, StatusInProgress, StatusRequiresAction, StatusCancelling:
+		return true
+	}
+	return false
+}
+
+// StepDetails 运行步骤的详情
+type StepDetails struct {
+	Type            string           `json:"type"`
+	MessageCreation *MessageCreation `json:"message_creation,omitempty"`
+	ToolCalls       []ToolCall       `json:"tool_calls,omitempty"`
+}
+
+// MessageCreation 创建消息的运行步骤详情
+type MessageCreation struct {
+	MessageID string `json:"message_id"`
+}
+
+// RunStep 运行步骤
+type RunStep struct {
+	ID          string            `json:"id"`
+	Object      string            `json:"object"`
+	CreatedAt   int64             `json:"created_at"`
+	AssistantID string            `json:"assistant_id"`
+	ThreadID    string            `json:"thread_id"`
+	RunID       string            `json:"run_id"`
+	Type        string            `json:"type"`
+	Status      string            `json:"status"`
+	StepDetails StepDetails       `json:"step_details"`
+	LastError   *RunError         `json:"last_error"`
+	ExpiredAt   *int64            `json:"expired_at"`
+	CancelledAt *int64            `json:"cancelled_at"`
+	FailedAt    *int64            `json:"failed_at"`
+	CompletedAt *int64            `json:"completed_at"`
+	Metadata    map[string]string `json:"metadata"`
+	Usage       *Usage            `json:"usage"`
+}
+
+// MessageDelta 流式返回的消息增量
+type MessageDelta struct {
+	ID     string `json:"id"`
+	Object string `json:"object"`
+	Delta  struct {
+		Content []MessageDeltaContent `json:"content"`
+	} `json:"delta"`
+}
+
+// MessageDeltaContent 消息增量中的文本片段
+type MessageDeltaContent struct {
+	Index int         `json:"index"`
+	Type  string      `json:"type"`
+	Text  MessageText `json:"text"`
+}
+
+// Event 运行状态变化产生的流式事件，data 为变化后的对象快照
+type Event struct {
+	Name string
+	Data interface{}
+}
@@ -0,0 +1,41 @@
+// Package atomicfile 以崩溃安全的方式写入文件：先写入同目录下的临时文件并同步到磁盘，再重命名覆盖目标文件，
+// 读取方（包括文件变化检测）只会看到完整的旧文件或新文件
+package atomicfile
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// WriteFile 原子地写入文件，写入失败时目标文件保持不变
+func WriteFile(filename string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(filename), "."+filepath.Base(filename)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpName := tmp.Name()
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmpName)
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		os.Remove(tmpName)
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpName)
+		return err
+	}
+	if err := os.Chmod(tmpName, perm); err != nil {
+		os.Remove(tmpName)
+		return err
+	}
+	if err := os.Rename(tmpName, filename); err != nil {
+		os.Remove(tmpName)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// 默认审计日志目录
+	defaultAuditDir = "audit_data"
+	// 默认审计日志文件名，每行一条JSON记录
+	defaultAuditFile = "audit.log"
+	// 内存中保留的最近记录数
+	maxRecentEntries = 1000
+)
+
+// Entry 一条管理操作的审计记录
+type Entry struct {
+	ID         string          `json:"id"`
+	Time       time.Time       `json:"time"`
+	Actor      string          `json:"actor"` // 管理凭据名称，未开启管理鉴权时为 anonymous
+	Role       string          `json:"role"`
+	RemoteAddr string          `json:"remote_addr"`
+	Workspace  string          `json:"workspace,omitempty"`
+	Method     string          `json:"method"`
+	Path       string          `json:"path"`
+	Status     int             `json:"status"`
+	Request    json.RawMessage `json:"request,omitempty"`
+	Before     interface{}     `json:"before,omitempty"`
+	After      interface{}     `json:"after,omitempty"`
+}
+
+// Log 审计日志，记录追加写入文件，同时在内存中保留最近的记录
+type Log struct {
+	entries    []Entry
+	mu         sync.RWMutex
+	storageDir string
+	filename   string
+}
+
+// NewLog 创建一个新的审计日志
+func NewLog(storageDir, filename string) *Log {
+	if storageDir == "" {
+		storageDir = defaultAuditDir
+	}
+	if filename == "" {
+		filename = defaultAuditFile
+	}
+
+	log := &Log{
+		storageDir: storageDir,
+		filename:   filename,
+	}
+
+	// 加载已有的审计记录
+	if err := log.loadEntries(); err != nil {
+		fmt.Printf("Error loading audit log: %v\n", err)
+	}
+
+	return log
+}
+
+// Record 追加一条审计记录
+func (l *Log) Record(entry Entry) (Entry, error) {
+	entry.ID = "audit-" + uuid.New().String()[:8]
+	if entry.Time.IsZero() {
+		entry.Time = time.Now().UTC()
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return Entry{}, fmt.Errorf("error encoding audit entry: %v", err)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if err := os.MkdirAll(l.storageDir, 0755); err != nil {
+		return Entry{}, fmt.Errorf("error creating audit directory: %v", err)
+	}
+	file, err := os.OpenFile(filepath.Join(l.storageDir, l.filename), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return Entry{}, fmt.Errorf("error opening audit log: %v", err)
+	}
+	defer file.Close()
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		return Entry{}, fmt.Errorf("error writing audit log: %v", err)
+	}
+
+	l.entries = append(l.entries, entry)
+	if len(l.entries) > maxRecentEntries {
+		l.entries = l.entries[len(l.entries)-maxRecentEntries:]
+	}
+	return entry, nil
+}
+
+// List 按时间倒序返回最近的审计记录，limit小于等于0时返回全部
+func (l *Log) List(limit int) []Entry {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	if limit <= 0 || limit > len(l.entries) {
+		limit = len(l.entries)
+	}
+	result := make([]Entry, 0, limit)
+	for i := len(l.entries) - 1; i >= 0 && len(result) < limit; i-- {
+		result = append(result, l.entries[i])
+	}
+	return result
+}
+
+// loadEntries 从文件加载最近的审计记录
+func (l *Log) loadEntries() error {
+	data, err := os.ReadFile(filepath.Join(l.storageDir, l.filename))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error reading audit log: %v", err)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	for decoder.More() {
+		var entry Entry
+		if err := decoder.Decode(&entry); err != nil {
+			return fmt.Errorf("error parsing audit log: %v", err)
+		}
+		l.entries = append(l.entries, entry)
+	}
+	if len(l.entries) > maxRecentEntries {
+		l.entries = l.entries[len(l.entries)-maxRecentEntries:]
+	}
+	return nil
+}
@@ -0,0 +1,300 @@
+package azure
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+
+	"RobinPenn974/OpenAI-mocker/api"
+	"RobinPenn974/OpenAI-mocker/atomicfile"
+)
+
+const (
+	// 默认内容过滤规则存储目录
+	defaultContentFilterDir = "azure_data"
+	// 默认内容过滤规则文件名
+	defaultContentFilterFile = "content_filter.json"
+)
+
+// 内容过滤类别
+const (
+	CategoryHate      = "hate"
+	CategorySelfHarm  = "self_harm"
+	CategorySexual    = "sexual"
+	CategoryViolence  = "violence"
+	CategoryJailbreak = "jailbreak"
+)
+
+// 内容过滤作用目标
+const (
+	TargetPrompt     = "prompt"
+	TargetCompletion = "completion"
+)
+
+// ContentFilterRule 内容过滤规则，命中后触发Azure的内容过滤行为
+type ContentFilterRule struct {
+	ID         string `json:"id"`
+	Pattern    string `json:"pattern" binding:"required"` // 关键字，或在 regex 为 true 时的正则表达式
+	Regex      bool   `json:"regex,omitempty"`            // 是否按正则匹配
+	Category   string `json:"category"`                   // hate, self_harm, sexual, violence, jailbreak
+	Severity   string `json:"severity,omitempty"`         // low, medium, high
+	Target     string `json:"target,omitempty"`           // prompt 或 completion，默认为 prompt
+	Deployment string `json:"deployment,omitempty"`       // 仅对指定部署生效，为空表示全部部署
+	ModelID    string `json:"model_id,omitempty"`         // 仅对指定模型生效，为空表示全部模型
+
+	compiled *regexp.Regexp
+}
+
+// ContentFilter 保存内容过滤规则，规则持久化到文件
+type ContentFilter struct {
+	rules      []ContentFilterRule
+	mu         sync.RWMutex
+	storageDir string
+	filename   string
+}
+
+// NewContentFilter 创建一个新的内容过滤器
+func NewContentFilter(storageDir, filename string) *ContentFilter {
+	if storageDir == "" {
+		storageDir = defaultContentFilterDir
+	}
+	if filename == "" {
+		filename = defaultContentFilterFile
+	}
+
+	filter := &ContentFilter{
+		storageDir: storageDir,
+		filename:   filename,
+	}
+
+	// 加载内容过滤规则
+	if err := filter.loadRules(); err != nil {
+		fmt.Printf("Error loading content filter rules: %v\n", err)
+	}
+
+	return filter
+}
+
+// AddRule 校验并添加一条规则
+func (f *ContentFilter) AddRule(rule ContentFilterRule) (ContentFilterRule, error) {
+	rule, err := validateRule(rule)
+	if err != nil {
+		return ContentFilterRule{}, err
+	}
+	if rule.ID == "" {
+		rule.ID = "cfr-" + api.GenerateShortUUID()
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	rules := append(f.copyRules(), rule)
+	if err := f.saveRules(rules); err != nil {
+		return ContentFilterRule{}, err
+	}
+	return rule, nil
+}
+
+// validateRule 校验规则，补全默认值并编译正则表达式
+func validateRule(rule ContentFilterRule) (ContentFilterRule, error) {
+	if rule.Pattern == "" {
+		return ContentFilterRule{}, errors.New("pattern is required")
+	}
+
+	switch rule.Category {
+	case "":
+		rule.Category = CategoryHate
+	case CategoryHate, CategorySelfHarm, CategorySexual, CategoryViolence, CategoryJailbreak:
+	default:
+		return ContentFilterRule{}, fmt.Errorf("unsupported category: %s", rule.Category)
+	}
+
+	switch rule.Severity {
+	case "":
+		rule.Severity = "high"
+	case "low", "medium", "high":
+	default:
+		return ContentFilterRule{}, fmt.Errorf("unsupported severity: %s", rule.Severity)
+	}
+
+	switch rule.Target {
+	case "":
+		rule.Target = TargetPrompt
+	case TargetPrompt, TargetCompletion:
+	default:
+		return ContentFilterRule{}, fmt.Errorf("unsupported target: %s", rule.Target)
+	}
+
+	if rule.Regex {
+		compiled, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			return ContentFilterRule{}, fmt.Errorf("invalid regex pattern: %v", err)
+		}
+		rule.compiled = compiled
+	}
+	return rule, nil
+}
+
+// ListRules 列出所有规则
+func (f *ContentFilter) ListRules() []ContentFilterRule {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	result := make([]ContentFilterRule, len(f.rules))
+	copy(result, f.rules)
+	return result
+}
+
+// DeleteRule 删除指定ID的规则
+func (f *ContentFilter) DeleteRule(id string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for i, rule := range f.rules {
+		if rule.ID == id {
+			rules := f.copyRules()
+			return f.saveRules(append(rules[:i], rules[i+1:]...))
+		}
+	}
+	return errors.New("rule not found")
+}
+
+// DeleteRulesForModel 删除只作用于指定模型的规则
+func (f *ContentFilter) DeleteRulesForModel(modelID string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var kept []ContentFilterRule
+	for _, rule := range f.rules {
+		if rule.ModelID != modelID {
+			kept = append(kept, rule)
+		}
+	}
+	return f.saveRules(kept)
+}
+
+// RemoveAllRules 删除所有规则
+func (f *ContentFilter) RemoveAllRules() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.saveRules(nil)
+}
+
+// copyRules 复制当前规则，调用方需持有锁
+func (f *ContentFilter) copyRules() []ContentFilterRule {
+	return append([]ContentFilterRule(nil), f.rules...)
+}
+
+// saveRules 将规则写入文件，成功后更新内存，调用方需持有写锁
+func (f *ContentFilter) saveRules(rules []ContentFilterRule) error {
+	if rules == nil {
+		rules = []ContentFilterRule{}
+	}
+	data, err := json.MarshalIndent(rules, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding content filter rules: %v", err)
+	}
+
+	if err := os.MkdirAll(f.storageDir, 0755); err != nil {
+		return fmt.Errorf("error creating content filter directory: %v", err)
+	}
+	if err := atomicfile.WriteFile(filepath.Join(f.storageDir, f.filename), data, 0644); err != nil {
+		return fmt.Errorf("error writing content filter file: %v", err)
+	}
+
+	f.rules = rules
+	return nil
+}
+
+// loadRules 从文件加载规则，文件不存在时没有规则
+func (f *ContentFilter) loadRules() error {
+	data, err := os.ReadFile(filepath.Join(f.storageDir, f.filename))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error reading content filter file: %v", err)
+	}
+
+	var rules []ContentFilterRule
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return fmt.Errorf("error parsing content filter file: %v", err)
+	}
+	for i := range rules {
+		rule, err := validateRule(rules[i])
+		if err != nil {
+			return fmt.Errorf("invalid content filter rule %s: %v", rules[i].ID, err)
+		}
+		rules[i] = rule
+	}
+
+	f.rules = rules
+	return nil
+}
+
+// Evaluate 对文本执行过滤，返回各类别的过滤结果以及是否需要拦截
+func (f *ContentFilter) Evaluate(target, deployment, modelID, text string) (api.ContentFilterResults, bool) {
+	results := SafeResults(target)
+
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	filtered := false
+	for _, rule := range f.rules {
+		if rule.Target != target {
+			continue
+		}
+		if rule.Deployment != "" && rule.Deployment != deployment {
+			continue
+		}
+		if rule.ModelID != "" && rule.ModelID != modelID {
+			continue
+		}
+		if !rule.matches(text) {
+			continue
+		}
+
+		filtered = true
+		switch rule.Category {
+		case CategoryHate:
+			results.Hate = &api.ContentFilterSeverityResult{Filtered: true, Severity: rule.Severity}
+		case CategorySelfHarm:
+			results.SelfHarm = &api.ContentFilterSeverityResult{Filtered: true, Severity: rule.Severity}
+		case CategorySexual:
+			results.Sexual = &api.ContentFilterSeverityResult{Filtered: true, Severity: rule.Severity}
+		case CategoryViolence:
+			results.Violence = &api.ContentFilterSeverityResult{Filtered: true, Severity: rule.Severity}
+		case CategoryJailbreak:
+			results.Jailbreak = &api.ContentFilterDetectedResult{Filtered: true, Detected: true}
+		}
+	}
+
+	return results, filtered
+}
+
+// matches 判断规则是否命中文本
+func (r ContentFilterRule) matches(text string) bool {
+	if r.compiled != nil {
+		return r.compiled.MatchString(text)
+	}
+	return strings.Contains(strings.ToLower(text), strings.ToLower(r.Pattern))
+}
+
+// SafeResults 返回所有类别均为安全的过滤结果
+func SafeResults(target string) api.ContentFilterResults {
+	results := api.ContentFilterResults{
+		Hate:     &api.ContentFilterSeverityResult{Severity: "safe"},
+		SelfHarm: &api.ContentFilterSeverityResult{Severity: "safe"},
+		Sexual:   &api.ContentFilterSeverityResult{Severity: "safe"},
+		Violence: &api.ContentFilterSeverityResult{Severity: "safe"},
+	}
+	if target == TargetPrompt {
+		results.Jailbreak = &api.ContentFilterDetectedResult{}
+	}
+	return results
+}
@@ -0,0 +1,38 @@
+package azure
+
+// SupportedAPIVersions Azure OpenAI 数据面支持的 api-version 列表
+var SupportedAPIVersions = []string{
+	"2022-12-01",
+	"2023-05-15",
+	"2023-06-01-preview",
+	"2023-07-01-preview",
+	"2023-08-01-preview",
+	"2023-09-01-preview",
+	"2023-12-01-preview",
+	"2024-02-01",
+	"2024-02-15-preview",
+	"2024-03-01-preview",
+	"2024-04-01-preview",
+	"2024-05-01-preview",
+	"2024-06-01",
+	"2024-07-01-preview",
+	"2024-08-01-preview",
+	"2024-09-01-preview",
+	"2024-10-01-preview",
+	"2024-10-21",
+	"2024-12-01-preview",
+	"2025-01-01-preview",
+	"2025-02-01-preview",
+	"2025-03-01-preview",
+	"2025-04-01-preview",
+}
+
+// IsSupportedAPIVersion 检查 api-version 是否受支持
+func IsSupportedAPIVersion(version string) bool {
+	for _, v := range SupportedAPIVersions {
+		if v == version {
+			return true
+		}
+	}
+	return false
+}
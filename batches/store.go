@@ -0,0 +1,335 @@
+package batches
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"RobinPenn974/OpenAI-mocker/api"
+	"RobinPenn974/OpenAI-mocker/files"
+
+	"github.com/google/uuid"
+)
+
+// 批处理的状态
+const (
+	StatusValidating = "validating"
+	StatusFailed     = "failed"
+	StatusInProgress = "in_progress"
+	StatusFinalizing = "finalizing"
+	StatusCompleted  = "completed"
+	StatusExpired    = "expired"
+	StatusCancelling = "cancelling"
+	StatusCancelled  = "cancelled"
+)
+
+// CompletionWindow 支持的完成时间窗口，与OpenAI一致只支持24小时
+const CompletionWindow = "24h"
+
+// Batch OpenAI格式的批处理对象
+type Batch struct {
+	ID               string            `json:"id"`
+	Object           string            `json:"object"`
+	Endpoint         string            `json:"endpoint"`
+	Errors           *Errors           `json:"errors"`
+	InputFileID      string            `json:"input_file_id"`
+	CompletionWindow string            `json:"completion_window"`
+	Status           string            `json:"status"`
+	OutputFileID     *string           `json:"output_file_id"`
+	ErrorFileID      *string           `json:"error_file_id"`
+	CreatedAt        int64             `json:"created_at"`
+	InProgressAt     *int64            `json:"in_progress_at"`
+	ExpiresAt        *int64            `json:"expires_at"`
+	FinalizingAt     *int64            `json:"finalizing_at"`
+	CompletedAt      *int64            `json:"completed_at"`
+	FailedAt         *int64            `json:"failed_at"`
+	ExpiredAt        *int64            `json:"expired_at"`
+	CancellingAt     *int64            `json:"cancelling_at"`
+	CancelledAt      *int64            `json:"cancelled_at"`
+	RequestCounts    RequestCounts     `json:"request_counts"`
+	Metadata         map[string]string `json:"metadata"`
+}
+
+// Errors 批处理输入文件校验失败的原因
+type Errors struct {
+	Object string  `json:"object"`
+	Data   []Error `json:"data"`
+}
+
+// Error 输入文件中一行的校验错误
+type Error struct {
+	Code    string  `json:"code"`
+	Message string  `json:"message"`
+	Param   *string `json:"param"`
+	Line    *int    `json:"line"`
+}
+
+// RequestCounts 批处理中各状态的请求数
+type RequestCounts struct {
+	Total     int `json:"total"`
+	Completed int `json:"completed"`
+	Failed    int `json:"failed"`
+}
+
+// Result 批处理中一个请求的执行结果，StatusCode 不是2xx的结果写入错误文件
+type Result struct {
+	CustomID   string
+	StatusCode int
+	Body       json.RawMessage
+}
+
+// NotFoundError 请求的批处理不存在
+type NotFoundError struct {
+	ID string
+}
+
+// Error 返回与OpenAI一致的错误信息
+func (e *NotFoundError) Error() string {
+	return fmt.Sprintf("No batch found with id '%s'.", e.ID)
+}
+
+// Store 在内存中保存一个工作区的批处理，结果文件写入工作区的文件存储
+type Store struct {
+	batches []*batchState // 按创建顺序排列
+	files   *files.Store
+	mu      sync.Mutex
+}
+
+// batchState 批处理及其预先执行的请求结果，状态和请求计数按经过的时间推进
+type batchState struct {
+	Batch
+	results  []Result
+	start    time.Time
+	duration time.Duration
+	done     int // 已经完成的请求数，取消时保留
+}
+
+// NewStore 创建一个新的存储，结果文件写入fileStore
+func NewStore(fileStore *files.Store) *Store {
+	return &Store{files: fileStore}
+}
+
+// newID 生成带前缀的对象ID
+func newID(prefix string) string {
+	return prefix + strings.ReplaceAll(uuid.NewString(), "-", "")[:24]
+}
+
+// unix 返回时间的Unix时间戳指针
+func unix(t time.Time) *int64 {
+	timestamp := t.Unix()
+	return &timestamp
+}
+
+// newBatch 初始化批处理的ID、时间和元数据
+func newBatch(batch Batch, now time.Time) *batchState {
+	batch.ID = newID("batch_")
+	batch.Object = "batch"
+	batch.CompletionWindow = CompletionWindow
+	batch.CreatedAt = now.Unix()
+	batch.ExpiresAt = unix(now.Add(24 * time.Hour))
+	if batch.Metadata == nil {
+		batch.Metadata = map[string]string{}
+	}
+	return &batchState{Batch: batch, start: now}
+}
+
+// Create 保存输入文件校验通过的批处理，results 为按输入文件顺序执行的请求结果
+// 批处理在duration内依次经过 validating、in_progress 和 finalizing，duration 为0时立即完成
+func (s *Store) Create(batch Batch, results []Result, duration time.Duration) Batch {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	state := newBatch(batch, now)
+	state.Status = StatusValidating
+	state.results = results
+	state.duration = duration
+	state.RequestCounts.Total = len(results)
+	s.batches = append(s.batches, state)
+
+	s.advance(state, now)
+	return state.Batch
+}
+
+// CreateFailed 保存输入文件校验失败的批处理
+func (s *Store) CreateFailed(batch Batch, errs []Error) Batch {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	state := newBatch(batch, now)
+	state.Status = StatusFailed
+	state.FailedAt = unix(now)
+	state.Errors = &Errors{Object: "list", Data: errs}
+	s.batches = append(s.batches, state)
+	return state.Batch
+}
+
+// Get 获取批处理
+func (s *Store) Get(id string) (Batch, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state, err := s.batch(id)
+	if err != nil {
+		return Batch{}, err
+	}
+	return state.Batch, nil
+}
+
+// List 分页列出批处理
+func (s *Store) List(params api.ListParams) ([]Batch, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	batches := make([]Batch, len(s.batches))
+	for i, state := range s.batches {
+		s.advance(state, now)
+		batches[i] = state.Batch
+	}
+	return api.Paginate(batches, func(batch Batch) string { return batch.ID }, params)
+}
+
+// Cancel 取消尚未进入 finalizing 的批处理，批处理先进入 cancelling，下次查询时变为 cancelled 并写入已完成请求的结果
+func (s *Store) Cancel(id string) (Batch, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state, err := s.batch(id)
+	if err != nil {
+		return Batch{}, err
+	}
+	if state.Status != StatusValidating && state.Status != StatusInProgress {
+		return Batch{}, fmt.Errorf("Cannot cancel a batch with status '%s'.", state.Status)
+	}
+	state.Status = StatusCancelling
+	state.CancellingAt = unix(time.Now())
+	return state.Batch, nil
+}
+
+// batch 查找批处理并按经过的时间推进状态
+func (s *Store) batch(id string) (*batchState, error) {
+	for _, state := range s.batches {
+		if state.ID == id {
+			s.advance(state, time.Now())
+			return state, nil
+		}
+	}
+	return nil, &NotFoundError{ID: id}
+}
+
+// advance 按经过的时间推进批处理的状态和请求计数
+// validating 和 finalizing 各占总耗时的十分之一，in_progress 期间请求按顺序匀速完成
+func (s *Store) advance(state *batchState, now time.Time) {
+	switch state.Status {
+	case StatusCancelling:
+		state.CancelledAt = unix(now)
+		s.finish(state, StatusCancelled)
+		return
+	case StatusValidating, StatusInProgress, StatusFinalizing:
+	default:
+		return
+	}
+
+	elapsed := now.Sub(state.start)
+	validating := state.duration / 10
+	finalizing := state.duration - state.duration/10
+
+	if elapsed < validating {
+		return
+	}
+	if state.InProgressAt == nil {
+		state.InProgressAt = unix(state.start.Add(validating))
+		state.Status = StatusInProgress
+	}
+
+	if elapsed < finalizing {
+		state.done = int(float64(len(state.results)) * float64(elapsed-validating) / float64(finalizing-validating))
+		state.RequestCounts = countResults(state.results, state.done)
+		return
+	}
+	state.done = len(state.results)
+	state.RequestCounts = countResults(state.results, state.done)
+	if state.FinalizingAt == nil {
+		state.FinalizingAt = unix(state.start.Add(finalizing))
+		state.Status = StatusFinalizing
+	}
+
+	if elapsed < state.duration {
+		return
+	}
+	state.CompletedAt = unix(state.start.Add(state.duration))
+	s.finish(state, StatusCompleted)
+}
+
+// finish 将已完成请求的结果写入输出文件和错误文件，并结束批处理
+func (s *Store) finish(state *batchState, status string) {
+	var output, errorOutput []byte
+	for _, result := range state.results[:state.done] {
+		line, err := json.Marshal(newResultLine(result))
+		if err != nil {
+			continue
+		}
+		line = append(line, '\n')
+		if result.StatusCode >= 200 && result.StatusCode < 300 {
+			output = append(output, line...)
+		} else {
+			errorOutput = append(errorOutput, line...)
+		}
+	}
+
+	state.OutputFileID = s.writeFile(fmt.Sprintf("batch_%s_output.jsonl", state.ID), output)
+	state.ErrorFileID = s.writeFile(fmt.Sprintf("batch_%s_error.jsonl", state.ID), errorOutput)
+	state.RequestCounts = countResults(state.results, state.done)
+	state.Status = status
+}
+
+// writeFile 将结果写入用途为 batch_output 的文件，没有结果时不创建文件
+func (s *Store) writeFile(filename string, data []byte) *string {
+	if len(data) == 0 {
+		return nil
+	}
+	file, err := s.files.Create(filename, files.PurposeBatchOutput, data)
+	if err != nil {
+		fmt.Printf("Error writing batch result file: %v\n", err)
+		return nil
+	}
+	return &file.ID
+}
+
+// resultLine 结果文件中的一行，与OpenAI一致请求失败时 response 中保存错误响应
+type resultLine struct {
+	ID       string `json:"id"`
+	CustomID string `json:"custom_id"`
+	Response struct {
+		StatusCode int             `json:"status_code"`
+		RequestID  string          `json:"request_id"`
+		Body       json.RawMessage `json:"body"`
+	} `json:"response"`
+	Error interface{} `json:"error"`
+}
+
+// newResultLine 构造请求结果对应的结果文件行
+func newResultLine(result Result) resultLine {
+	line := resultLine{ID: newID("batch_req_"), CustomID: result.CustomID}
+	line.Response.StatusCode = result.StatusCode
+	line.Response.RequestID = newID("req_")
+	line.Response.Body = result.Body
+	return line
+}
+
+// countResults 统计前done个请求中成功和失败的请求数
+func countResults(results []Result, done int) RequestCounts {
+	counts := RequestCounts{Total: len(results)}
+	for _, result := range results[:done] {
+		if result.StatusCode >= 200 && result.StatusCode < 300 {
+			counts.Completed++
+		} else {
+			counts.Failed++
+		}
+	}
+	return counts
+}
@@ -0,0 +1,141 @@
+package batches
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"RobinPenn974/OpenAI-mocker/files"
+)
+
+// testResults 构造指定数量的请求结果，failed 中的下标对应失败的请求
+func testResults(count int, failed ...int) []Result {
+	results := make([]Result, count)
+	for i := range results {
+		results[i] = Result{CustomID: "request-" + string(rune('a'+i)), StatusCode: http.StatusOK, Body: json.RawMessage(`{}`)}
+	}
+	for _, i := range failed {
+		results[i].StatusCode = http.StatusInternalServerError
+	}
+	return results
+}
+
+// customIDs 返回结果文件中各行的 custom_id
+func customIDs(t *testing.T, fileStore *files.Store, id *string) []string {
+	t.Helper()
+	if id == nil {
+		return nil
+	}
+	_, data, err := fileStore.Content(*id)
+	if err != nil {
+		t.Fatalf("read result file: %v", err)
+	}
+	var ids []string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		var line struct {
+			CustomID string `json:"custom_id"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			t.Fatalf("parse result line: %v", err)
+		}
+		ids = append(ids, line.CustomID)
+	}
+	return ids
+}
+
+func TestBatchLifecycle(t *testing.T) {
+	fileStore := files.NewStore(t.TempDir())
+	store := NewStore(fileStore)
+	created := store.Create(Batch{Endpoint: "/v1/chat/completions"}, testResults(10, 3), 10*time.Second)
+	if created.Status != StatusValidating || created.RequestCounts.Total != 10 {
+		t.Fatalf("created batch: %+v", created)
+	}
+	state, err := store.batch(created.ID)
+	if err != nil {
+		t.Fatalf("find batch: %v", err)
+	}
+
+	// validating 和 finalizing 各占十分之一，in_progress 期间请求匀速完成
+	steps := []struct {
+		elapsed   time.Duration
+		status    string
+		completed int
+		failed    int
+	}{
+		{500 * time.Millisecond, StatusValidating, 0, 0},
+		{1 * time.Second, StatusInProgress, 0, 0},
+		{5 * time.Second, StatusInProgress, 4, 1},
+		{9 * time.Second, StatusFinalizing, 9, 1},
+		{10 * time.Second, StatusCompleted, 9, 1},
+	}
+	for _, step := range steps {
+		store.advance(state, state.start.Add(step.elapsed))
+		counts := state.RequestCounts
+		if state.Status != step.status || counts.Completed != step.completed || counts.Failed != step.failed {
+			t.Fatalf("after %v: status %s, counts %+v, want %s %d/%d", step.elapsed, state.Status, counts, step.status, step.completed, step.failed)
+		}
+	}
+
+	if state.InProgressAt == nil || state.FinalizingAt == nil || state.CompletedAt == nil {
+		t.Fatalf("missing timestamps: %+v", state.Batch)
+	}
+	if got := customIDs(t, fileStore, state.OutputFileID); len(got) != 9 {
+		t.Fatalf("output file has %d lines, want 9", len(got))
+	}
+	if got := customIDs(t, fileStore, state.ErrorFileID); len(got) != 1 || got[0] != "request-d" {
+		t.Fatalf("error file lines %v, want [request-d]", got)
+	}
+}
+
+func TestBatchImmediateCompletion(t *testing.T) {
+	store := NewStore(files.NewStore(t.TempDir()))
+	batch := store.Create(Batch{Endpoint: "/v1/embeddings"}, testResults(2), 0)
+	if batch.Status != StatusCompleted || batch.RequestCounts.Completed != 2 || batch.OutputFileID == nil || batch.ErrorFileID != nil {
+		t.Fatalf("batch with zero duration: %+v", batch)
+	}
+	if _, err := store.Cancel(batch.ID); err == nil {
+		t.Fatal("expected cancelling a completed batch to fail")
+	}
+}
+
+func TestBatchCancel(t *testing.T) {
+	fileStore := files.NewStore(t.TempDir())
+	store := NewStore(fileStore)
+	created := store.Create(Batch{Endpoint: "/v1/chat/completions"}, testResults(10), 10*time.Second)
+	state, _ := store.batch(created.ID)
+	store.advance(state, state.start.Add(5*time.Second))
+
+	cancelling, err := store.Cancel(created.ID)
+	if err != nil || cancelling.Status != StatusCancelling || cancelling.CancellingAt == nil {
+		t.Fatalf("cancel: %+v, %v", cancelling, err)
+	}
+
+	// 下次查询时变为 cancelled，并只写入已完成请求的结果
+	cancelled, _ := store.Get(created.ID)
+	if cancelled.Status != StatusCancelled || cancelled.CancelledAt == nil || cancelled.CompletedAt != nil {
+		t.Fatalf("cancelled batch: %+v", cancelled)
+	}
+	if got := customIDs(t, fileStore, cancelled.OutputFileID); len(got) != 5 {
+		t.Fatalf("output file has %d lines, want 5", len(got))
+	}
+	if _, err := store.Cancel(created.ID); err == nil {
+		t.Fatal("expected cancelling a cancelled batch to fail")
+	}
+
+	if _, err := store.Get("batch_missing"); err == nil {
+		t.Fatal("expected an error for a missing batch")
+	}
+}
+
+func TestCreateFailedBatch(t *testing.T) {
+	store := NewStore(files.NewStore(t.TempDir()))
+	line := 2
+	batch := store.CreateFailed(Batch{Endpoint: "/v1/chat/completions"}, []Error{{Code: "invalid_json_line", Message: "bad line", Line: &line}})
+	if batch.Status != StatusFailed || batch.FailedAt == nil || batch.Errors == nil || len(batch.Errors.Data) != 1 {
+		t.Fatalf("failed batch: %+v", batch)
+	}
+}
@@ -0,0 +1,374 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"RobinPenn974/OpenAI-mocker/api"
+	"RobinPenn974/OpenAI-mocker/apikeys"
+	"RobinPenn974/OpenAI-mocker/faults"
+	"RobinPenn974/OpenAI-mocker/models"
+	"RobinPenn974/OpenAI-mocker/provider"
+	"RobinPenn974/OpenAI-mocker/templates"
+)
+
+// 管理员角色
+const (
+	AdminRoleAdmin    = "admin"
+	AdminRoleReadOnly = "read_only"
+)
+
+// Config 服务的启动配置
+type Config struct {
+	Listen      Addresses `json:"listen"`                 // API监听地址，可以监听多个地址
+	AdminListen string    `json:"admin_listen,omitempty"` // 管理API单独监听的地址，如 ":9090" 或 "unix:/tmp/mocker-admin.sock"，为空时与API共用
+	TLS         TLS       `json:"tls"`
+	StateDir    string    `json:"state_dir"` // 模型、模板、密钥和审计日志的存储目录，为空时使用当前目录
+
+	Admin Admin `json:"admin"`
+
+	// 启动时写入默认工作区的模型、模板、密钥和故障注入规则
+	Models    []models.ModelInfo           `json:"models,omitempty"`
+	Templates []templates.ResponseTemplate `json:"templates,omitempty"`
+	Keys      []api.CreateKeyRequest       `json:"keys,omitempty"`
+	Faults    []faults.Rule                `json:"faults,omitempty"`
+
+	RateLimit api.ApiKeyRateLimit `json:"rate_limit"` // 未设置速率限制的API密钥使用的默认限制
+	Latency   []LatencyProfile    `json:"latency,omitempty"`
+	Features  Features            `json:"features"`
+
+	Generation Generation `json:"generation"`
+	Assistants Assistants `json:"assistants"`
+	Files      Files      `json:"files"`
+	Batches    Batches    `json:"batches"`
+	FineTuning FineTuning `json:"fine_tuning"`
+
+	// 检查配置文件和模板文件变化的间隔，为0时不自动重新加载，仍可通过 POST /admin/reload 手动重新加载
+	ReloadIntervalMs int `json:"reload_interval_ms"`
+
+	File string   `json:"-"` // 加载的配置文件路径
+	args []string // 启动参数，重新加载时使用相同的参数
+}
+
+// Addresses 监听地址列表，配置文件中可以写成单个地址或地址列表
+type Addresses []string
+
+// ParseAddresses 解析以逗号分隔的监听地址，用于环境变量和命令行参数
+func ParseAddresses(value string) Addresses {
+	var addrs Addresses
+	for _, addr := range strings.Split(value, ",") {
+		if addr = strings.TrimSpace(addr); addr != "" {
+			addrs = append(addrs, addr)
+		}
+	}
+	return addrs
+}
+
+// UnmarshalJSON 同时支持单个地址字符串和地址数组
+func (a *Addresses) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = Addresses{single}
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return errors.New("listen must be an address or a list of addresses")
+	}
+	*a = list
+	return nil
+}
+
+// TLS HTTPS证书配置，证书和私钥都设置时启用HTTPS
+type TLS struct {
+	CertFile string `json:"cert_file,omitempty"`
+	KeyFile  string `json:"key_file,omitempty"`
+}
+
+// Admin 管理API配置
+type Admin struct {
+	Credentials []AdminCredential `json:"credentials,omitempty"`
+}
+
+// AdminCredential 管理API的访问凭据
+type AdminCredential struct {
+	Name  string `json:"name"`
+	Token string `json:"token"`
+	Role  string `json:"role"` // admin 或 read_only
+}
+
+// LatencyProfile 流式响应的延迟配置，按顺序匹配第一个模型名命中的配置
+type LatencyProfile struct {
+	Model        string `json:"model,omitempty"`          // 模型名通配符，为空表示全部模型
+	FirstChunkMs int    `json:"first_chunk_ms,omitempty"` // 发送第一个数据块后的延迟
+	ChunkDelayMs int    `json:"chunk_delay_ms,omitempty"` // 后续数据块之间的延迟
+}
+
+// Features 功能开关
+type Features struct {
+	ReasoningField bool `json:"reasoning_field"` // 推理内容使用 reasoning_content 字段返回，否则以<think>标签合并到content中
+	// 未在API密钥和模型中指定时使用的服务商兼容配置，为空时使用 openai
+	ProviderProfile string `json:"provider_profile,omitempty"`
+}
+
+// Generation 模板候选回复中文本生成器的配置
+type Generation struct {
+	// 生成器未设置分布时使用的目标token数分布，默认为中位数150的对数正态分布，覆盖从几个token的短回复到数千token的长回答
+	Tokens    templates.TokenDistribution `json:"tokens"`
+	CorpusDir string                      `json:"corpus_dir,omitempty"` // 马尔可夫链语料文件所在目录，默认为 state_dir 下的 template_data
+}
+
+// Assistants Assistants API 中运行各状态的模拟耗时
+type Assistants struct {
+	QueuedMs     int `json:"queued_ms"`      // 运行保持 queued 状态的时间
+	InProgressMs int `json:"in_progress_ms"` // 运行保持 in_progress 状态的时间
+}
+
+// Files Files API 的上传限制
+type Files struct {
+	MaxBytes int64 `json:"max_bytes"` // 单个上传文件的最大字节数，为0时不限制
+}
+
+// Batches Batch API 中批处理的模拟耗时
+type Batches struct {
+	// 批处理从创建到完成的时间，依次经过 validating、in_progress 和 finalizing，为0时创建后立即完成
+	DurationMs int `json:"duration_ms"`
+}
+
+// FineTuning 微调任务的模拟耗时
+type FineTuning struct {
+	// 任务从创建到完成的时间，依次经过 validating_files、queued 和 running，为0时创建后立即完成
+	DurationMs int `json:"duration_ms"`
+}
+
+// Default 返回默认配置
+func Default() *Config {
+	return &Config{
+		Listen:           Addresses{":8080"},
+		ReloadIntervalMs: 2000,
+		Generation: Generation{
+			Tokens: templates.TokenDistribution{
+				Type:   templates.DistributionLognormal,
+				Min:    5,
+				Max:    4000,
+				Mean:   150,
+				Stddev: 1,
+			},
+		},
+		Assistants: Assistants{
+			QueuedMs:     200,
+			InProgressMs: 800,
+		},
+		Files: Files{
+			MaxBytes: 512 << 20,
+		},
+	}
+}
+
+// Enabled 判断是否启用HTTPS
+func (t TLS) Enabled() bool {
+	return t.CertFile != "" && t.KeyFile != ""
+}
+
+// CorpusDir 返回马尔可夫链语料文件所在的目录
+func (c *Config) CorpusDir() string {
+	if c.Generation.CorpusDir != "" {
+		return c.Generation.CorpusDir
+	}
+	return filepath.Join(c.StateDir, "template_data")
+}
+
+// LatencyFor 返回模型命中的延迟配置
+func (c *Config) LatencyFor(modelID string) (LatencyProfile, bool) {
+	for _, profile := range c.Latency {
+		if profile.Model == "" {
+			return profile, true
+		}
+		if matched, _ := path.Match(profile.Model, modelID); matched {
+			return profile, true
+		}
+	}
+	return LatencyProfile{}, false
+}
+
+// Validate 校验配置并补全默认值，返回所有错误
+func (c *Config) Validate() error {
+	var errs []error
+	fail := func(format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	if len(c.Listen) == 0 {
+		fail("listen: must not be empty")
+	}
+	seen := make(map[string]bool, len(c.Listen))
+	for i, addr := range c.Listen {
+		switch {
+		case addr == "":
+			fail("listen[%d]: address must not be empty", i)
+		case seen[addr]:
+			fail("listen[%d]: duplicate address '%s'", i, addr)
+		case addr == c.AdminListen:
+			fail("listen[%d]: address '%s' is also used by admin_listen", i, addr)
+		}
+		seen[addr] = true
+	}
+	if (c.TLS.CertFile == "") != (c.TLS.KeyFile == "") {
+		fail("tls: cert_file and key_file must be set together")
+	}
+	for _, file := range []string{c.TLS.CertFile, c.TLS.KeyFile} {
+		if file == "" {
+			continue
+		}
+		if _, err := os.Stat(file); err != nil {
+			fail("tls: %v", err)
+		}
+	}
+
+	for i := range c.Admin.Credentials {
+		credential := &c.Admin.Credentials[i]
+		if credential.Token == "" {
+			fail("admin.credentials[%d]: token must not be empty", i)
+		}
+		switch credential.Role {
+		case "":
+			credential.Role = AdminRoleAdmin
+		case AdminRoleAdmin, AdminRoleReadOnly:
+		default:
+			fail("admin.credentials[%d]: invalid role '%s', must be one of: admin, read_only", i, credential.Role)
+		}
+		if credential.Name == "" {
+			credential.Name = fmt.Sprintf("admin-%d", i+1)
+		}
+	}
+
+	for i, model := range c.Models {
+		if model.ID == "" {
+			fail("models[%d]: id must not be empty", i)
+			continue
+		}
+		if model.ModelType == "" {
+			c.Models[i].ModelType = models.ModelTypeLLM
+		}
+		if err := models.ValidateModel(c.Models[i]); err != nil {
+			fail("models[%d] (%s): %v", i, model.ID, err)
+		}
+		if model.ProviderProfile != "" && !provider.IsSupportedProfile(model.ProviderProfile) {
+			fail("models[%d] (%s): invalid provider_profile '%s'", i, model.ID, model.ProviderProfile)
+		}
+	}
+
+	for i, template := range c.Templates {
+		if template.ModelID == "" {
+			fail("templates[%d]: model_id must not be empty", i)
+		}
+		if templates.IsPattern(template.ModelID) {
+			if _, err := path.Match(template.ModelID, ""); err != nil {
+				fail("templates[%d]: invalid model pattern '%s'", i, template.ModelID)
+			}
+		}
+	}
+
+	for i, key := range c.Keys {
+		if key.Name == "" {
+			fail("keys[%d]: name must not be empty", i)
+		}
+		if key.Key == "" {
+			fail("keys[%d] (%s): key must be set for seeded keys", i, key.Name)
+		}
+		for _, scope := range key.Scopes {
+			if !apikeys.IsSupportedScope(scope) {
+				fail("keys[%d] (%s): invalid scope '%s'", i, key.Name, scope)
+			}
+		}
+		for _, pattern := range key.AllowedModels {
+			if _, err := path.Match(pattern, ""); err != nil {
+				fail("keys[%d] (%s): invalid model pattern '%s'", i, key.Name, pattern)
+			}
+		}
+		if key.RateLimit.RequestsPerMinute < 0 {
+			fail("keys[%d] (%s): rate_limit.requests_per_minute must not be negative", i, key.Name)
+		}
+		if key.ProviderProfile != "" && !provider.IsSupportedProfile(key.ProviderProfile) {
+			fail("keys[%d] (%s): invalid provider_profile '%s'", i, key.Name, key.ProviderProfile)
+		}
+	}
+
+	// 用临时的故障注入器校验规则
+	injector := faults.NewInjector()
+	for i, rule := range c.Faults {
+		if _, err := injector.AddRule(rule); err != nil {
+			fail("faults[%d]: %v", i, err)
+		}
+	}
+
+	if c.RateLimit.RequestsPerMinute < 0 {
+		fail("rate_limit.requests_per_minute: must not be negative")
+	}
+
+	for i, profile := range c.Latency {
+		if _, err := path.Match(profile.Model, ""); err != nil {
+			fail("latency[%d]: invalid model pattern '%s'", i, profile.Model)
+		}
+		if profile.FirstChunkMs < 0 || profile.ChunkDelayMs < 0 {
+			fail("latency[%d]: delays must not be negative", i)
+		}
+	}
+
+	if c.Features.ProviderProfile != "" && !provider.IsSupportedProfile(c.Features.ProviderProfile) {
+		fail("features.provider_profile: invalid provider profile '%s'", c.Features.ProviderProfile)
+	}
+
+	if err := c.Generation.Tokens.Validate(); err != nil {
+		fail("generation.tokens: %v", err)
+	}
+
+	if c.Assistants.QueuedMs < 0 || c.Assistants.InProgressMs < 0 {
+		fail("assistants: durations must not be negative")
+	}
+
+	if c.Files.MaxBytes < 0 {
+		fail("files.max_bytes: must not be negative")
+	}
+
+	if c.Batches.DurationMs < 0 {
+		fail("batches.duration_ms: must not be negative")
+	}
+
+	if c.FineTuning.DurationMs < 0 {
+		fail("fine_tuning.duration_ms: must not be negative")
+	}
+
+	if c.ReloadIntervalMs < 0 {
+		fail("reload_interval_ms: must not be negative")
+	}
+
+	return errors.Join(errs...)
+}
+
+// RestartRequired 返回与other相比发生变化、需要重启才能生效的配置项
+func (c *Config) RestartRequired(other *Config) []string {
+	var fields []string
+	if !slices.Equal(c.Listen, other.Listen) {
+		fields = append(fields, "listen")
+	}
+	if c.AdminListen != other.AdminListen {
+		fields = append(fields, "admin_listen")
+	}
+	if c.TLS != other.TLS {
+		fields = append(fields, "tls")
+	}
+	if c.StateDir != other.StateDir {
+		fields = append(fields, "state_dir")
+	}
+	if c.ReloadIntervalMs != other.ReloadIntervalMs {
+		fields = append(fields, "reload_interval_ms")
+	}
+	return fields
+}
@@ -0,0 +1,64 @@
+package config
+
+import (
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestAddressesUnmarshal(t *testing.T) {
+	cases := []struct {
+		input string
+		want  Addresses
+	}{
+		{`{"listen": ":8080"}`, Addresses{":8080"}},
+		{`{"listen": [":8080", "unix:/tmp/mocker.sock"]}`, Addresses{":8080", "unix:/tmp/mocker.sock"}},
+	}
+	for _, tc := range cases {
+		var cfg Config
+		if err := json.Unmarshal([]byte(tc.input), &cfg); err != nil {
+			t.Fatalf("%s: %v", tc.input, err)
+		}
+		if !slices.Equal(cfg.Listen, tc.want) {
+			t.Errorf("%s: got %v, want %v", tc.input, cfg.Listen, tc.want)
+		}
+	}
+
+	var cfg Config
+	if err := json.Unmarshal([]byte(`{"listen": 8080}`), &cfg); err == nil {
+		t.Error("expected an error for a numeric listen address")
+	}
+}
+
+func TestParseAddresses(t *testing.T) {
+	got := ParseAddresses(" :8080, 127.0.0.1:8081 ,,")
+	if want := (Addresses{":8080", "127.0.0.1:8081"}); !slices.Equal(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
+func TestValidateListen(t *testing.T) {
+	cases := []struct {
+		listen      Addresses
+		adminListen string
+		wantErr     string
+	}{
+		{Addresses{":8080", ":8081"}, ":9090", ""},
+		{nil, "", "listen: must not be empty"},
+		{Addresses{":8080", ":8080"}, "", "duplicate address"},
+		{Addresses{":8080"}, ":8080", "also used by admin_listen"},
+	}
+	for _, tc := range cases {
+		cfg := Default()
+		cfg.Listen = tc.listen
+		cfg.AdminListen = tc.adminListen
+		err := cfg.Validate()
+		switch {
+		case tc.wantErr == "" && err != nil:
+			t.Errorf("listen %v: unexpected error: %v", tc.listen, err)
+		case tc.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tc.wantErr)):
+			t.Errorf("listen %v: got error %v, want %q", tc.listen, err, tc.wantErr)
+		}
+	}
+}
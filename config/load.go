@@ -0,0 +1,198 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// 配置相关的环境变量，优先级高于配置文件，低于命令行参数
+const (
+	EnvConfigFile         = "MOCKER_CONFIG"
+	EnvListen             = "MOCKER_LISTEN"
+	EnvAdminAddr          = "MOCKER_ADMIN_ADDR"
+	EnvStateDir           = "MOCKER_STATE_DIR"
+	EnvTLSCert            = "MOCKER_TLS_CERT"
+	EnvTLSKey             = "MOCKER_TLS_KEY"
+	EnvAdminToken         = "MOCKER_ADMIN_TOKEN"
+	EnvAdminReadOnlyToken = "MOCKER_ADMIN_READONLY_TOKEN"
+	EnvAdminConfig        = "MOCKER_ADMIN_CONFIG"
+	EnvEnableReasoning    = "ENABLE_REASONING"
+)
+
+// Load 解析命令行参数，按默认值、配置文件、环境变量、命令行参数的顺序合并配置并校验
+// 第二个返回值表示是否指定了 --print-config
+func Load(args []string) (*Config, bool, error) {
+	flags := flag.NewFlagSet("openai-mocker", flag.ContinueOnError)
+	configFile := flags.String("config", os.Getenv(EnvConfigFile), "配置文件路径（YAML或JSON）")
+	listen := flags.String("listen", "", "API监听地址，多个地址以逗号分隔，如 :8080,127.0.0.1:8081")
+	adminListen := flags.String("admin-listen", "", "管理API单独监听的地址，如 :9090 或 unix:/tmp/mocker-admin.sock")
+	stateDir := flags.String("state-dir", "", "状态存储目录")
+	tlsCert := flags.String("tls-cert", "", "HTTPS证书文件")
+	tlsKey := flags.String("tls-key", "", "HTTPS私钥文件")
+	enableReasoning := flags.Bool("enable-reasoning", false, "推理内容使用 reasoning_content 字段返回")
+	printConfig := flags.Bool("print-config", false, "打印生效的配置后退出")
+	if err := flags.Parse(args); err != nil {
+		return nil, false, err
+	}
+
+	cfg := Default()
+	if *configFile != "" {
+		if err := readFile(*configFile, cfg); err != nil {
+			return nil, false, err
+		}
+	}
+
+	if err := applyEnv(cfg); err != nil {
+		return nil, false, err
+	}
+
+	// 只覆盖命令行中显式指定的参数
+	flags.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "listen":
+			cfg.Listen = ParseAddresses(*listen)
+		case "admin-listen":
+			cfg.AdminListen = *adminListen
+		case "state-dir":
+			cfg.StateDir = *stateDir
+		case "tls-cert":
+			cfg.TLS.CertFile = *tlsCert
+		case "tls-key":
+			cfg.TLS.KeyFile = *tlsKey
+		case "enable-reasoning":
+			cfg.Features.ReasoningField = *enableReasoning
+		}
+	})
+
+	if err := cfg.Validate(); err != nil {
+		return nil, false, fmt.Errorf("invalid config:\n%v", err)
+	}
+	cfg.File = *configFile
+	cfg.args = args
+	return cfg, *printConfig, nil
+}
+
+// Reload 使用与启动时相同的参数和环境变量重新加载配置，配置无效时返回错误
+func (c *Config) Reload() (*Config, error) {
+	cfg, _, err := Load(c.args)
+	return cfg, err
+}
+
+// Print 以YAML格式输出配置，令牌和密钥以掩码形式输出
+func Print(w io.Writer, cfg *Config) error {
+	redacted := *cfg
+	redacted.Admin.Credentials = make([]AdminCredential, len(cfg.Admin.Credentials))
+	for i, credential := range cfg.Admin.Credentials {
+		credential.Token = redact(credential.Token)
+		redacted.Admin.Credentials[i] = credential
+	}
+	redacted.Keys = append(redacted.Keys[:0:0], cfg.Keys...)
+	for i := range redacted.Keys {
+		redacted.Keys[i].Key = redact(redacted.Keys[i].Key)
+	}
+
+	// 先转换为JSON再转换为YAML，字段名与JSON配置文件一致
+	data, err := json.Marshal(redacted)
+	if err != nil {
+		return err
+	}
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(value); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
+
+// readFile 读取YAML或JSON配置文件，未知字段视为错误
+func readFile(filePath string, cfg *Config) error {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return fmt.Errorf("error reading config file: %v", err)
+	}
+
+	// YAML先转换为JSON，字段名与JSON标签一致
+	if ext := strings.ToLower(filepath.Ext(filePath)); ext != ".json" {
+		var value interface{}
+		if err := yaml.Unmarshal(data, &value); err != nil {
+			return fmt.Errorf("error parsing config file %s: %v", filePath, err)
+		}
+		if value == nil {
+			return nil
+		}
+		if data, err = json.Marshal(value); err != nil {
+			return fmt.Errorf("error parsing config file %s: %v", filePath, err)
+		}
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(cfg); err != nil {
+		return fmt.Errorf("error parsing config file %s: %v", filePath, err)
+	}
+	return nil
+}
+
+// applyEnv 使用环境变量覆盖配置
+func applyEnv(cfg *Config) error {
+	if value := os.Getenv(EnvListen); value != "" {
+		cfg.Listen = ParseAddresses(value)
+	}
+	if value := os.Getenv(EnvAdminAddr); value != "" {
+		cfg.AdminListen = value
+	}
+	if value := os.Getenv(EnvStateDir); value != "" {
+		cfg.StateDir = value
+	}
+	if value := os.Getenv(EnvTLSCert); value != "" {
+		cfg.TLS.CertFile = value
+	}
+	if value := os.Getenv(EnvTLSKey); value != "" {
+		cfg.TLS.KeyFile = value
+	}
+	if value := os.Getenv(EnvEnableReasoning); value != "" {
+		cfg.Features.ReasoningField = strings.ToLower(value) == "true" || value == "1"
+	}
+
+	if token := os.Getenv(EnvAdminToken); token != "" {
+		cfg.Admin.Credentials = append(cfg.Admin.Credentials, AdminCredential{Name: "env-admin", Token: token, Role: AdminRoleAdmin})
+	}
+	if token := os.Getenv(EnvAdminReadOnlyToken); token != "" {
+		cfg.Admin.Credentials = append(cfg.Admin.Credentials, AdminCredential{Name: "env-read-only", Token: token, Role: AdminRoleReadOnly})
+	}
+
+	// 管理员凭据文件为JSON格式：{"credentials": [{"name": "...", "token": "...", "role": "admin"}]}
+	if path := os.Getenv(EnvAdminConfig); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("error reading admin config: %v", err)
+		}
+		var admin Admin
+		if err := json.Unmarshal(data, &admin); err != nil {
+			return fmt.Errorf("error parsing admin config: %v", err)
+		}
+		cfg.Admin.Credentials = append(cfg.Admin.Credentials, admin.Credentials...)
+	}
+	return nil
+}
+
+// redact 隐藏令牌中间部分
+func redact(value string) string {
+	if len(value) <= 8 {
+		return strings.Repeat("*", len(value))
+	}
+	return value[:4] + "..." + value[len(value)-4:]
+}
@@ -0,0 +1,397 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"RobinPenn974/OpenAI-mocker/api"
+	"RobinPenn974/OpenAI-mocker/assistants"
+	"RobinPenn974/OpenAI-mocker/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 元数据的数量和长度上限，与OpenAI一致
+const (
+	maxMetadataPairs       = 16
+	maxMetadataKeyLength   = 64
+	maxMetadataValueLength = 512
+)
+
+// HandleCreateAssistant 处理创建助手的请求
+func HandleCreateAssistant(c *gin.Context) {
+	var req api.AssistantRequest
+	if !bindAssistantsRequest(c, &req) {
+		return
+	}
+	if req.Model == nil || *req.Model == "" {
+		c.JSON(http.StatusBadRequest, api.NewErrorResponse("Missing required parameter: 'model'.", "invalid_request_error", "model", "missing_required_parameter"))
+		return
+	}
+
+	assistant := assistants.Assistant{}
+	if status, errResp := applyAssistantRequest(c, &assistant, req); errResp != nil {
+		c.JSON(status, errResp)
+		return
+	}
+	c.JSON(http.StatusOK, currentWorkspace(c).Assistants.CreateAssistant(assistant))
+}
+
+// HandleListAssistants 处理分页列出助手的请求
+func HandleListAssistants(c *gin.Context) {
+	params, errResp := listParams(c, api.OrderDesc)
+	if errResp != nil {
+		c.JSON(http.StatusBadRequest, errResp)
+		return
+	}
+
+	data, hasMore := currentWorkspace(c).Assistants.ListAssistants(params)
+	ids := make([]string, len(data))
+	for i, assistant := range data {
+		ids[i] = assistant.ID
+	}
+	c.JSON(http.StatusOK, api.NewListResponse(data, ids, hasMore))
+}
+
+// HandleGetAssistant 处理获取助手的请求
+func HandleGetAssistant(c *gin.Context) {
+	assistant, err := currentWorkspace(c).Assistants.GetAssistant(c.Param("assistant_id"))
+	if err != nil {
+		respondAssistantsError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, assistant)
+}
+
+// HandleModifyAssistant 处理修改助手的请求，只更新请求中传入的字段
+func HandleModifyAssistant(c *gin.Context) {
+	var req api.AssistantRequest
+	if !bindAssistantsRequest(c, &req) {
+		return
+	}
+
+	store := currentWorkspace(c).Assistants
+	assistant, err := store.GetAssistant(c.Param("assistant_id"))
+	if err != nil {
+		respondAssistantsError(c, err)
+		return
+	}
+	if status, errResp := applyAssistantRequest(c, &assistant, req); errResp != nil {
+		c.JSON(status, errResp)
+		return
+	}
+
+	assistant, err = store.UpdateAssistant(assistant.ID, func(stored *assistants.Assistant) {
+		*stored = assistant
+	})
+	if err != nil {
+		respondAssistantsError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, assistant)
+}
+
+// HandleDeleteAssistant 处理删除助手的请求
+func HandleDeleteAssistant(c *gin.Context) {
+	id := c.Param("assistant_id")
+	if err := currentWorkspace(c).Assistants.DeleteAssistant(id); err != nil {
+		respondAssistantsError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, api.DeletedResponse{ID: id, Object: "assistant.deleted", Deleted: true})
+}
+
+// applyAssistantRequest 校验请求并将传入的字段写入助手，失败时返回HTTP状态码和错误响应
+func applyAssistantRequest(c *gin.Context, assistant *assistants.Assistant, req api.AssistantRequest) (int, *api.ErrorResponse) {
+	modelID := assistant.Model
+	if req.Model != nil {
+		modelID = *req.Model
+	}
+	model, modelName, status, errResp := lookupModelForEndpoint(c, modelID, models.EndpointChatCompletions)
+	if errResp != nil {
+		return status, errResp
+	}
+
+	tools := assistant.Tools
+	if req.Tools != nil {
+		tools = *req.Tools
+	}
+	if errResp := validateAssistantTools(model, tools); errResp != nil {
+		return http.StatusBadRequest, errResp
+	}
+	if errResp := validateMetadata(req.Metadata); errResp != nil {
+		return http.StatusBadRequest, errResp
+	}
+
+	assistant.Model = modelName
+	assistant.Tools = tools
+	if req.Name != nil {
+		assistant.Name = req.Name
+	}
+	if req.Description != nil {
+		assistant.Description = req.Description
+	}
+	if req.Instructions != nil {
+		assistant.Instructions = req.Instructions
+	}
+	if req.ToolResources != nil {
+		assistant.ToolResources = req.ToolResources
+	}
+	if req.Metadata != nil {
+		assistant.Metadata = req.Metadata
+	}
+	if req.Temperature != nil {
+		assistant.Temperature = req.Temperature
+	}
+	if req.TopP != nil {
+		assistant.TopP = req.TopP
+	}
+	if req.ResponseFormat != nil {
+		assistant.ResponseFormat = req.ResponseFormat
+	}
+	return http.StatusOK, nil
+}
+
+// HandleCreateThread 处理创建线程的请求，可同时添加初始消息
+func HandleCreateThread(c *gin.Context) {
+	var req api.ThreadRequest
+	if !bindAssistantsRequest(c, &req) {
+		return
+	}
+
+	thread, messages, errResp := threadFromRequest(req)
+	if errResp != nil {
+		c.JSON(http.StatusBadRequest, errResp)
+		return
+	}
+	c.JSON(http.StatusOK, currentWorkspace(c).Assistants.CreateThread(thread, messages))
+}
+
+// HandleGetThread 处理获取线程的请求
+func HandleGetThread(c *gin.Context) {
+	thread, err := currentWorkspace(c).Assistants.GetThread(c.Param("thread_id"))
+	if err != nil {
+		respondAssistantsError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, thread)
+}
+
+// HandleModifyThread 处理修改线程元数据和工具资源的请求
+func HandleModifyThread(c *gin.Context) {
+	var req api.ThreadRequest
+	if !bindAssistantsRequest(c, &req) {
+		return
+	}
+	if errResp := validateMetadata(req.Metadata); errResp != nil {
+		c.JSON(http.StatusBadRequest, errResp)
+		return
+	}
+
+	thread, err := currentWorkspace(c).Assistants.UpdateThread(c.Param("thread_id"), func(thread *assistants.Thread) {
+		if req.Metadata != nil {
+			thread.Metadata = req.Metadata
+		}
+		if req.ToolResources != nil {
+			thread.ToolResources = req.ToolResources
+		}
+	})
+	if err != nil {
+		respondAssistantsError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, thread)
+}
+
+// HandleDeleteThread 处理删除线程的请求
+func HandleDeleteThread(c *gin.Context) {
+	id := c.Param("thread_id")
+	if err := currentWorkspace(c).Assistants.DeleteThread(id); err != nil {
+		respondAssistantsError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, api.DeletedResponse{ID: id, Object: "thread.deleted", Deleted: true})
+}
+
+// threadFromRequest 将创建线程的请求转换为线程和初始消息
+func threadFromRequest(req api.ThreadRequest) (assistants.Thread, []assistants.Message, *api.ErrorResponse) {
+	if errResp := validateMetadata(req.Metadata); errResp != nil {
+		return assistants.Thread{}, nil, errResp
+	}
+	messages, errResp := messagesFromRequest(req.Messages, "messages")
+	if errResp != nil {
+		return assistants.Thread{}, nil, errResp
+	}
+	return assistants.Thread{Metadata: req.Metadata, ToolResources: req.ToolResources}, messages, nil
+}
+
+// HandleCreateMessage 处理向线程添加消息的请求
+func HandleCreateMessage(c *gin.Context) {
+	var req api.AssistantMessageRequest
+	if !bindAssistantsRequest(c, &req) {
+		return
+	}
+
+	message, errResp := messageFromRequest(req, "")
+	if errResp != nil {
+		c.JSON(http.StatusBadRequest, errResp)
+		return
+	}
+	message, err := currentWorkspace(c).Assistants.CreateMessage(c.Param("thread_id"), message)
+	if err != nil {
+		respondAssistantsError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, message)
+}
+
+// HandleListMessages 处理分页列出线程消息的请求，run_id 参数只列出该运行创建的消息
+func HandleListMessages(c *gin.Context) {
+	params, errResp := listParams(c, api.OrderDesc)
+	if errResp != nil {
+		c.JSON(http.StatusBadRequest, errResp)
+		return
+	}
+
+	data, hasMore, err := currentWorkspace(c).Assistants.ListMessages(c.Param("thread_id"), c.Query("run_id"), params)
+	if err != nil {
+		respondAssistantsError(c, err)
+		return
+	}
+	ids := make([]string, len(data))
+	for i, message := range data {
+		ids[i] = message.ID
+	}
+	c.JSON(http.StatusOK, api.NewListResponse(data, ids, hasMore))
+}
+
+// HandleGetMessage 处理获取线程消息的请求
+func HandleGetMessage(c *gin.Context) {
+	message, err := currentWorkspace(c).Assistants.GetMessage(c.Param("thread_id"), c.Param("message_id"))
+	if err != nil {
+		respondAssistantsError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, message)
+}
+
+// HandleModifyMessage 处理修改消息元数据的请求
+func HandleModifyMessage(c *gin.Context) {
+	var req api.MetadataRequest
+	if !bindAssistantsRequest(c, &req) {
+		return
+	}
+	if errResp := validateMetadata(req.Metadata); errResp != nil {
+		c.JSON(http.StatusBadRequest, errResp)
+		return
+	}
+
+	message, err := currentWorkspace(c).Assistants.UpdateMessage(c.Param("thread_id"), c.Param("message_id"), req.Metadata)
+	if err != nil {
+		respondAssistantsError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, message)
+}
+
+// HandleDeleteMessage 处理删除线程消息的请求
+func HandleDeleteMessage(c *gin.Context) {
+	id := c.Param("message_id")
+	if err := currentWorkspace(c).Assistants.DeleteMessage(c.Param("thread_id"), id); err != nil {
+		respondAssistantsError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, api.DeletedResponse{ID: id, Object: "thread.message.deleted", Deleted: true})
+}
+
+// messagesFromRequest 转换请求中的消息列表，param 为错误信息中的参数名前缀
+func messagesFromRequest(reqs []api.AssistantMessageRequest, param string) ([]assistants.Message, *api.ErrorResponse) {
+	messages := make([]assistants.Message, 0, len(reqs))
+	for i, req := range reqs {
+		message, errResp := messageFromRequest(req, fmt.Sprintf("%s[%d].", param, i))
+		if errResp != nil {
+			return nil, errResp
+		}
+		messages = append(messages, message)
+	}
+	return messages, nil
+}
+
+// messageFromRequest 校验并转换添加消息的请求，prefix 为错误信息中的参数名前缀
+func messageFromRequest(req api.AssistantMessageRequest, prefix string) (assistants.Message, *api.ErrorResponse) {
+	if req.Role != "user" && req.Role != "assistant" {
+		return assistants.Message{}, invalidValue(prefix+"role", req.Role, "'user' and 'assistant'")
+	}
+	if len(req.Content) == 0 {
+		errResp := api.NewErrorResponse(fmt.Sprintf("Missing required parameter: '%scontent'.", prefix), "invalid_request_error", prefix+"content", "missing_required_parameter")
+		return assistants.Message{}, &errResp
+	}
+	content, err := assistants.ParseMessageContent(req.Content)
+	if err != nil {
+		errResp := api.NewErrorResponse(fmt.Sprintf("Invalid '%scontent': %s.", prefix, err.Error()), "invalid_request_error", prefix+"content", "invalid_type")
+		return assistants.Message{}, &errResp
+	}
+	if errResp := validateMetadata(req.Metadata); errResp != nil {
+		return assistants.Message{}, errResp
+	}
+	return assistants.Message{
+		Role:        req.Role,
+		Content:     content,
+		Attachments: req.Attachments,
+		Metadata:    req.Metadata,
+	}, nil
+}
+
+// validateAssistantTools 校验工具定义，函数工具需要模型支持工具调用
+func validateAssistantTools(model models.ModelInfo, tools []api.AssistantTool) *api.ErrorResponse {
+	if param, err := assistants.ValidateTools(tools); err != nil {
+		errResp := api.NewErrorResponse(err.Error(), "invalid_request_error", param, "invalid_value")
+		return &errResp
+	}
+	for _, tool := range tools {
+		if tool.Type == assistants.ToolFunction && !model.SupportsTools {
+			return unsupportedParameter("tools")
+		}
+	}
+	return nil
+}
+
+// validateMetadata 校验元数据的数量和键值长度
+func validateMetadata(metadata map[string]string) *api.ErrorResponse {
+	if len(metadata) > maxMetadataPairs {
+		errResp := api.NewErrorResponse(fmt.Sprintf("Invalid 'metadata': too many properties. Expected an object with at most %d properties, but got an object with %d properties instead.", maxMetadataPairs, len(metadata)), "invalid_request_error", "metadata", "object_above_max_properties")
+		return &errResp
+	}
+	for key, value := range metadata {
+		if len(key) > maxMetadataKeyLength {
+			errResp := api.NewErrorResponse(fmt.Sprintf("Invalid 'metadata': key '%s' is too long. Expected a key with maximum length %d.", key, maxMetadataKeyLength), "invalid_request_error", "metadata", "invalid_value")
+			return &errResp
+		}
+		if len(value) > maxMetadataValueLength {
+			errResp := api.NewErrorResponse(fmt.Sprintf("Invalid 'metadata.%s': string too long. Expected a string with maximum length %d, but got a string with length %d instead.", key, maxMetadataValueLength, len(value)), "invalid_request_error", "metadata."+key, "string_above_max_length")
+			return &errResp
+		}
+	}
+	return nil
+}
+
+// bindAssistantsRequest 绑定Assistants API的JSON请求体，请求体为空时使用零值，失败时返回400错误
+func bindAssistantsRequest(c *gin.Context, req interface{}) bool {
+	if err := c.ShouldBindJSON(req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, api.NewErrorResponse("Invalid request: "+err.Error(), "invalid_request_error", "", ""))
+		return false
+	}
+	return true
+}
+
+// respondAssistantsError 返回存储操作的错误，对象不存在时返回404，其余返回400
+func respondAssistantsError(c *gin.Context, err error) {
+	var notFound *assistants.NotFoundError
+	if errors.As(err, &notFound) {
+		c.JSON(http.StatusNotFound, api.NewErrorResponse(err.Error(), "invalid_request_error", "", ""))
+		return
+	}
+	c.JSON(http.StatusBadRequest, api.NewErrorResponse(err.Error(), "invalid_request_error", "", ""))
+}
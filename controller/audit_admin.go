@@ -0,0 +1,98 @@
+package controller
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HandleListAuditLog 处理查看管理操作审计日志的请求，按时间倒序返回
+func HandleListAuditLog(c *gin.Context) {
+	limit := 100
+	if value := c.Query("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": gin.H{
+					"message": "Invalid limit: " + value,
+					"type":    "invalid_request_error",
+				},
+			})
+			return
+		}
+		limit = parsed
+	}
+
+	entries := currentInstance(c).Audit.List(limit)
+	c.JSON(http.StatusOK, gin.H{
+		"entries": entries,
+		"count":   len(entries),
+	})
+}
+
+// SnapshotModels 返回模型注册表和路由规则的快照，用于审计
+func SnapshotModels(c *gin.Context) interface{} {
+	ws := currentWorkspace(c)
+	return gin.H{
+		"models":  ws.Models.ListModels(),
+		"routing": ws.Router.Rules(),
+	}
+}
+
+// SnapshotTemplates 返回响应模板的快照，用于审计
+func SnapshotTemplates(c *gin.Context) interface{} {
+	ws := currentWorkspace(c)
+	templateList := ws.Templates.ListTemplates()
+	sort.Slice(templateList, func(i, j int) bool {
+		return templateList[i].ModelID < templateList[j].ModelID
+	})
+	return templateList
+}
+
+// SnapshotApiKeys 返回API密钥的快照，密钥以掩码形式记录，用于审计
+func SnapshotApiKeys(c *gin.Context) interface{} {
+	keys := currentInstance(c).Keys.ListKeys()
+	infos := make([]interface{}, 0, len(keys))
+	for _, key := range keys {
+		infos = append(infos, key.Info())
+	}
+	return infos
+}
+
+// SnapshotEmbeddingPins 返回固定向量的快照，只记录模型和输入，用于审计
+func SnapshotEmbeddingPins(c *gin.Context) interface{} {
+	ws := currentWorkspace(c)
+	pins := ws.Pins.ListPins()
+	sort.Slice(pins, func(i, j int) bool {
+		return pins[i].ID < pins[j].ID
+	})
+	result := make([]gin.H, 0, len(pins))
+	for _, pin := range pins {
+		result = append(result, gin.H{
+			"id":         pin.ID,
+			"model_id":   pin.ModelID,
+			"input":      pin.Input,
+			"dimensions": len(pin.Embedding),
+		})
+	}
+	return result
+}
+
+// SnapshotAzure 返回Azure部署和内容过滤规则的快照，用于审计
+func SnapshotAzure(c *gin.Context) interface{} {
+	ws := currentWorkspace(c)
+	deployments := ws.Deployments.ListDeployments()
+	sort.Slice(deployments, func(i, j int) bool {
+		return deployments[i].Name < deployments[j].Name
+	})
+	rules := ws.ContentFilter.ListRules()
+	sort.Slice(rules, func(i, j int) bool {
+		return rules[i].ID < rules[j].ID
+	})
+	return gin.H{
+		"deployments":          deployments,
+		"content_filter_rules": rules,
+	}
+}
@@ -1,49 +1,17 @@
 package controller
 
 import (
-	"crypto/rand"
-	"encoding/hex"
 	"net/http"
 
-	"RobinPenn974/OpenAI-mocker/middleware"
+	"RobinPenn974/OpenAI-mocker/api"
+	"RobinPenn974/OpenAI-mocker/apikeys"
 
 	"github.com/gin-gonic/gin"
 )
 
-// ApiKeyRequest 创建API密钥的请求结构
-type ApiKeyRequest struct {
-	Name string `json:"name" binding:"required"`
-}
-
-// ApiKeyResponse API密钥响应结构
-type ApiKeyResponse struct {
-	Key  string `json:"key"`
-	Name string `json:"name"`
-}
-
-// ApiKeyListResponse API密钥列表响应结构
-type ApiKeyListResponse struct {
-	Keys []ApiKeyInfo `json:"keys"`
-}
-
-// ApiKeyInfo API密钥信息
-type ApiKeyInfo struct {
-	Key  string `json:"key"`
-	Name string `json:"name"`
-}
-
-// GenerateAPIKey 生成一个新的API密钥
-func GenerateAPIKey() (string, error) {
-	bytes := make([]byte, 16)
-	if _, err := rand.Read(bytes); err != nil {
-		return "", err
-	}
-	return "sk-mock-" + hex.EncodeToString(bytes), nil
-}
-
-// HandleCreateApiKey 处理创建API密钥的请求
+// HandleCreateApiKey 处理创建API密钥的请求，完整密钥只在创建时返回一次
 func HandleCreateApiKey(c *gin.Context) {
-	var req ApiKeyRequest
+	var req api.CreateKeyRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error": gin.H{
@@ -54,28 +22,56 @@ func HandleCreateApiKey(c *gin.Context) {
 		return
 	}
 
-	// 生成新的API密钥
-	apiKey, err := GenerateAPIKey()
+	// 绑定的工作区必须已存在
+	if req.Workspace != "" {
+		if _, err := currentInstance(c).Workspaces.Get(req.Workspace); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": gin.H{
+					"message": "Workspace '" + req.Workspace + "' not found",
+					"type":    "invalid_request_error",
+				},
+			})
+			return
+		}
+	}
+
+	key, secret, err := currentInstance(c).Keys.CreateKey(apikeys.OptionsFromRequest(req))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
+		c.JSON(http.StatusBadRequest, gin.H{
 			"error": gin.H{
-				"message": "Failed to generate API key: " + err.Error(),
-				"type":    "server_error",
+				"message": "Failed to create API key: " + err.Error(),
+				"type":    "invalid_request_error",
 			},
 		})
 		return
 	}
 
-	// 添加API密钥到全局存储
-	middleware.GlobalApiKeys.AddKey(apiKey, req.Name)
+	info := key.Info()
+	info.Key = secret
+	c.JSON(http.StatusOK, info)
+}
 
-	c.JSON(http.StatusOK, ApiKeyResponse{
-		Key:  apiKey,
-		Name: req.Name,
+// HandleRevokeApiKey 处理吊销API密钥的请求，吊销后的密钥保留在列表中
+func HandleRevokeApiKey(c *gin.Context) {
+	key, err := currentInstance(c).Keys.RevokeKey(c.Param("key_id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": gin.H{
+				"message": err.Error(),
+				"type":    "invalid_request_error",
+			},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "API key revoked successfully",
+		"key":     key.Info(),
 	})
 }
 
-// HandleDeleteApiKey 处理删除API密钥的请求
+// HandleDeleteApiKey 处理删除API密钥的请求，参数可以是密钥ID或完整密钥
 func HandleDeleteApiKey(c *gin.Context) {
 	keyID := c.Param("key_id")
 	if keyID == "" {
@@ -89,7 +85,15 @@ func HandleDeleteApiKey(c *gin.Context) {
 	}
 
 	// 删除API密钥
-	middleware.GlobalApiKeys.RemoveKey(keyID)
+	if err := currentInstance(c).Keys.DeleteKey(keyID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": gin.H{
+				"message": err.Error(),
+				"type":    "invalid_request_error",
+			},
+		})
+		return
+	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
@@ -100,7 +104,15 @@ func HandleDeleteApiKey(c *gin.Context) {
 // HandleDeleteAllApiKeys 处理删除所有API密钥的请求
 func HandleDeleteAllApiKeys(c *gin.Context) {
 	// 删除所有API密钥
-	middleware.GlobalApiKeys.RemoveAllKeys()
+	if err := currentInstance(c).Keys.RemoveAllKeys(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": gin.H{
+				"message": err.Error(),
+				"type":    "internal_server_error",
+			},
+		})
+		return
+	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
@@ -108,19 +120,17 @@ func HandleDeleteAllApiKeys(c *gin.Context) {
 	})
 }
 
-// HandleListApiKeys 处理列出所有API密钥的请求
+// HandleListApiKeys 处理列出所有API密钥的请求，密钥以掩码形式展示
 func HandleListApiKeys(c *gin.Context) {
-	keys := middleware.GlobalApiKeys.GetAllKeys()
+	keys := currentInstance(c).Keys.ListKeys()
 
-	var keyInfos []ApiKeyInfo
-	for key, name := range keys {
-		keyInfos = append(keyInfos, ApiKeyInfo{
-			Key:  key,
-			Name: name,
-		})
+	keyInfos := make([]api.ApiKey, 0, len(keys))
+	for _, key := range keys {
+		keyInfos = append(keyInfos, key.Info())
 	}
 
-	c.JSON(http.StatusOK, ApiKeyListResponse{
-		Keys: keyInfos,
+	c.JSON(http.StatusOK, gin.H{
+		"keys":  keyInfos,
+		"count": len(keyInfos),
 	})
 }
@@ -0,0 +1,270 @@
+package controller
+
+import (
+	"net/http"
+	"strings"
+
+	"RobinPenn974/OpenAI-mocker/api"
+	"RobinPenn974/OpenAI-mocker/azure"
+	"RobinPenn974/OpenAI-mocker/models"
+	"RobinPenn974/OpenAI-mocker/provider"
+	"RobinPenn974/OpenAI-mocker/responses"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HandleAzureChatCompletions 处理Azure部署风格的Chat Completions请求
+func HandleAzureChatCompletions(c *gin.Context) {
+	var req api.ChatCompletionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondAzureError(c, http.StatusBadRequest, "Invalid request: "+err.Error(), "BadRequest")
+		return
+	}
+
+	deployment := c.Param("deployment")
+	model, modelName, ok := resolveAzureDeployment(c, deployment)
+	if !ok {
+		return
+	}
+	req.Model = modelName
+
+	if !checkAzureOperation(c, model, models.EndpointChatCompletions, "chatCompletion") {
+		return
+	}
+	if errResp := validateChatRequest(model, req); errResp != nil {
+		c.JSON(http.StatusBadRequest, errResp)
+		return
+	}
+
+	respondAzureChatCompletion(c, req, deployment, model)
+}
+
+// respondAzureChatCompletion 对已校验的Chat请求执行内容过滤并返回Azure风格的回复
+func respondAzureChatCompletion(c *gin.Context, req api.ChatCompletionRequest, deployment string, model models.ModelInfo) {
+	ws := currentWorkspace(c)
+
+	// 检查提示词是否触发内容过滤
+	prompts := make([]string, 0, len(req.Messages))
+	for _, message := range req.Messages {
+		prompts = append(prompts, message.Content)
+	}
+	promptResults, filtered := ws.ContentFilter.Evaluate(azure.TargetPrompt, deployment, model.ID, strings.Join(prompts, "\n"))
+	if filtered {
+		respondAzureContentFilterError(c, promptResults)
+		return
+	}
+	promptFilterResults := []api.PromptFilterResult{{PromptIndex: 0, ContentFilterResults: promptResults}}
+
+	if req.Stream {
+		// 回复内容触发内容过滤时只发送带有 content_filter 结束原因的空数据块
+		responseContent := generateChatContent(req, ws.Template(req.Model), responseOptions(c))
+		if _, completionFiltered := ws.ContentFilter.Evaluate(azure.TargetCompletion, deployment, model.ID, responseContent.Content); completionFiltered {
+			responseContent = filteredContent(responseContent)
+		}
+		chunks := chatStreamChunks(req, responseContent)
+		first := chunks[0].data.(api.ChatCompletionChunkResponse)
+		promptChunk := azurePromptFilterChunk(first.ID, first.Object, first.Created, first.Model, promptFilterResults)
+		chunks = shapeStream(currentProviderProfile(c), provider.KindChatChunk, chunks, chatUsage(req, responseContent))
+		writeStream(c, newStreamPacer(c, req.Model), append([]streamChunk{promptChunk}, chunks...))
+		return
+	}
+
+	response := generateChatResponse(req, ws.Template(req.Model), responseOptions(c))
+	choices := make([]api.AzureChatCompletionChoice, 0, len(response.Choices))
+	for _, choice := range response.Choices {
+		completionResults, completionFiltered := ws.ContentFilter.Evaluate(azure.TargetCompletion, deployment, model.ID, choice.Message.Content)
+		if completionFiltered {
+			choice.Message.Content = ""
+			choice.FinishReason = "content_filter"
+		}
+		choices = append(choices, api.AzureChatCompletionChoice{
+			ChatCompletionChoice: choice,
+			ContentFilterResults: completionResults,
+		})
+	}
+
+	c.JSON(http.StatusOK, api.AzureChatCompletionResponse{
+		ID:                  response.ID,
+		Object:              response.Object,
+		Created:             response.Created,
+		Model:               response.Model,
+		PromptFilterResults: promptFilterResults,
+		Choices:             choices,
+		Usage:               response.Usage,
+	})
+}
+
+// HandleAzureCompletions 处理Azure部署风格的文本补全请求
+func HandleAzureCompletions(c *gin.Context) {
+	var req api.CompletionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondAzureError(c, http.StatusBadRequest, "Invalid request: "+err.Error(), "BadRequest")
+		return
+	}
+
+	deployment := c.Param("deployment")
+	model, modelName, ok := resolveAzureDeployment(c, deployment)
+	if !ok {
+		return
+	}
+	req.Model = modelName
+
+	if !checkAzureOperation(c, model, models.EndpointCompletions, "completion") {
+		return
+	}
+	if errResp := validateCompletionRequest(model, req); errResp != nil {
+		c.JSON(http.StatusBadRequest, errResp)
+		return
+	}
+
+	respondAzureCompletion(c, req, deployment, model)
+}
+
+// respondAzureCompletion 对已校验的文本补全请求执行内容过滤并返回Azure风格的回复
+func respondAzureCompletion(c *gin.Context, req api.CompletionRequest, deployment string, model models.ModelInfo) {
+	ws := currentWorkspace(c)
+
+	promptResults, filtered := ws.ContentFilter.Evaluate(azure.TargetPrompt, deployment, model.ID, req.Prompt)
+	if filtered {
+		respondAzureContentFilterError(c, promptResults)
+		return
+	}
+	promptFilterResults := []api.PromptFilterResult{{PromptIndex: 0, ContentFilterResults: promptResults}}
+
+	if req.Stream {
+		responseContent := generateCompletionContent(req, ws.Template(req.Model), responseOptions(c))
+		if _, completionFiltered := ws.ContentFilter.Evaluate(azure.TargetCompletion, deployment, model.ID, responseContent.Content); completionFiltered {
+			responseContent = filteredContent(responseContent)
+		}
+		chunks := completionStreamChunks(req, responseContent)
+		first := chunks[0].data.(api.CompletionChunkResponse)
+		promptChunk := azurePromptFilterChunk(first.ID, first.Object, first.Created, first.Model, promptFilterResults)
+		chunks = shapeStream(currentProviderProfile(c), provider.KindCompletionChunk, chunks, completionUsage(req, responseContent))
+		writeStream(c, newStreamPacer(c, req.Model), append([]streamChunk{promptChunk}, chunks...))
+		return
+	}
+
+	response := generateCompletion(req, ws.Template(req.Model), responseOptions(c))
+	choices := make([]api.AzureCompletionChoice, 0, len(response.Choices))
+	for _, choice := range response.Choices {
+		completionResults, completionFiltered := ws.ContentFilter.Evaluate(azure.TargetCompletion, deployment, model.ID, choice.Text)
+		if completionFiltered {
+			choice.Text = ""
+			choice.FinishReason = "content_filter"
+		}
+		choices = append(choices, api.AzureCompletionChoice{
+			CompletionChoice:     choice,
+			ContentFilterResults: completionResults,
+		})
+	}
+
+	c.JSON(http.StatusOK, api.AzureCompletionResponse{
+		ID:                  response.ID,
+		Object:              response.Object,
+		Created:             response.Created,
+		Model:               response.Model,
+		PromptFilterResults: promptFilterResults,
+		Choices:             choices,
+		Usage:               response.Usage,
+	})
+}
+
+// HandleAzureEmbeddings 处理Azure部署风格的Embeddings请求
+func HandleAzureEmbeddings(c *gin.Context) {
+	var req api.EmbeddingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondAzureError(c, http.StatusBadRequest, "Invalid request: "+err.Error(), "BadRequest")
+		return
+	}
+
+	ws := currentWorkspace(c)
+	model, modelName, ok := resolveAzureDeployment(c, c.Param("deployment"))
+	if !ok {
+		return
+	}
+	if !checkAzureOperation(c, model, models.EndpointEmbeddings, "embeddings") {
+		return
+	}
+	req.Model = modelName
+
+	spec := models.GetEmbeddingSpec(model)
+	tokenCounts, errResp := validateEmbeddingRequest(req, spec)
+	if errResp != nil {
+		c.JSON(http.StatusBadRequest, errResp)
+		return
+	}
+
+	response, err := generateMockEmbeddings(req, model, spec, tokenCounts, ws.Pins)
+	if err != nil {
+		respondAzureError(c, http.StatusInternalServerError, err.Error(), "InternalServerError")
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// azurePromptFilterChunk 构造流式响应中携带提示词过滤结果的首个数据块，Azure在其中返回与后续数据块相同的ID和模型
+func azurePromptFilterChunk(id, object string, created int64, model string, results []api.PromptFilterResult) streamChunk {
+	return streamChunk{data: api.AzurePromptFilterChunk{
+		ID:                  id,
+		Object:              object,
+		Created:             created,
+		Model:               model,
+		PromptFilterResults: results,
+		Choices:             []interface{}{},
+	}}
+}
+
+// filteredContent 清空触发内容过滤的回复，结束原因为 content_filter
+func filteredContent(responseContent responses.ResponseContent) responses.ResponseContent {
+	responseContent.Content = ""
+	responseContent.ReasoningContent = nil
+	responseContent.FinishReason = "content_filter"
+	return responseContent
+}
+
+// resolveAzureDeployment 将URL中的部署名解析为模型和响应中回显的模型名，部署不存在时返回Azure风格的404错误，
+// API密钥无权访问部署的模型时返回403错误
+func resolveAzureDeployment(c *gin.Context, deployment string) (models.ModelInfo, string, bool) {
+	resolution, err := currentWorkspace(c).ResolveDeployment(deployment)
+	if err != nil {
+		respondAzureError(c, http.StatusNotFound, "The API deployment for this resource does not exist. If you created the deployment within the last 5 minutes, please wait a moment and try again.", "DeploymentNotFound")
+		return models.ModelInfo{}, "", false
+	}
+	if errResp := modelAccessDenied(c, deployment, resolution.Model.ID); errResp != nil {
+		respondAzureError(c, http.StatusForbidden, errResp.Error.Message, "403")
+		return models.ModelInfo{}, "", false
+	}
+	return resolution.Model, resolution.Name, true
+}
+
+// checkAzureOperation 检查部署的模型是否支持指定接口，不支持时返回Azure风格的400错误
+func checkAzureOperation(c *gin.Context, model models.ModelInfo, endpoint, operation string) bool {
+	if model.SupportsEndpoint(endpoint) {
+		return true
+	}
+	respondAzureError(c, http.StatusBadRequest, "The "+operation+" operation does not work with the specified model, "+model.ID+". Please choose different model and try again. You can learn more about which models can be used with each operation here: https://go.microsoft.com/fwlink/?linkid=2197993.", "OperationNotSupported")
+	return false
+}
+
+// respondAzureError 返回Azure风格的错误响应
+func respondAzureError(c *gin.Context, status int, message, code string) {
+	var response api.AzureErrorResponse
+	response.Error.Message = message
+	response.Error.Code = code
+	c.JSON(status, response)
+}
+
+// respondAzureContentFilterError 返回提示词触发内容过滤时的400错误
+func respondAzureContentFilterError(c *gin.Context, results api.ContentFilterResults) {
+	param := "prompt"
+	var response api.AzureErrorResponse
+	response.Error.Message = "The response was filtered due to the prompt triggering Azure OpenAI's content management policy. Please modify your prompt and retry. To learn more about our content filtering policies please read our documentation: https://go.microsoft.com/fwlink/?linkid=2198766"
+	response.Error.Param = &param
+	response.Error.Code = "content_filter"
+	response.Error.Status = http.StatusBadRequest
+	response.Error.InnerError = &api.AzureInnerError{
+		Code:                "ResponsibleAIPolicyViolation",
+		ContentFilterResult: results,
+	}
+	c.JSON(http.StatusBadRequest, response)
+}
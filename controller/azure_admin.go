@@ -0,0 +1,166 @@
+package controller
+
+import (
+	"net/http"
+
+	"RobinPenn974/OpenAI-mocker/api"
+	"RobinPenn974/OpenAI-mocker/azure"
+	"RobinPenn974/OpenAI-mocker/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HandleListDeployments 处理列出所有Azure部署的请求
+func HandleListDeployments(c *gin.Context) {
+	ws := currentWorkspace(c)
+	deploymentList := ws.Deployments.ListDeployments()
+	c.JSON(http.StatusOK, gin.H{
+		"deployments": deploymentList,
+		"count":       len(deploymentList),
+	})
+}
+
+// HandleCreateDeployment 处理创建或更新Azure部署的请求
+func HandleCreateDeployment(c *gin.Context) {
+	ws := currentWorkspace(c)
+	var req api.CreateDeploymentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": gin.H{
+				"message": "Invalid request: " + err.Error(),
+				"type":    "invalid_request_error",
+			},
+		})
+		return
+	}
+
+	// 部署必须指向已注册的模型，模型名可以是别名
+	if _, err := ws.LookupModel(req.ModelID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": gin.H{
+				"message": "Model '" + req.ModelID + "' not found",
+				"type":    "invalid_request_error",
+			},
+		})
+		return
+	}
+
+	deployment := models.Deployment{
+		Name:    req.Name,
+		ModelID: req.ModelID,
+	}
+	if err := ws.Deployments.RegisterDeployment(deployment); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": gin.H{
+				"message": "Failed to register deployment: " + err.Error(),
+				"type":    "internal_server_error",
+			},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":    true,
+		"message":    "Deployment created successfully",
+		"deployment": deployment,
+	})
+}
+
+// HandleDeleteDeployment 处理删除Azure部署的请求
+func HandleDeleteDeployment(c *gin.Context) {
+	ws := currentWorkspace(c)
+	if err := ws.Deployments.DeleteDeployment(c.Param("name")); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": gin.H{
+				"message": err.Error(),
+				"type":    "invalid_request_error",
+			},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Deployment deleted successfully",
+	})
+}
+
+// HandleListContentFilterRules 处理列出所有内容过滤规则的请求
+func HandleListContentFilterRules(c *gin.Context) {
+	ws := currentWorkspace(c)
+	rules := ws.ContentFilter.ListRules()
+	c.JSON(http.StatusOK, gin.H{
+		"rules": rules,
+		"count": len(rules),
+	})
+}
+
+// HandleCreateContentFilterRule 处理创建内容过滤规则的请求
+func HandleCreateContentFilterRule(c *gin.Context) {
+	ws := currentWorkspace(c)
+	var rule azure.ContentFilterRule
+	if err := c.ShouldBindJSON(&rule); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": gin.H{
+				"message": "Invalid request: " + err.Error(),
+				"type":    "invalid_request_error",
+			},
+		})
+		return
+	}
+
+	rule, err := ws.ContentFilter.AddRule(rule)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": gin.H{
+				"message": err.Error(),
+				"type":    "invalid_request_error",
+			},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Content filter rule created successfully",
+		"rule":    rule,
+	})
+}
+
+// HandleDeleteContentFilterRule 处理删除内容过滤规则的请求
+func HandleDeleteContentFilterRule(c *gin.Context) {
+	ws := currentWorkspace(c)
+	if err := ws.ContentFilter.DeleteRule(c.Param("rule_id")); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": gin.H{
+				"message": err.Error(),
+				"type":    "invalid_request_error",
+			},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Content filter rule deleted successfully",
+	})
+}
+
+// HandleDeleteAllContentFilterRules 处理删除所有内容过滤规则的请求
+func HandleDeleteAllContentFilterRules(c *gin.Context) {
+	ws := currentWorkspace(c)
+	if err := ws.ContentFilter.RemoveAllRules(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": gin.H{
+				"message": "Failed to delete content filter rules: " + err.Error(),
+				"type":    "internal_server_error",
+			},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "All content filter rules deleted successfully",
+	})
+}
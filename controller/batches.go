@@ -0,0 +1,189 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	"RobinPenn974/OpenAI-mocker/api"
+	"RobinPenn974/OpenAI-mocker/batches"
+	"RobinPenn974/OpenAI-mocker/files"
+	"RobinPenn974/OpenAI-mocker/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// batchHandlers 批处理支持的请求路径对应的处理器，与直接调用接口使用相同的处理流程
+var batchHandlers = map[string]gin.HandlerFunc{
+	"/v1/chat/completions": HandleChatCompletions,
+	"/v1/embeddings":       HandleEmbeddings,
+	"/v1/completions":      HandleCompletions,
+}
+
+// HandleCreateBatch 处理创建批处理的请求，立即执行输入文件中的全部请求，状态和结果文件按配置的耗时推进
+func HandleCreateBatch(c *gin.Context) {
+	var req api.CreateBatchRequest
+	if !bindAssistantsRequest(c, &req) {
+		return
+	}
+	if req.InputFileID == "" {
+		c.JSON(http.StatusBadRequest, api.NewErrorResponse("Missing required parameter: 'input_file_id'.", "invalid_request_error", "input_file_id", "missing_required_parameter"))
+		return
+	}
+	if _, ok := batchHandlers[req.Endpoint]; !ok {
+		c.JSON(http.StatusBadRequest, invalidValue("endpoint", req.Endpoint, "'"+strings.Join(files.BatchURLs, "', '")+"'"))
+		return
+	}
+	if req.CompletionWindow != batches.CompletionWindow {
+		c.JSON(http.StatusBadRequest, invalidValue("completion_window", req.CompletionWindow, "'"+batches.CompletionWindow+"'"))
+		return
+	}
+	if errResp := validateMetadata(req.Metadata); errResp != nil {
+		c.JSON(http.StatusBadRequest, errResp)
+		return
+	}
+	if key, ok := middleware.CurrentKey(c); ok {
+		if scope := middleware.EndpointScope(req.Endpoint); !key.HasScope(scope) {
+			c.JSON(http.StatusForbidden, api.NewErrorResponse(fmt.Sprintf("You have insufficient permissions for this operation. Missing scopes: %s. Check that your API key has the necessary scopes.", scope), "invalid_request_error", "", "insufficient_permissions"))
+			return
+		}
+	}
+
+	ws := currentWorkspace(c)
+	file, data, err := ws.Files.Content(req.InputFileID)
+	if err != nil {
+		respondFilesError(c, err)
+		return
+	}
+	if file.Purpose != files.PurposeBatch {
+		c.JSON(http.StatusBadRequest, api.NewErrorResponse(fmt.Sprintf("Invalid 'input_file_id': '%s'. Expected a file with purpose 'batch', but got '%s'.", file.ID, file.Purpose), "invalid_request_error", "input_file_id", "invalid_value"))
+		return
+	}
+
+	batch := batches.Batch{
+		Endpoint:    req.Endpoint,
+		InputFileID: req.InputFileID,
+		Metadata:    req.Metadata,
+	}
+
+	// 输入文件格式不正确时批处理直接失败，与OpenAI一致在批处理的 errors 中返回出错的行
+	requests, err := files.ParseBatchRequests(data)
+	var lineErr *files.LineError
+	if errors.As(err, &lineErr) {
+		c.JSON(http.StatusOK, ws.Batches.CreateFailed(batch, []batches.Error{batchError(lineErr.Code, lineErr.Message, lineErr.Line)}))
+		return
+	}
+	if requests[0].URL != req.Endpoint {
+		message := fmt.Sprintf("The url '%s' does not match the batch endpoint '%s'.", requests[0].URL, req.Endpoint)
+		c.JSON(http.StatusOK, ws.Batches.CreateFailed(batch, []batches.Error{batchError("mismatched_endpoint", message, requests[0].Line)}))
+		return
+	}
+
+	results := make([]batches.Result, len(requests))
+	for i, request := range requests {
+		results[i] = executeBatchRequest(c, request)
+	}
+	duration := time.Duration(currentInstance(c).Config().Batches.DurationMs) * time.Millisecond
+	c.JSON(http.StatusOK, ws.Batches.Create(batch, results, duration))
+}
+
+// HandleListBatches 处理分页列出批处理的请求
+func HandleListBatches(c *gin.Context) {
+	params, errResp := listParams(c, api.OrderDesc)
+	if errResp != nil {
+		c.JSON(http.StatusBadRequest, errResp)
+		return
+	}
+
+	data, hasMore := currentWorkspace(c).Batches.List(params)
+	ids := make([]string, len(data))
+	for i, batch := range data {
+		ids[i] = batch.ID
+	}
+	c.JSON(http.StatusOK, api.NewListResponse(data, ids, hasMore))
+}
+
+// HandleGetBatch 处理获取批处理的请求
+func HandleGetBatch(c *gin.Context) {
+	batch, err := currentWorkspace(c).Batches.Get(c.Param("batch_id"))
+	if err != nil {
+		respondBatchesError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, batch)
+}
+
+// HandleCancelBatch 处理取消批处理的请求
+func HandleCancelBatch(c *gin.Context) {
+	batch, err := currentWorkspace(c).Batches.Cancel(c.Param("batch_id"))
+	if err != nil {
+		respondBatchesError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, batch)
+}
+
+// executeBatchRequest 使用当前请求的密钥、工作区和服务商兼容配置执行批处理中的一个请求
+// 请求先按故障注入规则检查，设置了 custom_id 的规则只命中对应的请求
+func executeBatchRequest(c *gin.Context, request files.BatchRequest) batches.Result {
+	var body struct {
+		Model  string `json:"model"`
+		Stream bool   `json:"stream"`
+	}
+	json.Unmarshal(request.Body, &body)
+
+	result := func(status int, response interface{}) batches.Result {
+		data, _ := json.Marshal(response)
+		return batches.Result{CustomID: request.CustomID, StatusCode: status, Body: data}
+	}
+	if body.Stream {
+		return result(http.StatusBadRequest, api.NewErrorResponse("Streaming is not supported in the Batch API.", "invalid_request_error", "stream", "unsupported_value"))
+	}
+
+	ws := currentWorkspace(c)
+	if rule, matched := ws.Faults.MatchBatchRequest(request.URL, body.Model, request.CustomID); matched {
+		ws.Usage.Record(body.Model, rule.Status)
+		return result(rule.Status, api.NewErrorResponse(rule.Message, rule.ErrorType, "", rule.ErrorCode))
+	}
+
+	recorder := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(recorder)
+	ctx.Request, _ = http.NewRequestWithContext(c.Request.Context(), http.MethodPost, request.URL, bytes.NewReader(request.Body))
+	ctx.Request.Header.Set("Content-Type", "application/json")
+	for key, value := range c.Keys {
+		ctx.Set(key, value)
+	}
+	batchHandlers[request.URL](ctx)
+
+	status := ctx.Writer.Status()
+	ws.Usage.Record(body.Model, status)
+	data := recorder.Body.Bytes()
+	if !json.Valid(data) {
+		data, _ = json.Marshal(string(data))
+	}
+	return batches.Result{CustomID: request.CustomID, StatusCode: status, Body: data}
+}
+
+// batchError 构造批处理输入文件的校验错误，line为0时不返回行号
+func batchError(code, message string, line int) batches.Error {
+	err := batches.Error{Code: code, Message: message}
+	if line > 0 {
+		err.Line = &line
+	}
+	return err
+}
+
+// respondBatchesError 返回批处理存储的错误，批处理不存在时返回404，其余返回400
+func respondBatchesError(c *gin.Context, err error) {
+	var notFound *batches.NotFoundError
+	if errors.As(err, &notFound) {
+		c.JSON(http.StatusNotFound, api.NewErrorResponse(err.Error(), "invalid_request_error", "", ""))
+		return
+	}
+	c.JSON(http.StatusBadRequest, api.NewErrorResponse(err.Error(), "invalid_request_error", "", ""))
+}
@@ -1,14 +1,14 @@
 package controller
 
 import (
-	"fmt"
 	"net/http"
-	"strings"
-	"time"
 
 	"RobinPenn974/OpenAI-mocker/api"
 	"RobinPenn974/OpenAI-mocker/models"
+	"RobinPenn974/OpenAI-mocker/provider"
 	"RobinPenn974/OpenAI-mocker/responses"
+	"RobinPenn974/OpenAI-mocker/templates"
+	"RobinPenn974/OpenAI-mocker/tokenizer"
 
 	"github.com/gin-gonic/gin"
 )
@@ -17,16 +17,7 @@ import (
 func HandleCompletions(c *gin.Context) {
 	var req api.CompletionRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, api.ErrorResponse{
-			Error: struct {
-				Message string `json:"message"`
-				Type    string `json:"type"`
-				Code    string `json:"code,omitempty"`
-			}{
-				Message: "Invalid request: " + err.Error(),
-				Type:    "invalid_request_error",
-			},
-		})
+		c.JSON(http.StatusBadRequest, api.NewErrorResponse("Invalid request: "+err.Error(), "invalid_request_error", "", ""))
 		return
 	}
 
@@ -36,62 +27,51 @@ func HandleCompletions(c *gin.Context) {
 		modelID = "mock-davinci-002" // 默认模型
 	}
 
-	_, err := models.GetModel(modelID)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, api.ErrorResponse{
-			Error: struct {
-				Message string `json:"message"`
-				Type    string `json:"type"`
-				Code    string `json:"code,omitempty"`
-			}{
-				Message: fmt.Sprintf("Model '%s' not found", modelID),
-				Type:    "model_not_found_error",
-			},
-		})
+	ws := currentWorkspace(c)
+	model, modelName, status, errResp := lookupModelForEndpoint(c, modelID, models.EndpointCompletions)
+	if errResp != nil {
+		c.JSON(status, errResp)
+		return
+	}
+	req.Model = modelName
+
+	// 按模型能力校验请求
+	if errResp := validateCompletionRequest(model, req); errResp != nil {
+		c.JSON(http.StatusBadRequest, errResp)
+		return
+	}
+
+	// 模仿Azure时按模型名评估内容过滤
+	profile := currentProviderProfile(c)
+	if profile.Name == provider.ProfileAzure {
+		respondAzureCompletion(c, req, req.Model, model)
 		return
 	}
 
 	// 根据Stream参数决定响应方式
 	if req.Stream {
-		handleStreamingCompletion(c, req)
+		handleStreamingCompletion(c, req, ws.Template(req.Model), responseOptions(c))
 	} else {
 		// 生成模拟回复
-		response := generateCompletion(req)
-		c.JSON(http.StatusOK, response)
+		response := generateCompletion(req, ws.Template(req.Model), responseOptions(c))
+		c.JSON(http.StatusOK, profile.Shape(response, provider.KindCompletion))
 	}
 }
 
 // handleStreamingCompletion 处理流式返回
-func handleStreamingCompletion(c *gin.Context, req api.CompletionRequest) {
-	c.Header("Content-Type", "text/event-stream")
-	c.Header("Cache-Control", "no-cache")
-	c.Header("Connection", "keep-alive")
-	c.Header("Transfer-Encoding", "chunked")
+func handleStreamingCompletion(c *gin.Context, req api.CompletionRequest, template templates.ResponseTemplate, opts responses.Options) {
+	responseContent := generateCompletionContent(req, template, opts)
+	chunks := shapeStream(currentProviderProfile(c), provider.KindCompletionChunk, completionStreamChunks(req, responseContent), completionUsage(req, responseContent))
+	writeStream(c, newStreamPacer(c, req.Model), chunks)
+}
 
+// completionStreamChunks 将响应内容按语言拆分为流式数据块，切分方式与Chat回复一致，最后一块带有结束原因
+func completionStreamChunks(req api.CompletionRequest, responseContent responses.ResponseContent) []streamChunk {
 	// 生成唯一ID
 	responseID := responses.GenerateID("cmpl")
 	now := responses.GetCurrentTimestamp()
-
-	// 获取响应生成器
-	generator := responses.ModelFactory(req.Model)
-
-	// 生成响应内容
-	responseContent := generator.GenerateResponse(req.Prompt, req.Model)
-
-	// 流式发送内容
-	words := strings.Split(responseContent.Content, " ")
-	chunkSize := 2 // 每次发送2个词
-
-	// 发送所有块，除了最后一块
-	for i := 0; i < len(words)-chunkSize; i += chunkSize {
-		chunk := strings.Join(words[i:i+chunkSize], " ")
-		if i == 0 {
-			chunk = strings.TrimSpace(chunk)
-		}
-
-		var finishReason *string
-
-		chunkResponse := api.CompletionChunkResponse{
+	newChunk := func(text string, finishReason *string) api.CompletionChunkResponse {
+		return api.CompletionChunkResponse{
 			ID:      responseID,
 			Object:  "text_completion",
 			Created: now,
@@ -99,58 +79,60 @@ func handleStreamingCompletion(c *gin.Context, req api.CompletionRequest) {
 			Choices: []api.CompletionChunkChoice{
 				{
 					Index:        0,
-					Text:         chunk,
+					Text:         text,
 					FinishReason: finishReason,
 				},
 			},
 		}
-
-		// 发送数据
-		c.SSEvent("", chunkResponse)
-		c.Writer.Flush()
-		time.Sleep(100 * time.Millisecond) // 模拟流式生成延迟
 	}
 
-	// 发送最后一块
-	if len(words) > 0 {
-		lastChunk := strings.Join(words[max(0, len(words)-chunkSize):], " ")
-		finishReason := responseContent.FinishReason
-
-		chunkResponse := api.CompletionChunkResponse{
-			ID:      responseID,
-			Object:  "text_completion",
-			Created: now,
-			Model:   req.Model,
-			Choices: []api.CompletionChunkChoice{
-				{
-					Index:        0,
-					Text:         lastChunk,
-					FinishReason: &finishReason,
-				},
-			},
-		}
+	pieces, delay := splitStream(responseContent.Content, 2)
+	last := ""
+	if len(pieces) > 0 {
+		last = pieces[len(pieces)-1]
+		pieces = pieces[:len(pieces)-1]
+	}
 
-		c.SSEvent("", chunkResponse)
-		c.Writer.Flush()
+	var chunks []streamChunk
+	for _, piece := range pieces {
+		chunks = append(chunks, streamChunk{data: newChunk(piece, nil), delay: delay})
 	}
+	finishReason := responseContent.FinishReason
+	chunks = append(chunks, streamChunk{data: newChunk(last, &finishReason)})
 
-	// 发送结束事件
-	c.SSEvent("", "")
+	return chunks
 }
 
 // generateCompletion 生成模拟的文本完成回复
-func generateCompletion(req api.CompletionRequest) api.CompletionResponse {
+func generateCompletion(req api.CompletionRequest, template templates.ResponseTemplate, opts responses.Options) api.CompletionResponse {
+	return buildCompletionResponse(req, generateCompletionContent(req, template, opts))
+}
+
+// generateCompletionContent 根据提示词生成文本补全内容
+func generateCompletionContent(req api.CompletionRequest, template templates.ResponseTemplate, opts responses.Options) responses.ResponseContent {
 	// 获取响应生成器
-	generator := responses.ModelFactory(req.Model)
+	generator := responses.ModelFactory(req.Model, template, completionOptions(req, opts))
 
 	// 生成响应内容
-	responseContent := generator.GenerateResponse(req.Prompt, req.Model)
+	return generator.GenerateResponse(req.Prompt, req.Model)
+}
 
-	// 创建模拟的Token使用量
-	promptTokens := len(strings.Split(req.Prompt, " "))
-	completionTokens := len(strings.Split(responseContent.Content, " "))
-	totalTokens := promptTokens + completionTokens
+// completionOptions 将请求的seed和最大生成token数加入响应生成选项
+func completionOptions(req api.CompletionRequest, opts responses.Options) responses.Options {
+	opts.Seed = req.Seed
+	opts.MaxTokens = req.MaxTokens
+	return opts
+}
+
+// completionUsage 计算文本完成请求和回复的Token使用量
+func completionUsage(req api.CompletionRequest, responseContent responses.ResponseContent) api.ChatCompletionUsage {
+	promptTokens := tokenizer.CountTokens(req.Prompt)
+	completionTokens := tokenizer.CountTokens(responseContent.Content)
+	return usage(promptTokens, completionTokens, responseContent.ReasoningTokens)
+}
 
+// buildCompletionResponse 根据生成的响应内容构建文本完成回复并计算Token使用量
+func buildCompletionResponse(req api.CompletionRequest, responseContent responses.ResponseContent) api.CompletionResponse {
 	// 构建响应
 	now := responses.GetCurrentTimestamp()
 	response := api.CompletionResponse{
@@ -165,11 +147,7 @@ func generateCompletion(req api.CompletionRequest) api.CompletionResponse {
 				FinishReason: responseContent.FinishReason,
 			},
 		},
-		Usage: api.ChatCompletionUsage{
-			PromptTokens:     promptTokens,
-			CompletionTokens: completionTokens,
-			TotalTokens:      totalTokens,
-		},
+		Usage: completionUsage(req, responseContent),
 	}
 
 	return response
@@ -0,0 +1,103 @@
+package controller
+
+import (
+	"net/http"
+
+	"RobinPenn974/OpenAI-mocker/embeddings"
+	"RobinPenn974/OpenAI-mocker/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HandleListEmbeddingPins 处理列出所有固定向量的请求
+func HandleListEmbeddingPins(c *gin.Context) {
+	ws := currentWorkspace(c)
+	pins := ws.Pins.ListPins()
+	c.JSON(http.StatusOK, gin.H{
+		"pins":  pins,
+		"count": len(pins),
+	})
+}
+
+// HandleCreateEmbeddingPin 处理为指定输入固定向量的请求
+func HandleCreateEmbeddingPin(c *gin.Context) {
+	ws := currentWorkspace(c)
+	var pin embeddings.Pin
+	if err := c.ShouldBindJSON(&pin); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": gin.H{
+				"message": "Invalid request: " + err.Error(),
+				"type":    "invalid_request_error",
+			},
+		})
+		return
+	}
+
+	// 固定向量按解析后的模型ID匹配，维度必须与模型一致
+	resolution, err := ws.LookupModel(pin.ModelID)
+	if err != nil || resolution.Model.ModelType != models.ModelTypeEmbedding {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": gin.H{
+				"message": "Embedding model not found: " + pin.ModelID,
+				"type":    "invalid_request_error",
+			},
+		})
+		return
+	}
+	pin.ModelID = resolution.Model.ID
+
+	pin, err = ws.Pins.AddPin(pin, models.GetEmbeddingSpec(resolution.Model).Dimensions)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": gin.H{
+				"message": err.Error(),
+				"type":    "invalid_request_error",
+			},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Embedding pinned successfully",
+		"pin":     pin,
+	})
+}
+
+// HandleDeleteEmbeddingPin 处理删除固定向量的请求
+func HandleDeleteEmbeddingPin(c *gin.Context) {
+	ws := currentWorkspace(c)
+	if err := ws.Pins.DeletePin(c.Param("pin_id")); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": gin.H{
+				"message": err.Error(),
+				"type":    "invalid_request_error",
+			},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Embedding pin deleted successfully",
+	})
+}
+
+// HandleDeleteAllEmbeddingPins 处理删除所有固定向量的请求
+func HandleDeleteAllEmbeddingPins(c *gin.Context) {
+	ws := currentWorkspace(c)
+	if err := ws.Pins.RemoveAllPins(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": gin.H{
+				"message": "Failed to delete embedding pins: " + err.Error(),
+				"type":    "internal_server_error",
+			},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "All embedding pins deleted successfully",
+	})
+}
@@ -1,31 +1,32 @@
 package controller
 
 import (
+	"encoding/base64"
+	"encoding/binary"
 	"fmt"
-	"math/rand"
+	"math"
 	"net/http"
-	"time"
 
 	"RobinPenn974/OpenAI-mocker/api"
+	"RobinPenn974/OpenAI-mocker/embeddings"
 	"RobinPenn974/OpenAI-mocker/models"
+	"RobinPenn974/OpenAI-mocker/tokenizer"
 
 	"github.com/gin-gonic/gin"
 )
 
+const (
+	// 单次请求最多允许的输入条数
+	maxEmbeddingInputs = 2048
+	// 单次请求所有输入合计允许的最大token数
+	maxEmbeddingRequestTokens = 300000
+)
+
 // HandleEmbeddings 处理Embeddings请求
 func HandleEmbeddings(c *gin.Context) {
 	var req api.EmbeddingRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, api.ErrorResponse{
-			Error: struct {
-				Message string `json:"message"`
-				Type    string `json:"type"`
-				Code    string `json:"code,omitempty"`
-			}{
-				Message: "Invalid request: " + err.Error(),
-				Type:    "invalid_request_error",
-			},
-		})
+		c.JSON(http.StatusBadRequest, api.NewErrorResponse("Invalid request: "+err.Error(), "invalid_request_error", "", ""))
 		return
 	}
 
@@ -35,66 +36,135 @@ func HandleEmbeddings(c *gin.Context) {
 		modelID = "mock-embedding-ada-002" // 默认模型
 	}
 
-	model, err := models.GetModel(modelID)
-	if err != nil || model.ModelType != models.ModelTypeEmbedding {
-		c.JSON(http.StatusBadRequest, api.ErrorResponse{
-			Error: struct {
-				Message string `json:"message"`
-				Type    string `json:"type"`
-				Code    string `json:"code,omitempty"`
-			}{
-				Message: fmt.Sprintf("Model '%s' not found or not an embedding model", modelID),
-				Type:    "model_not_found_error",
-			},
-		})
+	ws := currentWorkspace(c)
+	model, modelName, status, errResp := lookupModelForEndpoint(c, modelID, models.EndpointEmbeddings)
+	if errResp != nil {
+		c.JSON(status, errResp)
+		return
+	}
+	req.Model = modelName
+
+	// 校验输入、维度和编码格式
+	spec := models.GetEmbeddingSpec(model)
+	tokenCounts, errResp := validateEmbeddingRequest(req, spec)
+	if errResp != nil {
+		c.JSON(http.StatusBadRequest, errResp)
 		return
 	}
 
 	// 生成模拟嵌入向量
-	response := generateMockEmbeddings(req)
+	response, err := generateMockEmbeddings(req, model, spec, tokenCounts, ws.Pins)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, api.NewErrorResponse(err.Error(), "server_error", "", ""))
+		return
+	}
 	c.JSON(http.StatusOK, response)
 }
 
-// generateMockEmbeddings 生成模拟的嵌入向量
-func generateMockEmbeddings(req api.EmbeddingRequest) api.EmbeddingResponse {
-	// 设置随机种子
-	rand.Seed(time.Now().UnixNano())
+// validateEmbeddingRequest 按OpenAI的规则校验Embeddings请求，返回每条输入的token数
+func validateEmbeddingRequest(req api.EmbeddingRequest, spec models.EmbeddingSpec) ([]int, *api.ErrorResponse) {
+	invalidInput := api.NewErrorResponse("'$.input' is invalid. Please check the API reference: https://platform.openai.com/docs/api-reference.", "invalid_request_error", "", "")
 
-	// 为每个输入生成模拟嵌入向量
-	data := make([]api.EmbeddingData, 0, len(req.Input))
+	if req.Input.Len() == 0 {
+		return nil, &invalidInput
+	}
+	if req.Input.Len() > maxEmbeddingInputs {
+		errResp := api.NewErrorResponse(fmt.Sprintf("Too many inputs. The max number of inputs is %d.  We recommend sending batches of %d or fewer inputs.", maxEmbeddingInputs, maxEmbeddingInputs), "invalid_request_error", "input", "")
+		return nil, &errResp
+	}
+
+	switch req.EncodingFormat {
+	case "", "float", "base64":
+	default:
+		errResp := api.NewErrorResponse(fmt.Sprintf("Invalid value: '%s'. Supported values are: 'float' and 'base64'.", req.EncodingFormat), "invalid_request_error", "encoding_format", "invalid_value")
+		return nil, &errResp
+	}
+
+	if req.Dimensions != nil {
+		if !spec.SupportsDimensions {
+			errResp := api.NewErrorResponse("This model does not support specifying dimensions.", "invalid_request_error", "", "")
+			return nil, &errResp
+		}
+		if *req.Dimensions < 1 {
+			errResp := api.NewErrorResponse(fmt.Sprintf("Invalid value for 'dimensions' = %d. Must be greater than 0.", *req.Dimensions), "invalid_request_error", "dimensions", "")
+			return nil, &errResp
+		}
+		if *req.Dimensions > spec.Dimensions {
+			errResp := api.NewErrorResponse(fmt.Sprintf("Invalid value for 'dimensions' = %d. Must be less than or equal to %d.", *req.Dimensions, spec.Dimensions), "invalid_request_error", "dimensions", "")
+			return nil, &errResp
+		}
+	}
+
+	// 计算每条输入的token数并检查长度限制
+	tokenCounts := make([]int, req.Input.Len())
 	totalTokens := 0
+	for i := range tokenCounts {
+		var tokens int
+		if req.Input.Tokens != nil {
+			tokens = len(req.Input.Tokens[i])
+		} else {
+			tokens = tokenizer.CountTokens(req.Input.Texts[i])
+		}
 
-	for i, input := range req.Input {
-		// 生成1536维的随机向量并标准化
-		embedding := make([]float64, 1536)
-		var sum float64
-		for j := range embedding {
-			embedding[j] = rand.Float64() - 0.5
-			sum += embedding[j] * embedding[j]
+		// 空字符串和空token数组都是无效输入
+		if tokens == 0 {
+			return nil, &invalidInput
+		}
+		if tokens > spec.MaxInputTokens {
+			errResp := api.NewErrorResponse(fmt.Sprintf("This model's maximum context length is %d tokens, however you requested %d tokens (%d in your prompt; 0 for the completion). Please reduce your prompt; or completion length.", spec.MaxInputTokens, tokens, tokens), "invalid_request_error", "", "")
+			return nil, &errResp
 		}
 
-		// 标准化向量
-		magnitude := 0.0
-		if sum > 0 {
-			magnitude = 1.0 / (sum * 0.5)
+		tokenCounts[i] = tokens
+		totalTokens += tokens
+	}
+
+	if totalTokens > maxEmbeddingRequestTokens {
+		errResp := api.NewErrorResponse(fmt.Sprintf("Requested %d tokens, max %d tokens per request", totalTokens, maxEmbeddingRequestTokens), "max_tokens_per_request", "", "max_tokens_per_request")
+		return nil, &errResp
+	}
+
+	return tokenCounts, nil
+}
+
+// generateMockEmbeddings 生成模拟的嵌入向量
+// 向量由输入内容确定性地派生，优先使用管理接口固定的向量
+func generateMockEmbeddings(req api.EmbeddingRequest, model models.ModelInfo, spec models.EmbeddingSpec, tokenCounts []int, pins *embeddings.PinStore) (api.EmbeddingResponse, error) {
+	dimensions := spec.Dimensions
+	if req.Dimensions != nil {
+		dimensions = *req.Dimensions
+	}
+
+	// 为每个输入生成模拟嵌入向量
+	data := make([]api.EmbeddingData, 0, req.Input.Len())
+	totalTokens := 0
+
+	for i := 0; i < req.Input.Len(); i++ {
+		var embedding []float64
+		var err error
+		if req.Input.Tokens != nil {
+			if pinned, ok := pins.LookupTokens(model.ID, req.Input.Tokens[i]); ok {
+				embedding = pinned
+			} else {
+				embedding, err = embeddings.GenerateFromTokens(model.EmbeddingAlgorithm, req.Input.Tokens[i], spec.Dimensions)
+			}
+		} else if pinned, ok := pins.Lookup(model.ID, req.Input.Texts[i]); ok {
+			embedding = pinned
+		} else {
+			embedding, err = embeddings.Generate(model.EmbeddingAlgorithm, req.Input.Texts[i], spec.Dimensions)
 		}
-		for j := range embedding {
-			embedding[j] *= magnitude
+		if err != nil {
+			return api.EmbeddingResponse{}, err
 		}
 
 		// 添加到结果集
 		data = append(data, api.EmbeddingData{
 			Object:    "embedding",
-			Embedding: embedding,
+			Embedding: encodeEmbedding(embeddings.Truncate(embedding, dimensions), req.EncodingFormat),
 			Index:     i,
 		})
 
-		// 估算token数量
-		tokens := len([]rune(input)) / 4
-		if tokens < 1 {
-			tokens = 1
-		}
-		totalTokens += tokens
+		totalTokens += tokenCounts[i]
 	}
 
 	// 构建响应
@@ -109,5 +179,18 @@ func generateMockEmbeddings(req api.EmbeddingRequest) api.EmbeddingResponse {
 			PromptTokens: totalTokens,
 			TotalTokens:  totalTokens,
 		},
+	}, nil
+}
+
+// encodeEmbedding 按encoding_format编码向量，base64格式为小端序float32打包后的base64字符串
+func encodeEmbedding(embedding []float64, format string) interface{} {
+	if format != "base64" {
+		return embedding
+	}
+
+	buf := make([]byte, 4*len(embedding))
+	for i, value := range embedding {
+		binary.LittleEndian.PutUint32(buf[i*4:], math.Float32bits(float32(value)))
 	}
+	return base64.StdEncoding.EncodeToString(buf)
 }
@@ -0,0 +1,87 @@
+package controller
+
+import (
+	"net/http"
+
+	"RobinPenn974/OpenAI-mocker/faults"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HandleListFaults 处理列出所有故障注入规则的请求
+func HandleListFaults(c *gin.Context) {
+	ws := currentWorkspace(c)
+	rules := ws.Faults.ListRules()
+	c.JSON(http.StatusOK, gin.H{
+		"faults": rules,
+		"count":  len(rules),
+	})
+}
+
+// HandleCreateFault 处理创建故障注入规则的请求
+func HandleCreateFault(c *gin.Context) {
+	ws := currentWorkspace(c)
+	var rule faults.Rule
+	if err := c.ShouldBindJSON(&rule); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": gin.H{
+				"message": "Invalid request: " + err.Error(),
+				"type":    "invalid_request_error",
+			},
+		})
+		return
+	}
+
+	rule, err := ws.Faults.AddRule(rule)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": gin.H{
+				"message": err.Error(),
+				"type":    "invalid_request_error",
+			},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Fault created successfully",
+		"fault":   rule,
+	})
+}
+
+// HandleDeleteFault 处理删除故障注入规则的请求
+func HandleDeleteFault(c *gin.Context) {
+	ws := currentWorkspace(c)
+	if err := ws.Faults.DeleteRule(c.Param("fault_id")); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": gin.H{
+				"message": err.Error(),
+				"type":    "invalid_request_error",
+			},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Fault deleted successfully",
+	})
+}
+
+// HandleDeleteAllFaults 处理删除所有故障注入规则的请求
+func HandleDeleteAllFaults(c *gin.Context) {
+	ws := currentWorkspace(c)
+	ws.Faults.RemoveAllRules()
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "All faults deleted successfully",
+	})
+}
+
+// SnapshotFaults 返回故障注入规则的快照，用于审计
+func SnapshotFaults(c *gin.Context) interface{} {
+	// 规则按匹配顺序记录
+	return currentWorkspace(c).Faults.ListRules()
+}
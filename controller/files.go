@@ -0,0 +1,143 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strings"
+
+	"RobinPenn974/OpenAI-mocker/api"
+	"RobinPenn974/OpenAI-mocker/files"
+	"RobinPenn974/OpenAI-mocker/middleware"
+	"RobinPenn974/OpenAI-mocker/workspace"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 上传请求体中除文件内容外允许的表单字段和分隔符的大小
+const multipartOverheadBytes = 1 << 20
+
+// HandleUploadFile 处理上传文件的请求，请求体为包含 file 和 purpose 字段的 multipart 表单
+func HandleUploadFile(c *gin.Context) {
+	maxBytes := currentInstance(c).Config().Files.MaxBytes
+	if maxBytes > 0 {
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBytes+multipartOverheadBytes)
+	}
+
+	header, err := c.FormFile("file")
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) || (err == nil && maxBytes > 0 && header.Size > maxBytes) {
+		c.JSON(http.StatusRequestEntityTooLarge, api.NewErrorResponse(fmt.Sprintf("File is too large. The maximum file size is %d bytes.", maxBytes), "invalid_request_error", "file", "file_too_large"))
+		return
+	}
+
+	purpose := c.PostForm("purpose")
+	if purpose == "" {
+		c.JSON(http.StatusBadRequest, api.NewErrorResponse("Missing required parameter: 'purpose'.", "invalid_request_error", "purpose", "missing_required_parameter"))
+		return
+	}
+	if !files.IsUploadPurpose(purpose) {
+		supported := make([]string, len(files.UploadPurposes))
+		for i, p := range files.UploadPurposes {
+			supported[i] = "'" + p + "'"
+		}
+		c.JSON(http.StatusBadRequest, invalidValue("purpose", purpose, strings.Join(supported, ", ")))
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, api.NewErrorResponse("Missing required parameter: 'file'.", "invalid_request_error", "file", "missing_required_parameter"))
+		return
+	}
+
+	data, err := readFormFile(header)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, api.NewErrorResponse(fmt.Sprintf("Error reading uploaded file: %v", err), "server_error", "", ""))
+		return
+	}
+	if err := files.Validate(purpose, header.Filename, data); err != nil {
+		c.JSON(http.StatusBadRequest, api.NewErrorResponse(err.Error(), "invalid_request_error", "file", "invalid_file_format"))
+		return
+	}
+
+	file, err := currentWorkspace(c).Files.Create(header.Filename, purpose, data)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, api.NewErrorResponse(err.Error(), "server_error", "", ""))
+		return
+	}
+	c.Set(middleware.ContextKeyJournalFile, workspace.JournalFile{
+		ID:       file.ID,
+		Filename: file.Filename,
+		Purpose:  file.Purpose,
+		Bytes:    file.Bytes,
+	})
+	c.JSON(http.StatusOK, file)
+}
+
+// HandleListFiles 处理列出文件的请求，支持按 purpose 过滤和分页
+func HandleListFiles(c *gin.Context) {
+	params, errResp := listParams(c, api.OrderDesc)
+	if errResp != nil {
+		c.JSON(http.StatusBadRequest, errResp)
+		return
+	}
+
+	data, hasMore := currentWorkspace(c).Files.List(c.Query("purpose"), params)
+	ids := make([]string, len(data))
+	for i, file := range data {
+		ids[i] = file.ID
+	}
+	c.JSON(http.StatusOK, api.NewListResponse(data, ids, hasMore))
+}
+
+// HandleGetFile 处理获取文件对象的请求
+func HandleGetFile(c *gin.Context) {
+	file, err := currentWorkspace(c).Files.Get(c.Param("file_id"))
+	if err != nil {
+		respondFilesError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, file)
+}
+
+// HandleGetFileContent 处理获取文件内容的请求
+func HandleGetFileContent(c *gin.Context) {
+	file, data, err := currentWorkspace(c).Files.Content(c.Param("file_id"))
+	if err != nil {
+		respondFilesError(c, err)
+		return
+	}
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", file.Filename))
+	c.Data(http.StatusOK, "application/octet-stream", data)
+}
+
+// HandleDeleteFile 处理删除文件的请求
+func HandleDeleteFile(c *gin.Context) {
+	id := c.Param("file_id")
+	if err := currentWorkspace(c).Files.Delete(id); err != nil {
+		respondFilesError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, api.DeletedResponse{ID: id, Object: "file", Deleted: true})
+}
+
+// readFormFile 读取 multipart 表单中上传文件的内容
+func readFormFile(header *multipart.FileHeader) ([]byte, error) {
+	file, err := header.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return io.ReadAll(file)
+}
+
+// respondFilesError 返回文件存储的错误，文件不存在时返回404
+func respondFilesError(c *gin.Context, err error) {
+	var notFound *files.NotFoundError
+	if errors.As(err, &notFound) {
+		c.JSON(http.StatusNotFound, api.NewErrorResponse(err.Error(), "invalid_request_error", "id", ""))
+		return
+	}
+	c.JSON(http.StatusInternalServerError, api.NewErrorResponse(err.Error(), "server_error", "", ""))
+}
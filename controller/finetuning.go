@@ -0,0 +1,303 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"net/http"
+	"time"
+
+	"RobinPenn974/OpenAI-mocker/api"
+	"RobinPenn974/OpenAI-mocker/files"
+	"RobinPenn974/OpenAI-mocker/finetuning"
+	"RobinPenn974/OpenAI-mocker/models"
+	"RobinPenn974/OpenAI-mocker/tokenizer"
+	"RobinPenn974/OpenAI-mocker/workspace"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 微调任务参数的限制，与OpenAI一致
+const (
+	minTrainingExamples       = 10
+	maxFineTuningSuffix       = 64
+	maxFineTuningEpochs       = 50
+	maxFineTuningBatchSize    = 256
+	maxLearningRateMultiplier = 10
+)
+
+// HandleCreateFineTuningJob 处理创建微调任务的请求，按训练文件校验超参数，设置为 auto 的超参数按样本数确定
+func HandleCreateFineTuningJob(c *gin.Context) {
+	var req api.CreateFineTuningJobRequest
+	if !bindAssistantsRequest(c, &req) {
+		return
+	}
+	if req.Model == "" {
+		c.JSON(http.StatusBadRequest, api.NewErrorResponse("Missing required parameter: 'model'.", "invalid_request_error", "model", "missing_required_parameter"))
+		return
+	}
+	if req.TrainingFile == "" {
+		c.JSON(http.StatusBadRequest, api.NewErrorResponse("Missing required parameter: 'training_file'.", "invalid_request_error", "training_file", "missing_required_parameter"))
+		return
+	}
+
+	ws := currentWorkspace(c)
+	resolution, err := ws.LookupModel(req.Model)
+	if err != nil || resolution.Model.ModelType != models.ModelTypeLLM {
+		c.JSON(http.StatusBadRequest, api.NewErrorResponse(fmt.Sprintf("Model %s is not available for fine-tuning or does not exist.", req.Model), "invalid_request_error", "model", "model_not_available"))
+		return
+	}
+	if errResp := modelAccessDenied(c, req.Model, resolution.Model.ID); errResp != nil {
+		c.JSON(http.StatusForbidden, errResp)
+		return
+	}
+	if req.Suffix != nil && len(*req.Suffix) > maxFineTuningSuffix {
+		c.JSON(http.StatusBadRequest, api.NewErrorResponse(fmt.Sprintf("Invalid 'suffix': string too long. Expected a string with maximum length %d, but got a string with length %d instead.", maxFineTuningSuffix, len(*req.Suffix)), "invalid_request_error", "suffix", "string_above_max_length"))
+		return
+	}
+	if errResp := validateMetadata(req.Metadata); errResp != nil {
+		c.JSON(http.StatusBadRequest, errResp)
+		return
+	}
+
+	// method 中的超参数优先于已弃用的 hyperparameters
+	hyperparameters, param := req.Hyperparameters, "hyperparameters"
+	if req.Method != nil {
+		if req.Method.Type != finetuning.MethodSupervised {
+			c.JSON(http.StatusBadRequest, invalidValue("method.type", req.Method.Type, "'"+finetuning.MethodSupervised+"'"))
+			return
+		}
+		if req.Method.Supervised != nil && req.Method.Supervised.Hyperparameters != nil {
+			hyperparameters, param = req.Method.Supervised.Hyperparameters, "method.supervised.hyperparameters"
+		}
+	}
+
+	examples, errResp := fineTuningExamples(ws, "training_file", req.TrainingFile)
+	if errResp != nil {
+		c.JSON(http.StatusBadRequest, errResp)
+		return
+	}
+	if len(examples) < minTrainingExamples {
+		c.JSON(http.StatusBadRequest, api.NewErrorResponse(fmt.Sprintf("Training file has %d example(s), but must have at least %d examples", len(examples), minTrainingExamples), "invalid_request_error", "training_file", "invalid_n_examples"))
+		return
+	}
+	job := finetuning.Job{
+		Model:        resolution.Model.ID,
+		TrainingFile: req.TrainingFile,
+		Metadata:     req.Metadata,
+	}
+	plan := finetuning.Plan{Examples: len(examples), Tokens: exampleTokens(examples)}
+	if req.ValidationFile != "" {
+		if _, errResp := fineTuningExamples(ws, "validation_file", req.ValidationFile); errResp != nil {
+			c.JSON(http.StatusBadRequest, errResp)
+			return
+		}
+		job.ValidationFile = &req.ValidationFile
+		plan.Validation = true
+	}
+
+	job.Hyperparameters, errResp = resolveHyperparameters(hyperparameters, param, len(examples))
+	if errResp != nil {
+		c.JSON(http.StatusBadRequest, errResp)
+		return
+	}
+	if req.Suffix != nil && *req.Suffix != "" {
+		job.UserProvidedSuffix = req.Suffix
+	}
+	job.Seed = rand.Int63n(math.MaxInt32)
+	if req.Seed != nil {
+		job.Seed = *req.Seed
+	}
+
+	duration := time.Duration(currentInstance(c).Config().FineTuning.DurationMs) * time.Millisecond
+	c.JSON(http.StatusOK, ws.FineTuning.Create(job, plan, duration))
+}
+
+// HandleListFineTuningJobs 处理分页列出微调任务的请求，支持 metadata[key]=value 过滤
+func HandleListFineTuningJobs(c *gin.Context) {
+	params, errResp := listParams(c, api.OrderDesc)
+	if errResp != nil {
+		c.JSON(http.StatusBadRequest, errResp)
+		return
+	}
+
+	data, hasMore := currentWorkspace(c).FineTuning.List(params, c.QueryMap("metadata"))
+	ids := make([]string, len(data))
+	for i, job := range data {
+		ids[i] = job.ID
+	}
+	c.JSON(http.StatusOK, api.NewListResponse(data, ids, hasMore))
+}
+
+// HandleGetFineTuningJob 处理获取微调任务的请求
+func HandleGetFineTuningJob(c *gin.Context) {
+	job, err := currentWorkspace(c).FineTuning.Get(c.Param("job_id"))
+	if err != nil {
+		respondFineTuningError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, job)
+}
+
+// HandleCancelFineTuningJob 处理取消微调任务的请求
+func HandleCancelFineTuningJob(c *gin.Context) {
+	job, err := currentWorkspace(c).FineTuning.Cancel(c.Param("job_id"))
+	if err != nil {
+		respondFineTuningError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, job)
+}
+
+// HandleListFineTuningEvents 处理分页列出微调任务事件的请求，默认从新到旧
+func HandleListFineTuningEvents(c *gin.Context) {
+	params, errResp := listParams(c, api.OrderDesc)
+	if errResp != nil {
+		c.JSON(http.StatusBadRequest, errResp)
+		return
+	}
+
+	data, hasMore, err := currentWorkspace(c).FineTuning.Events(c.Param("job_id"), params)
+	if err != nil {
+		respondFineTuningError(c, err)
+		return
+	}
+	ids := make([]string, len(data))
+	for i, event := range data {
+		ids[i] = event.ID
+	}
+	c.JSON(http.StatusOK, api.NewListResponse(data, ids, hasMore))
+}
+
+// HandleListFineTuningCheckpoints 处理分页列出微调任务检查点的请求，默认从新到旧
+func HandleListFineTuningCheckpoints(c *gin.Context) {
+	params, errResp := listParams(c, api.OrderDesc)
+	if errResp != nil {
+		c.JSON(http.StatusBadRequest, errResp)
+		return
+	}
+
+	data, hasMore, err := currentWorkspace(c).FineTuning.Checkpoints(c.Param("job_id"), params)
+	if err != nil {
+		respondFineTuningError(c, err)
+		return
+	}
+	ids := make([]string, len(data))
+	for i, checkpoint := range data {
+		ids[i] = checkpoint.ID
+	}
+	c.JSON(http.StatusOK, api.NewListResponse(data, ids, hasMore))
+}
+
+// fineTuningExamples 读取并校验用途为 fine-tune 的训练或验证文件，param 为请求中的参数名
+func fineTuningExamples(ws *workspace.Workspace, param, fileID string) ([]files.FineTuneExample, *api.ErrorResponse) {
+	file, data, err := ws.Files.Content(fileID)
+	if err != nil {
+		errResp := api.NewErrorResponse(fmt.Sprintf("Invalid '%s': file '%s' does not exist.", param, fileID), "invalid_request_error", param, "file_not_found")
+		return nil, &errResp
+	}
+	if file.Purpose != files.PurposeFineTune {
+		errResp := api.NewErrorResponse(fmt.Sprintf("Invalid '%s': file '%s' has purpose '%s', but fine-tuning files must have purpose '%s'.", param, fileID, file.Purpose, files.PurposeFineTune), "invalid_request_error", param, "invalid_file_purpose")
+		return nil, &errResp
+	}
+	examples, err := files.ParseFineTuneExamples(data)
+	if err != nil {
+		errResp := api.NewErrorResponse(fmt.Sprintf("Invalid file format for Fine-Tuning API. %v", err), "invalid_request_error", param, "invalid_file_format")
+		return nil, &errResp
+	}
+	return examples, nil
+}
+
+// exampleTokens 统计训练样本中消息内容、prompt 和 completion 的token数
+func exampleTokens(examples []files.FineTuneExample) int {
+	tokens := 0
+	for _, example := range examples {
+		for _, message := range example.Messages {
+			var content string
+			if err := json.Unmarshal(message.Content, &content); err != nil {
+				content = string(message.Content)
+			}
+			tokens += tokenizer.CountTokens(content)
+		}
+		if example.Prompt != nil && example.Completion != nil {
+			tokens += tokenizer.CountTokens(*example.Prompt) + tokenizer.CountTokens(*example.Completion)
+		}
+	}
+	return tokens
+}
+
+// resolveHyperparameters 校验请求的超参数，未设置或为 auto 的超参数按训练样本数确定
+func resolveHyperparameters(req *api.FineTuningHyperparameters, param string, examples int) (finetuning.Hyperparameters, *api.ErrorResponse) {
+	result := finetuning.AutoHyperparameters(examples)
+	if req == nil {
+		return result, nil
+	}
+
+	if value, ok, errResp := hyperparameter(req.NEpochs, param+".n_epochs", true); errResp != nil {
+		return result, errResp
+	} else if ok {
+		if value < 1 || value > maxFineTuningEpochs {
+			return result, hyperparameterRange(param+".n_epochs", value, fmt.Sprintf("an integer between 1 and %d", maxFineTuningEpochs))
+		}
+		result.NEpochs = int(value)
+	}
+
+	if value, ok, errResp := hyperparameter(req.BatchSize, param+".batch_size", true); errResp != nil {
+		return result, errResp
+	} else if ok {
+		if value < 1 || value > maxFineTuningBatchSize {
+			return result, hyperparameterRange(param+".batch_size", value, fmt.Sprintf("an integer between 1 and %d", maxFineTuningBatchSize))
+		}
+		if int(value) > examples {
+			errResp := api.NewErrorResponse(fmt.Sprintf("Invalid '%s.batch_size': %d is larger than the number of training examples (%d).", param, int(value), examples), "invalid_request_error", param+".batch_size", "invalid_value")
+			return result, &errResp
+		}
+		result.BatchSize = int(value)
+	}
+
+	if value, ok, errResp := hyperparameter(req.LearningRateMultiplier, param+".learning_rate_multiplier", false); errResp != nil {
+		return result, errResp
+	} else if ok {
+		if value <= 0 || value > maxLearningRateMultiplier {
+			return result, hyperparameterRange(param+".learning_rate_multiplier", value, fmt.Sprintf("a number greater than 0 and at most %d", maxLearningRateMultiplier))
+		}
+		result.LearningRateMultiplier = value
+	}
+	return result, nil
+}
+
+// hyperparameter 解析 "auto" 或数值的超参数，ok 为 false 表示未设置或为 auto
+func hyperparameter(raw json.RawMessage, param string, integer bool) (value float64, ok bool, errResp *api.ErrorResponse) {
+	var auto string
+	if len(raw) == 0 || string(raw) == "null" || (json.Unmarshal(raw, &auto) == nil && auto == "auto") {
+		return 0, false, nil
+	}
+
+	expected := "'auto' or a number"
+	if integer {
+		expected = "'auto' or an integer"
+	}
+	if err := json.Unmarshal(raw, &value); err != nil || (integer && value != math.Trunc(value)) {
+		resp := api.NewErrorResponse(fmt.Sprintf("Invalid '%s': expected %s, but got %s instead.", param, expected, string(raw)), "invalid_request_error", param, "invalid_type")
+		return 0, false, &resp
+	}
+	return value, true, nil
+}
+
+// hyperparameterRange 构造超参数超出取值范围的错误
+func hyperparameterRange(param string, value float64, expected string) *api.ErrorResponse {
+	errResp := api.NewErrorResponse(fmt.Sprintf("Invalid '%s': expected %s, but got %v instead.", param, expected, value), "invalid_request_error", param, "invalid_value")
+	return &errResp
+}
+
+// respondFineTuningError 返回微调任务存储的错误，任务不存在时返回404，其余返回400
+func respondFineTuningError(c *gin.Context, err error) {
+	var notFound *finetuning.NotFoundError
+	if errors.As(err, &notFound) {
+		c.JSON(http.StatusNotFound, api.NewErrorResponse(err.Error(), "invalid_request_error", "", ""))
+		return
+	}
+	c.JSON(http.StatusBadRequest, api.NewErrorResponse(err.Error(), "invalid_request_error", "", ""))
+}
@@ -1,41 +1,24 @@
 package controller
 
 import (
+	"errors"
 	"net/http"
 	"time"
 
+	"RobinPenn974/OpenAI-mocker/api"
+	"RobinPenn974/OpenAI-mocker/embeddings"
 	"RobinPenn974/OpenAI-mocker/models"
+	"RobinPenn974/OpenAI-mocker/provider"
+	"RobinPenn974/OpenAI-mocker/rerank"
 	"RobinPenn974/OpenAI-mocker/templates"
 
 	"github.com/gin-gonic/gin"
 )
 
-// ModelLoadRequest 加载模型的请求结构
-type ModelLoadRequest struct {
-	ModelID      string `json:"model_id" binding:"required"`
-	ModelType    string `json:"model_type" binding:"required"`
-	OwnedBy      string `json:"owned_by,omitempty"`
-	ResponseType string `json:"response_type,omitempty"` // chat, completion, reasoning
-
-	// 可选的响应模板
-	Template *TemplateConfig `json:"template,omitempty"`
-}
-
-// TemplateConfig 模型响应模板配置
-type TemplateConfig struct {
-	Prefix           string `json:"prefix,omitempty"`
-	Greeting         string `json:"greeting,omitempty"`
-	Question         string `json:"question,omitempty"`
-	HelpRequest      string `json:"help_request,omitempty"`
-	Default          string `json:"default,omitempty"`
-	SupportReasoning bool   `json:"support_reasoning,omitempty"`
-	ReasoningPrefix  string `json:"reasoning_prefix,omitempty"`
-	CompletionPrefix string `json:"completion_prefix,omitempty"`
-}
-
 // HandleLoadModel 处理加载模型的请求
 func HandleLoadModel(c *gin.Context) {
-	var req ModelLoadRequest
+	ws := currentWorkspace(c)
+	var req api.ModelLoadRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error": gin.H{
@@ -46,13 +29,56 @@ func HandleLoadModel(c *gin.Context) {
 		return
 	}
 
+	if req.EmbeddingAlgorithm != "" && !embeddings.IsSupportedAlgorithm(req.EmbeddingAlgorithm) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": gin.H{
+				"message": "Unsupported embedding algorithm: " + req.EmbeddingAlgorithm,
+				"type":    "invalid_request_error",
+			},
+		})
+		return
+	}
+
+	if req.RerankProfile != "" && !rerank.IsSupportedProfile(req.RerankProfile) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": gin.H{
+				"message": "Unsupported rerank profile: " + req.RerankProfile,
+				"type":    "invalid_request_error",
+			},
+		})
+		return
+	}
+
+	if req.ProviderProfile != "" && !provider.IsSupportedProfile(req.ProviderProfile) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": gin.H{
+				"message": "Unsupported provider profile: " + req.ProviderProfile,
+				"type":    "invalid_request_error",
+			},
+		})
+		return
+	}
+
 	// 创建模型信息
 	modelInfo := models.ModelInfo{
-		ID:        req.ModelID,
-		Object:    "model",
-		Created:   time.Now().Unix(),
-		OwnedBy:   req.OwnedBy,
-		ModelType: req.ModelType,
+		ID:                 req.ModelID,
+		Object:             "model",
+		Created:            time.Now().Unix(),
+		OwnedBy:            req.OwnedBy,
+		ModelType:          req.ModelType,
+		EmbeddingAlgorithm: req.EmbeddingAlgorithm,
+		RerankProfile:      req.RerankProfile,
+		ProviderProfile:    req.ProviderProfile,
+
+		ContextWindow:       req.ContextWindow,
+		MaxOutputTokens:     req.MaxOutputTokens,
+		Endpoints:           req.Endpoints,
+		Modalities:          req.Modalities,
+		SupportsTools:       req.SupportsTools,
+		SupportsJSONSchema:  req.SupportsJSONSchema,
+		SupportsReasoning:   req.SupportsReasoning,
+		EmbeddingDimensions: req.EmbeddingDimensions,
+		SupportsDimensions:  req.SupportsDimensions,
 	}
 
 	// 如果没有提供OwnedBy，设置默认值
@@ -60,45 +86,48 @@ func HandleLoadModel(c *gin.Context) {
 		modelInfo.OwnedBy = "openai-mocker"
 	}
 
-	// 注册模型
-	models.RegisterModel(modelInfo)
+	// 校验模型类型和能力元数据
+	if err := models.ValidateModel(modelInfo); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": gin.H{
+				"message": err.Error(),
+				"type":    "invalid_request_error",
+			},
+		})
+		return
+	}
 
-	// 如果提供了模板，注册模板
+	// 如果提供了模板，注册模板，未设置的字段在生成响应时使用父模板和默认模板的值
 	if req.Template != nil {
 		template := templates.ResponseTemplate{
-			ModelID:          req.ModelID,
-			Prefix:           req.Template.Prefix,
-			Greeting:         req.Template.Greeting,
-			Question:         req.Template.Question,
-			HelpRequest:      req.Template.HelpRequest,
-			Default:          req.Template.Default,
-			SupportReasoning: req.Template.SupportReasoning,
-			ReasoningPrefix:  req.Template.ReasoningPrefix,
-			CompletionPrefix: req.Template.CompletionPrefix,
+			ModelID:           req.ModelID,
+			Extends:           req.Template.Extends,
+			Prefix:            req.Template.Prefix,
+			Greeting:          req.Template.Greeting,
+			Question:          req.Template.Question,
+			HelpRequest:       req.Template.HelpRequest,
+			Default:           req.Template.Default,
+			Variants:          req.Template.Variants,
+			Localized:         req.Template.Localized,
+			SupportReasoning:  req.Template.SupportReasoning,
+			ReasoningPrefix:   req.Template.ReasoningPrefix,
+			ReasoningTemplate: req.Template.ReasoningTemplate,
+			ReasoningFormat:   req.Template.ReasoningFormat,
+			CompletionPrefix:  req.Template.CompletionPrefix,
 		}
 
-		// 设置默认值
-		if template.Prefix == "" {
-			template.Prefix = "[" + req.ModelID + "] "
-		}
-		if template.Greeting == "" {
-			template.Greeting = "Hello! I'm a mock AI model based on " + req.ModelID + ". How can I assist you today?"
-		}
-		if template.Question == "" {
-			template.Question = "That's an interesting question. As a " + req.ModelID + " mock, I'll provide a simulated answer."
-		}
-		if template.HelpRequest == "" {
-			template.HelpRequest = "I'm here to help! As a " + req.ModelID + " mock, I can provide simulated assistance."
-		}
-		if template.Default == "" {
-			template.Default = "I understand. As a " + req.ModelID + " mock, I'm providing this simulated response."
-		}
-		if template.ReasoningPrefix == "" && template.SupportReasoning {
-			template.ReasoningPrefix = "REASONING: "
+		// 先注册模板，模板按与 PUT /admin/templates 相同的规则校验，无效时不注册模型
+		err := ws.Templates.RegisterTemplate(template)
+		if errors.Is(err, templates.ErrInvalidTemplate) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": gin.H{
+					"message": err.Error(),
+					"type":    "invalid_request_error",
+				},
+			})
+			return
 		}
-
-		// 注册模板
-		if err := templates.RegisterTemplate(template); err != nil {
+		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{
 				"error": gin.H{
 					"message": "Failed to register template: " + err.Error(),
@@ -109,6 +138,18 @@ func HandleLoadModel(c *gin.Context) {
 		}
 	}
 
+	// 注册模型，未设置的元数据会被补全
+	if err := ws.Models.RegisterModel(modelInfo); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": gin.H{
+				"message": "Failed to register model: " + err.Error(),
+				"type":    "internal_server_error",
+			},
+		})
+		return
+	}
+	modelInfo, _ = ws.Models.GetModel(modelInfo.ID)
+
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"message": "Model loaded successfully",
@@ -118,9 +159,8 @@ func HandleLoadModel(c *gin.Context) {
 
 // HandleUnloadModel 处理卸载模型的请求
 func HandleUnloadModel(c *gin.Context) {
-	var req struct {
-		ModelID string `json:"model_id" binding:"required"`
-	}
+	ws := currentWorkspace(c)
+	var req api.UnloadModelRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error": gin.H{
@@ -132,7 +172,7 @@ func HandleUnloadModel(c *gin.Context) {
 	}
 
 	// 卸载模型
-	if err := models.UnloadModel(req.ModelID); err != nil {
+	if err := ws.Models.UnloadModel(req.ModelID); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error": gin.H{
 				"message": err.Error(),
@@ -142,8 +182,8 @@ func HandleUnloadModel(c *gin.Context) {
 		return
 	}
 
-	// 删除模板
-	templates.DeleteTemplate(req.ModelID)
+	// 清理模型关联的资源
+	ws.CleanupModel(req.ModelID)
 
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
@@ -153,8 +193,21 @@ func HandleUnloadModel(c *gin.Context) {
 
 // HandleUnloadAllModels 处理卸载所有模型的请求
 func HandleUnloadAllModels(c *gin.Context) {
-	// 卸载所有模型
-	models.UnloadAllModels()
+	ws := currentWorkspace(c)
+	// 卸载所有模型并清理关联的资源
+	modelsList := ws.Models.ListModels()
+	if err := ws.Models.UnloadAllModels(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": gin.H{
+				"message": err.Error(),
+				"type":    "internal_server_error",
+			},
+		})
+		return
+	}
+	for _, model := range modelsList {
+		ws.CleanupModel(model.ID)
+	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
@@ -164,8 +217,17 @@ func HandleUnloadAllModels(c *gin.Context) {
 
 // HandlePreloadModels 处理预加载默认模型的请求
 func HandlePreloadModels(c *gin.Context) {
-	// 初始化默认模型
-	models.InitDefaultModels()
+	ws := currentWorkspace(c)
+	// 从默认模型清单重新注册默认模型
+	if err := ws.Models.LoadDefaultModels(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": gin.H{
+				"message": err.Error(),
+				"type":    "internal_server_error",
+			},
+		})
+		return
+	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
@@ -1,17 +1,20 @@
 package controller
 
 import (
+	"fmt"
 	"net/http"
+	"strings"
 
 	"RobinPenn974/OpenAI-mocker/api"
-	"RobinPenn974/OpenAI-mocker/models"
+	"RobinPenn974/OpenAI-mocker/middleware"
 
 	"github.com/gin-gonic/gin"
 )
 
 // HandleListModels 处理获取模型列表请求
 func HandleListModels(c *gin.Context) {
-	modelsList := models.ListModels()
+	ws := currentWorkspace(c)
+	modelsList := ws.ListModels()
 
 	// 转换为API响应格式
 	response := api.ModelListResponse{
@@ -19,7 +22,12 @@ func HandleListModels(c *gin.Context) {
 		Data:   make([]api.ModelData, 0, len(modelsList)),
 	}
 
+	// 密钥设置了模型白名单时只列出可访问的模型
+	key, hasKey := middleware.CurrentKey(c)
 	for _, model := range modelsList {
+		if hasKey && !key.AllowsModel(model.ID) {
+			continue
+		}
 		response.Data = append(response.Data, api.ModelData{
 			ID:      model.ID,
 			Object:  model.Object,
@@ -30,3 +38,52 @@ func HandleListModels(c *gin.Context) {
 
 	c.JSON(http.StatusOK, response)
 }
+
+// HandleRetrieveModel 处理获取单个模型信息的请求，返回包含能力元数据的模型信息
+// 别名和匹配模式解析到的模型以请求的名称返回
+func HandleRetrieveModel(c *gin.Context) {
+	ws := currentWorkspace(c)
+	// 模型ID可能包含斜杠（如 org/model），路由使用通配参数
+	modelID := strings.TrimPrefix(c.Param("model_id"), "/")
+	resolution, err := ws.LookupModel(modelID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, api.NewErrorResponse(fmt.Sprintf("The model '%s' does not exist", modelID), "invalid_request_error", "model", "model_not_found"))
+		return
+	}
+	if errResp := modelAccessDenied(c, modelID, resolution.Model.ID); errResp != nil {
+		c.JSON(http.StatusForbidden, errResp)
+		return
+	}
+
+	model := resolution.Model
+	model.ID = modelID
+	c.JSON(http.StatusOK, model)
+}
+
+// HandleDeleteModel 处理删除模型的请求，同时清理模型关联的资源
+func HandleDeleteModel(c *gin.Context) {
+	ws := currentWorkspace(c)
+	modelID := strings.TrimPrefix(c.Param("model_id"), "/")
+	if errResp := modelAccessDenied(c, modelID, modelID); errResp != nil {
+		c.JSON(http.StatusForbidden, errResp)
+		return
+	}
+	if err := ws.Models.UnloadModel(modelID); err != nil {
+		status := http.StatusInternalServerError
+		errResp := api.NewErrorResponse(err.Error(), "server_error", "", "")
+		if _, getErr := ws.Models.GetModel(modelID); getErr != nil {
+			status = http.StatusNotFound
+			errResp = api.NewErrorResponse(fmt.Sprintf("The model '%s' does not exist", modelID), "invalid_request_error", "model", "model_not_found")
+		}
+		c.JSON(status, errResp)
+		return
+	}
+
+	ws.CleanupModel(modelID)
+
+	c.JSON(http.StatusOK, api.ModelDeleteResponse{
+		ID:      modelID,
+		Object:  "model",
+		Deleted: true,
+	})
+}
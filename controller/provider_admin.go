@@ -0,0 +1,17 @@
+package controller
+
+import (
+	"net/http"
+
+	"RobinPenn974/OpenAI-mocker/provider"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HandleListProviderProfiles 处理列出所有服务商兼容配置的请求，同时返回配置中的默认值
+func HandleListProviderProfiles(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"profiles": provider.List(),
+		"default":  provider.Get(currentInstance(c).Config().Features.ProviderProfile).Name,
+	})
+}
@@ -0,0 +1,45 @@
+package controller
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HandleGetStatus 处理获取服务状态的请求，包括配置文件和模板文件最近一次重新加载的结果
+func HandleGetStatus(c *gin.Context) {
+	inst := currentInstance(c)
+	c.JSON(http.StatusOK, gin.H{
+		"started_at":         inst.StartedAt(),
+		"uptime_seconds":     int64(time.Since(inst.StartedAt()).Seconds()),
+		"reload_interval_ms": inst.Config().ReloadIntervalMs,
+		"reload":             inst.ReloadStatus(),
+	})
+}
+
+// HandleReload 处理重新加载配置文件和模板文件的请求，加载失败的文件继续使用上一个有效版本
+func HandleReload(c *gin.Context) {
+	inst := currentInstance(c)
+	if err := inst.Reload(); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error": gin.H{
+				"message": err.Error(),
+				"type":    "reload_error",
+			},
+			"reload": inst.ReloadStatus(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Reloaded successfully",
+		"reload":  inst.ReloadStatus(),
+	})
+}
+
+// SnapshotReloadStatus 返回重新加载状态的快照，用于审计
+func SnapshotReloadStatus(c *gin.Context) interface{} {
+	return currentInstance(c).ReloadStatus()
+}
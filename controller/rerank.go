@@ -1,9 +1,6 @@
 package controller
 
 import (
-	"fmt"
-	"math"
-	"math/rand"
 	"net/http"
 	"sort"
 	"strings"
@@ -11,25 +8,37 @@ import (
 
 	"RobinPenn974/OpenAI-mocker/api"
 	"RobinPenn974/OpenAI-mocker/models"
+	"RobinPenn974/OpenAI-mocker/rerank"
+	"RobinPenn974/OpenAI-mocker/responses"
+	"RobinPenn974/OpenAI-mocker/tokenizer"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 )
 
-// HandleRerank 处理文档重排序请求
+// rankedDocument 带分数的文档
+type rankedDocument struct {
+	index    int
+	score    float64
+	document api.RerankDocument
+	text     string
+}
+
+// HandleRerank 处理文档重排序请求，响应格式由模型配置决定
 func HandleRerank(c *gin.Context) {
+	handleRerankWithProfile(c, "")
+}
+
+// HandleCohereRerank 处理Cohere /v2/rerank 风格的重排序请求
+func HandleCohereRerank(c *gin.Context) {
+	handleRerankWithProfile(c, rerank.ProfileCohere)
+}
+
+// handleRerankWithProfile 处理重排序请求，profile为空时使用模型配置的响应格式
+func handleRerankWithProfile(c *gin.Context, profile string) {
 	var req api.RerankRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, api.ErrorResponse{
-			Error: struct {
-				Message string `json:"message"`
-				Type    string `json:"type"`
-				Code    string `json:"code,omitempty"`
-			}{
-				Message: "Invalid request: " + err.Error(),
-				Type:    "invalid_request_error",
-			},
-		})
+		c.JSON(http.StatusBadRequest, api.NewErrorResponse("Invalid request: "+err.Error(), "invalid_request_error", "", ""))
 		return
 	}
 
@@ -39,78 +48,198 @@ func HandleRerank(c *gin.Context) {
 		modelID = "mock-rerank-v1" // 默认模型
 	}
 
-	model, err := models.GetModel(modelID)
-	if err != nil || model.ModelType != models.ModelTypeRerank {
-		c.JSON(http.StatusBadRequest, api.ErrorResponse{
-			Error: struct {
-				Message string `json:"message"`
-				Type    string `json:"type"`
-				Code    string `json:"code,omitempty"`
-			}{
-				Message: fmt.Sprintf("Model '%s' not found or not a rerank model", modelID),
-				Type:    "model_not_found_error",
-			},
-		})
+	model, modelName, status, errResp := lookupModelForEndpoint(c, modelID, models.EndpointRerank)
+	if errResp != nil {
+		c.JSON(status, errResp)
+		return
+	}
+	req.Model = modelName
+
+	if profile == "" {
+		profile = model.RerankProfile
+	}
+	if profile == "" {
+		profile = rerank.ProfileDefault
+	}
+
+	if req.Query == "" {
+		c.JSON(http.StatusBadRequest, api.NewErrorResponse("query must not be empty", "invalid_request_error", "query", ""))
+		return
+	}
+
+	// 计算每个文档的相关性分数
+	ranked, err := rankDocuments(req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, api.NewErrorResponse(err.Error(), "invalid_request_error", "rank_fields", ""))
 		return
 	}
 
-	// 生成模拟的重排序结果
-	response := generateMockRerank(req)
-	c.JSON(http.StatusOK, response)
+	returnDocuments := rerank.DefaultReturnDocuments(profile)
+	if req.ReturnDocuments != nil {
+		returnDocuments = *req.ReturnDocuments
+	}
+
+	c.JSON(http.StatusOK, buildRerankResponse(req, ranked, profile, returnDocuments))
 }
 
-// generateMockRerank 生成模拟的重排序结果
-func generateMockRerank(req api.RerankRequest) api.RerankResponse {
-	// 设置随机种子
-	rand.Seed(time.Now().UnixNano())
+// HandleScore 处理vLLM /score 风格的打分请求
+func HandleScore(c *gin.Context) {
+	var req api.ScoreRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, api.NewErrorResponse("Invalid request: "+err.Error(), "invalid_request_error", "", ""))
+		return
+	}
 
-	// 限制topN
-	topN := req.TopN
-	if topN <= 0 || topN > len(req.Documents) {
-		topN = len(req.Documents)
+	modelID := req.Model
+	if modelID == "" {
+		modelID = "mock-rerank-v1" // 默认模型
+	}
+	_, modelName, status, errResp := lookupModelForEndpoint(c, modelID, models.EndpointScore)
+	if errResp != nil {
+		c.JSON(status, errResp)
+		return
 	}
 
-	// 准备结果
-	results := make([]api.RerankResult, len(req.Documents))
+	// text_1为单条时与text_2中每条配对，否则按位置一一配对
+	if len(req.Text1) == 0 || len(req.Text2) == 0 || (len(req.Text1) > 1 && len(req.Text1) != len(req.Text2)) {
+		c.JSON(http.StatusBadRequest, api.NewErrorResponse("text_1 must be a single string or have the same length as text_2", "invalid_request_error", "", ""))
+		return
+	}
+
+	data := make([]api.ScoreData, len(req.Text2))
+	promptTokens := 0
+	for i, text2 := range req.Text2 {
+		text1 := req.Text1[0]
+		if len(req.Text1) > 1 {
+			text1 = req.Text1[i]
+		}
+		data[i] = api.ScoreData{
+			Index:  i,
+			Object: "score",
+			Score:  rerank.Score(text1, []string{text2}, 0)[0],
+		}
+		promptTokens += tokenizer.CountTokens(text1) + tokenizer.CountTokens(text2)
+	}
 
-	// 计算相关性分数 - 基于简单的字符匹配和随机因子
-	query := strings.ToLower(req.Query)
-	queryWords := strings.Fields(query)
+	c.JSON(http.StatusOK, api.ScoreResponse{
+		ID:      responses.GenerateID("score"),
+		Object:  "list",
+		Created: time.Now().Unix(),
+		Model:   modelName,
+		Data:    data,
+		Usage: api.ChatCompletionUsage{
+			PromptTokens: promptTokens,
+			TotalTokens:  promptTokens,
+		},
+	})
+}
 
+// rankDocuments 使用BM25为文档打分并按分数降序排列，截取top_n
+func rankDocuments(req api.RerankRequest) ([]rankedDocument, error) {
+	texts := make([]string, len(req.Documents))
 	for i, doc := range req.Documents {
-		// 计算基础分数 - 字符匹配越多分数越高
-		docLower := strings.ToLower(doc)
-
-		// 计算查询词在文档中出现的次数
-		matchScore := 0.0
-		for _, word := range queryWords {
-			if strings.Contains(docLower, word) {
-				matchScore += 0.2 + rand.Float64()*0.1
-			}
+		text, err := doc.RankText(req.RankFields)
+		if err != nil {
+			return nil, err
 		}
+		texts[i] = text
+	}
 
-		// 添加随机因子，保证分数有差异
-		randomFactor := 0.2 + rand.Float64()*0.3
-
-		// 计算最终分数，确保范围在0到1之间
-		score := math.Min(0.2+matchScore+randomFactor, 0.99)
+	scores := rerank.Score(req.Query, texts, req.MaxChunksPerDoc)
 
-		results[i] = api.RerankResult{
-			Index:          i,
-			Document:       doc,
-			Score:          score,
-			RelevanceScore: score,
+	ranked := make([]rankedDocument, len(req.Documents))
+	for i, doc := range req.Documents {
+		ranked[i] = rankedDocument{
+			index:    i,
+			score:    scores[i],
+			document: doc,
+			text:     texts[i],
 		}
 	}
 
-	// 按分数降序排序
-	sort.Slice(results, func(i, j int) bool {
-		return results[i].Score > results[j].Score
+	// 按分数降序排序，分数相同时保持原始顺序
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return ranked[i].score > ranked[j].score
 	})
 
-	// 只保留topN个结果
-	if topN < len(results) {
-		results = results[:topN]
+	// 限制topN
+	topN := req.TopN
+	if topN > 0 && topN < len(ranked) {
+		ranked = ranked[:topN]
+	}
+	return ranked, nil
+}
+
+// buildRerankResponse 按响应格式构建重排序结果
+func buildRerankResponse(req api.RerankRequest, ranked []rankedDocument, profile string, returnDocuments bool) interface{} {
+	switch profile {
+	case rerank.ProfileCohere:
+		results := make([]api.CohereRerankResult, len(ranked))
+		for i, item := range ranked {
+			results[i] = api.CohereRerankResult{
+				Index:          item.index,
+				RelevanceScore: item.score,
+			}
+			if returnDocuments {
+				document := item.document
+				results[i].Document = &document
+			}
+		}
+		response := api.CohereRerankResponse{
+			ID:      uuid.NewString(),
+			Results: results,
+		}
+		response.Meta.APIVersion.Version = "2"
+		response.Meta.BilledUnits.SearchUnits = 1
+		return response
+
+	case rerank.ProfileJina, rerank.ProfileVLLM:
+		results := make([]api.JinaRerankResult, len(ranked))
+		for i, item := range ranked {
+			results[i] = api.JinaRerankResult{
+				Index:          item.index,
+				RelevanceScore: item.score,
+			}
+			if returnDocuments {
+				document := item.document
+				results[i].Document = &document
+			}
+		}
+
+		texts := make([]string, 0, len(req.Documents)+1)
+		texts = append(texts, req.Query)
+		for _, doc := range req.Documents {
+			text, _ := doc.RankText(req.RankFields)
+			texts = append(texts, text)
+		}
+		totalTokens := tokenizer.CountTokens(strings.Join(texts, "\n"))
+
+		response := api.JinaRerankResponse{
+			Model:   req.Model,
+			Results: results,
+			Usage: api.JinaRerankUsage{
+				TotalTokens: totalTokens,
+			},
+		}
+		if profile == rerank.ProfileVLLM {
+			response.ID = responses.GenerateID("rerank")
+		} else {
+			response.Usage.PromptTokens = totalTokens
+		}
+		return response
+	}
+
+	results := make([]api.RerankResult, len(ranked))
+	for i, item := range ranked {
+		results[i] = api.RerankResult{
+			Index:          item.index,
+			Score:          item.score,
+			RelevanceScore: item.score,
+		}
+		if returnDocuments {
+			text := item.text
+			results[i].Document = &text
+		}
 	}
 
 	// 构建响应
@@ -0,0 +1,152 @@
+package controller
+
+import (
+	"net/http"
+	"strings"
+
+	"RobinPenn974/OpenAI-mocker/api"
+	"RobinPenn974/OpenAI-mocker/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HandleGetModelRouting 处理获取模型路由规则的请求
+func HandleGetModelRouting(c *gin.Context) {
+	ws := currentWorkspace(c)
+	c.JSON(http.StatusOK, ws.Router.Rules())
+}
+
+// HandleCreateModelAlias 处理创建或更新模型别名的请求
+func HandleCreateModelAlias(c *gin.Context) {
+	ws := currentWorkspace(c)
+	var req api.ModelAliasRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": gin.H{
+				"message": "Invalid request: " + err.Error(),
+				"type":    "invalid_request_error",
+			},
+		})
+		return
+	}
+
+	if err := ws.Router.SetAlias(req.Alias, req.Target); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": gin.H{
+				"message": err.Error(),
+				"type":    "invalid_request_error",
+			},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Model alias created successfully",
+		"alias":   req.Alias,
+		"target":  req.Target,
+	})
+}
+
+// HandleDeleteModelAlias 处理删除模型别名的请求
+func HandleDeleteModelAlias(c *gin.Context) {
+	ws := currentWorkspace(c)
+	// 别名可能包含斜杠，路由使用通配参数
+	alias := strings.TrimPrefix(c.Param("alias"), "/")
+	if err := ws.Router.DeleteAlias(alias); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": gin.H{
+				"message": err.Error(),
+				"type":    "invalid_request_error",
+			},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Model alias deleted successfully",
+	})
+}
+
+// HandleCreateModelPattern 处理创建模型名匹配模式的请求
+func HandleCreateModelPattern(c *gin.Context) {
+	ws := currentWorkspace(c)
+	var pattern models.ModelPattern
+	if err := c.ShouldBindJSON(&pattern); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": gin.H{
+				"message": "Invalid request: " + err.Error(),
+				"type":    "invalid_request_error",
+			},
+		})
+		return
+	}
+
+	pattern, err := ws.Router.AddPattern(pattern)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": gin.H{
+				"message": err.Error(),
+				"type":    "invalid_request_error",
+			},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Model pattern created successfully",
+		"pattern": pattern,
+	})
+}
+
+// HandleDeleteModelPattern 处理删除模型名匹配模式的请求
+func HandleDeleteModelPattern(c *gin.Context) {
+	ws := currentWorkspace(c)
+	if err := ws.Router.DeletePattern(c.Param("pattern_id")); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": gin.H{
+				"message": err.Error(),
+				"type":    "invalid_request_error",
+			},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Model pattern deleted successfully",
+	})
+}
+
+// HandleSetAutoRegister 处理设置自动注册未知模型的请求
+func HandleSetAutoRegister(c *gin.Context) {
+	ws := currentWorkspace(c)
+	var config models.AutoRegisterConfig
+	if err := c.ShouldBindJSON(&config); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": gin.H{
+				"message": "Invalid request: " + err.Error(),
+				"type":    "invalid_request_error",
+			},
+		})
+		return
+	}
+
+	if err := ws.Router.SetAutoRegister(config); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": gin.H{
+				"message": err.Error(),
+				"type":    "invalid_request_error",
+			},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":       true,
+		"message":       "Auto register settings updated successfully",
+		"auto_register": config,
+	})
+}
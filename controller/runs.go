@@ -0,0 +1,386 @@
+package controller
+
+import (
+	"net/http"
+	"time"
+
+	"RobinPenn974/OpenAI-mocker/api"
+	"RobinPenn974/OpenAI-mocker/assistants"
+	"RobinPenn974/OpenAI-mocker/models"
+	"RobinPenn974/OpenAI-mocker/responses"
+	"RobinPenn974/OpenAI-mocker/templates"
+	"RobinPenn974/OpenAI-mocker/tokenizer"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HandleCreateRun 处理在线程上创建运行的请求，stream 为true时以SSE事件返回运行过程
+func HandleCreateRun(c *gin.Context) {
+	var req api.RunRequest
+	if !bindAssistantsRequest(c, &req) {
+		return
+	}
+
+	threadID := c.Param("thread_id")
+	if _, err := currentWorkspace(c).Assistants.GetThread(threadID); err != nil {
+		respondAssistantsError(c, err)
+		return
+	}
+	run, additional, status, errResp := runFromRequest(c, req)
+	if errResp != nil {
+		c.JSON(status, errResp)
+		return
+	}
+	startRun(c, threadID, run, additional, req.Stream, nil)
+}
+
+// HandleCreateThreadAndRun 处理同时创建线程和运行的请求
+func HandleCreateThreadAndRun(c *gin.Context) {
+	var req api.RunRequest
+	if !bindAssistantsRequest(c, &req) {
+		return
+	}
+
+	run, additional, status, errResp := runFromRequest(c, req)
+	if errResp != nil {
+		c.JSON(status, errResp)
+		return
+	}
+	threadReq := api.ThreadRequest{}
+	if req.Thread != nil {
+		threadReq = *req.Thread
+	}
+	thread, messages, errResp := threadFromRequest(threadReq)
+	if errResp != nil {
+		c.JSON(http.StatusBadRequest, errResp)
+		return
+	}
+
+	thread = currentWorkspace(c).Assistants.CreateThread(thread, messages)
+	startRun(c, thread.ID, run, additional, req.Stream, []assistants.Event{{Name: "thread.created", Data: thread}})
+}
+
+// HandleListRuns 处理分页列出线程中运行的请求
+func HandleListRuns(c *gin.Context) {
+	params, errResp := listParams(c, api.OrderDesc)
+	if errResp != nil {
+		c.JSON(http.StatusBadRequest, errResp)
+		return
+	}
+
+	data, hasMore, err := currentWorkspace(c).Assistants.ListRuns(c.Param("thread_id"), params)
+	if err != nil {
+		respondAssistantsError(c, err)
+		return
+	}
+	ids := make([]string, len(data))
+	for i, run := range data {
+		ids[i] = run.ID
+	}
+	c.JSON(http.StatusOK, api.NewListResponse(data, ids, hasMore))
+}
+
+// HandleGetRun 处理获取运行的请求，运行的状态按创建后经过的时间推进
+func HandleGetRun(c *gin.Context) {
+	run, err := currentWorkspace(c).Assistants.GetRun(c.Param("thread_id"), c.Param("run_id"))
+	if err != nil {
+		respondAssistantsError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, run)
+}
+
+// HandleModifyRun 处理修改运行元数据的请求
+func HandleModifyRun(c *gin.Context) {
+	var req api.MetadataRequest
+	if !bindAssistantsRequest(c, &req) {
+		return
+	}
+	if errResp := validateMetadata(req.Metadata); errResp != nil {
+		c.JSON(http.StatusBadRequest, errResp)
+		return
+	}
+
+	run, err := currentWorkspace(c).Assistants.UpdateRun(c.Param("thread_id"), c.Param("run_id"), req.Metadata)
+	if err != nil {
+		respondAssistantsError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, run)
+}
+
+// HandleCancelRun 处理取消运行的请求
+func HandleCancelRun(c *gin.Context) {
+	run, err := currentWorkspace(c).Assistants.CancelRun(c.Param("thread_id"), c.Param("run_id"))
+	if err != nil {
+		respondAssistantsError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, run)
+}
+
+// HandleSubmitToolOutputs 处理提交工具输出的请求，运行随后生成助手消息
+func HandleSubmitToolOutputs(c *gin.Context) {
+	var req api.SubmitToolOutputsRequest
+	if !bindAssistantsRequest(c, &req) {
+		return
+	}
+
+	threadID, runID := c.Param("thread_id"), c.Param("run_id")
+	store := currentWorkspace(c).Assistants
+	run, err := store.GetRun(threadID, runID)
+	if err != nil {
+		respondAssistantsError(c, err)
+		return
+	}
+	messages, err := store.ThreadMessages(threadID)
+	if err != nil {
+		respondAssistantsError(c, err)
+		return
+	}
+
+	plan, delay := planRun(c, run, messages, true)
+	run, events, err := store.SubmitToolOutputs(threadID, runID, req.ToolOutputs, plan)
+	if err != nil {
+		respondAssistantsError(c, err)
+		return
+	}
+	if !req.Stream {
+		c.JSON(http.StatusOK, run)
+		return
+	}
+	streamRun(c, run, events, delay)
+}
+
+// HandleListRunSteps 处理分页列出运行步骤的请求
+func HandleListRunSteps(c *gin.Context) {
+	params, errResp := listParams(c, api.OrderDesc)
+	if errResp != nil {
+		c.JSON(http.StatusBadRequest, errResp)
+		return
+	}
+
+	data, hasMore, err := currentWorkspace(c).Assistants.ListRunSteps(c.Param("thread_id"), c.Param("run_id"), params)
+	if err != nil {
+		respondAssistantsError(c, err)
+		return
+	}
+	ids := make([]string, len(data))
+	for i, step := range data {
+		ids[i] = step.ID
+	}
+	c.JSON(http.StatusOK, api.NewListResponse(data, ids, hasMore))
+}
+
+// HandleGetRunStep 处理获取运行步骤的请求
+func HandleGetRunStep(c *gin.Context) {
+	step, err := currentWorkspace(c).Assistants.GetRunStep(c.Param("thread_id"), c.Param("run_id"), c.Param("step_id"))
+	if err != nil {
+		respondAssistantsError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, step)
+}
+
+// runFromRequest 按助手的设置和请求中的覆盖项构建运行，返回运行和附加消息，失败时返回HTTP状态码和错误响应
+func runFromRequest(c *gin.Context, req api.RunRequest) (assistants.Run, []assistants.Message, int, *api.ErrorResponse) {
+	if req.AssistantID == "" {
+		errResp := api.NewErrorResponse("Missing required parameter: 'assistant_id'.", "invalid_request_error", "assistant_id", "missing_required_parameter")
+		return assistants.Run{}, nil, http.StatusBadRequest, &errResp
+	}
+	ws := currentWorkspace(c)
+	assistant, err := ws.Assistants.GetAssistant(req.AssistantID)
+	if err != nil {
+		errResp := api.NewErrorResponse(err.Error(), "invalid_request_error", "", "")
+		return assistants.Run{}, nil, http.StatusNotFound, &errResp
+	}
+
+	modelID := assistant.Model
+	if req.Model != nil && *req.Model != "" {
+		modelID = *req.Model
+	}
+	model, modelName, status, errResp := lookupModelForEndpoint(c, modelID, models.EndpointChatCompletions)
+	if errResp != nil {
+		return assistants.Run{}, nil, status, errResp
+	}
+
+	tools := assistant.Tools
+	if req.Tools != nil {
+		tools = *req.Tools
+	}
+	if errResp := validateAssistantTools(model, tools); errResp != nil {
+		return assistants.Run{}, nil, http.StatusBadRequest, errResp
+	}
+	if err := assistants.ValidateToolChoice(tools, req.ToolChoice); err != nil {
+		errResp := api.NewErrorResponse(err.Error(), "invalid_request_error", "tool_choice", "invalid_value")
+		return assistants.Run{}, nil, http.StatusBadRequest, &errResp
+	}
+	if errResp := validateMetadata(req.Metadata); errResp != nil {
+		return assistants.Run{}, nil, http.StatusBadRequest, errResp
+	}
+	if req.MaxCompletionTokens != nil {
+		if errResp := checkMaxTokens(model, *req.MaxCompletionTokens, "max_completion_tokens"); errResp != nil {
+			return assistants.Run{}, nil, http.StatusBadRequest, errResp
+		}
+	}
+	additional, errResp := messagesFromRequest(req.AdditionalMessages, "additional_messages")
+	if errResp != nil {
+		return assistants.Run{}, nil, http.StatusBadRequest, errResp
+	}
+
+	instructions := ""
+	if assistant.Instructions != nil {
+		instructions = *assistant.Instructions
+	}
+	if req.Instructions != nil {
+		instructions = *req.Instructions
+	}
+	if req.AdditionalInstructions != nil && *req.AdditionalInstructions != "" {
+		if instructions != "" {
+			instructions += "\n\n"
+		}
+		instructions += *req.AdditionalInstructions
+	}
+
+	run := assistants.Run{
+		AssistantID:         assistant.ID,
+		Model:               modelName,
+		Instructions:        instructions,
+		Tools:               tools,
+		Metadata:            req.Metadata,
+		Temperature:         assistant.Temperature,
+		TopP:                assistant.TopP,
+		MaxPromptTokens:     req.MaxPromptTokens,
+		MaxCompletionTokens: req.MaxCompletionTokens,
+		TruncationStrategy:  assistants.TruncationStrategy{Type: "auto"},
+		ToolChoice:          assistants.ToolChoiceAuto,
+		ParallelToolCalls:   true,
+		ResponseFormat:      assistant.ResponseFormat,
+	}
+	if req.Temperature != nil {
+		run.Temperature = req.Temperature
+	}
+	if req.TopP != nil {
+		run.TopP = req.TopP
+	}
+	if req.TruncationStrategy != nil {
+		run.TruncationStrategy = req.TruncationStrategy
+	}
+	if req.ToolChoice != nil {
+		run.ToolChoice = req.ToolChoice
+	}
+	if req.ParallelToolCalls != nil {
+		run.ParallelToolCalls = *req.ParallelToolCalls
+	}
+	if req.ResponseFormat != nil {
+		run.ResponseFormat = req.ResponseFormat
+	}
+	return run, additional, http.StatusOK, nil
+}
+
+// startRun 生成运行的结果并创建运行，stream 为true时立即推进运行并以SSE事件返回，leading 为运行事件之前的事件
+func startRun(c *gin.Context, threadID string, run assistants.Run, additional []assistants.Message, stream bool, leading []assistants.Event) {
+	store := currentWorkspace(c).Assistants
+	messages, err := store.ThreadMessages(threadID)
+	if err != nil {
+		respondAssistantsError(c, err)
+		return
+	}
+
+	plan, delay := planRun(c, run, append(messages, additional...), false)
+	run, err = store.CreateRun(threadID, run, additional, plan)
+	if err != nil {
+		respondAssistantsError(c, err)
+		return
+	}
+	if !stream {
+		c.JSON(http.StatusOK, run)
+		return
+	}
+
+	events := append(leading,
+		assistants.Event{Name: "thread.run.created", Data: run},
+		assistants.Event{Name: "thread.run.queued", Data: run},
+	)
+	streamRun(c, run, events, delay)
+}
+
+// planRun 用模板生成器预先生成运行的结果：有可调用的函数工具且尚未提交工具输出时生成工具调用，
+// 否则按线程中最后一条用户消息生成助手消息，返回生成结果和流式返回消息增量的默认延迟
+func planRun(c *gin.Context, run assistants.Run, messages []assistants.Message, toolOutputsSubmitted bool) (assistants.Plan, time.Duration) {
+	cfg := currentInstance(c).Config()
+	plan := assistants.Plan{
+		QueuedFor: time.Duration(cfg.Assistants.QueuedMs) * time.Millisecond,
+		RunFor:    time.Duration(cfg.Assistants.InProgressMs) * time.Millisecond,
+	}
+
+	contents := []string{run.Instructions}
+	input := ""
+	for _, message := range messages {
+		contents = append(contents, message.Text())
+		if message.Role == "user" {
+			input = message.Text()
+		}
+	}
+	promptTokens := tokenizer.CountMessagesTokens(contents)
+
+	if !toolOutputsSubmitted {
+		if calls := assistants.FunctionCalls(run.Tools, run.ToolChoice); len(calls) > 0 {
+			completionTokens := 0
+			for _, call := range calls {
+				completionTokens += tokenizer.CountTokens(call.Function.Name + call.Function.Arguments)
+			}
+			plan.ToolCalls = calls
+			plan.Usage = runUsage(promptTokens, completionTokens)
+			return plan, 0
+		}
+	}
+
+	// Assistants API 不返回推理内容
+	opts := responseOptions(c)
+	opts.Thinking = templates.Bool(false)
+	if run.MaxCompletionTokens != nil {
+		opts.MaxTokens = *run.MaxCompletionTokens
+	}
+	generator := responses.ModelFactory(run.Model, currentWorkspace(c).Template(run.Model), opts)
+	content := generator.GenerateResponse(input, run.Model)
+
+	chunks, delay := splitStream(content.Content, 2)
+	plan.Chunks = chunks
+	plan.Usage = runUsage(promptTokens, tokenizer.CountTokens(content.Content))
+	return plan, delay
+}
+
+// runUsage 构造运行的token使用量
+func runUsage(promptTokens, completionTokens int) assistants.Usage {
+	return assistants.Usage{
+		PromptTokens:     promptTokens,
+		CompletionTokens: completionTokens,
+		TotalTokens:      promptTokens + completionTokens,
+	}
+}
+
+// streamRun 将运行推进到需要调用方操作或结束，以SSE事件依次发送 events 和运行过程中的事件，最后发送 done 事件
+func streamRun(c *gin.Context, run assistants.Run, events []assistants.Event, delay time.Duration) {
+	more, err := currentWorkspace(c).Assistants.StreamRun(run.ThreadID, run.ID)
+	if err != nil {
+		respondAssistantsError(c, err)
+		return
+	}
+	events = append(events, more...)
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("Transfer-Encoding", "chunked")
+
+	pacer := newStreamPacer(c, run.Model)
+	for _, event := range events {
+		c.SSEvent(event.Name, event.Data)
+		c.Writer.Flush()
+		if event.Name == "thread.message.delta" {
+			pacer.wait(delay)
+		}
+	}
+	c.SSEvent("done", "[DONE]")
+}
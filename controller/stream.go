@@ -0,0 +1,124 @@
+package controller
+
+import (
+	"regexp"
+	"time"
+
+	"RobinPenn974/OpenAI-mocker/api"
+	"RobinPenn974/OpenAI-mocker/config"
+	"RobinPenn974/OpenAI-mocker/provider"
+	"RobinPenn974/OpenAI-mocker/responses"
+
+	"github.com/gin-gonic/gin"
+)
+
+// responseOptions 根据实例配置和服务商兼容配置返回响应生成选项
+func responseOptions(c *gin.Context) responses.Options {
+	cfg := currentInstance(c).Config()
+	return responses.Options{
+		ReasoningField:  cfg.Features.ReasoningField,
+		ReasoningFormat: currentProviderProfile(c).ReasoningFormat,
+		Tokens:          cfg.Generation.Tokens,
+		CorpusDir:       cfg.CorpusDir(),
+	}
+}
+
+// streamPacer 控制流式响应数据块之间的延迟，模型命中延迟配置时使用配置的延迟，否则使用各处的默认延迟
+type streamPacer struct {
+	profile config.LatencyProfile
+	matched bool
+	sent    int
+}
+
+// newStreamPacer 为模型创建流式延迟控制器
+func newStreamPacer(c *gin.Context, modelID string) *streamPacer {
+	profile, matched := currentInstance(c).Config().LatencyFor(modelID)
+	return &streamPacer{profile: profile, matched: matched}
+}
+
+// next 返回发送一个数据块后应等待的时间
+func (p *streamPacer) next(defaultDelay time.Duration) time.Duration {
+	delay := defaultDelay
+	if p.matched {
+		if p.sent == 0 {
+			delay = time.Duration(p.profile.FirstChunkMs) * time.Millisecond
+		} else {
+			delay = time.Duration(p.profile.ChunkDelayMs) * time.Millisecond
+		}
+	}
+	p.sent++
+	return delay
+}
+
+// wait 在发送一个数据块后等待
+func (p *streamPacer) wait(defaultDelay time.Duration) {
+	if delay := p.next(defaultDelay); delay > 0 {
+		time.Sleep(delay)
+	}
+}
+
+// streamWordPattern 匹配带前导空白的词
+var streamWordPattern = regexp.MustCompile(`\s*\S+`)
+
+// splitStream 按文本的语言切分流式数据块并返回数据块之间的默认延迟：中文和日文每块5个字符，
+// 其他语言每块words个词，数据块保留原文中的空白，拼接后与原文一致
+func splitStream(text string, words int) ([]string, time.Duration) {
+	var pieces []string
+	if responses.IsCJKLanguage(responses.DetectLanguage(text)) {
+		runes := []rune(text)
+		for i := 0; i < len(runes); i += 5 {
+			pieces = append(pieces, string(runes[i:min(i+5, len(runes))]))
+		}
+		return pieces, 50 * time.Millisecond
+	}
+
+	matches := streamWordPattern.FindAllStringIndex(text, -1)
+	start := 0
+	for i := 0; i < len(matches); i += words {
+		end := matches[min(i+words, len(matches))-1][1]
+		pieces = append(pieces, text[start:end])
+		start = end
+	}
+	// 末尾的空白并入最后一块
+	if len(pieces) > 0 && start < len(text) {
+		pieces[len(pieces)-1] += text[start:]
+	}
+	return pieces, 100 * time.Millisecond
+}
+
+// streamChunk 流式响应中的一个数据块
+type streamChunk struct {
+	data  interface{}
+	delay time.Duration // 发送后的默认延迟，为0时紧接着发送下一块
+}
+
+// shapeStream 按服务商兼容配置转换流式数据块，usage 为整个响应的token使用量
+func shapeStream(profile provider.Profile, kind provider.Kind, chunks []streamChunk, usage api.ChatCompletionUsage) []streamChunk {
+	data := make([]interface{}, len(chunks))
+	for i, chunk := range chunks {
+		data[i] = chunk.data
+	}
+	for i, shaped := range profile.ShapeStream(data, kind, usage) {
+		chunks[i].data = shaped
+	}
+	return chunks
+}
+
+// writeStream 以SSE格式依次发送数据块，最后发送结束事件
+func writeStream(c *gin.Context, pacer *streamPacer, chunks []streamChunk) {
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("Transfer-Encoding", "chunked")
+
+	for _, chunk := range chunks {
+		c.SSEvent("", chunk.data)
+		c.Writer.Flush()
+		if chunk.delay > 0 {
+			pacer.wait(chunk.delay)
+		}
+	}
+
+	// 发送结束事件
+	c.SSEvent("", "")
+}
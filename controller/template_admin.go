@@ -1,8 +1,13 @@
 package controller
 
 import (
+	"encoding/json"
+	"errors"
 	"net/http"
+	"strconv"
+	"strings"
 
+	"RobinPenn974/OpenAI-mocker/middleware"
 	"RobinPenn974/OpenAI-mocker/templates"
 
 	"github.com/gin-gonic/gin"
@@ -10,15 +15,18 @@ import (
 
 // HandleListTemplates 处理列出所有模板的请求
 func HandleListTemplates(c *gin.Context) {
-	templateList := templates.ListTemplates()
+	ws := currentWorkspace(c)
+	templateList := ws.Templates.ListTemplates()
 	c.JSON(http.StatusOK, gin.H{
 		"templates": templateList,
 		"count":     len(templateList),
 	})
 }
 
-// HandleGetTemplate 处理获取指定模型模板的请求
+// HandleGetTemplate 处理获取指定模型模板的请求，默认返回合并父模板、模式模板和默认模板后的生效模板，
+// 生效模板不能直接写回，因此不返回ETag；stored=true 时返回保存的模板本身和用于 If-Match 的ETag
 func HandleGetTemplate(c *gin.Context) {
+	ws := currentWorkspace(c)
 	modelID := c.Param("model_id")
 	if modelID == "" {
 		c.JSON(http.StatusBadRequest, gin.H{
@@ -30,12 +38,23 @@ func HandleGetTemplate(c *gin.Context) {
 		return
 	}
 
-	template := templates.GetTemplate(modelID)
+	if c.Query("stored") != "true" {
+		c.JSON(http.StatusOK, ws.Template(modelID))
+		return
+	}
+
+	template, exists := ws.Templates.StoredTemplate(modelID)
+	if !exists {
+		respondTemplateNotFound(c, modelID)
+		return
+	}
+	c.Header("ETag", ws.Templates.ETag(modelID))
 	c.JSON(http.StatusOK, template)
 }
 
-// HandleUpdateTemplate 处理更新模板的请求
+// HandleUpdateTemplate 处理更新模板的请求，请求带有 If-Match 头时只在模板未被他人修改时更新
 func HandleUpdateTemplate(c *gin.Context) {
+	ws := currentWorkspace(c)
 	var template templates.ResponseTemplate
 	if err := c.ShouldBindJSON(&template); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
@@ -62,7 +81,62 @@ func HandleUpdateTemplate(c *gin.Context) {
 	template.ModelID = modelID
 
 	// 注册模板
-	if err := templates.RegisterTemplate(template); err != nil {
+	etag, err := ws.Templates.UpdateTemplate(template, changeAuthor(c), c.GetHeader("If-Match"))
+	if errors.Is(err, templates.ErrPreconditionFailed) {
+		respondPreconditionFailed(c, ws.Templates.ETag(modelID))
+		return
+	}
+	if errors.Is(err, templates.ErrInvalidTemplate) {
+		respondInvalidTemplate(c, err)
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": gin.H{
+				"message": "Failed to update template: " + err.Error(),
+				"type":    "internal_server_error",
+			},
+		})
+		return
+	}
+
+	c.Header("ETag", etag)
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"message":  "Template updated successfully",
+		"template": template,
+	})
+}
+
+// HandlePatchTemplate 处理部分更新模板的请求，请求体按 JSON Merge Patch 合并到保存的模板中，
+// 值为null的字段恢复为未设置，请求带有 If-Match 头时只在模板未被他人修改时更新
+func HandlePatchTemplate(c *gin.Context) {
+	ws := currentWorkspace(c)
+	modelID := c.Param("model_id")
+
+	var patch map[string]json.RawMessage
+	if err := c.ShouldBindJSON(&patch); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": gin.H{
+				"message": "Invalid request: " + err.Error(),
+				"type":    "invalid_request_error",
+			},
+		})
+		return
+	}
+
+	template, etag, err := ws.Templates.PatchTemplate(modelID, patch, changeAuthor(c), c.GetHeader("If-Match"))
+	switch {
+	case errors.Is(err, templates.ErrTemplateNotFound):
+		respondTemplateNotFound(c, modelID)
+		return
+	case errors.Is(err, templates.ErrPreconditionFailed):
+		respondPreconditionFailed(c, ws.Templates.ETag(modelID))
+		return
+	case errors.Is(err, templates.ErrInvalidTemplate):
+		respondInvalidTemplate(c, err)
+		return
+	case err != nil:
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": gin.H{
 				"message": "Failed to update template: " + err.Error(),
@@ -72,6 +146,7 @@ func HandleUpdateTemplate(c *gin.Context) {
 		return
 	}
 
+	c.Header("ETag", etag)
 	c.JSON(http.StatusOK, gin.H{
 		"success":  true,
 		"message":  "Template updated successfully",
@@ -81,6 +156,7 @@ func HandleUpdateTemplate(c *gin.Context) {
 
 // HandleDeleteTemplate 处理删除模板的请求
 func HandleDeleteTemplate(c *gin.Context) {
+	ws := currentWorkspace(c)
 	modelID := c.Param("model_id")
 	if modelID == "" {
 		c.JSON(http.StatusBadRequest, gin.H{
@@ -93,7 +169,12 @@ func HandleDeleteTemplate(c *gin.Context) {
 	}
 
 	// 删除模板
-	if err := templates.DeleteTemplate(modelID); err != nil {
+	err := ws.Templates.RemoveTemplate(modelID, changeAuthor(c), c.GetHeader("If-Match"))
+	if errors.Is(err, templates.ErrPreconditionFailed) {
+		respondPreconditionFailed(c, ws.Templates.ETag(modelID))
+		return
+	}
+	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error": gin.H{
 				"message": err.Error(),
@@ -108,3 +189,115 @@ func HandleDeleteTemplate(c *gin.Context) {
 		"message": "Template deleted successfully",
 	})
 }
+
+// HandleGetTemplateHistory 处理获取模板历史版本的请求，版本按从旧到新排列
+func HandleGetTemplateHistory(c *gin.Context) {
+	ws := currentWorkspace(c)
+	modelID := c.Param("model_id")
+	versions := ws.Templates.History(modelID)
+
+	c.Header("ETag", ws.Templates.ETag(modelID))
+	c.JSON(http.StatusOK, gin.H{
+		"model_id": modelID,
+		"versions": versions,
+		"count":    len(versions),
+	})
+}
+
+// HandleRollbackTemplate 处理将模板恢复为指定历史版本的请求
+func HandleRollbackTemplate(c *gin.Context) {
+	ws := currentWorkspace(c)
+	modelID := c.Param("model_id")
+	version, err := strconv.Atoi(strings.TrimPrefix(c.Param("version"), "v"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": gin.H{
+				"message": "Invalid version: " + c.Param("version"),
+				"type":    "invalid_request_error",
+			},
+		})
+		return
+	}
+
+	template, etag, err := ws.Templates.Rollback(modelID, version, changeAuthor(c), c.GetHeader("If-Match"))
+	switch {
+	case errors.Is(err, templates.ErrPreconditionFailed):
+		respondPreconditionFailed(c, ws.Templates.ETag(modelID))
+		return
+	case errors.Is(err, templates.ErrVersionNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": gin.H{
+				"message": "Version " + strconv.Itoa(version) + " of template " + modelID + " not found or is a deletion",
+				"type":    "invalid_request_error",
+			},
+		})
+		return
+	case errors.Is(err, templates.ErrInvalidTemplate):
+		respondInvalidTemplate(c, err)
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": gin.H{
+				"message": "Failed to roll back template: " + err.Error(),
+				"type":    "internal_server_error",
+			},
+		})
+		return
+	}
+
+	c.Header("ETag", etag)
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"message":  "Template rolled back to version " + strconv.Itoa(version),
+		"template": template,
+	})
+}
+
+// respondPreconditionFailed 返回 If-Match 与模板当前版本不一致的错误，并返回当前的ETag
+func respondPreconditionFailed(c *gin.Context, etag string) {
+	c.Header("ETag", etag)
+	c.JSON(http.StatusPreconditionFailed, gin.H{
+		"error": gin.H{
+			"message": "The template has been modified since it was read, current version is " + etag,
+			"type":    "precondition_failed",
+		},
+	})
+}
+
+// respondTemplateNotFound 返回模板不存在的错误
+func respondTemplateNotFound(c *gin.Context, modelID string) {
+	c.JSON(http.StatusNotFound, gin.H{
+		"error": gin.H{
+			"message": "Template for model " + modelID + " not found",
+			"type":    "invalid_request_error",
+		},
+	})
+}
+
+// respondInvalidTemplate 返回模板的模式或继承关系无效的错误
+func respondInvalidTemplate(c *gin.Context, err error) {
+	c.JSON(http.StatusBadRequest, gin.H{
+		"error": gin.H{
+			"message": err.Error(),
+			"type":    "invalid_request_error",
+		},
+	})
+}
+
+// changeAuthor 返回记录到模板历史中的修改者：管理员名称，未开启管理鉴权时使用请求携带的API密钥名称
+func changeAuthor(c *gin.Context) string {
+	if actor := c.GetString(middleware.ContextKeyAdminActor); actor != "" && actor != "anonymous" {
+		return "admin:" + actor
+	}
+
+	token := strings.TrimSpace(c.GetHeader("Authorization"))
+	if len(token) > 7 && strings.EqualFold(token[:7], "bearer ") {
+		token = strings.TrimSpace(token[7:])
+	}
+	if token != "" {
+		if key, err := currentInstance(c).Keys.Authenticate(token); err == nil {
+			return "api_key:" + key.Name
+		}
+	}
+	return "anonymous"
+}
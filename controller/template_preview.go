@@ -0,0 +1,169 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+
+	"RobinPenn974/OpenAI-mocker/api"
+	"RobinPenn974/OpenAI-mocker/models"
+	"RobinPenn974/OpenAI-mocker/responses"
+	"RobinPenn974/OpenAI-mocker/templates"
+	"RobinPenn974/OpenAI-mocker/workspace"
+
+	"github.com/gin-gonic/gin"
+)
+
+// previewChunk 预览中的一个流式数据块及发送后的延迟
+type previewChunk struct {
+	Data    interface{} `json:"data"`
+	DelayMs int64       `json:"delay_ms"`
+}
+
+// HandlePreviewTemplate 处理预览模板渲染结果的请求，使用模板草稿或当前模板生成示例请求的响应，不保存任何修改
+func HandlePreviewTemplate(c *gin.Context) {
+	ws := currentWorkspace(c)
+	modelID := c.Param("model_id")
+
+	var req api.TemplatePreviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondPreviewError(c, "Invalid request: "+err.Error())
+		return
+	}
+	// 服务没有 /v1/responses 接口，预览不在范围内，明确拒绝而不是返回虚构的响应
+	if len(req.Responses) > 0 {
+		respondPreviewError(c, "Previewing responses requests is not supported because this server does not implement the Responses API (/v1/responses), preview a chat or completion request instead")
+		return
+	}
+	if (req.Chat == nil) == (req.Completion == nil) {
+		respondPreviewError(c, "Exactly one of chat or completion must be provided")
+		return
+	}
+
+	template := ws.Template(modelID)
+	source := "current"
+	if req.Template != nil {
+		// 草稿按保存模板时相同的规则校验，无效的候选回复权重等会导致渲染失败
+		draft := *req.Template
+		draft.ModelID = modelID
+		if err := ws.Templates.CheckTemplates([]templates.ResponseTemplate{draft}); err != nil {
+			respondInvalidTemplate(c, err)
+			return
+		}
+		template = ws.DraftTemplate(modelID, draft)
+		source = "draft"
+	}
+
+	opts := responseOptions(c)
+	var (
+		warnings  []string
+		endpoint  string
+		generator responses.ResponseGenerator
+		content   responses.ResponseContent
+		response  interface{}
+		usage     api.ChatCompletionUsage
+		chunks    []streamChunk
+	)
+
+	if req.Chat != nil {
+		chatReq := *req.Chat
+		chatReq.Model = modelID
+		model, name, warning := lookupPreviewModel(ws, modelID, models.EndpointChatCompletions)
+		if warning != "" {
+			warnings = append(warnings, warning)
+		} else {
+			chatReq.Model = name
+			if errResp := validateChatRequest(model, chatReq); errResp != nil {
+				warnings = append(warnings, "the request would be rejected: "+errResp.Error.Message)
+			}
+		}
+		if len(chatReq.Tools) > 0 {
+			warnings = append(warnings, "tool calls are not simulated, the response never contains tool_calls")
+		}
+
+		var lastContent string
+		if len(chatReq.Messages) > 0 {
+			lastContent = chatReq.Messages[len(chatReq.Messages)-1].Content
+		}
+		generator = responses.ModelFactory(chatReq.Model, template, chatOptions(chatReq, opts))
+		content = generator.GenerateResponse(lastContent, chatReq.Model)
+		chatResponse := buildChatResponse(chatReq, content)
+		endpoint, response, usage = "/v1/chat/completions", chatResponse, chatResponse.Usage
+		chunks = chatStreamChunks(chatReq, content)
+	} else {
+		completionReq := *req.Completion
+		completionReq.Model = modelID
+		model, name, warning := lookupPreviewModel(ws, modelID, models.EndpointCompletions)
+		if warning != "" {
+			warnings = append(warnings, warning)
+		} else {
+			completionReq.Model = name
+			if errResp := validateCompletionRequest(model, completionReq); errResp != nil {
+				warnings = append(warnings, "the request would be rejected: "+errResp.Error.Message)
+			}
+		}
+
+		generator = responses.ModelFactory(completionReq.Model, template, completionOptions(completionReq, opts))
+		content = generator.GenerateResponse(completionReq.Prompt, completionReq.Model)
+		completionResponse := buildCompletionResponse(completionReq, content)
+		endpoint, response, usage = "/v1/completions", completionResponse, completionResponse.Usage
+		chunks = completionStreamChunks(completionReq, content)
+	}
+	warnings = append(warnings, responses.TemplateWarnings(template, generator, content)...)
+	if len(template.Variants[content.Rule])+len(template.Variants[content.Rule+"."+content.Language]) > 0 && (req.Chat == nil || req.Chat.Seed == nil) && (req.Completion == nil || req.Completion.Seed == nil) {
+		warnings = append(warnings, fmt.Sprintf("field '%s' has variants, the response differs between requests unless seed is set", content.Rule))
+	}
+
+	if warnings == nil {
+		warnings = []string{}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"model_id":          modelID,
+		"template_source":   source,
+		"endpoint":          endpoint,
+		"generator":         responses.GeneratorName(generator),
+		"matched_rule":      content.Rule,
+		"language":          content.Language,
+		"content":           content.Content,
+		"reasoning_content": content.ReasoningContent,
+		"finish_reason":     content.FinishReason,
+		"usage":             usage,
+		"response":          response,
+		"stream":            previewChunks(newStreamPacer(c, modelID), chunks),
+		"warnings":          warnings,
+	})
+}
+
+// lookupPreviewModel 按别名和匹配模式解析模型，不会自动注册未知模型，无法处理该请求时返回警告
+func lookupPreviewModel(ws *workspace.Workspace, modelID, endpoint string) (models.ModelInfo, string, string) {
+	resolution, err := ws.LookupModel(modelID)
+	if err != nil {
+		return models.ModelInfo{}, "", fmt.Sprintf("model '%s' is not registered, real requests fail with model_not_found unless auto-registration is enabled", modelID)
+	}
+	if !resolution.Model.SupportsEndpoint(endpoint) {
+		return models.ModelInfo{}, "", fmt.Sprintf("model '%s' does not support the %s endpoint", modelID, endpointPaths[endpoint])
+	}
+	return resolution.Model, resolution.Name, ""
+}
+
+// previewChunks 将流式数据块转换为预览结果，延迟按模型的延迟配置计算
+func previewChunks(pacer *streamPacer, chunks []streamChunk) []previewChunk {
+	preview := make([]previewChunk, 0, len(chunks))
+	for _, chunk := range chunks {
+		var delayMs int64
+		if chunk.delay > 0 {
+			delayMs = pacer.next(chunk.delay).Milliseconds()
+		}
+		preview = append(preview, previewChunk{Data: chunk.data, DelayMs: delayMs})
+	}
+	return preview
+}
+
+// respondPreviewError 返回预览请求无效的错误
+func respondPreviewError(c *gin.Context, message string) {
+	c.JSON(http.StatusBadRequest, gin.H{
+		"error": gin.H{
+			"message": message,
+			"type":    "invalid_request_error",
+		},
+	})
+}
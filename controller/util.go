@@ -1,5 +1,14 @@
 package controller
 
+import (
+	"fmt"
+	"strconv"
+
+	"RobinPenn974/OpenAI-mocker/api"
+
+	"github.com/gin-gonic/gin"
+)
+
 // max 返回两个整数中的较大值
 func max(a, b int) int {
 	if a > b {
@@ -20,3 +29,48 @@ func min(a, b int) int {
 func stringPtr(s string) *string {
 	return &s
 }
+
+// usage 构造token使用量，生成了推理内容时返回推理token数明细
+func usage(promptTokens, completionTokens, reasoningTokens int) api.ChatCompletionUsage {
+	usage := api.ChatCompletionUsage{
+		PromptTokens:     promptTokens,
+		CompletionTokens: completionTokens,
+		TotalTokens:      promptTokens + completionTokens,
+	}
+	if reasoningTokens > 0 {
+		usage.CompletionTokensDetails = &api.CompletionTokensDetails{ReasoningTokens: reasoningTokens}
+	}
+	return usage
+}
+
+// listParams 解析分页列表的 after、before、limit 和 order 查询参数，defaultOrder 为未指定 order 时的排序方向
+func listParams(c *gin.Context, defaultOrder string) (api.ListParams, *api.ErrorResponse) {
+	params := api.ListParams{
+		After:  c.Query("after"),
+		Before: c.Query("before"),
+		Limit:  api.DefaultListLimit,
+		Order:  c.DefaultQuery("order", defaultOrder),
+	}
+
+	if value := c.Query("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil {
+			errResp := api.NewErrorResponse(fmt.Sprintf("Invalid 'limit': expected an integer, but got '%s' instead.", value), "invalid_request_error", "limit", "invalid_type")
+			return params, &errResp
+		}
+		if limit < 1 {
+			errResp := api.NewErrorResponse(fmt.Sprintf("Invalid 'limit': integer below minimum value. Expected a value >= 1, but got %d instead.", limit), "invalid_request_error", "limit", "integer_below_min_value")
+			return params, &errResp
+		}
+		if limit > api.MaxListLimit {
+			errResp := api.NewErrorResponse(fmt.Sprintf("Invalid 'limit': integer above maximum value. Expected a value <= %d, but got %d instead.", api.MaxListLimit, limit), "invalid_request_error", "limit", "integer_above_max_value")
+			return params, &errResp
+		}
+		params.Limit = limit
+	}
+
+	if params.Order != api.OrderAsc && params.Order != api.OrderDesc {
+		return params, invalidValue("order", params.Order, "'asc' and 'desc'")
+	}
+	return params, nil
+}
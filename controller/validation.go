@@ -0,0 +1,189 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+
+	"RobinPenn974/OpenAI-mocker/api"
+	"RobinPenn974/OpenAI-mocker/middleware"
+	"RobinPenn974/OpenAI-mocker/models"
+	"RobinPenn974/OpenAI-mocker/responses"
+	"RobinPenn974/OpenAI-mocker/tokenizer"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 接口对应的URL路径，用于错误信息
+var endpointPaths = map[string]string{
+	models.EndpointChatCompletions: "v1/chat/completions",
+	models.EndpointCompletions:     "v1/completions",
+	models.EndpointEmbeddings:      "v1/embeddings",
+	models.EndpointRerank:          "v1/rerank",
+	models.EndpointScore:           "v1/score",
+}
+
+// thinking 参数的取值
+const (
+	thinkingEnabled  = "enabled"
+	thinkingDisabled = "disabled"
+)
+
+// lookupModelForEndpoint 按别名和匹配模式解析模型，检查当前API密钥的模型白名单以及模型是否支持指定接口
+// 返回模型信息和响应中回显的模型名，失败时返回HTTP状态码和错误响应
+func lookupModelForEndpoint(c *gin.Context, modelID, endpoint string) (models.ModelInfo, string, int, *api.ErrorResponse) {
+	ws := currentWorkspace(c)
+	resolution, err := ws.LookupModel(modelID)
+	// 自动注册前按请求的模型名检查白名单，不为无权访问的密钥注册模型
+	if err != nil && modelAccessDenied(c, modelID, modelID) == nil {
+		resolution, err = ws.ResolveModel(modelID, endpoint)
+	}
+	if err != nil {
+		errResp := api.NewErrorResponse(fmt.Sprintf("The model `%s` does not exist or you do not have access to it.", modelID), "invalid_request_error", "model", "model_not_found")
+		return models.ModelInfo{}, "", http.StatusNotFound, &errResp
+	}
+	if errResp := modelAccessDenied(c, modelID, resolution.Model.ID); errResp != nil {
+		return models.ModelInfo{}, "", http.StatusForbidden, errResp
+	}
+	model := resolution.Model
+
+	if !model.SupportsEndpoint(endpoint) {
+		message := fmt.Sprintf("This model is not supported in the %s endpoint.", endpointPaths[endpoint])
+		switch {
+		case endpoint == models.EndpointChatCompletions && model.SupportsEndpoint(models.EndpointCompletions):
+			message = "This is not a chat model and thus not supported in the v1/chat/completions endpoint. Did you mean to use v1/completions?"
+		case endpoint == models.EndpointCompletions && model.SupportsEndpoint(models.EndpointChatCompletions):
+			message = "This is a chat model and not supported in the v1/completions endpoint. Did you mean to use v1/chat/completions?"
+		}
+		errResp := api.NewErrorResponse(message, "invalid_request_error", "model", "")
+		return models.ModelInfo{}, "", http.StatusNotFound, &errResp
+	}
+
+	return model, resolution.Name, http.StatusOK, nil
+}
+
+// modelAccessDenied 按解析后的模型ID检查当前API密钥的模型白名单，name 为请求中的模型名，允许访问时返回nil
+func modelAccessDenied(c *gin.Context, name, modelID string) *api.ErrorResponse {
+	if key, ok := middleware.CurrentKey(c); ok && !key.AllowsModel(modelID) {
+		errResp := api.NewErrorResponse(fmt.Sprintf("This API key does not have access to model `%s`.", name), "invalid_request_error", "model", "model_not_found")
+		return &errResp
+	}
+	return nil
+}
+
+// validateChatRequest 按模型能力校验Chat请求
+func validateChatRequest(model models.ModelInfo, req api.ChatCompletionRequest) *api.ErrorResponse {
+	if len(req.Tools) > 0 && !model.SupportsTools {
+		return unsupportedParameter("tools")
+	}
+	if req.ResponseFormat != nil && req.ResponseFormat.Type == "json_schema" && !model.SupportsJSONSchema {
+		errResp := api.NewErrorResponse("Invalid parameter: 'response_format' of type 'json_schema' is not supported with this model.", "invalid_request_error", "response_format", "unsupported_parameter")
+		return &errResp
+	}
+	if req.ReasoningEffort != "" && !model.SupportsReasoning {
+		return unsupportedParameter("reasoning_effort")
+	}
+	switch req.ReasoningEffort {
+	case "", responses.ReasoningEffortLow, responses.ReasoningEffortMedium, responses.ReasoningEffortHigh:
+	default:
+		return invalidValue("reasoning_effort", req.ReasoningEffort, "'low', 'medium', and 'high'")
+	}
+	if req.Thinking != nil {
+		if req.Thinking.Type != thinkingEnabled && req.Thinking.Type != thinkingDisabled {
+			return invalidValue("thinking.type", req.Thinking.Type, "'enabled' and 'disabled'")
+		}
+		if req.Thinking.BudgetTokens < 0 {
+			errResp := api.NewErrorResponse(fmt.Sprintf("Invalid 'thinking.budget_tokens': integer below minimum value. Expected a value >= 0, but got %d instead.", req.Thinking.BudgetTokens), "invalid_request_error", "thinking.budget_tokens", "integer_below_min_value")
+			return &errResp
+		}
+	}
+
+	contents := make([]string, 0, len(req.Messages))
+	for _, message := range req.Messages {
+		for _, partType := range message.PartTypes {
+			if errResp := checkModality(model, partType); errResp != nil {
+				return errResp
+			}
+		}
+		contents = append(contents, message.Content)
+	}
+
+	maxTokens := req.MaxTokens
+	param := "max_tokens"
+	if req.MaxCompletionTokens > 0 {
+		maxTokens = req.MaxCompletionTokens
+		param = "max_completion_tokens"
+	}
+	if errResp := checkMaxTokens(model, maxTokens, param); errResp != nil {
+		return errResp
+	}
+
+	promptTokens := tokenizer.CountMessagesTokens(contents)
+	if promptTokens+maxTokens > model.ContextWindow {
+		var message string
+		if maxTokens > 0 {
+			message = fmt.Sprintf("This model's maximum context length is %d tokens. However, you requested %d tokens (%d in the messages, %d in the completion). Please reduce the length of the messages or completion.", model.ContextWindow, promptTokens+maxTokens, promptTokens, maxTokens)
+		} else {
+			message = fmt.Sprintf("This model's maximum context length is %d tokens. However, your messages resulted in %d tokens. Please reduce the length of the messages.", model.ContextWindow, promptTokens)
+		}
+		errResp := api.NewErrorResponse(message, "invalid_request_error", "messages", "context_length_exceeded")
+		return &errResp
+	}
+
+	return nil
+}
+
+// validateCompletionRequest 按模型能力校验文本补全请求
+func validateCompletionRequest(model models.ModelInfo, req api.CompletionRequest) *api.ErrorResponse {
+	if errResp := checkMaxTokens(model, req.MaxTokens, "max_tokens"); errResp != nil {
+		return errResp
+	}
+
+	promptTokens := tokenizer.CountTokens(req.Prompt)
+	if promptTokens+req.MaxTokens > model.ContextWindow {
+		message := fmt.Sprintf("This model's maximum context length is %d tokens, however you requested %d tokens (%d in your prompt; %d for the completion). Please reduce your prompt; or completion length.", model.ContextWindow, promptTokens+req.MaxTokens, promptTokens, req.MaxTokens)
+		errResp := api.NewErrorResponse(message, "invalid_request_error", "prompt", "context_length_exceeded")
+		return &errResp
+	}
+
+	return nil
+}
+
+// checkMaxTokens 检查请求的最大生成token数是否超过模型上限
+func checkMaxTokens(model models.ModelInfo, maxTokens int, param string) *api.ErrorResponse {
+	if model.MaxOutputTokens > 0 && maxTokens > model.MaxOutputTokens {
+		errResp := api.NewErrorResponse(fmt.Sprintf("%s is too large: %d. This model supports at most %d completion tokens, whereas you provided %d.", param, maxTokens, model.MaxOutputTokens, maxTokens), "invalid_request_error", param, "invalid_value")
+		return &errResp
+	}
+	return nil
+}
+
+// checkModality 检查消息中的内容片段类型是否为模型支持的模态
+func checkModality(model models.ModelInfo, partType string) *api.ErrorResponse {
+	modality := ""
+	switch partType {
+	case "image_url", "input_image":
+		modality = models.ModalityImage
+	case "input_audio":
+		modality = models.ModalityAudio
+	default:
+		return nil
+	}
+
+	if !model.SupportsModality(modality) {
+		errResp := api.NewErrorResponse(fmt.Sprintf("Invalid content type. %s is only supported by certain models.", partType), "invalid_request_error", "messages", "unsupported_parameter")
+		return &errResp
+	}
+	return nil
+}
+
+// invalidValue 构造参数取值不合法的错误，supported 为支持的取值列表
+func invalidValue(param, value, supported string) *api.ErrorResponse {
+	errResp := api.NewErrorResponse(fmt.Sprintf("Invalid value: '%s'. Supported values are: %s.", value, supported), "invalid_request_error", param, "invalid_value")
+	return &errResp
+}
+
+// unsupportedParameter 构造参数不被模型支持的错误
+func unsupportedParameter(param string) *api.ErrorResponse {
+	errResp := api.NewErrorResponse(fmt.Sprintf("Unsupported parameter: '%s' is not supported with this model.", param), "invalid_request_error", param, "unsupported_parameter")
+	return &errResp
+}
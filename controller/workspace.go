@@ -0,0 +1,28 @@
+package controller
+
+import (
+	"RobinPenn974/OpenAI-mocker/instance"
+	"RobinPenn974/OpenAI-mocker/middleware"
+	"RobinPenn974/OpenAI-mocker/provider"
+	"RobinPenn974/OpenAI-mocker/workspace"
+
+	"github.com/gin-gonic/gin"
+)
+
+// currentInstance 返回当前请求所属的服务实例
+func currentInstance(c *gin.Context) *instance.Instance {
+	return middleware.CurrentInstance(c)
+}
+
+// currentWorkspace 返回当前请求所在的工作区，未选择工作区时使用默认工作区
+func currentWorkspace(c *gin.Context) *workspace.Workspace {
+	if ws, ok := middleware.CurrentWorkspace(c); ok {
+		return ws
+	}
+	return currentInstance(c).DefaultWorkspace()
+}
+
+// currentProviderProfile 返回当前请求使用的服务商兼容配置
+func currentProviderProfile(c *gin.Context) provider.Profile {
+	return middleware.CurrentProviderProfile(c)
+}
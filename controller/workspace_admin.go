@@ -0,0 +1,135 @@
+package controller
+
+import (
+	"net/http"
+
+	"RobinPenn974/OpenAI-mocker/api"
+	"RobinPenn974/OpenAI-mocker/workspace"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HandleListWorkspaces 处理列出所有工作区的请求
+func HandleListWorkspaces(c *gin.Context) {
+	workspaces := currentInstance(c).Workspaces.List()
+	result := make([]workspace.Info, 0, len(workspaces))
+	for _, ws := range workspaces {
+		result = append(result, ws.Info())
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"workspaces": result,
+		"count":      len(result),
+	})
+}
+
+// HandleCreateWorkspace 处理创建工作区的请求，新工作区复制基础工作区的状态
+func HandleCreateWorkspace(c *gin.Context) {
+	var req api.CreateWorkspaceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": gin.H{
+				"message": "Invalid request: " + err.Error(),
+				"type":    "invalid_request_error",
+			},
+		})
+		return
+	}
+
+	ws, err := currentInstance(c).Workspaces.Create(req.ID, req.Base)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": gin.H{
+				"message": err.Error(),
+				"type":    "invalid_request_error",
+			},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":   true,
+		"message":   "Workspace created successfully",
+		"workspace": ws.Info(),
+	})
+}
+
+// HandleGetWorkspace 处理获取工作区详情和用量的请求
+func HandleGetWorkspace(c *gin.Context) {
+	ws, ok := findWorkspace(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, ws.Info())
+}
+
+// HandleDeleteWorkspace 处理删除工作区的请求
+func HandleDeleteWorkspace(c *gin.Context) {
+	if err := currentInstance(c).DeleteWorkspace(c.Param("workspace_id")); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": gin.H{
+				"message": err.Error(),
+				"type":    "invalid_request_error",
+			},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Workspace deleted successfully",
+	})
+}
+
+// HandleGetWorkspaceJournal 处理查看工作区请求日志的请求
+func HandleGetWorkspaceJournal(c *gin.Context) {
+	ws, ok := findWorkspace(c)
+	if !ok {
+		return
+	}
+	entries := ws.Journal.List()
+	c.JSON(http.StatusOK, gin.H{
+		"entries": entries,
+		"count":   len(entries),
+	})
+}
+
+// HandleResetWorkspaceJournal 处理清空工作区请求日志和用量计数的请求
+func HandleResetWorkspaceJournal(c *gin.Context) {
+	ws, ok := findWorkspace(c)
+	if !ok {
+		return
+	}
+	ws.Journal.Clear()
+	ws.Usage.Reset()
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Workspace journal cleared successfully",
+	})
+}
+
+// findWorkspace 查找URL中指定的工作区，不存在时返回404错误
+func findWorkspace(c *gin.Context) (*workspace.Workspace, bool) {
+	id := c.Param("workspace_id")
+	ws, err := currentInstance(c).Workspaces.Get(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": gin.H{
+				"message": "Workspace '" + id + "' not found",
+				"type":    "invalid_request_error",
+			},
+		})
+		return nil, false
+	}
+	return ws, true
+}
+
+// SnapshotWorkspaces 返回工作区列表的快照，用于审计
+func SnapshotWorkspaces(c *gin.Context) interface{} {
+	workspaces := currentInstance(c).Workspaces.List()
+	ids := make([]string, 0, len(workspaces))
+	for _, ws := range workspaces {
+		ids = append(ids, ws.ID)
+	}
+	return ids
+}
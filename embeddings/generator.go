@@ -0,0 +1,188 @@
+package embeddings
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// 向量生成算法
+const (
+	// AlgorithmHash 基于字符n-gram和词特征哈希投影，语义相近（字面相近）的文本余弦相似度高
+	AlgorithmHash = "hash"
+	// AlgorithmRandom 以文本哈希为种子的伪随机向量，确定但不保留相似度
+	AlgorithmRandom = "random"
+)
+
+// DefaultAlgorithm 模型未指定算法时使用的默认算法
+const DefaultAlgorithm = AlgorithmHash
+
+// 特征权重
+const (
+	wordFeatureWeight    = 1.0
+	bigramFeatureWeight  = 0.5
+	trigramFeatureWeight = 0.35
+)
+
+// IsSupportedAlgorithm 检查算法名称是否受支持
+func IsSupportedAlgorithm(algorithm string) bool {
+	switch algorithm {
+	case AlgorithmHash, AlgorithmRandom:
+		return true
+	}
+	return false
+}
+
+// Generate 使用指定算法为文本生成L2归一化的向量，结果只取决于输入文本，重启后保持不变
+func Generate(algorithm, text string, dimensions int) ([]float64, error) {
+	switch algorithm {
+	case "", AlgorithmHash:
+		return project(textFeatures(text), dimensions), nil
+	case AlgorithmRandom:
+		return project(map[string]float64{"random:" + text: 1}, dimensions), nil
+	}
+	return nil, fmt.Errorf("unsupported embedding algorithm: %s", algorithm)
+}
+
+// GenerateFromTokens 为token ID数组生成向量，以token及相邻token对作为特征
+func GenerateFromTokens(algorithm string, tokens []int, dimensions int) ([]float64, error) {
+	ids := make([]string, len(tokens))
+	for i, token := range tokens {
+		ids[i] = strconv.Itoa(token)
+	}
+
+	switch algorithm {
+	case "", AlgorithmHash:
+		features := make(map[string]float64)
+		for i, id := range ids {
+			features["t:"+id] += wordFeatureWeight
+			if i > 0 {
+				features["tb:"+ids[i-1]+" "+id] += bigramFeatureWeight
+			}
+		}
+		return project(features, dimensions), nil
+	case AlgorithmRandom:
+		return project(map[string]float64{"random-tokens:" + strings.Join(ids, ","): 1}, dimensions), nil
+	}
+	return nil, fmt.Errorf("unsupported embedding algorithm: %s", algorithm)
+}
+
+// Truncate 按OpenAI缩减维度的方式截断向量并重新归一化
+func Truncate(embedding []float64, dimensions int) []float64 {
+	if dimensions <= 0 || dimensions >= len(embedding) {
+		return embedding
+	}
+	result := make([]float64, dimensions)
+	copy(result, embedding[:dimensions])
+	normalize(result)
+	return result
+}
+
+// textFeatures 提取文本的词、词二元组和字符三元组特征
+func textFeatures(text string) map[string]float64 {
+	features := make(map[string]float64)
+	words := splitWords(strings.ToLower(text))
+
+	for i, word := range words {
+		features["w:"+word] += wordFeatureWeight
+		if i > 0 {
+			features["b:"+words[i-1]+" "+word] += bigramFeatureWeight
+		}
+	}
+
+	// 字符三元组让拼写相近或共享词根的文本也能相似
+	runes := []rune(" " + strings.Join(words, " ") + " ")
+	for i := 0; i+3 <= len(runes); i++ {
+		features["c:"+string(runes[i:i+3])] += trigramFeatureWeight
+	}
+
+	return features
+}
+
+// splitWords 将文本切分为词，中日韩文字按单字切分
+func splitWords(text string) []string {
+	var words []string
+	var current []rune
+	flush := func() {
+		if len(current) > 0 {
+			words = append(words, string(current))
+			current = current[:0]
+		}
+	}
+
+	for _, r := range text {
+		switch {
+		case unicode.Is(unicode.Han, r) || unicode.Is(unicode.Hiragana, r) ||
+			unicode.Is(unicode.Katakana, r) || unicode.Is(unicode.Hangul, r):
+			flush()
+			words = append(words, string(r))
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			current = append(current, r)
+		default:
+			flush()
+		}
+	}
+	flush()
+
+	return words
+}
+
+// project 将加权特征随机投影到指定维度并归一化
+// 每个特征由其哈希确定一个稠密的伪随机方向，向量为各方向的加权和
+func project(features map[string]float64, dimensions int) []float64 {
+	embedding := make([]float64, dimensions)
+
+	// 按特征名排序，保证浮点累加顺序稳定
+	names := make([]string, 0, len(features))
+	for name := range features {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		weight := features[name]
+		state := hashString(name)
+		for i := range embedding {
+			embedding[i] += weight * nextUniform(&state)
+		}
+	}
+
+	normalize(embedding)
+	return embedding
+}
+
+// normalize 将向量L2归一化为单位向量
+func normalize(embedding []float64) {
+	var sum float64
+	for _, value := range embedding {
+		sum += value * value
+	}
+	if sum == 0 {
+		return
+	}
+	norm := math.Sqrt(sum)
+	for i := range embedding {
+		embedding[i] /= norm
+	}
+}
+
+// hashString 计算字符串的64位FNV-1a哈希
+func hashString(s string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(s))
+	return h.Sum64()
+}
+
+// nextUniform 使用splitmix64生成[-1, 1)区间的伪随机数
+func nextUniform(state *uint64) float64 {
+	*state += 0x9E3779B97F4A7C15
+	z := *state
+	z = (z ^ (z >> 30)) * 0xBF58476D1CE4E5B9
+	z = (z ^ (z >> 27)) * 0x94D049BB133111EB
+	z ^= z >> 31
+	return float64(z>>11)/float64(1<<52) - 1
+}
@@ -0,0 +1,132 @@
+package embeddings
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+// norm 返回向量的L2范数
+func norm(v []float64) float64 {
+	sum := 0.0
+	for _, x := range v {
+		sum += x * x
+	}
+	return math.Sqrt(sum)
+}
+
+// cosine 返回两个归一化向量的余弦相似度
+func cosine(a, b []float64) float64 {
+	sum := 0.0
+	for i := range a {
+		sum += a[i] * b[i]
+	}
+	return sum
+}
+
+// mustGenerate 生成向量，失败时终止测试
+func mustGenerate(t *testing.T, algorithm, text string, dimensions int) []float64 {
+	t.Helper()
+	embedding, err := Generate(algorithm, text, dimensions)
+	if err != nil {
+		t.Fatalf("generate %q: %v", text, err)
+	}
+	return embedding
+}
+
+func TestGenerateDeterministic(t *testing.T) {
+	for _, algorithm := range []string{AlgorithmHash, AlgorithmRandom} {
+		a := mustGenerate(t, algorithm, "The quick brown fox", 256)
+		b := mustGenerate(t, algorithm, "The quick brown fox", 256)
+		if !reflect.DeepEqual(a, b) {
+			t.Errorf("%s: same input produced different vectors", algorithm)
+		}
+		if c := mustGenerate(t, algorithm, "A different sentence", 256); reflect.DeepEqual(a, c) {
+			t.Errorf("%s: different inputs produced the same vector", algorithm)
+		}
+	}
+
+	a, _ := GenerateFromTokens(AlgorithmHash, []int{1, 2, 3}, 64)
+	b, _ := GenerateFromTokens(AlgorithmHash, []int{1, 2, 3}, 64)
+	if !reflect.DeepEqual(a, b) {
+		t.Error("same tokens produced different vectors")
+	}
+
+	if _, err := Generate("unknown", "text", 8); err == nil {
+		t.Error("expected an error for an unsupported algorithm")
+	}
+}
+
+func TestGenerateUnitNorm(t *testing.T) {
+	cases := []struct {
+		algorithm string
+		text      string
+	}{
+		{AlgorithmHash, "hello world"},
+		{AlgorithmHash, "你好，世界"},
+		{AlgorithmRandom, "hello world"},
+	}
+	for _, tc := range cases {
+		embedding := mustGenerate(t, tc.algorithm, tc.text, 1536)
+		if len(embedding) != 1536 {
+			t.Errorf("%s %q: %d dimensions, want 1536", tc.algorithm, tc.text, len(embedding))
+		}
+		if n := norm(embedding); math.Abs(n-1) > 1e-9 {
+			t.Errorf("%s %q: norm %v, want 1", tc.algorithm, tc.text, n)
+		}
+	}
+
+	// 空文本没有特征，得到零向量，接口层拒绝空输入
+	if n := norm(mustGenerate(t, AlgorithmHash, "", 16)); n != 0 {
+		t.Errorf("empty text: norm %v, want 0", n)
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	full := mustGenerate(t, AlgorithmHash, "truncate me", 1536)
+	truncated := Truncate(full, 256)
+	if len(truncated) != 256 {
+		t.Fatalf("%d dimensions, want 256", len(truncated))
+	}
+	if n := norm(truncated); math.Abs(n-1) > 1e-9 {
+		t.Fatalf("truncated norm %v, want 1", n)
+	}
+
+	// 截断后的方向与原向量的前缀一致
+	scale := norm(full[:256])
+	for i, x := range truncated {
+		if math.Abs(x-full[i]/scale) > 1e-12 {
+			t.Fatalf("dimension %d: %v, want %v", i, x, full[i]/scale)
+		}
+	}
+
+	// 维度不小于原向量时原样返回，不修改原向量
+	if got := Truncate(full, 0); len(got) != 1536 {
+		t.Errorf("dimensions 0: %d dimensions", len(got))
+	}
+	if got := Truncate(full, 2048); len(got) != 1536 {
+		t.Errorf("dimensions 2048: %d dimensions", len(got))
+	}
+	if math.Abs(norm(full)-1) > 1e-9 {
+		t.Error("Truncate modified the original vector")
+	}
+}
+
+func TestSimilarityOrdering(t *testing.T) {
+	query := mustGenerate(t, AlgorithmHash, "How do I reset my password?", 512)
+	close := mustGenerate(t, AlgorithmHash, "How can I reset my password", 512)
+	related := mustGenerate(t, AlgorithmHash, "Password requirements for new accounts", 512)
+	unrelated := mustGenerate(t, AlgorithmHash, "Recipe for chocolate cake with berries", 512)
+
+	if !(cosine(query, close) > cosine(query, related) && cosine(query, related) > cosine(query, unrelated)) {
+		t.Fatalf("similarities not ordered: close %.3f, related %.3f, unrelated %.3f",
+			cosine(query, close), cosine(query, related), cosine(query, unrelated))
+	}
+
+	// random 算法不保留相似度，近似文本的相似度接近0
+	a := mustGenerate(t, AlgorithmRandom, "How do I reset my password?", 512)
+	b := mustGenerate(t, AlgorithmRandom, "How can I reset my password", 512)
+	if sim := cosine(a, b); math.Abs(sim) > 0.3 {
+		t.Fatalf("random algorithm similarity %.3f, want close to 0", sim)
+	}
+}
@@ -0,0 +1,234 @@
+package embeddings
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"RobinPenn974/OpenAI-mocker/api"
+	"RobinPenn974/OpenAI-mocker/atomicfile"
+)
+
+const (
+	// 默认固定向量存储目录
+	defaultPinDir = "embedding_data"
+	// 默认固定向量文件名
+	defaultPinFile = "pins.json"
+)
+
+// Pin 为指定模型的指定输入固定返回的向量，输入为文本或token数组
+type Pin struct {
+	ID        string    `json:"id"`
+	ModelID   string    `json:"model_id" binding:"required"`
+	Input     string    `json:"input,omitempty"`
+	Tokens    []int     `json:"tokens,omitempty"`
+	Embedding []float64 `json:"embedding" binding:"required"`
+}
+
+// PinStore 保存固定向量，固定向量持久化到文件
+type PinStore struct {
+	pins       map[string]Pin // pinKey -> Pin
+	mu         sync.RWMutex
+	storageDir string
+	filename   string
+}
+
+// NewPinStore 创建一个新的固定向量存储
+func NewPinStore(storageDir, filename string) *PinStore {
+	if storageDir == "" {
+		storageDir = defaultPinDir
+	}
+	if filename == "" {
+		filename = defaultPinFile
+	}
+
+	store := &PinStore{
+		pins:       make(map[string]Pin),
+		storageDir: storageDir,
+		filename:   filename,
+	}
+
+	// 加载固定向量
+	if err := store.loadPins(); err != nil {
+		fmt.Printf("Error loading embedding pins: %v\n", err)
+	}
+
+	return store
+}
+
+// pinKey 生成模型和文本输入组合的键
+func pinKey(modelID, input string) string {
+	return modelID + "\x00" + input
+}
+
+// tokenPinKey 生成模型和token输入组合的键，与文本输入的键不会冲突
+func tokenPinKey(modelID string, tokens []int) string {
+	return modelID + "\x01" + fmt.Sprint(tokens)
+}
+
+// key 返回固定向量的输入对应的键
+func (p Pin) key() string {
+	if p.Tokens != nil {
+		return tokenPinKey(p.ModelID, p.Tokens)
+	}
+	return pinKey(p.ModelID, p.Input)
+}
+
+// AddPin 添加或替换一个固定向量，input 和 tokens 必须且只能设置一个，dimensions 为模型的向量维度
+func (s *PinStore) AddPin(pin Pin, dimensions int) (Pin, error) {
+	if (pin.Input == "") == (len(pin.Tokens) == 0) {
+		return Pin{}, errors.New("exactly one of input and tokens must be set")
+	}
+	if len(pin.Embedding) == 0 {
+		return Pin{}, errors.New("embedding must not be empty")
+	}
+	if len(pin.Embedding) != dimensions {
+		return Pin{}, fmt.Errorf("embedding has %d dimensions, but model %s has %d", len(pin.Embedding), pin.ModelID, dimensions)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := pin.key()
+	if existing, exists := s.pins[key]; exists {
+		pin.ID = existing.ID
+	} else if pin.ID == "" {
+		pin.ID = "pin-" + api.GenerateShortUUID()
+	}
+	pins := s.copyPins()
+	pins[key] = pin
+	if err := s.savePins(pins); err != nil {
+		return Pin{}, err
+	}
+	return pin, nil
+}
+
+// Lookup 查找指定模型和文本输入的固定向量
+func (s *PinStore) Lookup(modelID, input string) ([]float64, bool) {
+	return s.lookup(pinKey(modelID, input))
+}
+
+// LookupTokens 查找指定模型和token输入的固定向量
+func (s *PinStore) LookupTokens(modelID string, tokens []int) ([]float64, bool) {
+	return s.lookup(tokenPinKey(modelID, tokens))
+}
+
+// lookup 按键查找固定向量并返回其副本
+func (s *PinStore) lookup(key string) ([]float64, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	pin, exists := s.pins[key]
+	if !exists {
+		return nil, false
+	}
+	embedding := make([]float64, len(pin.Embedding))
+	copy(embedding, pin.Embedding)
+	return embedding, true
+}
+
+// ListPins 列出所有固定向量
+func (s *PinStore) ListPins() []Pin {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make([]Pin, 0, len(s.pins))
+	for _, pin := range s.pins {
+		result = append(result, pin)
+	}
+	return result
+}
+
+// DeletePin 删除指定ID的固定向量
+func (s *PinStore) DeletePin(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for key, pin := range s.pins {
+		if pin.ID == id {
+			pins := s.copyPins()
+			delete(pins, key)
+			return s.savePins(pins)
+		}
+	}
+	return errors.New("pin not found")
+}
+
+// DeletePinsForModel 删除指定模型的所有固定向量
+func (s *PinStore) DeletePinsForModel(modelID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	pins := s.copyPins()
+	for key, pin := range pins {
+		if pin.ModelID == modelID {
+			delete(pins, key)
+		}
+	}
+	return s.savePins(pins)
+}
+
+// RemoveAllPins 删除所有固定向量
+func (s *PinStore) RemoveAllPins() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.savePins(make(map[string]Pin))
+}
+
+// copyPins 复制当前固定向量，调用方需持有锁
+func (s *PinStore) copyPins() map[string]Pin {
+	pins := make(map[string]Pin, len(s.pins))
+	for key, pin := range s.pins {
+		pins[key] = pin
+	}
+	return pins
+}
+
+// savePins 将固定向量按ID顺序写入文件，成功后更新内存，调用方需持有写锁
+func (s *PinStore) savePins(pins map[string]Pin) error {
+	list := make([]Pin, 0, len(pins))
+	for _, pin := range pins {
+		list = append(list, pin)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].ID < list[j].ID
+	})
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding embedding pins: %v", err)
+	}
+
+	if err := os.MkdirAll(s.storageDir, 0755); err != nil {
+		return fmt.Errorf("error creating embedding pin directory: %v", err)
+	}
+	if err := atomicfile.WriteFile(filepath.Join(s.storageDir, s.filename), data, 0644); err != nil {
+		return fmt.Errorf("error writing embedding pin file: %v", err)
+	}
+
+	s.pins = pins
+	return nil
+}
+
+// loadPins 从文件加载固定向量，文件不存在时没有固定向量
+func (s *PinStore) loadPins() error {
+	data, err := os.ReadFile(filepath.Join(s.storageDir, s.filename))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error reading embedding pin file: %v", err)
+	}
+
+	var list []Pin
+	if err := json.Unmarshal(data, &list); err != nil {
+		return fmt.Errorf("error parsing embedding pin file: %v", err)
+	}
+	for _, pin := range list {
+		s.pins[pin.key()] = pin
+	}
+	return nil
+}
@@ -0,0 +1,184 @@
+package faults
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"RobinPenn974/OpenAI-mocker/api"
+)
+
+// Rule 故障注入规则，命中的请求会被延迟或直接返回错误
+type Rule struct {
+	ID          string  `json:"id"`
+	Endpoint    string  `json:"endpoint,omitempty"`    // 请求路径后缀，如 /chat/completions，为空表示全部接口
+	ModelID     string  `json:"model_id,omitempty"`    // 仅对指定模型生效，为空表示全部模型
+	CustomID    string  `json:"custom_id,omitempty"`   // 仅对批处理中指定 custom_id 的请求生效，为空表示全部请求
+	Status      int     `json:"status,omitempty"`      // 返回的HTTP状态码，为0时只注入延迟
+	ErrorType   string  `json:"error_type,omitempty"`  // 错误响应中的type字段
+	ErrorCode   string  `json:"error_code,omitempty"`  // 错误响应中的code字段
+	Message     string  `json:"message,omitempty"`     // 错误信息
+	DelayMs     int     `json:"delay_ms,omitempty"`    // 处理请求前的延迟
+	Probability float64 `json:"probability,omitempty"` // 命中概率，为0时总是命中
+	Count       int     `json:"count,omitempty"`       // 剩余触发次数，为0时不限次数
+}
+
+// Injector 保存故障注入规则
+type Injector struct {
+	rules []Rule
+	mu    sync.Mutex
+}
+
+// NewInjector 创建一个新的故障注入器
+func NewInjector() *Injector {
+	return &Injector{}
+}
+
+// AddRule 校验并添加一条规则
+func (i *Injector) AddRule(rule Rule) (Rule, error) {
+	if rule.Status == 0 && rule.DelayMs == 0 {
+		return Rule{}, errors.New("status or delay_ms is required")
+	}
+	if rule.Status != 0 && (rule.Status < 400 || rule.Status > 599) {
+		return Rule{}, fmt.Errorf("unsupported status: %d", rule.Status)
+	}
+	if rule.DelayMs < 0 || rule.Count < 0 {
+		return Rule{}, errors.New("delay_ms and count must not be negative")
+	}
+	if rule.Probability < 0 || rule.Probability > 1 {
+		return Rule{}, errors.New("probability must be between 0 and 1")
+	}
+
+	if rule.Status != 0 {
+		if rule.ErrorType == "" {
+			rule.ErrorType = defaultErrorType(rule.Status)
+		}
+		if rule.Message == "" {
+			rule.Message = http.StatusText(rule.Status)
+		}
+	}
+	if rule.ID == "" {
+		rule.ID = "fault-" + api.GenerateShortUUID()
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.rules = append(i.rules, rule)
+	return rule, nil
+}
+
+// ListRules 列出所有规则
+func (i *Injector) ListRules() []Rule {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	result := make([]Rule, len(i.rules))
+	copy(result, i.rules)
+	return result
+}
+
+// DeleteRule 删除指定ID的规则
+func (i *Injector) DeleteRule(id string) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	for index, rule := range i.rules {
+		if rule.ID == id {
+			i.rules = append(i.rules[:index], i.rules[index+1:]...)
+			return nil
+		}
+	}
+	return errors.New("fault not found")
+}
+
+// DeleteRulesForModel 删除只作用于指定模型的规则
+func (i *Injector) DeleteRulesForModel(modelID string) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	kept := i.rules[:0]
+	for _, rule := range i.rules {
+		if rule.ModelID != modelID {
+			kept = append(kept, rule)
+		}
+	}
+	i.rules = kept
+}
+
+// RemoveAllRules 删除所有规则
+func (i *Injector) RemoveAllRules() {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.rules = nil
+}
+
+// Match 返回第一条命中请求的规则，命中后扣减剩余次数，次数用完的规则会被删除
+func (i *Injector) Match(path, modelID string) (Rule, bool) {
+	return i.match(path, modelID, "", false)
+}
+
+// MatchBatchRequest 与 Match 相同，customID 为批处理请求的 custom_id，设置了 custom_id 的规则只命中对应的批处理请求
+// 批处理中的请求不注入延迟，只注入延迟的规则不参与匹配，也不扣减剩余次数
+func (i *Injector) MatchBatchRequest(path, modelID, customID string) (Rule, bool) {
+	return i.match(path, modelID, customID, true)
+}
+
+// match 返回第一条命中请求的规则，errorsOnly 为true时跳过只注入延迟的规则
+func (i *Injector) match(path, modelID, customID string, errorsOnly bool) (Rule, bool) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	for index, rule := range i.rules {
+		if errorsOnly && rule.Status == 0 {
+			continue
+		}
+		if rule.Endpoint != "" && !strings.HasSuffix(path, rule.Endpoint) {
+			continue
+		}
+		if rule.ModelID != "" && rule.ModelID != modelID {
+			continue
+		}
+		if rule.CustomID != "" && rule.CustomID != customID {
+			continue
+		}
+		if rule.Probability > 0 && rand.Float64() >= rule.Probability {
+			continue
+		}
+
+		if rule.Count > 0 {
+			i.rules[index].Count--
+			if i.rules[index].Count == 0 {
+				i.rules = append(i.rules[:index], i.rules[index+1:]...)
+			}
+		}
+		return rule, true
+	}
+	return Rule{}, false
+}
+
+// Delay 返回规则注入的延迟
+func (r Rule) Delay() time.Duration {
+	return time.Duration(r.DelayMs) * time.Millisecond
+}
+
+// defaultErrorType 返回状态码对应的OpenAI错误类型
+func defaultErrorType(status int) string {
+	switch status {
+	case http.StatusUnauthorized:
+		return "authentication_error"
+	case http.StatusForbidden:
+		return "permission_error"
+	case http.StatusNotFound:
+		return "not_found_error"
+	case http.StatusTooManyRequests:
+		return "rate_limit_error"
+	}
+	if status >= 500 {
+		return "server_error"
+	}
+	return "invalid_request_error"
+}
@@ -0,0 +1,190 @@
+package files
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"RobinPenn974/OpenAI-mocker/api"
+	"RobinPenn974/OpenAI-mocker/atomicfile"
+
+	"github.com/google/uuid"
+)
+
+// 文件索引的文件名，文件内容以文件ID为文件名保存在同一目录
+const indexFile = "files.json"
+
+// File OpenAI格式的文件对象
+type File struct {
+	ID            string  `json:"id"`
+	Object        string  `json:"object"`
+	Bytes         int64   `json:"bytes"`
+	CreatedAt     int64   `json:"created_at"`
+	Filename      string  `json:"filename"`
+	Purpose       string  `json:"purpose"`
+	Status        string  `json:"status"`
+	StatusDetails *string `json:"status_details"`
+}
+
+// NotFoundError 请求的文件不存在
+type NotFoundError struct {
+	ID string
+}
+
+// Error 返回与OpenAI一致的错误信息
+func (e *NotFoundError) Error() string {
+	return fmt.Sprintf("No such File object: %s", e.ID)
+}
+
+// Store 将一个工作区上传和生成的文件保存在本地目录中，重启后仍然保留
+type Store struct {
+	files []File // 按创建顺序排列
+	dir   string
+	mu    sync.RWMutex
+}
+
+// NewStore 创建文件存储并加载目录中已保存的文件
+func NewStore(dir string) *Store {
+	store := &Store{dir: dir}
+	if err := store.load(); err != nil {
+		fmt.Printf("Error loading files: %v\n", err)
+	}
+	return store
+}
+
+// Create 保存文件内容并分配ID，purpose不做校验，用于上传的文件和批处理等生成的结果文件
+func (s *Store) Create(filename, purpose string, data []byte) (File, error) {
+	file := File{
+		ID:        "file-" + strings.ReplaceAll(uuid.NewString(), "-", "")[:24],
+		Object:    "file",
+		Bytes:     int64(len(data)),
+		CreatedAt: time.Now().Unix(),
+		Filename:  filename,
+		Purpose:   purpose,
+		Status:    "processed",
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return File{}, fmt.Errorf("error creating file directory: %v", err)
+	}
+	if err := atomicfile.WriteFile(s.path(file.ID), data, 0644); err != nil {
+		return File{}, fmt.Errorf("error writing file: %v", err)
+	}
+
+	files := append(append([]File{}, s.files...), file)
+	if err := s.writeIndex(files); err != nil {
+		os.Remove(s.path(file.ID))
+		return File{}, err
+	}
+	s.files = files
+	return file, nil
+}
+
+// Get 获取文件对象
+func (s *Store) Get(id string) (File, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	index := s.indexOf(id)
+	if index < 0 {
+		return File{}, &NotFoundError{ID: id}
+	}
+	return s.files[index], nil
+}
+
+// Content 获取文件对象和文件内容
+func (s *Store) Content(id string) (File, []byte, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	index := s.indexOf(id)
+	if index < 0 {
+		return File{}, nil, &NotFoundError{ID: id}
+	}
+	data, err := os.ReadFile(s.path(id))
+	if err != nil {
+		return File{}, nil, fmt.Errorf("error reading file: %v", err)
+	}
+	return s.files[index], data, nil
+}
+
+// List 分页列出文件，purpose不为空时只列出该用途的文件
+func (s *Store) List(purpose string, params api.ListParams) ([]File, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	files := make([]File, 0, len(s.files))
+	for _, file := range s.files {
+		if purpose == "" || file.Purpose == purpose {
+			files = append(files, file)
+		}
+	}
+	return api.Paginate(files, func(file File) string { return file.ID }, params)
+}
+
+// Delete 删除文件对象和文件内容
+func (s *Store) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	index := s.indexOf(id)
+	if index < 0 {
+		return &NotFoundError{ID: id}
+	}
+
+	files := append(append([]File{}, s.files[:index]...), s.files[index+1:]...)
+	if err := s.writeIndex(files); err != nil {
+		return err
+	}
+	s.files = files
+	if err := os.Remove(s.path(id)); err != nil && !os.IsNotExist(err) {
+		fmt.Printf("Error removing file %s: %v\n", id, err)
+	}
+	return nil
+}
+
+// indexOf 返回文件在列表中的位置，不存在时返回-1
+func (s *Store) indexOf(id string) int {
+	for i, file := range s.files {
+		if file.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// path 返回文件内容的保存路径，文件ID只包含字母、数字和连字符
+func (s *Store) path(id string) string {
+	return filepath.Join(s.dir, id)
+}
+
+// load 从索引文件加载文件列表
+func (s *Store) load() error {
+	data, err := os.ReadFile(filepath.Join(s.dir, indexFile))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, &s.files)
+}
+
+// writeIndex 将文件列表写入索引文件
+func (s *Store) writeIndex(files []File) error {
+	data, err := json.MarshalIndent(files, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := atomicfile.WriteFile(filepath.Join(s.dir, indexFile), data, 0644); err != nil {
+		return fmt.Errorf("error writing file index: %v", err)
+	}
+	return nil
+}
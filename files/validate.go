@@ -0,0 +1,220 @@
+package files
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// 文件用途，结果文件的用途由批处理和微调任务生成，不能上传
+const (
+	PurposeAssistants       = "assistants"
+	PurposeAssistantsOutput = "assistants_output"
+	PurposeBatch            = "batch"
+	PurposeBatchOutput      = "batch_output"
+	PurposeFineTune         = "fine-tune"
+	PurposeFineTuneResults  = "fine-tune-results"
+	PurposeVision           = "vision"
+	PurposeUserData         = "user_data"
+	PurposeEvals            = "evals"
+)
+
+// UploadPurposes 可以上传的文件用途
+var UploadPurposes = []string{PurposeFineTune, PurposeAssistants, PurposeBatch, PurposeUserData, PurposeVision, PurposeEvals}
+
+// BatchURLs 批处理输入文件支持的请求路径
+var BatchURLs = []string{"/v1/chat/completions", "/v1/embeddings", "/v1/completions"}
+
+// 批处理输入文件的最大请求数
+const MaxBatchRequests = 50000
+
+// IsUploadPurpose 检查是否为可以上传的文件用途
+func IsUploadPurpose(purpose string) bool {
+	for _, supported := range UploadPurposes {
+		if purpose == supported {
+			return true
+		}
+	}
+	return false
+}
+
+// LineError JSONL文件内容的校验错误，Line为出错的行号，为0时表示整个文件
+type LineError struct {
+	Line    int
+	Code    string
+	Message string
+}
+
+// Error 返回带行号的错误信息
+func (e *LineError) Error() string {
+	if e.Line == 0 {
+		return e.Message
+	}
+	return fmt.Sprintf("Line %d: %s", e.Line, e.Message)
+}
+
+// lineError 构造校验错误
+func lineError(line int, code, format string, args ...interface{}) *LineError {
+	return &LineError{Line: line, Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// Line JSONL文件中的一行，Number从1开始
+type Line struct {
+	Number int
+	Data   []byte
+}
+
+// Lines 将JSONL内容拆分为非空行
+func Lines(data []byte) []Line {
+	var lines []Line
+	for i, line := range bytes.Split(data, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		if len(line) > 0 {
+			lines = append(lines, Line{Number: i + 1, Data: line})
+		}
+	}
+	return lines
+}
+
+// BatchRequest 批处理输入文件中的一个请求
+type BatchRequest struct {
+	CustomID string          `json:"custom_id"`
+	Method   string          `json:"method"`
+	URL      string          `json:"url"`
+	Body     json.RawMessage `json:"body"`
+	Line     int             `json:"-"` // 所在的行号
+}
+
+// ParseBatchRequests 解析并校验批处理输入文件，所有请求必须使用相同的路径且 custom_id 不能重复，校验失败时返回 *LineError
+func ParseBatchRequests(data []byte) ([]BatchRequest, error) {
+	lines := Lines(data)
+	if len(lines) == 0 {
+		return nil, lineError(0, "empty_file", "The file is empty.")
+	}
+	if len(lines) > MaxBatchRequests {
+		return nil, lineError(0, "too_many_requests", "The file contains %d requests, but the maximum is %d.", len(lines), MaxBatchRequests)
+	}
+
+	requests := make([]BatchRequest, 0, len(lines))
+	customIDs := make(map[string]bool, len(lines))
+	for _, line := range lines {
+		var req BatchRequest
+		if err := json.Unmarshal(line.Data, &req); err != nil {
+			return nil, lineError(line.Number, "invalid_json_line", "This line is not a valid JSON object.")
+		}
+		switch {
+		case req.CustomID == "":
+			return nil, lineError(line.Number, "missing_required_parameter", "Missing required parameter: 'custom_id'.")
+		case customIDs[req.CustomID]:
+			return nil, lineError(line.Number, "duplicate_custom_id", "The custom_id '%s' is not unique. The custom_id for each request must be unique.", req.CustomID)
+		case req.Method != "POST":
+			return nil, lineError(line.Number, "invalid_method", "Invalid method '%s'. Only 'POST' is supported.", req.Method)
+		case !isBatchURL(req.URL):
+			return nil, lineError(line.Number, "invalid_url", "Invalid url '%s'. Supported urls are %s.", req.URL, strings.Join(BatchURLs, ", "))
+		case len(requests) > 0 && req.URL != requests[0].URL:
+			return nil, lineError(line.Number, "mismatched_url", "The url '%s' does not match '%s'. All requests in a batch must use the same url.", req.URL, requests[0].URL)
+		case !isJSONObject(req.Body):
+			return nil, lineError(line.Number, "invalid_body", "Missing required parameter: 'body', or it is not a JSON object.")
+		}
+		req.Line = line.Number
+		customIDs[req.CustomID] = true
+		requests = append(requests, req)
+	}
+	return requests, nil
+}
+
+// FineTuneExample 微调训练文件中的一个样本，支持对话格式和 prompt/completion 格式
+type FineTuneExample struct {
+	Messages []struct {
+		Role    string          `json:"role"`
+		Content json.RawMessage `json:"content"`
+	} `json:"messages"`
+	Prompt     *string `json:"prompt"`
+	Completion *string `json:"completion"`
+}
+
+// ParseFineTuneExamples 解析并校验微调训练文件，每个对话样本至少需要一条助手消息，校验失败时返回 *LineError
+func ParseFineTuneExamples(data []byte) ([]FineTuneExample, error) {
+	lines := Lines(data)
+	if len(lines) == 0 {
+		return nil, lineError(0, "empty_file", "The file is empty.")
+	}
+
+	examples := make([]FineTuneExample, 0, len(lines))
+	for _, line := range lines {
+		var example FineTuneExample
+		if err := json.Unmarshal(line.Data, &example); err != nil {
+			return nil, lineError(line.Number, "invalid_json_line", "This line is not a valid JSON object.")
+		}
+		if example.Messages == nil {
+			if example.Prompt == nil || example.Completion == nil {
+				return nil, lineError(line.Number, "missing_required_parameter", "Missing required parameter: 'messages'.")
+			}
+			examples = append(examples, example)
+			continue
+		}
+
+		hasAssistant := false
+		for i, message := range example.Messages {
+			switch message.Role {
+			case "system", "developer", "user", "tool":
+			case "assistant":
+				hasAssistant = true
+			default:
+				return nil, lineError(line.Number, "invalid_role", "Invalid role '%s' in messages[%d].", message.Role, i)
+			}
+		}
+		if !hasAssistant {
+			return nil, lineError(line.Number, "missing_assistant_message", "No messages with role 'assistant'. Each example must contain at least one assistant message.")
+		}
+		examples = append(examples, example)
+	}
+	return examples, nil
+}
+
+// Validate 校验上传文件的内容，batch 和 fine-tune 用途的文件必须是符合对应格式的JSONL文件
+func Validate(purpose, filename string, data []byte) error {
+	var name string
+	var parse func([]byte) error
+	switch purpose {
+	case PurposeBatch:
+		name = "Batch API"
+		parse = func(data []byte) error {
+			_, err := ParseBatchRequests(data)
+			return err
+		}
+	case PurposeFineTune:
+		name = "Fine-Tuning API"
+		parse = func(data []byte) error {
+			_, err := ParseFineTuneExamples(data)
+			return err
+		}
+	default:
+		return nil
+	}
+
+	if !strings.HasSuffix(filename, ".jsonl") {
+		return fmt.Errorf("Invalid file format for %s. Must be .jsonl", name)
+	}
+	if err := parse(data); err != nil {
+		return fmt.Errorf("Invalid file format for %s. %v", name, err)
+	}
+	return nil
+}
+
+// isBatchURL 检查是否为批处理支持的请求路径
+func isBatchURL(url string) bool {
+	for _, supported := range BatchURLs {
+		if url == supported {
+			return true
+		}
+	}
+	return false
+}
+
+// isJSONObject 检查是否为JSON对象
+func isJSONObject(raw json.RawMessage) bool {
+	var object map[string]json.RawMessage
+	return json.Unmarshal(raw, &object) == nil && object != nil
+}
@@ -0,0 +1,343 @@
+package finetuning
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"RobinPenn974/OpenAI-mocker/api"
+	"RobinPenn974/OpenAI-mocker/files"
+
+	"github.com/google/uuid"
+)
+
+// Store 在内存中保存一个工作区的微调任务，结果文件写入工作区的文件存储，任务成功时通过register注册微调模型
+type Store struct {
+	jobs     []*jobState // 按创建顺序排列
+	files    *files.Store
+	register func(Job) error
+	mu       sync.Mutex
+}
+
+// jobState 微调任务及其事件和检查点，均按创建顺序排列，状态和训练进度按经过的时间推进
+type jobState struct {
+	Job
+	events        []Event
+	checkpoints   []Checkpoint
+	plan          Plan
+	modelName     string // 成功后的微调模型名，检查点名以此为前缀
+	start         time.Time
+	duration      time.Duration
+	stepsPerEpoch int
+	totalSteps    int
+	step          int // 已经训练的步数
+}
+
+// NewStore 创建一个新的存储，结果文件写入fileStore，register 注册成功的任务生成的模型
+func NewStore(fileStore *files.Store, register func(Job) error) *Store {
+	return &Store{files: fileStore, register: register}
+}
+
+// newID 生成带前缀的对象ID
+func newID(prefix string, length int) string {
+	return prefix + strings.ReplaceAll(uuid.NewString(), "-", "")[:length]
+}
+
+// unix 返回时间的Unix时间戳指针
+func unix(t time.Time) *int64 {
+	timestamp := t.Unix()
+	return &timestamp
+}
+
+// Create 保存新的微调任务，任务在duration内依次经过 validating_files、queued 和 running，duration 为0时立即完成
+// 没有后台定时器，状态在访问任务或调用 AdvanceAll 时推进，到达完成时间后的第一次推进注册微调模型
+// job 中需要设置基础模型、训练文件、验证文件、解析后的超参数、后缀、种子和元数据
+func (s *Store) Create(job Job, plan Plan, duration time.Duration) Job {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	job.ID = newID("ftjob-", 24)
+	job.Object = "fine_tuning.job"
+	job.CreatedAt = now.Unix()
+	job.OrganizationID = OrganizationID
+	job.Status = StatusValidatingFiles
+	job.ResultFiles = []string{}
+	job.Integrations = []interface{}{}
+	job.Method.Type = MethodSupervised
+	job.Method.Supervised.Hyperparameters = job.Hyperparameters
+	if job.Metadata == nil {
+		job.Metadata = map[string]string{}
+	}
+
+	suffix := ""
+	if job.UserProvidedSuffix != nil {
+		suffix = *job.UserProvidedSuffix
+	}
+	state := &jobState{
+		Job:           job,
+		plan:          plan,
+		modelName:     fmt.Sprintf("ft:%s:%s:%s:%s", baseModel(job.Model), OrganizationSlug, suffix, job.ID[len("ftjob-"):len("ftjob-")+8]),
+		start:         now,
+		duration:      duration,
+		stepsPerEpoch: (plan.Examples + job.Hyperparameters.BatchSize - 1) / job.Hyperparameters.BatchSize,
+	}
+	state.totalSteps = state.stepsPerEpoch * job.Hyperparameters.NEpochs
+	state.EstimatedFinish = unix(now.Add(duration))
+	state.addEvent(now, "info", "Created fine-tuning job: "+job.ID, nil)
+	state.addEvent(now, "info", "Validating training file: "+job.TrainingFile, nil)
+	s.jobs = append(s.jobs, state)
+	s.advance(state, now)
+	return state.Job
+}
+
+// Get 获取微调任务
+func (s *Store) Get(id string) (Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state, err := s.job(id)
+	if err != nil {
+		return Job{}, err
+	}
+	return state.Job, nil
+}
+
+// List 分页列出微调任务，metadata 不为空时只列出元数据全部匹配的任务
+func (s *Store) List(params api.ListParams, metadata map[string]string) ([]Job, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	jobs := make([]Job, 0, len(s.jobs))
+	for _, state := range s.jobs {
+		s.advance(state, now)
+		if matchMetadata(state.Metadata, metadata) {
+			jobs = append(jobs, state.Job)
+		}
+	}
+	return api.Paginate(jobs, func(job Job) string { return job.ID }, params)
+}
+
+// AdvanceAll 按当前时间推进所有任务，查找和列出模型前调用，使已经完成的任务的模型无需先查询任务即可使用
+func (s *Store) AdvanceAll() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for _, state := range s.jobs {
+		s.advance(state, now)
+	}
+}
+
+// Cancel 取消尚未完成的微调任务
+func (s *Store) Cancel(id string) (Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state, err := s.job(id)
+	if err != nil {
+		return Job{}, err
+	}
+	switch state.Status {
+	case StatusValidatingFiles, StatusQueued, StatusRunning:
+	default:
+		return Job{}, fmt.Errorf("Cannot cancel a job with status '%s'.", state.Status)
+	}
+
+	now := time.Now()
+	state.Status = StatusCancelled
+	state.EstimatedFinish = nil
+	state.addEvent(now, "info", "Fine-tuning job cancelled", nil)
+	return state.Job, nil
+}
+
+// Events 分页列出任务的事件，默认从新到旧
+func (s *Store) Events(id string, params api.ListParams) ([]Event, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state, err := s.job(id)
+	if err != nil {
+		return nil, false, err
+	}
+	events, hasMore := api.Paginate(state.events, func(event Event) string { return event.ID }, params)
+	return events, hasMore, nil
+}
+
+// Checkpoints 分页列出任务的检查点，默认从新到旧
+func (s *Store) Checkpoints(id string, params api.ListParams) ([]Checkpoint, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state, err := s.job(id)
+	if err != nil {
+		return nil, false, err
+	}
+	checkpoints, hasMore := api.Paginate(state.checkpoints, func(checkpoint Checkpoint) string { return checkpoint.ID }, params)
+	return checkpoints, hasMore, nil
+}
+
+// job 查找任务并按经过的时间推进状态
+func (s *Store) job(id string) (*jobState, error) {
+	for _, state := range s.jobs {
+		if state.ID == id {
+			s.advance(state, time.Now())
+			return state, nil
+		}
+	}
+	return nil, &NotFoundError{ID: id}
+}
+
+// advance 按经过的时间推进任务：validating_files 和 queued 各占总耗时的二十分之一，
+// 其余时间匀速训练，训练过程中按间隔产生指标事件并在每个epoch结束时保存检查点
+func (s *Store) advance(state *jobState, now time.Time) {
+	switch state.Status {
+	case StatusValidatingFiles, StatusQueued, StatusRunning:
+	default:
+		return
+	}
+
+	elapsed := now.Sub(state.start)
+	validated := state.duration / 20
+	started := state.duration / 10
+
+	if elapsed < validated {
+		return
+	}
+	if state.Status == StatusValidatingFiles {
+		state.Status = StatusQueued
+		state.addEvent(state.start.Add(validated), "info", "Files validated, moving job to queued state", nil)
+	}
+	if elapsed < started {
+		return
+	}
+	if state.Status == StatusQueued {
+		state.Status = StatusRunning
+		state.addEvent(state.start.Add(started), "info", "Fine-tuning job started", nil)
+	}
+
+	target := state.totalSteps
+	if elapsed < state.duration {
+		target = int(float64(state.totalSteps) * float64(elapsed-started) / float64(state.duration-started))
+	}
+	interval := max(1, (state.totalSteps+maxMetricsEvents-1)/maxMetricsEvents)
+	for step := state.step + 1; step <= target; step++ {
+		at := state.start.Add(started + time.Duration(float64(state.duration-started)*float64(step)/float64(state.totalSteps)))
+		if step%interval == 0 || step == state.totalSteps {
+			m := metrics(state.Seed, step, state.totalSteps, state.Hyperparameters.LearningRateMultiplier, state.plan.Validation)
+			state.addEvent(at, "info", fmt.Sprintf("Step %d/%d: training loss=%.2f", step, state.totalSteps, m.TrainLoss), map[string]interface{}{
+				"step":                      step,
+				"train_loss":                m.TrainLoss,
+				"train_mean_token_accuracy": m.TrainMeanTokenAccuracy,
+				"total_steps":               state.totalSteps,
+			})
+		}
+		if step%state.stepsPerEpoch == 0 {
+			state.addCheckpoint(at, step)
+		}
+	}
+	state.step = target
+
+	if elapsed < state.duration {
+		return
+	}
+	s.succeed(state, state.start.Add(state.duration))
+}
+
+// succeed 写入训练指标结果文件并注册微调模型，注册失败时任务失败
+func (s *Store) succeed(state *jobState, at time.Time) {
+	state.EstimatedFinish = nil
+	state.FinishedAt = unix(at)
+	state.addEvent(at, "info", "New fine-tuned model created: "+state.modelName, nil)
+
+	job := state.Job
+	job.FineTunedModel = &state.modelName
+	if err := s.register(job); err != nil {
+		state.Status = StatusFailed
+		state.Error = &JobError{Code: "internal_error", Message: fmt.Sprintf("Failed to register the fine-tuned model: %v", err)}
+		state.addEvent(at, "error", "Fine-tuning job failed: "+state.Error.Message, nil)
+		return
+	}
+
+	trainedTokens := state.plan.Tokens * state.Hyperparameters.NEpochs
+	state.TrainedTokens = &trainedTokens
+	state.FineTunedModel = job.FineTunedModel
+	if file, err := s.files.Create("step_metrics.csv", files.PurposeFineTuneResults, state.stepMetrics()); err == nil {
+		state.ResultFiles = []string{file.ID}
+	} else {
+		fmt.Printf("Error writing fine-tuning result file: %v\n", err)
+	}
+	state.Status = StatusSucceeded
+	state.addEvent(at, "info", "The job has successfully completed", nil)
+}
+
+// stepMetrics 生成每一步训练指标的CSV文件内容
+func (state *jobState) stepMetrics() []byte {
+	var buf bytes.Buffer
+	buf.WriteString("step,train_loss,train_accuracy,valid_loss,valid_mean_token_accuracy\n")
+	for step := 1; step <= state.totalSteps; step++ {
+		m := metrics(state.Seed, step, state.totalSteps, state.Hyperparameters.LearningRateMultiplier, state.plan.Validation)
+		validLoss, validAccuracy := "", ""
+		if m.ValidLoss != nil {
+			validLoss = fmt.Sprint(*m.ValidLoss)
+			validAccuracy = fmt.Sprint(*m.ValidMeanTokenAccuracy)
+		}
+		fmt.Fprintf(&buf, "%d,%v,%v,%s,%s\n", step, m.TrainLoss, m.TrainMeanTokenAccuracy, validLoss, validAccuracy)
+	}
+	return buf.Bytes()
+}
+
+// addEvent 添加任务事件，data 不为空时为训练指标事件
+func (state *jobState) addEvent(at time.Time, level, message string, data map[string]interface{}) {
+	event := Event{
+		ID:        newID("ftevent-", 24),
+		Object:    "fine_tuning.job.event",
+		CreatedAt: at.Unix(),
+		Level:     level,
+		Message:   message,
+		Data:      map[string]interface{}{},
+		Type:      "message",
+	}
+	if data != nil {
+		event.Data = data
+		event.Type = "metrics"
+	}
+	state.events = append(state.events, event)
+}
+
+// addCheckpoint 保存检查点，只保留最近的几个
+func (state *jobState) addCheckpoint(at time.Time, step int) {
+	state.checkpoints = append(state.checkpoints, Checkpoint{
+		ID:                       newID("ftckpt_", 24),
+		Object:                   "fine_tuning.job.checkpoint",
+		CreatedAt:                at.Unix(),
+		FineTunedModelCheckpoint: fmt.Sprintf("%s:ckpt-step-%d", state.modelName, step),
+		StepNumber:               step,
+		Metrics:                  metrics(state.Seed, step, state.totalSteps, state.Hyperparameters.LearningRateMultiplier, state.plan.Validation),
+		FineTuningJobID:          state.ID,
+	})
+	if len(state.checkpoints) > maxCheckpoints {
+		state.checkpoints = state.checkpoints[len(state.checkpoints)-maxCheckpoints:]
+	}
+}
+
+// baseModel 返回微调模型名中的基础模型，继续微调已有的微调模型时使用其基础模型
+func baseModel(model string) string {
+	if parts := strings.Split(model, ":"); len(parts) == 5 && parts[0] == "ft" {
+		return parts[1]
+	}
+	return model
+}
+
+// matchMetadata 判断元数据是否包含filter中的全部键值
+func matchMetadata(metadata, filter map[string]string) bool {
+	for key, value := range filter {
+		if metadata[key] != value {
+			return false
+		}
+	}
+	return true
+}
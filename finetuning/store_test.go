@@ -0,0 +1,169 @@
+package finetuning
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"RobinPenn974/OpenAI-mocker/files"
+)
+
+// testJob 返回20个样本、批大小4、3个epoch的任务，每个epoch 5步，共15步
+func testJob() (Job, Plan) {
+	suffix := "test"
+	job := Job{
+		Model:              "mock-gpt-3.5-turbo",
+		TrainingFile:       "file-training",
+		Hyperparameters:    Hyperparameters{NEpochs: 3, BatchSize: 4, LearningRateMultiplier: 1},
+		UserProvidedSuffix: &suffix,
+		Seed:               42,
+	}
+	return job, Plan{Examples: 20, Tokens: 1000}
+}
+
+// recorder 记录注册的微调模型，err 不为空时注册失败
+type recorder struct {
+	models []string
+	err    error
+}
+
+func (r *recorder) register(job Job) error {
+	if r.err != nil {
+		return r.err
+	}
+	r.models = append(r.models, *job.FineTunedModel)
+	return nil
+}
+
+func TestJobLifecycle(t *testing.T) {
+	registered := &recorder{}
+	store := NewStore(files.NewStore(t.TempDir()), registered.register)
+	job, plan := testJob()
+	created := store.Create(job, plan, 20*time.Second)
+	if created.Status != StatusValidatingFiles || created.EstimatedFinish == nil {
+		t.Fatalf("created job: %+v", created)
+	}
+	state, err := store.job(created.ID)
+	if err != nil {
+		t.Fatalf("find job: %v", err)
+	}
+
+	// validating_files 和 queued 各占二十分之一，其余时间匀速训练
+	steps := []struct {
+		elapsed     time.Duration
+		status      string
+		step        int
+		checkpoints int
+	}{
+		{500 * time.Millisecond, StatusValidatingFiles, 0, 0},
+		{1 * time.Second, StatusQueued, 0, 0},
+		{2 * time.Second, StatusRunning, 0, 0},
+		{11 * time.Second, StatusRunning, 7, 1},
+		{20 * time.Second, StatusSucceeded, 15, 3},
+	}
+	for _, step := range steps {
+		store.advance(state, state.start.Add(step.elapsed))
+		if state.Status != step.status || state.step != step.step || len(state.checkpoints) != step.checkpoints {
+			t.Fatalf("after %v: status %s, step %d, %d checkpoints, want %s %d %d", step.elapsed, state.Status, state.step, len(state.checkpoints), step.status, step.step, step.checkpoints)
+		}
+	}
+
+	want := "ft:mock-gpt-3.5-turbo:mock-org:test:" + created.ID[len("ftjob-"):len("ftjob-")+8]
+	if len(registered.models) != 1 || registered.models[0] != want {
+		t.Fatalf("registered models %v, want [%s]", registered.models, want)
+	}
+	if state.FineTunedModel == nil || *state.FineTunedModel != want || state.TrainedTokens == nil || *state.TrainedTokens != 3000 {
+		t.Fatalf("succeeded job: %+v", state.Job)
+	}
+	if len(state.ResultFiles) != 1 || state.EstimatedFinish != nil || state.FinishedAt == nil {
+		t.Fatalf("succeeded job: %+v", state.Job)
+	}
+	if last := state.checkpoints[len(state.checkpoints)-1]; last.StepNumber != 15 || last.FineTunedModelCheckpoint != want+":ckpt-step-15" {
+		t.Fatalf("last checkpoint: %+v", last)
+	}
+
+	// 成功后再次访问不会重复注册
+	store.advance(state, state.start.Add(time.Minute))
+	if len(registered.models) != 1 {
+		t.Fatalf("model registered %d times", len(registered.models))
+	}
+}
+
+func TestJobAdvancesOnlyOnAccess(t *testing.T) {
+	registered := &recorder{}
+	store := NewStore(files.NewStore(t.TempDir()), registered.register)
+	job, plan := testJob()
+	created := store.Create(job, plan, 10*time.Millisecond)
+
+	// 没有后台定时器，到达完成时间后访问任务前不注册模型
+	time.Sleep(50 * time.Millisecond)
+	store.mu.Lock()
+	registeredBeforeAccess := len(registered.models)
+	store.mu.Unlock()
+	if registeredBeforeAccess != 0 {
+		t.Fatal("model registered before the job was accessed")
+	}
+
+	got, err := store.Get(created.ID)
+	if err != nil || got.Status != StatusSucceeded || len(registered.models) != 1 {
+		t.Fatalf("job after access: %+v, %v, registered %v", got, err, registered.models)
+	}
+}
+
+func TestAdvanceAll(t *testing.T) {
+	registered := &recorder{}
+	store := NewStore(files.NewStore(t.TempDir()), registered.register)
+	job, plan := testJob()
+	finished := store.Create(job, plan, 10*time.Millisecond)
+	running := store.Create(job, plan, time.Hour)
+
+	// 查找模型前推进所有任务，只注册已经完成的任务的模型
+	time.Sleep(50 * time.Millisecond)
+	store.AdvanceAll()
+	if len(registered.models) != 1 {
+		t.Fatalf("registered models %v, want only the finished job's model", registered.models)
+	}
+	if got, _ := store.Get(finished.ID); got.Status != StatusSucceeded {
+		t.Fatalf("finished job status %s", got.Status)
+	}
+	if got, _ := store.Get(running.ID); got.Status == StatusSucceeded {
+		t.Fatalf("running job succeeded early")
+	}
+}
+
+func TestJobCancel(t *testing.T) {
+	registered := &recorder{}
+	store := NewStore(files.NewStore(t.TempDir()), registered.register)
+	job, plan := testJob()
+	created := store.Create(job, plan, time.Hour)
+
+	cancelled, err := store.Cancel(created.ID)
+	if err != nil || cancelled.Status != StatusCancelled || cancelled.EstimatedFinish != nil {
+		t.Fatalf("cancel: %+v, %v", cancelled, err)
+	}
+	if _, err := store.Cancel(created.ID); err == nil {
+		t.Fatal("expected cancelling a cancelled job to fail")
+	}
+
+	// 取消的任务不再推进，也不注册模型
+	state, _ := store.job(created.ID)
+	store.advance(state, state.start.Add(2*time.Hour))
+	if state.Status != StatusCancelled || len(registered.models) != 0 {
+		t.Fatalf("cancelled job advanced: status %s, registered %v", state.Status, registered.models)
+	}
+
+	var notFound *NotFoundError
+	if _, err := store.Get("ftjob-missing"); !errors.As(err, &notFound) {
+		t.Fatalf("expected NotFoundError, got %v", err)
+	}
+}
+
+func TestJobRegisterFailure(t *testing.T) {
+	registered := &recorder{err: errors.New("model already exists")}
+	store := NewStore(files.NewStore(t.TempDir()), registered.register)
+	job, plan := testJob()
+	failed := store.Create(job, plan, 0)
+	if failed.Status != StatusFailed || failed.Error == nil || failed.FineTunedModel != nil || len(failed.ResultFiles) != 0 {
+		t.Fatalf("job with failed registration: %+v", failed)
+	}
+}
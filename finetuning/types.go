@@ -0,0 +1,173 @@
+package finetuning
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+)
+
+// 微调任务的状态
+const (
+	StatusValidatingFiles = "validating_files"
+	StatusQueued          = "queued"
+	StatusRunning         = "running"
+	StatusSucceeded       = "succeeded"
+	StatusFailed          = "failed"
+	StatusCancelled       = "cancelled"
+)
+
+// MethodSupervised 支持的微调方法
+const MethodSupervised = "supervised"
+
+// 模拟的组织，微调模型名为 ft:{基础模型}:{组织}:{后缀}:{ID}
+const (
+	OrganizationID   = "org-mock"
+	OrganizationSlug = "mock-org"
+)
+
+// 每个任务保留的检查点数，与OpenAI一致只保留最后三个epoch的检查点
+const maxCheckpoints = 3
+
+// 每个任务最多产生的训练指标事件数，步数更多时按间隔采样
+const maxMetricsEvents = 100
+
+// Hyperparameters 解析后的训练超参数
+type Hyperparameters struct {
+	NEpochs                int     `json:"n_epochs"`
+	BatchSize              int     `json:"batch_size"`
+	LearningRateMultiplier float64 `json:"learning_rate_multiplier"`
+}
+
+// Method 微调方法及其超参数
+type Method struct {
+	Type       string `json:"type"`
+	Supervised struct {
+		Hyperparameters Hyperparameters `json:"hyperparameters"`
+	} `json:"supervised"`
+}
+
+// JobError 任务失败的原因
+type JobError struct {
+	Code    string  `json:"code"`
+	Message string  `json:"message"`
+	Param   *string `json:"param"`
+}
+
+// Job OpenAI格式的微调任务
+type Job struct {
+	ID                 string            `json:"id"`
+	Object             string            `json:"object"`
+	CreatedAt          int64             `json:"created_at"`
+	Error              *JobError         `json:"error"`
+	FineTunedModel     *string           `json:"fine_tuned_model"`
+	FinishedAt         *int64            `json:"finished_at"`
+	Hyperparameters    Hyperparameters   `json:"hyperparameters"`
+	Model              string            `json:"model"`
+	OrganizationID     string            `json:"organization_id"`
+	ResultFiles        []string          `json:"result_files"`
+	Seed               int64             `json:"seed"`
+	Status             string            `json:"status"`
+	TrainedTokens      *int              `json:"trained_tokens"`
+	TrainingFile       string            `json:"training_file"`
+	ValidationFile     *string           `json:"validation_file"`
+	EstimatedFinish    *int64            `json:"estimated_finish"`
+	Integrations       []interface{}     `json:"integrations"`
+	UserProvidedSuffix *string           `json:"user_provided_suffix"`
+	Method             Method            `json:"method"`
+	Metadata           map[string]string `json:"metadata"`
+}
+
+// Event 微调任务的事件，type 为 message 或 metrics
+type Event struct {
+	ID        string      `json:"id"`
+	Object    string      `json:"object"`
+	CreatedAt int64       `json:"created_at"`
+	Level     string      `json:"level"`
+	Message   string      `json:"message"`
+	Data      interface{} `json:"data"`
+	Type      string      `json:"type"`
+}
+
+// Metrics 训练到某一步时的指标，没有验证文件时验证指标为空
+type Metrics struct {
+	Step                       float64  `json:"step"`
+	TrainLoss                  float64  `json:"train_loss"`
+	TrainMeanTokenAccuracy     float64  `json:"train_mean_token_accuracy"`
+	ValidLoss                  *float64 `json:"valid_loss"`
+	ValidMeanTokenAccuracy     *float64 `json:"valid_mean_token_accuracy"`
+	FullValidLoss              *float64 `json:"full_valid_loss"`
+	FullValidMeanTokenAccuracy *float64 `json:"full_valid_mean_token_accuracy"`
+}
+
+// Checkpoint 每个epoch结束时保存的检查点
+type Checkpoint struct {
+	ID                       string  `json:"id"`
+	Object                   string  `json:"object"`
+	CreatedAt                int64   `json:"created_at"`
+	FineTunedModelCheckpoint string  `json:"fine_tuned_model_checkpoint"`
+	StepNumber               int     `json:"step_number"`
+	Metrics                  Metrics `json:"metrics"`
+	FineTuningJobID          string  `json:"fine_tuning_job_id"`
+}
+
+// NotFoundError 请求的微调任务不存在
+type NotFoundError struct {
+	ID string
+}
+
+// Error 返回与OpenAI一致的错误信息
+func (e *NotFoundError) Error() string {
+	return fmt.Sprintf("No fine-tuning job found with id '%s'.", e.ID)
+}
+
+// Plan 创建任务时根据训练文件确定的训练规模
+type Plan struct {
+	Examples   int  // 训练样本数
+	Tokens     int  // 训练文件的token数，训练的token数为其乘以epoch数
+	Validation bool // 是否提供了验证文件
+}
+
+// AutoHyperparameters 按训练样本数确定设置为 auto 的超参数：样本越少epoch越多，样本越多批大小越大
+func AutoHyperparameters(examples int) Hyperparameters {
+	return Hyperparameters{
+		NEpochs:                min(25, max(3, int(math.Ceil(100/float64(max(examples, 1)))))),
+		BatchSize:              min(256, max(1, examples/100)),
+		LearningRateMultiplier: 2,
+	}
+}
+
+// metrics 生成合成的训练指标：损失按指数衰减并叠加随迭代减小的噪声，同一种子和步数总是得到相同的值
+// 学习率倍数越大损失下降越快
+func metrics(seed int64, step, totalSteps int, learningRate float64, validation bool) Metrics {
+	noise := rand.New(rand.NewSource(seed*1000003 + int64(step)))
+	initial := 2.2 + rand.New(rand.NewSource(seed)).Float64()*0.6
+	final := 0.15 + 0.25/math.Max(learningRate, 0.1)
+	progress := float64(step) / float64(max(totalSteps, 1))
+
+	loss := final + (initial-final)*math.Exp(-4*progress*math.Min(learningRate, 4)/2) + (noise.Float64()-0.5)*0.2*(1-progress/2)
+	loss = math.Max(loss, 0.01)
+	result := Metrics{
+		Step:                   float64(step),
+		TrainLoss:              round(loss),
+		TrainMeanTokenAccuracy: round(accuracy(loss)),
+	}
+	if validation {
+		validLoss := round(loss*1.08 + 0.02)
+		validAccuracy := round(accuracy(validLoss))
+		result.ValidLoss = &validLoss
+		result.ValidMeanTokenAccuracy = &validAccuracy
+		result.FullValidLoss = &validLoss
+		result.FullValidMeanTokenAccuracy = &validAccuracy
+	}
+	return result
+}
+
+// accuracy 由损失估计平均token准确率
+func accuracy(loss float64) float64 {
+	return math.Max(0, math.Min(1, 1-loss/2.8))
+}
+
+// round 保留5位小数
+func round(value float64) float64 {
+	return math.Round(value*1e5) / 1e5
+}
@@ -0,0 +1,278 @@
+package instance
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"RobinPenn974/OpenAI-mocker/apikeys"
+	"RobinPenn974/OpenAI-mocker/audit"
+	"RobinPenn974/OpenAI-mocker/config"
+	"RobinPenn974/OpenAI-mocker/models"
+	"RobinPenn974/OpenAI-mocker/templates"
+	"RobinPenn974/OpenAI-mocker/watch"
+	"RobinPenn974/OpenAI-mocker/workspace"
+)
+
+// 将API密钥的使用情况写入文件的间隔
+const keyUsageFlushInterval = 10 * time.Second
+
+// Instance 一个模拟服务实例的全部状态，多个实例可以在同一进程中互不影响地运行
+type Instance struct {
+	Workspaces *workspace.Manager
+	Keys       *apikeys.KeyStore
+	Audit      *audit.Log
+
+	config atomic.Pointer[config.Config]
+
+	reloadMu     sync.Mutex // 串行化重新加载
+	configStamp  watch.Stamp
+	seededFaults []string // 配置中写入的故障注入规则ID，重新加载配置时替换
+
+	statusMu  sync.RWMutex
+	status    map[string]*ReloadStatus
+	startedAt time.Time
+
+	stopFlush func() // 停止定期写入API密钥使用情况
+}
+
+// New 按配置创建实例，所有状态存储在 cfg.StateDir 下，并写入配置中预置的模型、模板、密钥和故障注入规则
+func New(cfg *config.Config) (*Instance, error) {
+	inst := &Instance{
+		Workspaces: workspace.NewManager(filepath.Join(cfg.StateDir, "workspace_data"), workspace.NewDefault(cfg.StateDir)),
+		Keys:       apikeys.NewKeyStore(filepath.Join(cfg.StateDir, "auth_data"), ""),
+		Audit:      audit.NewLog(filepath.Join(cfg.StateDir, "audit_data"), ""),
+		status:     make(map[string]*ReloadStatus),
+		startedAt:  time.Now().UTC(),
+	}
+	if cfg.File != "" {
+		inst.configStamp = watch.StampOf(cfg.File)
+	}
+
+	if err := inst.applyConfig(cfg); err != nil {
+		return nil, err
+	}
+	inst.stopFlush = watch.Poll(keyUsageFlushInterval, inst.flushKeyUsage)
+	return inst, nil
+}
+
+// Close 停止定期任务并写入内存中尚未保存的API密钥使用情况，关闭后不应再处理请求
+func (i *Instance) Close() error {
+	i.stopFlush()
+	return i.Keys.Flush()
+}
+
+// flushKeyUsage 将API密钥的使用情况写入文件
+func (i *Instance) flushKeyUsage() {
+	if err := i.Keys.Flush(); err != nil {
+		fmt.Printf("Error writing API key usage: %v\n", err)
+	}
+}
+
+// Config 返回当前生效的配置，重新加载配置后返回新的配置，调用方不应修改返回值
+func (i *Instance) Config() *config.Config {
+	return i.config.Load()
+}
+
+// StartedAt 返回实例的创建时间
+func (i *Instance) StartedAt() time.Time {
+	return i.startedAt
+}
+
+// DefaultWorkspace 返回实例的默认工作区
+func (i *Instance) DefaultWorkspace() *workspace.Workspace {
+	return i.Workspaces.Default()
+}
+
+// DeleteWorkspace 删除工作区，仍有未吊销的API密钥绑定到该工作区时拒绝删除
+func (i *Instance) DeleteWorkspace(id string) error {
+	var bound []string
+	for _, key := range i.Keys.ListKeys() {
+		if key.Workspace == id && !key.Revoked {
+			bound = append(bound, key.ID)
+		}
+	}
+	if len(bound) > 0 {
+		return fmt.Errorf("workspace '%s' is still bound to API keys %s, revoke or delete them first", id, strings.Join(bound, ", "))
+	}
+	return i.Workspaces.Delete(id)
+}
+
+// applyConfig 写入配置中的预置状态并切换到新配置，写入失败时保留原有的状态和配置
+func (i *Instance) applyConfig(cfg *config.Config) error {
+	if err := i.seed(cfg, i.config.Load()); err != nil {
+		return err
+	}
+	i.Keys.SetDefaultRateLimit(cfg.RateLimit)
+	i.config.Store(cfg)
+	return nil
+}
+
+// seed 将配置中的模型、模板、密钥和故障注入规则写入默认工作区
+// 先校验所有条目再修改状态，任一步写入失败时撤销已写入的条目。previous 为当前生效的配置，
+// 重新加载时只写入与之相比发生变化的模型、模板和故障注入规则，不覆盖通过管理API所做的修改；
+// 已存在的密钥保持不变，故障注入规则变化时替换上一次配置写入的规则
+func (i *Instance) seed(cfg, previous *config.Config) error {
+	ws := i.DefaultWorkspace()
+
+	seededModels := changedModels(cfg, previous)
+	seededTemplates := changedTemplates(cfg, previous)
+	replaceFaults := previous == nil || !reflect.DeepEqual(cfg.Faults, previous.Faults)
+
+	var newKeys []apikeys.KeyOptions
+	for _, req := range cfg.Keys {
+		if _, err := i.Keys.Authenticate(req.Key); !errors.Is(err, apikeys.ErrKeyNotFound) {
+			continue
+		}
+		if req.Workspace != "" {
+			if _, err := i.Workspaces.Get(req.Workspace); err != nil {
+				return fmt.Errorf("error seeding key %s: workspace '%s' not found", req.Name, req.Workspace)
+			}
+		}
+		newKeys = append(newKeys, apikeys.OptionsFromRequest(req))
+	}
+	if err := ws.Templates.CheckTemplates(seededTemplates); err != nil {
+		return fmt.Errorf("error seeding templates: %v", err)
+	}
+
+	// 校验通过后再写入，撤销操作按写入的相反顺序执行，撤销失败时无法进一步处理，忽略其错误
+	var undo []func()
+	rollback := func(err error) error {
+		for j := len(undo) - 1; j >= 0; j-- {
+			undo[j]()
+		}
+		return err
+	}
+
+	if len(seededModels) > 0 {
+		restore := modelRestorer(ws, seededModels)
+		if err := ws.Models.RegisterModels(seededModels); err != nil {
+			return fmt.Errorf("error seeding models: %v", err)
+		}
+		undo = append(undo, restore)
+	}
+
+	if len(seededTemplates) > 0 {
+		restore := templateRestorer(ws, seededTemplates)
+		if err := ws.Templates.RegisterTemplates(seededTemplates); err != nil {
+			return rollback(fmt.Errorf("error seeding templates: %v", err))
+		}
+		undo = append(undo, restore)
+	}
+
+	for _, opts := range newKeys {
+		key, _, err := i.Keys.CreateKey(opts)
+		if err != nil {
+			return rollback(fmt.Errorf("error seeding key %s: %v", opts.Name, err))
+		}
+		undo = append(undo, func() { i.Keys.DeleteKey(key.ID) })
+	}
+
+	if !replaceFaults {
+		return nil
+	}
+	var added []string
+	for _, rule := range cfg.Faults {
+		rule, err := ws.Faults.AddRule(rule)
+		if err != nil {
+			for _, id := range added {
+				ws.Faults.DeleteRule(id)
+			}
+			return rollback(fmt.Errorf("error seeding fault: %v", err))
+		}
+		added = append(added, rule.ID)
+	}
+	// 规则可能已通过管理API删除，忽略删除失败
+	for _, id := range i.seededFaults {
+		ws.Faults.DeleteRule(id)
+	}
+	i.seededFaults = added
+	return nil
+}
+
+// changedModels 返回配置中需要写入的模型，previous 为nil时返回全部模型，并补全创建时间和所有者
+func changedModels(cfg, previous *config.Config) []models.ModelInfo {
+	before := make(map[string]models.ModelInfo)
+	if previous != nil {
+		for _, model := range previous.Models {
+			before[model.ID] = model
+		}
+	}
+
+	var changed []models.ModelInfo
+	for _, model := range cfg.Models {
+		if old, exists := before[model.ID]; previous != nil && exists && reflect.DeepEqual(old, model) {
+			continue
+		}
+		if model.Created == 0 {
+			model.Created = time.Now().Unix()
+		}
+		if model.OwnedBy == "" {
+			model.OwnedBy = "openai-mocker"
+		}
+		changed = append(changed, model)
+	}
+	return changed
+}
+
+// changedTemplates 返回配置中需要写入的模板，previous 为nil时返回全部模板
+func changedTemplates(cfg, previous *config.Config) []templates.ResponseTemplate {
+	before := make(map[string]templates.ResponseTemplate)
+	if previous != nil {
+		for _, template := range previous.Templates {
+			before[template.ModelID] = template
+		}
+	}
+
+	var changed []templates.ResponseTemplate
+	for _, template := range cfg.Templates {
+		if old, exists := before[template.ModelID]; previous != nil && exists && reflect.DeepEqual(old, template) {
+			continue
+		}
+		changed = append(changed, template)
+	}
+	return changed
+}
+
+// modelRestorer 记录即将写入的模型的当前状态，返回将其恢复到该状态的函数
+func modelRestorer(ws *workspace.Workspace, seeded []models.ModelInfo) func() {
+	var existing []models.ModelInfo
+	var added []string
+	for _, model := range seeded {
+		if old, err := ws.Models.GetModel(model.ID); err == nil {
+			existing = append(existing, old)
+		} else {
+			added = append(added, model.ID)
+		}
+	}
+	return func() {
+		ws.Models.RegisterModels(existing)
+		for _, id := range added {
+			ws.Models.UnloadModel(id)
+		}
+	}
+}
+
+// templateRestorer 记录即将写入的模板的当前状态，返回将其恢复到该状态的函数
+func templateRestorer(ws *workspace.Workspace, seeded []templates.ResponseTemplate) func() {
+	var existing []templates.ResponseTemplate
+	var added []string
+	for _, template := range seeded {
+		if old, exists := ws.Templates.StoredTemplate(template.ModelID); exists {
+			existing = append(existing, old)
+		} else {
+			added = append(added, template.ModelID)
+		}
+	}
+	return func() {
+		ws.Templates.RegisterTemplates(existing)
+		for _, id := range added {
+			ws.Templates.DeleteTemplate(id)
+		}
+	}
+}
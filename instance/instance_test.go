@@ -0,0 +1,155 @@
+package instance
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"RobinPenn974/OpenAI-mocker/config"
+)
+
+// newTestInstance 使用临时目录中的配置文件创建实例，返回实例和配置文件路径
+func newTestInstance(t *testing.T, content string) (*Instance, string) {
+	t.Helper()
+
+	dir := t.TempDir()
+	file := filepath.Join(dir, "mocker.yaml")
+	writeConfig(t, file, content)
+	cfg, _, err := config.Load([]string{"-config", file, "-state-dir", dir})
+	if err != nil {
+		t.Fatalf("load config: %v", err)
+	}
+	inst, err := New(cfg)
+	if err != nil {
+		t.Fatalf("new instance: %v", err)
+	}
+	t.Cleanup(func() { inst.Close() })
+	return inst, file
+}
+
+// writeConfig 写入配置文件
+func writeConfig(t *testing.T, file, content string) {
+	t.Helper()
+	if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+}
+
+const baseConfig = `
+models:
+  - id: seeded-model
+    context_window: 1000
+templates:
+  - model_id: seeded-model
+    greeting: hello from config
+faults:
+  - endpoint: /chat/completions
+    status: 503
+`
+
+func TestReloadFailureKeepsState(t *testing.T) {
+	inst, file := newTestInstance(t, baseConfig)
+	ws := inst.DefaultWorkspace()
+	before := inst.Config()
+	faultIDs := inst.seededFaults
+
+	// 密钥绑定的工作区不存在，重新加载失败前不能写入任何条目
+	writeConfig(t, file, `
+models:
+  - id: seeded-model
+    context_window: 2000
+  - id: another-model
+templates:
+  - model_id: seeded-model
+    greeting: changed greeting
+faults:
+  - endpoint: /embeddings
+    status: 500
+keys:
+  - name: bound
+    key: sk-mock-bound-to-missing-workspace
+    workspace: missing
+`)
+	if err := inst.Reload(); err == nil {
+		t.Fatal("expected reload to fail")
+	}
+
+	if inst.Config() != before {
+		t.Error("config was swapped in despite the failed reload")
+	}
+	model, err := ws.Models.GetModel("seeded-model")
+	if err != nil || model.ContextWindow != 1000 {
+		t.Errorf("seeded model changed: %+v, %v", model, err)
+	}
+	if _, err := ws.Models.GetModel("another-model"); err == nil {
+		t.Error("new model registered despite the failed reload")
+	}
+	if template, _ := ws.Templates.StoredTemplate("seeded-model"); template.Greeting != "hello from config" {
+		t.Errorf("template changed: %q", template.Greeting)
+	}
+	rules := ws.Faults.ListRules()
+	if len(rules) != 1 || rules[0].ID != faultIDs[0] || rules[0].Endpoint != "/chat/completions" {
+		t.Errorf("fault rules changed: %+v", rules)
+	}
+	if inst.Keys.HasKeys() {
+		t.Error("key created despite the failed reload")
+	}
+}
+
+func TestReloadOnlySeedsChangedEntries(t *testing.T) {
+	inst, file := newTestInstance(t, baseConfig)
+	ws := inst.DefaultWorkspace()
+	faultIDs := inst.seededFaults
+
+	// 通过管理API修改预置的模型和模板
+	model, _ := ws.Models.GetModel("seeded-model")
+	model.ContextWindow = 4000
+	if err := ws.Models.RegisterModel(model); err != nil {
+		t.Fatalf("edit model: %v", err)
+	}
+	template, _ := ws.Templates.StoredTemplate("seeded-model")
+	template.Greeting = "edited by admin"
+	if err := ws.Templates.RegisterTemplate(template); err != nil {
+		t.Fatalf("edit template: %v", err)
+	}
+
+	// 只新增一个模型，未变化的模型、模板和故障注入规则保持不变
+	writeConfig(t, file, baseConfig+`
+  - endpoint: /embeddings
+    status: 500
+`)
+	if err := inst.Reload(); err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	if model, _ := ws.Models.GetModel("seeded-model"); model.ContextWindow != 4000 {
+		t.Errorf("admin edit of model overwritten: context_window %d", model.ContextWindow)
+	}
+	if template, _ := ws.Templates.StoredTemplate("seeded-model"); template.Greeting != "edited by admin" {
+		t.Errorf("admin edit of template overwritten: %q", template.Greeting)
+	}
+	if rules := ws.Faults.ListRules(); len(rules) != 2 || rules[0].ID == faultIDs[0] {
+		t.Errorf("changed fault rules not replaced: %+v", rules)
+	}
+
+	// 配置中的模型变化后按新配置写入
+	writeConfig(t, file, `
+models:
+  - id: seeded-model
+    context_window: 3000
+templates:
+  - model_id: seeded-model
+    greeting: hello from config
+`)
+	if err := inst.Reload(); err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	if model, _ := ws.Models.GetModel("seeded-model"); model.ContextWindow != 3000 {
+		t.Errorf("changed model not seeded: context_window %d", model.ContextWindow)
+	}
+	if template, _ := ws.Templates.StoredTemplate("seeded-model"); template.Greeting != "edited by admin" {
+		t.Errorf("unchanged template re-seeded: %q", template.Greeting)
+	}
+	if rules := ws.Faults.ListRules(); len(rules) != 0 {
+		t.Errorf("removed fault rules still active: %+v", rules)
+	}
+}
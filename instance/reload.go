@@ -0,0 +1,165 @@
+package instance
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"RobinPenn974/OpenAI-mocker/watch"
+	"RobinPenn974/OpenAI-mocker/workspace"
+)
+
+// 重新加载的来源
+const (
+	SourceConfig          = "config"
+	SourceTemplatesPrefix = "templates:" // 后接工作区ID
+)
+
+// ReloadStatus 一个文件来源最近一次重新加载的结果
+type ReloadStatus struct {
+	Source      string     `json:"source"` // config 或 templates:<工作区ID>
+	Path        string     `json:"path"`
+	LastAttempt *time.Time `json:"last_attempt,omitempty"`
+	LastSuccess *time.Time `json:"last_success,omitempty"`
+	Reloads     int        `json:"reloads"`            // 成功重新加载的次数
+	Error       string     `json:"error,omitempty"`    // 最近一次失败的原因，失败时继续使用上一个有效版本
+	Warnings    []string   `json:"warnings,omitempty"` // 如需要重启才能生效的配置项
+}
+
+// Reload 重新加载配置文件和所有工作区的模板文件，返回所有失败的原因
+func (i *Instance) Reload() error {
+	i.reloadMu.Lock()
+	defer i.reloadMu.Unlock()
+
+	var errs []error
+	if i.Config().File != "" {
+		if err := i.reloadConfig(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	for _, ws := range i.Workspaces.List() {
+		if err := i.reloadTemplates(ws); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Watch 每隔interval检查配置文件和模板文件，发生变化时重新加载，返回停止检查的函数
+func (i *Instance) Watch(interval time.Duration) (stop func()) {
+	return watch.Poll(interval, i.reloadChanged)
+}
+
+// reloadChanged 重新加载发生变化的文件
+func (i *Instance) reloadChanged() {
+	i.reloadMu.Lock()
+	defer i.reloadMu.Unlock()
+
+	if file := i.Config().File; file != "" && watch.StampOf(file) != i.configStamp {
+		if err := i.reloadConfig(); err != nil {
+			fmt.Printf("Error reloading config: %v\n", err)
+		} else {
+			fmt.Printf("Reloaded config file: %s\n", file)
+		}
+	}
+	for _, ws := range i.Workspaces.List() {
+		if !ws.Templates.Changed() {
+			continue
+		}
+		if err := i.reloadTemplates(ws); err != nil {
+			fmt.Printf("Error reloading templates: %v\n", err)
+		} else {
+			fmt.Printf("Reloaded templates of workspace %s: %s\n", ws.ID, ws.Templates.Path())
+		}
+	}
+}
+
+// reloadConfig 重新加载配置文件，需要重启才能生效的配置项保持原值
+func (i *Instance) reloadConfig() error {
+	current := i.Config()
+	i.configStamp = watch.StampOf(current.File)
+
+	next, err := current.Reload()
+	if err == nil {
+		warnings := current.RestartRequired(next)
+		if len(warnings) > 0 {
+			next.Listen = current.Listen
+			next.AdminListen = current.AdminListen
+			next.TLS = current.TLS
+			next.StateDir = current.StateDir
+			next.ReloadIntervalMs = current.ReloadIntervalMs
+			for j, field := range warnings {
+				warnings[j] = field + " changed, restart required"
+				fmt.Printf("Warning: config %s\n", warnings[j])
+			}
+		}
+		err = i.applyConfig(next)
+		i.recordReload(SourceConfig, current.File, err, warnings)
+	} else {
+		i.recordReload(SourceConfig, current.File, err, nil)
+	}
+	if err != nil {
+		return fmt.Errorf("config %s: %v", current.File, err)
+	}
+	return nil
+}
+
+// reloadTemplates 重新加载工作区的模板文件
+func (i *Instance) reloadTemplates(ws *workspace.Workspace) error {
+	err := ws.Templates.Reload()
+	i.recordReload(SourceTemplatesPrefix+ws.ID, ws.Templates.Path(), err, nil)
+	if err != nil {
+		return fmt.Errorf("templates of workspace %s: %v", ws.ID, err)
+	}
+	return nil
+}
+
+// recordReload 记录重新加载的结果
+func (i *Instance) recordReload(source, path string, err error, warnings []string) {
+	i.statusMu.Lock()
+	defer i.statusMu.Unlock()
+
+	status, exists := i.status[source]
+	if !exists {
+		status = &ReloadStatus{Source: source}
+		i.status[source] = status
+	}
+	now := time.Now().UTC()
+	status.Path = path
+	status.LastAttempt = &now
+	status.Warnings = warnings
+	if err != nil {
+		status.Error = err.Error()
+		return
+	}
+	status.Error = ""
+	status.LastSuccess = &now
+	status.Reloads++
+}
+
+// ReloadStatus 返回配置文件和各工作区模板文件最近一次重新加载的结果，按来源排序
+func (i *Instance) ReloadStatus() []ReloadStatus {
+	i.statusMu.RLock()
+	defer i.statusMu.RUnlock()
+
+	// 尚未重新加载过的来源也一并返回
+	var statuses []ReloadStatus
+	add := func(source, path string) {
+		if status, exists := i.status[source]; exists {
+			statuses = append(statuses, *status)
+		} else {
+			statuses = append(statuses, ReloadStatus{Source: source, Path: path})
+		}
+	}
+	if file := i.Config().File; file != "" {
+		add(SourceConfig, file)
+	}
+	for _, ws := range i.Workspaces.List() {
+		add(SourceTemplatesPrefix+ws.ID, ws.Templates.Path())
+	}
+	sort.Slice(statuses, func(a, b int) bool {
+		return statuses[a].Source < statuses[b].Source
+	})
+	return statuses
+}
@@ -3,27 +3,105 @@ package main
 import (
 	"fmt"
 	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
 
-	"RobinPenn974/OpenAI-mocker/models"
+	"RobinPenn974/OpenAI-mocker/config"
+	"RobinPenn974/OpenAI-mocker/instance"
 	"RobinPenn974/OpenAI-mocker/routes"
 
 	"github.com/gin-gonic/gin"
 )
 
 func main() {
-	// 初始化默认模型
-	models.InitDefaultModels()
+	// 加载配置，优先级：默认值 < 配置文件 < 环境变量 < 命令行参数
+	cfg, printConfig, err := config.Load(os.Args[1:])
+	if err != nil {
+		log.Fatalf("加载配置失败: %v", err)
+	}
+	if printConfig {
+		if err := config.Print(os.Stdout, cfg); err != nil {
+			log.Fatalf("输出配置失败: %v", err)
+		}
+		return
+	}
+
+	// 从状态目录加载状态，首次启动时从默认模型清单初始化模型注册表
+	inst, err := instance.New(cfg)
+	if err != nil {
+		log.Fatalf("初始化失败: %v", err)
+	}
+	fmt.Printf("已加载 %d 个模型\n", len(inst.DefaultWorkspace().Models.ListModels()))
+
+	// 定期检查配置文件和模板文件，修改后自动重新加载
+	if cfg.ReloadIntervalMs > 0 {
+		inst.Watch(time.Duration(cfg.ReloadIntervalMs) * time.Millisecond)
+	}
+
+	// 退出前写入内存中尚未保存的API密钥使用情况
+	go closeOnSignal(inst)
+
+	if len(cfg.Admin.Credentials) == 0 {
+		fmt.Printf("警告: 未配置管理员凭据（%s），管理API无需鉴权\n", config.EnvAdminToken)
+	}
 
 	// 创建默认的gin引擎
 	r := gin.Default()
 
 	// 设置路由
-	routes.SetupRoutes(r)
+	routes.SetupRoutes(r, inst)
+
+	// 管理API可以单独监听端口或Unix套接字
+	if cfg.AdminListen == "" {
+		routes.SetupAdminRoutes(r, inst)
+	} else {
+		adminEngine := gin.Default()
+		routes.SetupAdminRoutes(adminEngine, inst)
+		go serve(adminEngine, "管理API", cfg.AdminListen, cfg.TLS)
+	}
+
+	// 在每个监听地址上启动服务器，任一地址启动失败时退出
+	for _, addr := range cfg.Listen {
+		go serve(r, "OpenAI-mocker服务", addr, cfg.TLS)
+	}
+	select {}
+}
+
+// closeOnSignal 收到中断或终止信号时关闭实例并退出
+func closeOnSignal(inst *instance.Instance) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	<-signals
+	if err := inst.Close(); err != nil {
+		fmt.Printf("关闭实例失败: %v\n", err)
+	}
+	os.Exit(0)
+}
 
-	// 启动服务器
-	port := 8080
-	fmt.Printf("OpenAI-mocker服务启动在 :%d\n", port)
-	if err := r.Run(fmt.Sprintf(":%d", port)); err != nil {
-		log.Fatalf("启动服务失败: %v", err)
+// serve 在指定地址上启动服务，地址可以是TCP地址或 unix:/path 形式的Unix套接字，TCP地址在启用HTTPS时使用证书
+func serve(engine *gin.Engine, name, addr string, tls config.TLS) {
+	if socket, ok := strings.CutPrefix(addr, "unix:"); ok {
+		// 删除上次运行遗留的套接字文件
+		os.Remove(socket)
+		fmt.Printf("%s监听在Unix套接字 %s\n", name, socket)
+		if err := engine.RunUnix(socket); err != nil {
+			log.Fatalf("启动%s失败: %v", name, err)
+		}
+		return
+	}
+
+	var err error
+	if tls.Enabled() {
+		fmt.Printf("%s启动在 %s (HTTPS)\n", name, addr)
+		err = engine.RunTLS(addr, tls.CertFile, tls.KeyFile)
+	} else {
+		fmt.Printf("%s启动在 %s\n", name, addr)
+		err = engine.Run(addr)
+	}
+	if err != nil {
+		log.Fatalf("启动%s失败: %v", name, err)
 	}
 }
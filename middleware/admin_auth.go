@@ -0,0 +1,86 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"RobinPenn974/OpenAI-mocker/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+// gin上下文中保存当前管理员名称和角色的键
+const (
+	ContextKeyAdminActor = "admin_actor"
+	ContextKeyAdminRole  = "admin_role"
+)
+
+// 不修改任何状态的非GET管理接口，只读角色也可以访问
+var nonMutatingAdminRoutes = map[string]bool{
+	"/admin/templates/:model_id/preview": true,
+}
+
+// AdminAuthRequired 按实例当前配置中的凭据验证管理员身份的中间件，只读角色只能访问GET请求和不修改状态的接口
+// 未配置任何凭据时允许自由访问，与API密钥的零配置模式一致
+func AdminAuthRequired() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		credentials := CurrentInstance(c).Config().Admin.Credentials
+		if len(credentials) == 0 {
+			c.Set(ContextKeyAdminActor, "anonymous")
+			c.Set(ContextKeyAdminRole, config.AdminRoleAdmin)
+			c.Next()
+			return
+		}
+
+		token := strings.TrimSpace(c.GetHeader("Authorization"))
+		if len(token) > 7 && strings.EqualFold(token[:7], "bearer ") {
+			token = strings.TrimSpace(token[7:])
+		}
+
+		credential, ok := findAdminCredential(credentials, token)
+		if !ok {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error": gin.H{
+					"message": "Invalid or missing admin token",
+					"type":    "authentication_error",
+				},
+			})
+			c.Abort()
+			return
+		}
+
+		if credential.Role == config.AdminRoleReadOnly && !isNonMutating(c) {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": gin.H{
+					"message": "The read_only admin role cannot modify resources",
+					"type":    "permission_error",
+				},
+			})
+			c.Abort()
+			return
+		}
+
+		c.Set(ContextKeyAdminActor, credential.Name)
+		c.Set(ContextKeyAdminRole, credential.Role)
+		c.Next()
+	}
+}
+
+// isNonMutating 检查请求是否不会修改任何状态
+func isNonMutating(c *gin.Context) bool {
+	return c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead || nonMutatingAdminRoutes[c.FullPath()]
+}
+
+// findAdminCredential 按令牌查找管理员凭据，使用常量时间比较
+func findAdminCredential(credentials []config.AdminCredential, token string) (config.AdminCredential, bool) {
+	if token == "" {
+		return config.AdminCredential{}, false
+	}
+	for _, credential := range credentials {
+		if subtle.ConstantTimeCompare([]byte(credential.Token), []byte(token)) == 1 {
+			return credential, true
+		}
+	}
+	return config.AdminCredential{}, false
+}
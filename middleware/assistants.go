@@ -0,0 +1,36 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"RobinPenn974/OpenAI-mocker/api"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HeaderOpenAIBeta 启用测试版接口的请求头
+const HeaderOpenAIBeta = "OpenAI-Beta"
+
+// AssistantsBetaRequired 要求Assistants API请求带有 OpenAI-Beta: assistants=v2 头，与OpenAI一致拒绝v1和缺少该头的请求
+func AssistantsBetaRequired() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var version string
+		for _, value := range strings.Split(c.GetHeader(HeaderOpenAIBeta), ",") {
+			if name, v, ok := strings.Cut(strings.TrimSpace(value), "="); ok && name == "assistants" {
+				version = v
+			}
+		}
+
+		switch version {
+		case "v2":
+			c.Next()
+			return
+		case "":
+			c.JSON(http.StatusBadRequest, api.NewErrorResponse("You must provide the 'OpenAI-Beta' header to access the Assistants API. Please try again by setting the header 'OpenAI-Beta: assistants=v2'.", "invalid_request_error", "", "invalid_beta"))
+		default:
+			c.JSON(http.StatusBadRequest, api.NewErrorResponse("The v1 Assistants API has been deprecated. Please try again by setting the header 'OpenAI-Beta: assistants=v2'.", "invalid_request_error", "", "invalid_beta"))
+		}
+		c.Abort()
+	}
+}
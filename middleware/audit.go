@@ -0,0 +1,81 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"RobinPenn974/OpenAI-mocker/audit"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AuditMutations 记录管理API中所有修改操作的中间件
+// snapshot返回被修改资源的当前状态，在处理前后各调用一次
+func AuditMutations(snapshot func(c *gin.Context) interface{}) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead {
+			c.Next()
+			return
+		}
+
+		// 读取请求体后放回，后续处理器仍可正常绑定
+		var request json.RawMessage
+		if c.Request.Body != nil {
+			body, err := io.ReadAll(c.Request.Body)
+			c.Request.Body = io.NopCloser(bytes.NewReader(body))
+			if err == nil && json.Valid(body) {
+				request = redactSecrets(body)
+			}
+		}
+
+		before := snapshot(c)
+		c.Next()
+		after := snapshot(c)
+
+		entry := audit.Entry{
+			Actor:      c.GetString(ContextKeyAdminActor),
+			Role:       c.GetString(ContextKeyAdminRole),
+			RemoteAddr: c.ClientIP(),
+			Method:     c.Request.Method,
+			Path:       c.Request.URL.Path,
+			Status:     c.Writer.Status(),
+			Request:    request,
+			Before:     before,
+			After:      after,
+		}
+		if ws, ok := CurrentWorkspace(c); ok {
+			entry.Workspace = ws.ID
+		}
+		if _, err := CurrentInstance(c).Audit.Record(entry); err != nil {
+			fmt.Printf("Error recording audit entry: %v\n", err)
+		}
+	}
+}
+
+// redactSecrets 隐藏请求体中的密钥字段，避免完整密钥写入审计日志
+func redactSecrets(body []byte) json.RawMessage {
+	var fields map[string]interface{}
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return body
+	}
+
+	redacted := false
+	for _, name := range []string{"key", "token"} {
+		if value, ok := fields[name].(string); ok && value != "" {
+			fields[name] = redactKey(value)
+			redacted = true
+		}
+	}
+	if !redacted {
+		return body
+	}
+
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return nil
+	}
+	return data
+}
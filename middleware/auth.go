@@ -1,80 +1,38 @@
 package middleware
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
 	"net/http"
+	"strconv"
 	"strings"
-	"sync"
+
+	"RobinPenn974/OpenAI-mocker/api"
+	"RobinPenn974/OpenAI-mocker/apikeys"
 
 	"github.com/gin-gonic/gin"
 )
 
-// ApiKeys 存储API密钥的映射
-type ApiKeys struct {
-	Keys map[string]string // key -> name
-	mu   sync.RWMutex
-}
-
-// NewApiKeys 创建一个新的API密钥存储
-func NewApiKeys() *ApiKeys {
-	return &ApiKeys{
-		Keys: make(map[string]string),
-	}
-}
-
-// Global API Keys实例
-var GlobalApiKeys = NewApiKeys()
-
-// AddKey 添加一个新的API密钥
-func (a *ApiKeys) AddKey(key, name string) {
-	a.mu.Lock()
-	defer a.mu.Unlock()
-	a.Keys[key] = name
-}
-
-// RemoveKey 删除一个API密钥
-func (a *ApiKeys) RemoveKey(key string) {
-	a.mu.Lock()
-	defer a.mu.Unlock()
-	delete(a.Keys, key)
-}
-
-// RemoveAllKeys 删除所有API密钥
-func (a *ApiKeys) RemoveAllKeys() {
-	a.mu.Lock()
-	defer a.mu.Unlock()
-	a.Keys = make(map[string]string)
-}
-
-// IsValidKey 检查API密钥是否有效
-func (a *ApiKeys) IsValidKey(key string) bool {
-	a.mu.RLock()
-	defer a.mu.RUnlock()
-	_, exists := a.Keys[key]
-	return exists
-}
-
-// HasKeys 检查是否存在任何API密钥
-func (a *ApiKeys) HasKeys() bool {
-	a.mu.RLock()
-	defer a.mu.RUnlock()
-	return len(a.Keys) > 0
-}
-
-// GetAllKeys 获取所有API密钥
-func (a *ApiKeys) GetAllKeys() map[string]string {
-	a.mu.RLock()
-	defer a.mu.RUnlock()
-
-	// 创建一个副本以避免直接返回内部map
-	keysCopy := make(map[string]string, len(a.Keys))
-	for k, v := range a.Keys {
-		keysCopy[k] = v
-	}
-
-	return keysCopy
+// ContextKeyAPIKey gin上下文中保存当前请求API密钥记录的键
+const ContextKeyAPIKey = "api_key"
+
+// 接口路径后缀与所需权限的对应关系，同时适用于OpenAI和Azure路由
+var scopesByPathSuffix = []struct {
+	suffix string
+	scope  string
+}{
+	{"/chat/completions", apikeys.ScopeChatWrite},
+	{"/completions", apikeys.ScopeCompletionsWrite},
+	{"/embeddings", apikeys.ScopeEmbeddingsWrite},
+	{"/rerank", apikeys.ScopeRerankWrite},
+	{"/score", apikeys.ScopeRerankWrite},
 }
 
-// AuthRequired 验证API密钥的中间件
+// AuthRequired 验证API密钥的中间件，检查密钥的有效期、吊销状态、权限和速率限制
 func AuthRequired() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// 健康检查路由不需要认证
@@ -84,41 +42,31 @@ func AuthRequired() gin.HandlerFunc {
 		}
 
 		// 如果没有注册任何API密钥，允许自由访问
-		if !GlobalApiKeys.HasKeys() {
+		if !CurrentInstance(c).Keys.HasKeys() {
 			c.Next()
 			return
 		}
 
-		// 从Authorization头中提取API密钥
-		authHeader := c.GetHeader("Authorization")
-		if authHeader == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"error": gin.H{
-					"message": "No API key provided",
-					"type":    "auth_error",
-					"code":    "no_api_key",
-				},
-			})
+		// 从Authorization头中提取API密钥，密钥区分大小写
+		apiKey := strings.TrimSpace(c.GetHeader("Authorization"))
+		if len(apiKey) > 7 && strings.EqualFold(apiKey[:7], "bearer ") {
+			apiKey = strings.TrimSpace(apiKey[7:])
+		}
+		if apiKey == "" {
+			c.JSON(http.StatusUnauthorized, api.NewErrorResponse("You didn't provide an API key. You need to provide your API key in an Authorization header using Bearer auth (i.e. Authorization: Bearer YOUR_KEY).", "invalid_request_error", "", ""))
 			c.Abort()
 			return
 		}
 
-		// 支持Bearer token格式
-		apiKey := authHeader
-		if strings.HasPrefix(strings.ToLower(authHeader), "bearer ") {
-			apiKey = strings.TrimPrefix(strings.ToLower(authHeader), "bearer ")
-			apiKey = strings.TrimSpace(apiKey)
+		key, err := CurrentInstance(c).Keys.Authenticate(apiKey)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, authenticationError(err, apiKey))
+			c.Abort()
+			return
 		}
 
-		// 验证API密钥
-		if !GlobalApiKeys.IsValidKey(apiKey) {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"error": gin.H{
-					"message": "Invalid API key",
-					"type":    "auth_error",
-					"code":    "invalid_api_key",
-				},
-			})
+		if status, errResp := authorizeKey(c, key); errResp != nil {
+			c.JSON(status, errResp)
 			c.Abort()
 			return
 		}
@@ -127,7 +75,137 @@ func AuthRequired() gin.HandlerFunc {
 	}
 }
 
-// 我们不再需要默认API密钥，因为我们现在允许在没有API密钥时自由访问
-func init() {
-	// 不再添加默认测试密钥
+// authenticationError 按认证失败的原因返回错误响应，已吊销和已过期的密钥使用不同的错误码
+func authenticationError(err error, apiKey string) api.ErrorResponse {
+	switch {
+	case errors.Is(err, apikeys.ErrKeyRevoked):
+		return api.NewErrorResponse(fmt.Sprintf("The API key provided has been revoked: %s. Create a new API key to continue.", redactKey(apiKey)), "invalid_request_error", "", "api_key_revoked")
+	case errors.Is(err, apikeys.ErrKeyExpired):
+		return api.NewErrorResponse(fmt.Sprintf("The API key provided has expired: %s. Create a new API key to continue.", redactKey(apiKey)), "invalid_request_error", "", "api_key_expired")
+	default:
+		return api.NewErrorResponse(fmt.Sprintf("Incorrect API key provided: %s. You can find your API key at https://platform.openai.com/account/api-keys.", redactKey(apiKey)), "invalid_request_error", "", "invalid_api_key")
+	}
+}
+
+// CurrentKey 返回当前请求使用的API密钥记录，未鉴权的请求返回false
+func CurrentKey(c *gin.Context) (apikeys.Key, bool) {
+	value, exists := c.Get(ContextKeyAPIKey)
+	if !exists {
+		return apikeys.Key{}, false
+	}
+	key, ok := value.(apikeys.Key)
+	return key, ok
+}
+
+// authorizeKey 检查已认证密钥的权限和速率限制，通过后记录使用情况，模型白名单由处理器在解析模型后检查
+func authorizeKey(c *gin.Context, key apikeys.Key) (int, *api.ErrorResponse) {
+	c.Set(ContextKeyAPIKey, key)
+
+	if scope := requiredScope(c); !key.HasScope(scope) {
+		errResp := api.NewErrorResponse(fmt.Sprintf("You have insufficient permissions for this operation. Missing scopes: %s. Check that your API key has the necessary scopes.", scope), "invalid_request_error", "", "insufficient_permissions")
+		return http.StatusForbidden, &errResp
+	}
+
+	keys := CurrentInstance(c).Keys
+	allowed, remaining, reset := keys.AllowRequest(key)
+	if limit := keys.RateLimit(key).RequestsPerMinute; limit > 0 {
+		resetSeconds := int(math.Ceil(reset.Seconds()))
+		c.Header("x-ratelimit-limit-requests", strconv.Itoa(limit))
+		c.Header("x-ratelimit-remaining-requests", strconv.Itoa(remaining))
+		c.Header("x-ratelimit-reset-requests", fmt.Sprintf("%ds", resetSeconds))
+		if !allowed {
+			c.Header("retry-after", strconv.Itoa(resetSeconds))
+			errResp := api.NewErrorResponse(fmt.Sprintf("Rate limit reached for requests on API key %s: Limit %d / min. Please try again in %ds.", key.MaskedKey, limit, resetSeconds), "requests", "", "rate_limit_exceeded")
+			return http.StatusTooManyRequests, &errResp
+		}
+	}
+
+	keys.RecordUsage(key)
+	return http.StatusOK, nil
+}
+
+// requiredScope 根据请求方法和路由返回所需的权限
+func requiredScope(c *gin.Context) string {
+	path := c.FullPath()
+	if strings.HasPrefix(path, "/v1/models") {
+		if c.Request.Method == http.MethodGet {
+			return apikeys.ScopeModelsRead
+		}
+		return apikeys.ScopeModelsWrite
+	}
+	if strings.HasPrefix(path, "/v1/assistants") || strings.HasPrefix(path, "/v1/threads") {
+		if c.Request.Method == http.MethodGet {
+			return apikeys.ScopeAssistantsRead
+		}
+		return apikeys.ScopeAssistantsWrite
+	}
+	if strings.HasPrefix(path, "/v1/batches") {
+		if c.Request.Method == http.MethodGet {
+			return apikeys.ScopeBatchesRead
+		}
+		return apikeys.ScopeBatchesWrite
+	}
+	if strings.HasPrefix(path, "/v1/fine_tuning") {
+		if c.Request.Method == http.MethodGet {
+			return apikeys.ScopeFineTuningRead
+		}
+		return apikeys.ScopeFineTuningWrite
+	}
+	if strings.HasPrefix(path, "/v1/files") {
+		if c.Request.Method == http.MethodGet {
+			return apikeys.ScopeFilesRead
+		}
+		return apikeys.ScopeFilesWrite
+	}
+	return EndpointScope(path)
+}
+
+// EndpointScope 返回调用推理接口所需的权限，path为请求路径，不是推理接口时返回空
+func EndpointScope(path string) string {
+	for _, entry := range scopesByPathSuffix {
+		if strings.HasSuffix(path, entry.suffix) {
+			return entry.scope
+		}
+	}
+	return ""
+}
+
+// requestedModel 返回请求访问的模型名：路径中的模型ID、Azure部署指向的模型或JSON请求体中的model字段
+func requestedModel(c *gin.Context) string {
+	if modelID := strings.TrimPrefix(c.Param("model_id"), "/"); modelID != "" {
+		return modelID
+	}
+	if name := c.Param("deployment"); name != "" {
+		if ws, err := CurrentInstance(c).Workspaces.Get(requestedWorkspaceID(c)); err == nil {
+			if deployment, err := ws.Deployments.GetDeployment(name); err == nil {
+				return deployment.ModelID
+			}
+		}
+		return name
+	}
+	if c.Request.Body == nil || !strings.Contains(c.ContentType(), "json") {
+		return ""
+	}
+
+	// 读取请求体后放回，后续处理器仍可正常绑定
+	body, err := io.ReadAll(c.Request.Body)
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return ""
+	}
+	var req struct {
+		Model string `json:"model"`
+	}
+	if err := json.Unmarshal(body, &req); err != nil {
+		return ""
+	}
+	return req.Model
+}
+
+// redactKey 在错误信息中隐藏密钥的大部分内容
+func redactKey(key string) string {
+	if len(key) <= 8 {
+		return "***"
+	}
+	return key[:3] + "*****" + key[len(key)-4:]
 }
@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"time"
+
+	"RobinPenn974/OpenAI-mocker/api"
+
+	"github.com/gin-gonic/gin"
+)
+
+// InjectFaults 按当前工作区的故障注入规则延迟请求或直接返回错误，需放在WorkspaceRequired之后
+func InjectFaults() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ws, ok := CurrentWorkspace(c)
+		if !ok {
+			c.Next()
+			return
+		}
+
+		rule, matched := ws.Faults.Match(c.Request.URL.Path, requestedModel(c))
+		if !matched {
+			c.Next()
+			return
+		}
+
+		if delay := rule.Delay(); delay > 0 {
+			select {
+			case <-time.After(delay):
+			case <-c.Request.Context().Done():
+				c.Abort()
+				return
+			}
+		}
+		if rule.Status != 0 {
+			c.JSON(rule.Status, api.NewErrorResponse(rule.Message, rule.ErrorType, "", rule.ErrorCode))
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
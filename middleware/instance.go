@@ -0,0 +1,23 @@
+package middleware
+
+import (
+	"RobinPenn974/OpenAI-mocker/instance"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ContextKeyInstance gin上下文中保存当前服务实例的键
+const ContextKeyInstance = "instance"
+
+// WithInstance 将服务实例保存到gin上下文，后续的中间件和处理器从中读取状态，需放在其他中间件之前
+func WithInstance(inst *instance.Instance) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(ContextKeyInstance, inst)
+		c.Next()
+	}
+}
+
+// CurrentInstance 返回当前请求所属的服务实例
+func CurrentInstance(c *gin.Context) *instance.Instance {
+	return c.MustGet(ContextKeyInstance).(*instance.Instance)
+}
@@ -0,0 +1,145 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"RobinPenn974/OpenAI-mocker/api"
+	"RobinPenn974/OpenAI-mocker/provider"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// ContextKeyProviderProfile gin上下文中保存当前请求服务商兼容配置的键
+const ContextKeyProviderProfile = "provider_profile"
+
+// contextKeyProfileModel gin上下文中保存请求模型名的键，处理器绑定请求体后无法再读取模型名
+const contextKeyProfileModel = "provider_profile_model"
+
+// ProviderProfile 为响应添加服务商兼容配置的响应头，并将OpenAI格式的错误响应转换为该服务商的错误格式，
+// 需放在AuthRequired之前，鉴权失败的请求同样使用模型或配置指定的错误格式
+func ProviderProfile() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(contextKeyProfileModel, requestedModel(c))
+		writer := &profileWriter{ResponseWriter: c.Writer, c: c}
+		c.Writer = writer
+		c.Next()
+		writer.finish()
+	}
+}
+
+// FixedProviderProfile 为路由组固定使用指定的服务商兼容配置，如Azure部署风格路由
+func FixedProviderProfile(name string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(ContextKeyProviderProfile, provider.Get(name))
+		c.Next()
+	}
+}
+
+// CurrentProviderProfile 返回当前请求使用的服务商兼容配置，依次使用API密钥、请求的模型和配置中的设置
+func CurrentProviderProfile(c *gin.Context) provider.Profile {
+	if value, exists := c.Get(ContextKeyProviderProfile); exists {
+		if profile, ok := value.(provider.Profile); ok {
+			return profile
+		}
+	}
+
+	name := ""
+	if key, ok := CurrentKey(c); ok {
+		name = key.ProviderProfile
+	}
+	if name == "" {
+		name = modelProviderProfile(c)
+	}
+	if name == "" {
+		name = CurrentInstance(c).Config().Features.ProviderProfile
+	}
+	profile := provider.Get(name)
+	c.Set(ContextKeyProviderProfile, profile)
+	return profile
+}
+
+// modelProviderProfile 返回请求的模型指定的服务商兼容配置，不会自动注册未知模型
+func modelProviderProfile(c *gin.Context) string {
+	modelID := c.GetString(contextKeyProfileModel)
+	if modelID == "" {
+		return ""
+	}
+	ws, err := CurrentInstance(c).Workspaces.Get(requestedWorkspaceID(c))
+	if err != nil {
+		return ""
+	}
+	resolution, err := ws.LookupModel(modelID)
+	if err != nil {
+		return ""
+	}
+	return resolution.Model.ProviderProfile
+}
+
+// profileWriter 在写入响应前添加服务商的响应头，需要转换格式的错误响应先缓存，处理完成后再写入
+type profileWriter struct {
+	gin.ResponseWriter
+	c        *gin.Context
+	prepared bool
+	buffer   *bytes.Buffer
+}
+
+// WriteHeader 记录状态码，状态码有效时准备响应
+func (w *profileWriter) WriteHeader(code int) {
+	if code > 0 {
+		w.prepare(code)
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+// Write 写入响应体，错误响应写入缓存
+func (w *profileWriter) Write(data []byte) (int, error) {
+	w.prepare(w.ResponseWriter.Status())
+	if w.buffer != nil {
+		return w.buffer.Write(data)
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+// WriteString 写入字符串响应体
+func (w *profileWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// prepare 首次写入时添加响应头，错误格式不是OpenAI格式时缓存错误响应
+func (w *profileWriter) prepare(status int) {
+	if w.prepared {
+		return
+	}
+	w.prepared = true
+
+	profile := CurrentProviderProfile(w.c)
+	header := w.Header()
+	for name, value := range profile.Headers {
+		header.Set(name, value)
+	}
+	if profile.RequestIDHeader != "" && header.Get(profile.RequestIDHeader) == "" {
+		header.Set(profile.RequestIDHeader, "req_"+uuid.New().String())
+	}
+	if status >= 400 && profile.ErrorFormat != provider.ErrorFormatOpenAI {
+		w.buffer = &bytes.Buffer{}
+	}
+}
+
+// finish 将缓存的错误响应转换为服务商的错误格式后写入，无法解析为OpenAI格式的错误原样写入
+func (w *profileWriter) finish() {
+	if w.buffer == nil {
+		return
+	}
+	body := w.buffer.Bytes()
+	w.buffer = nil
+
+	var errResp api.ErrorResponse
+	if err := json.Unmarshal(body, &errResp); err == nil && errResp.Error.Type != "" {
+		if converted, err := json.Marshal(CurrentProviderProfile(w.c).FormatError(w.Status(), errResp)); err == nil {
+			body = converted
+		}
+	}
+	w.ResponseWriter.Write(body)
+}
@@ -0,0 +1,118 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"RobinPenn974/OpenAI-mocker/api"
+	"RobinPenn974/OpenAI-mocker/workspace"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HeaderWorkspace 选择工作区的请求头
+const HeaderWorkspace = "X-Mock-Workspace"
+
+// ContextKeyWorkspace gin上下文中保存当前工作区的键
+const ContextKeyWorkspace = "workspace"
+
+// ContextKeyJournalFile gin上下文中保存请求上传的文件的键，处理器设置后写入请求记录
+const ContextKeyJournalFile = "journal_file"
+
+// WorkspaceRequired 为API请求选择工作区并记录请求，需放在AuthRequired之后
+// 绑定了工作区的API密钥只能访问该工作区，否则按 X-Mock-Workspace 头选择，未指定时使用默认工作区
+func WorkspaceRequired() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		header := c.GetHeader(HeaderWorkspace)
+		id := requestedWorkspaceID(c)
+		if header != "" && header != id {
+			c.JSON(http.StatusForbidden, api.NewErrorResponse(fmt.Sprintf("This API key does not have access to workspace `%s`.", header), "invalid_request_error", "", "workspace_access_denied"))
+			c.Abort()
+			return
+		}
+
+		ws, ok := selectWorkspace(c, id)
+		if !ok {
+			c.JSON(http.StatusNotFound, api.NewErrorResponse(fmt.Sprintf("The workspace `%s` does not exist.", id), "invalid_request_error", "", "workspace_not_found"))
+			c.Abort()
+			return
+		}
+
+		start := time.Now()
+		modelID := requestedModel(c)
+		c.Next()
+
+		// 记录请求日志和用量
+		entry := workspace.JournalEntry{
+			Time:       start.UTC(),
+			Method:     c.Request.Method,
+			Path:       c.Request.URL.Path,
+			Model:      modelID,
+			Status:     c.Writer.Status(),
+			DurationMs: time.Since(start).Milliseconds(),
+		}
+		if key, ok := CurrentKey(c); ok {
+			entry.APIKeyID = key.ID
+		}
+		if file, ok := c.Get(ContextKeyJournalFile); ok {
+			if file, ok := file.(workspace.JournalFile); ok {
+				entry.File = &file
+			}
+		}
+		ws.Journal.Record(entry)
+		ws.Usage.Record(modelID, entry.Status)
+	}
+}
+
+// AdminWorkspace 为管理请求按 X-Mock-Workspace 头选择工作区，未指定时使用默认工作区
+func AdminWorkspace() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(HeaderWorkspace)
+		if _, ok := selectWorkspace(c, id); !ok {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": gin.H{
+					"message": "Workspace '" + id + "' not found",
+					"type":    "invalid_request_error",
+				},
+			})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// CurrentWorkspace 返回当前请求所在的工作区
+func CurrentWorkspace(c *gin.Context) (*workspace.Workspace, bool) {
+	value, exists := c.Get(ContextKeyWorkspace)
+	if !exists {
+		return nil, false
+	}
+	ws, ok := value.(*workspace.Workspace)
+	return ws, ok
+}
+
+// requestedWorkspaceID 返回请求的工作区ID，绑定了工作区的API密钥优先于请求头
+func requestedWorkspaceID(c *gin.Context) string {
+	if key, ok := CurrentKey(c); ok && key.Workspace != "" {
+		return key.Workspace
+	}
+	if id := c.GetHeader(HeaderWorkspace); id != "" {
+		return id
+	}
+	return workspace.DefaultID
+}
+
+// selectWorkspace 查找工作区并保存到gin上下文，id为空时使用默认工作区
+func selectWorkspace(c *gin.Context, id string) (*workspace.Workspace, bool) {
+	if id == "" {
+		id = workspace.DefaultID
+	}
+	ws, err := CurrentInstance(c).Workspaces.Get(id)
+	if err != nil {
+		return nil, false
+	}
+	c.Set(ContextKeyWorkspace, ws)
+	return ws, true
+}
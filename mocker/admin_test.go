@@ -0,0 +1,222 @@
+package mocker
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"RobinPenn974/OpenAI-mocker/config"
+	"RobinPenn974/OpenAI-mocker/templates"
+)
+
+func TestReadOnlyAdminRole(t *testing.T) {
+	srv := NewServer(WithAdminCredentials(
+		config.AdminCredential{Name: "ci", Token: "admin-secret", Role: config.AdminRoleAdmin},
+		config.AdminCredential{Name: "dashboard", Token: "readonly-secret", Role: config.AdminRoleReadOnly},
+	))
+	defer srv.Close()
+
+	readOnly := bearer("readonly-secret")
+	preview := map[string]interface{}{
+		"template": map[string]string{"greeting": "draft greeting"},
+		"chat":     chatRequest("mock-gpt-3.5-turbo"),
+	}
+	cases := []struct {
+		method string
+		path   string
+		body   interface{}
+		status int
+	}{
+		{http.MethodGet, "/admin/templates/mock-gpt-3.5-turbo", nil, http.StatusOK},
+		// 预览不修改状态，只读角色也可以调用
+		{http.MethodPost, "/admin/templates/mock-gpt-3.5-turbo/preview", preview, http.StatusOK},
+		{http.MethodPut, "/admin/templates/mock-gpt-3.5-turbo", map[string]string{"greeting": "changed"}, http.StatusForbidden},
+		{http.MethodPost, "/admin/templates/mock-gpt-3.5-turbo/rollback/1", nil, http.StatusForbidden},
+	}
+	for _, tc := range cases {
+		status, body := doJSON(t, srv, tc.method, tc.path, tc.body, readOnly)
+		if status != tc.status {
+			t.Errorf("%s %s: status %d, want %d, body %v", tc.method, tc.path, status, tc.status, body)
+		}
+	}
+
+	if status, body := doJSON(t, srv, http.MethodGet, "/admin/templates/mock-gpt-3.5-turbo", nil, nil); status != http.StatusUnauthorized {
+		t.Errorf("request without admin token: status %d, body %v", status, body)
+	}
+}
+
+func TestGetTemplateKeepsInheritance(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+
+	if err := srv.RegisterTemplate(templates.ResponseTemplate{ModelID: "parent-model", Greeting: "inherited greeting", Default: "inherited default"}); err != nil {
+		t.Fatalf("register parent: %v", err)
+	}
+	if err := srv.RegisterTemplate(templates.ResponseTemplate{ModelID: "child-model", Extends: "parent-model", Prefix: "[child] "}); err != nil {
+		t.Fatalf("register child: %v", err)
+	}
+
+	// 默认返回生效模板，不返回ETag，不能用于条件更新
+	status, header, body := doJSONWithHeader(t, srv, http.MethodGet, "/admin/templates/child-model", nil, nil)
+	if status != http.StatusOK || body["greeting"] != "inherited greeting" || body["prefix"] != "[child] " {
+		t.Fatalf("GET resolved template: status %d, body %v", status, body)
+	}
+	if etag := header.Get("ETag"); etag != "" {
+		t.Fatalf("resolved template returned ETag %q", etag)
+	}
+
+	// 没有保存模板的模型也返回生效模板
+	if status, body := doJSON(t, srv, http.MethodGet, "/admin/templates/no-stored-template", nil, nil); status != http.StatusOK || body["greeting"] == "" {
+		t.Fatalf("GET template of a model without a stored template: status %d, body %v", status, body)
+	}
+
+	// stored=true 返回保存的模板本身，写回后不会把继承的字段保存到子模板中
+	status, header, body = doJSONWithHeader(t, srv, http.MethodGet, "/admin/templates/child-model?stored=true", nil, nil)
+	if status != http.StatusOK || header.Get("ETag") == "" {
+		t.Fatalf("GET stored template: status %d, ETag %q, body %v", status, header.Get("ETag"), body)
+	}
+	if body["greeting"] != "" || body["extends"] != "parent-model" {
+		t.Fatalf("GET stored template returned inherited fields: %v", body)
+	}
+	body["prefix"] = "[edited] "
+	status, _, resp := doJSONWithHeader(t, srv, http.MethodPut, "/admin/templates/child-model", body, http.Header{"If-Match": {header.Get("ETag")}})
+	if status != http.StatusOK {
+		t.Fatalf("PUT with If-Match: status %d, body %v", status, resp)
+	}
+	stored, _ := srv.Workspace().Templates.StoredTemplate("child-model")
+	if stored.Greeting != "" || stored.Default != "" || stored.Extends != "parent-model" || stored.Prefix != "[edited] " {
+		t.Fatalf("inheritance broken after GET/PUT round trip: %+v", stored)
+	}
+
+	if status, body := doJSON(t, srv, http.MethodGet, "/admin/templates/no-stored-template?stored=true", nil, nil); status != http.StatusNotFound {
+		t.Fatalf("GET missing stored template: status %d, body %v", status, body)
+	}
+}
+
+func TestPreviewValidatesDraft(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+
+	cases := []struct {
+		name  string
+		draft map[string]interface{}
+	}{
+		{"negative weight", map[string]interface{}{"variants": map[string]interface{}{
+			"greeting": []map[string]interface{}{{"text": "a", "weight": -1}, {"text": "b", "weight": -2}},
+		}}},
+		{"unknown parent", map[string]interface{}{"extends": "no-such-template"}},
+		{"invalid generator", map[string]interface{}{"variants": map[string]interface{}{
+			"default": []map[string]interface{}{{"generator": map[string]string{"type": "unknown"}}},
+		}}},
+	}
+	for _, tc := range cases {
+		status, body := doJSON(t, srv, http.MethodPost, "/admin/templates/mock-gpt-3.5-turbo/preview", map[string]interface{}{
+			"template": tc.draft,
+			"chat":     chatRequest("mock-gpt-3.5-turbo"),
+		}, nil)
+		if status != http.StatusBadRequest {
+			t.Errorf("%s: status %d, want 400, body %v", tc.name, status, body)
+		}
+	}
+
+	// 不支持预览 Responses API 请求
+	status, body := doJSON(t, srv, http.MethodPost, "/admin/templates/mock-gpt-3.5-turbo/preview", map[string]interface{}{
+		"responses": map[string]string{"input": "hi"},
+	}, nil)
+	if status != http.StatusBadRequest || !strings.Contains(errorMessage(body), "/v1/responses") {
+		t.Errorf("responses preview: status %d, body %v", status, body)
+	}
+}
+
+// chatContent 返回聊天响应中第一条消息的内容
+func chatContent(body map[string]interface{}) string {
+	choices, _ := body["choices"].([]interface{})
+	if len(choices) == 0 {
+		return ""
+	}
+	choice, _ := choices[0].(map[string]interface{})
+	message, _ := choice["message"].(map[string]interface{})
+	content, _ := message["content"].(string)
+	return content
+}
+
+func TestLoadModelTemplateVariants(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+
+	// 无效的候选回复与 PUT /admin/templates 一样被拒绝，模型不会被加载
+	status, body := doJSON(t, srv, http.MethodPost, "/admin/models/load", map[string]interface{}{
+		"model_id":   "invalid-variants",
+		"model_type": "llm",
+		"template": map[string]interface{}{
+			"variants": map[string]interface{}{"greeting": []map[string]interface{}{{"text": "a", "weight": -1}}},
+		},
+	}, nil)
+	if status != http.StatusBadRequest {
+		t.Fatalf("load with a negative weight: status %d, body %v", status, body)
+	}
+	if _, err := srv.Workspace().Models.GetModel("invalid-variants"); err == nil {
+		t.Fatal("model loaded despite an invalid template")
+	}
+
+	status, body = doJSON(t, srv, http.MethodPost, "/admin/models/load", map[string]interface{}{
+		"model_id":   "variant-model",
+		"model_type": "llm",
+		"template": map[string]interface{}{
+			"greeting": "plain greeting",
+			"variants": map[string]interface{}{"greeting": []map[string]interface{}{{"text": "variant greeting", "weight": 3}}},
+		},
+	}, nil)
+	if status != http.StatusOK {
+		t.Fatalf("load with variants: status %d, body %v", status, body)
+	}
+	stored, _ := srv.Workspace().Templates.StoredTemplate("variant-model")
+	if len(stored.Variants["greeting"]) != 1 || stored.Variants["greeting"][0].Weight != 3 {
+		t.Fatalf("variants not stored: %+v", stored.Variants)
+	}
+	if status, body := doJSON(t, srv, http.MethodPost, "/v1/chat/completions", chatRequest("variant-model"), nil); status != http.StatusOK || !strings.HasSuffix(chatContent(body), "variant greeting") {
+		t.Fatalf("chat with variants: status %d, content %q", status, chatContent(body))
+	}
+}
+
+func TestLoadModelTemplateLocalized(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+
+	// 缺少语言后缀或使用不支持的语言时被拒绝，模型不会被加载
+	for _, localized := range []map[string]string{{"greeting": "你好"}, {"greeting.xx": "hello"}, {"greeting.zh": ""}} {
+		status, body := doJSON(t, srv, http.MethodPost, "/admin/models/load", map[string]interface{}{
+			"model_id":   "invalid-localized",
+			"model_type": "llm",
+			"template":   map[string]interface{}{"localized": localized},
+		}, nil)
+		if status != http.StatusBadRequest {
+			t.Errorf("load with localized %v: status %d, body %v", localized, status, body)
+		}
+	}
+	if _, err := srv.Workspace().Models.GetModel("invalid-localized"); err == nil {
+		t.Fatal("model loaded despite an invalid template")
+	}
+
+	status, body := doJSON(t, srv, http.MethodPost, "/admin/models/load", map[string]interface{}{
+		"model_id":   "localized-model",
+		"model_type": "llm",
+		"template": map[string]interface{}{
+			"greeting":  "plain greeting",
+			"localized": map[string]string{"greeting.zh": "中文问候"},
+		},
+	}, nil)
+	if status != http.StatusOK {
+		t.Fatalf("load with localized text: status %d, body %v", status, body)
+	}
+	request := map[string]interface{}{
+		"model":    "localized-model",
+		"messages": []map[string]string{{"role": "user", "content": "你好"}},
+	}
+	if status, body := doJSON(t, srv, http.MethodPost, "/v1/chat/completions", request, nil); status != http.StatusOK || !strings.HasSuffix(chatContent(body), "中文问候") {
+		t.Fatalf("chat in Chinese: status %d, content %q", status, chatContent(body))
+	}
+	if status, body := doJSON(t, srv, http.MethodPost, "/v1/chat/completions", chatRequest("localized-model"), nil); status != http.StatusOK || !strings.HasSuffix(chatContent(body), "plain greeting") {
+		t.Fatalf("chat in English: status %d, content %q", status, chatContent(body))
+	}
+}
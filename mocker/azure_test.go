@@ -0,0 +1,66 @@
+package mocker
+
+import (
+	"net/http"
+	"testing"
+
+	"RobinPenn974/OpenAI-mocker/azure"
+	"RobinPenn974/OpenAI-mocker/models"
+)
+
+func TestAzureAPIVersion(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+	if err := srv.RegisterDeployment(models.Deployment{Name: "chat", ModelID: "mock-gpt-3.5-turbo"}); err != nil {
+		t.Fatalf("register deployment: %v", err)
+	}
+
+	cases := []struct {
+		path   string
+		status int
+		code   string
+	}{
+		{"/openai/deployments/chat/chat/completions", http.StatusBadRequest, "MissingApiVersionParameter"},
+		{"/openai/deployments/chat/chat/completions?api-version=2099-01-01", http.StatusNotFound, "404"},
+		{"/openai/deployments/missing/chat/completions?api-version=2024-10-21", http.StatusNotFound, "DeploymentNotFound"},
+		{"/openai/deployments/chat/chat/completions?api-version=2024-10-21", http.StatusOK, ""},
+	}
+	for _, tc := range cases {
+		status, body := doJSON(t, srv, http.MethodPost, tc.path, chatRequest(""), nil)
+		if status != tc.status || (tc.code != "" && errorCode(body) != tc.code) {
+			t.Errorf("%s: status %d, body %v, want %d %s", tc.path, status, body, tc.status, tc.code)
+		}
+	}
+
+	// 部署的模型不支持的接口
+	status, body := doJSON(t, srv, http.MethodPost, "/openai/deployments/chat/embeddings?api-version=2024-10-21", map[string]string{"input": "hi"}, nil)
+	if status != http.StatusBadRequest || errorCode(body) != "OperationNotSupported" {
+		t.Errorf("embeddings on a chat deployment: status %d, body %v", status, body)
+	}
+}
+
+func TestAzureContentFilter(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+	if err := srv.RegisterDeployment(models.Deployment{Name: "chat", ModelID: "mock-gpt-3.5-turbo"}); err != nil {
+		t.Fatalf("register deployment: %v", err)
+	}
+	if _, err := srv.AddContentFilterRule(azure.ContentFilterRule{Pattern: "forbidden", Category: azure.CategoryViolence}); err != nil {
+		t.Fatalf("add rule: %v", err)
+	}
+
+	const path = "/openai/deployments/chat/chat/completions?api-version=2024-10-21"
+	status, body := doJSON(t, srv, http.MethodPost, path, chatRequest(""), nil)
+	if status != http.StatusOK {
+		t.Fatalf("safe prompt: status %d, body %v", status, body)
+	}
+	if results, _ := body["prompt_filter_results"].([]interface{}); len(results) != 1 {
+		t.Fatalf("missing prompt_filter_results: %v", body)
+	}
+
+	request := map[string]interface{}{"messages": []map[string]string{{"role": "user", "content": "something forbidden"}}}
+	status, body = doJSON(t, srv, http.MethodPost, path, request, nil)
+	if status != http.StatusBadRequest || errorCode(body) != "content_filter" {
+		t.Fatalf("filtered prompt: status %d, body %v", status, body)
+	}
+}
@@ -0,0 +1,78 @@
+package mocker
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"RobinPenn974/OpenAI-mocker/faults"
+	"RobinPenn974/OpenAI-mocker/files"
+)
+
+// uploadBatchInput 上传批处理输入文件，每个 custom_id 对应一个聊天请求
+func uploadBatchInput(t *testing.T, srv *Server, customIDs ...string) string {
+	t.Helper()
+
+	var lines []string
+	for _, customID := range customIDs {
+		line, err := json.Marshal(map[string]interface{}{
+			"custom_id": customID,
+			"method":    http.MethodPost,
+			"url":       "/v1/chat/completions",
+			"body":      chatRequest("mock-gpt-3.5-turbo"),
+		})
+		if err != nil {
+			t.Fatalf("encode batch line: %v", err)
+		}
+		lines = append(lines, string(line))
+	}
+	file, err := srv.Workspace().Files.Create("batch.jsonl", files.PurposeBatch, []byte(strings.Join(lines, "\n")))
+	if err != nil {
+		t.Fatalf("upload batch input: %v", err)
+	}
+	return file.ID
+}
+
+func TestBatchCustomIDFault(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+
+	// 只注入延迟的规则排在前面，不能挡住后面针对同一请求的错误规则
+	delay, err := srv.AddFault(faults.Rule{CustomID: "request-2", DelayMs: 1, Count: 1})
+	if err != nil {
+		t.Fatalf("add delay rule: %v", err)
+	}
+	if _, err := srv.AddFault(faults.Rule{CustomID: "request-2", Status: http.StatusInternalServerError, Message: "injected failure", Count: 1}); err != nil {
+		t.Fatalf("add error rule: %v", err)
+	}
+
+	inputFileID := uploadBatchInput(t, srv, "request-1", "request-2", "request-3")
+	status, body := doJSON(t, srv, http.MethodPost, "/v1/batches", map[string]string{
+		"input_file_id":     inputFileID,
+		"endpoint":          "/v1/chat/completions",
+		"completion_window": "24h",
+	}, nil)
+	if status != http.StatusOK || body["status"] != "completed" {
+		t.Fatalf("create batch: status %d, body %v", status, body)
+	}
+	counts, _ := body["request_counts"].(map[string]interface{})
+	if counts["completed"] != float64(2) || counts["failed"] != float64(1) {
+		t.Fatalf("request counts %v, want 2 completed and 1 failed", counts)
+	}
+
+	errorFileID, _ := body["error_file_id"].(string)
+	_, content, err := srv.Workspace().Files.Content(errorFileID)
+	if err != nil {
+		t.Fatalf("read error file: %v", err)
+	}
+	if !strings.Contains(string(content), `"request-2"`) || !strings.Contains(string(content), "injected failure") {
+		t.Fatalf("error file does not contain the injected failure: %s", content)
+	}
+
+	// 批处理不使用延迟规则，剩余次数保持不变
+	rules := srv.Workspace().Faults.ListRules()
+	if len(rules) != 1 || rules[0].ID != delay.ID || rules[0].Count != 1 {
+		t.Fatalf("unexpected fault rules after the batch: %+v", rules)
+	}
+}
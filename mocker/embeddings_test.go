@@ -0,0 +1,122 @@
+package mocker
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"math"
+	"net/http"
+	"testing"
+)
+
+// embeddingVectors 返回响应中每条输入的向量，base64格式按小端序float32解码
+func embeddingVectors(t *testing.T, body map[string]interface{}) [][]float64 {
+	t.Helper()
+	data, _ := body["data"].([]interface{})
+	vectors := make([][]float64, 0, len(data))
+	for _, item := range data {
+		switch embedding := item.(map[string]interface{})["embedding"].(type) {
+		case []interface{}:
+			vector := make([]float64, len(embedding))
+			for i, x := range embedding {
+				vector[i] = x.(float64)
+			}
+			vectors = append(vectors, vector)
+		case string:
+			raw, err := base64.StdEncoding.DecodeString(embedding)
+			if err != nil {
+				t.Fatalf("decode base64 embedding: %v", err)
+			}
+			vector := make([]float64, len(raw)/4)
+			for i := range vector {
+				vector[i] = float64(math.Float32frombits(binary.LittleEndian.Uint32(raw[i*4:])))
+			}
+			vectors = append(vectors, vector)
+		default:
+			t.Fatalf("unexpected embedding %T", embedding)
+		}
+	}
+	return vectors
+}
+
+func TestEmbeddingInputs(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+
+	cases := []struct {
+		name  string
+		input interface{}
+		count int
+	}{
+		{"string", "hello", 1},
+		{"strings", []string{"hello", "world", "again"}, 3},
+		{"tokens", []int{15339, 1917}, 1},
+		{"token arrays", [][]int{{15339}, {1917, 0}}, 2},
+	}
+	for _, tc := range cases {
+		request := map[string]interface{}{"model": "mock-text-embedding-3-small", "input": tc.input}
+		status, body := doJSON(t, srv, http.MethodPost, "/v1/embeddings", request, nil)
+		if status != http.StatusOK {
+			t.Errorf("%s: status %d, body %v", tc.name, status, body)
+			continue
+		}
+		vectors := embeddingVectors(t, body)
+		if len(vectors) != tc.count || len(vectors[0]) != 1536 {
+			t.Errorf("%s: %d vectors of %d dimensions, want %d of 1536", tc.name, len(vectors), len(vectors[0]), tc.count)
+		}
+	}
+}
+
+func TestEmbeddingDimensionsAndEncoding(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+
+	request := map[string]interface{}{"model": "mock-text-embedding-3-small", "input": "hello", "dimensions": 256}
+	status, body := doJSON(t, srv, http.MethodPost, "/v1/embeddings", request, nil)
+	if status != http.StatusOK {
+		t.Fatalf("dimensions: status %d, body %v", status, body)
+	}
+	floats := embeddingVectors(t, body)[0]
+	if len(floats) != 256 {
+		t.Fatalf("%d dimensions, want 256", len(floats))
+	}
+
+	// base64 编码的向量与 float 格式的向量在float32精度内一致
+	request["encoding_format"] = "base64"
+	status, body = doJSON(t, srv, http.MethodPost, "/v1/embeddings", request, nil)
+	if status != http.StatusOK {
+		t.Fatalf("base64: status %d, body %v", status, body)
+	}
+	decoded := embeddingVectors(t, body)[0]
+	if len(decoded) != 256 {
+		t.Fatalf("base64: %d dimensions, want 256", len(decoded))
+	}
+	for i := range decoded {
+		if math.Abs(decoded[i]-floats[i]) > 1e-6 {
+			t.Fatalf("dimension %d: base64 %v, float %v", i, decoded[i], floats[i])
+		}
+	}
+
+	cases := []struct {
+		name    string
+		request map[string]interface{}
+		param   string
+	}{
+		{"too many dimensions", map[string]interface{}{"model": "mock-text-embedding-3-small", "input": "a", "dimensions": 4096}, "dimensions"},
+		{"zero dimensions", map[string]interface{}{"model": "mock-text-embedding-3-small", "input": "a", "dimensions": 0}, "dimensions"},
+		{"dimensions unsupported", map[string]interface{}{"model": "mock-embedding-ada-002", "input": "a", "dimensions": 256}, ""},
+		{"unknown encoding", map[string]interface{}{"model": "mock-text-embedding-3-small", "input": "a", "encoding_format": "hex"}, "encoding_format"},
+		{"empty input", map[string]interface{}{"model": "mock-text-embedding-3-small", "input": []string{}}, ""},
+		{"object input", map[string]interface{}{"model": "mock-text-embedding-3-small", "input": map[string]string{"text": "a"}}, ""},
+	}
+	for _, tc := range cases {
+		status, body := doJSON(t, srv, http.MethodPost, "/v1/embeddings", tc.request, nil)
+		errBody, _ := body["error"].(map[string]interface{})
+		if status != http.StatusBadRequest || errBody == nil {
+			t.Errorf("%s: status %d, body %v", tc.name, status, body)
+			continue
+		}
+		if param, _ := errBody["param"].(string); param != tc.param {
+			t.Errorf("%s: param %q, want %q", tc.name, param, tc.param)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package mocker
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"RobinPenn974/OpenAI-mocker/config"
+	"RobinPenn974/OpenAI-mocker/files"
+)
+
+// uploadTrainingFile 上传包含count个对话样本的微调训练文件
+func uploadTrainingFile(t *testing.T, srv *Server, count int) string {
+	t.Helper()
+
+	lines := make([]string, count)
+	for i := range lines {
+		line, err := json.Marshal(map[string]interface{}{
+			"messages": []map[string]string{
+				{"role": "user", "content": "hello"},
+				{"role": "assistant", "content": "hi there"},
+			},
+		})
+		if err != nil {
+			t.Fatalf("encode training example: %v", err)
+		}
+		lines[i] = string(line)
+	}
+	file, err := srv.Workspace().Files.Create("train.jsonl", files.PurposeFineTune, []byte(strings.Join(lines, "\n")))
+	if err != nil {
+		t.Fatalf("upload training file: %v", err)
+	}
+	return file.ID
+}
+
+func TestFineTunedModelUsableWithoutPollingJob(t *testing.T) {
+	cfg := config.Default()
+	cfg.FineTuning.DurationMs = 50
+	srv := NewServer(WithConfig(cfg))
+	defer srv.Close()
+
+	status, body := doJSON(t, srv, http.MethodPost, "/v1/fine_tuning/jobs", map[string]string{
+		"model":         "mock-gpt-3.5-turbo",
+		"training_file": uploadTrainingFile(t, srv, 10),
+		"suffix":        "unpolled",
+	}, nil)
+	if status != http.StatusOK || body["status"] != "validating_files" {
+		t.Fatalf("create job: status %d, body %v", status, body)
+	}
+	jobID, _ := body["id"].(string)
+	model := "ft:mock-gpt-3.5-turbo:mock-org:unpolled:" + jobID[len("ftjob-"):len("ftjob-")+8]
+
+	// 任务完成前模型不存在
+	if status, _ := doJSON(t, srv, http.MethodGet, "/v1/models/"+model, nil, nil); status != http.StatusNotFound {
+		t.Fatalf("GET fine-tuned model before the job finished: status %d", status)
+	}
+
+	// 完成后不查询任务，直接查找、列出和调用模型
+	time.Sleep(100 * time.Millisecond)
+	if status, body := doJSON(t, srv, http.MethodGet, "/v1/models/"+model, nil, nil); status != http.StatusOK {
+		t.Fatalf("GET fine-tuned model: status %d, body %v", status, body)
+	}
+	if status, body := doJSON(t, srv, http.MethodPost, "/v1/chat/completions", chatRequest(model), nil); status != http.StatusOK {
+		t.Fatalf("chat with fine-tuned model: status %d, body %v", status, body)
+	}
+
+	status, body = doJSON(t, srv, http.MethodGet, "/v1/fine_tuning/jobs/"+jobID, nil, nil)
+	if status != http.StatusOK || body["status"] != "succeeded" || body["fine_tuned_model"] != model {
+		t.Fatalf("job after the model was used: status %d, body %v", status, body)
+	}
+}
+
+func TestFineTunedModelListed(t *testing.T) {
+	cfg := config.Default()
+	cfg.FineTuning.DurationMs = 50
+	srv := NewServer(WithConfig(cfg))
+	defer srv.Close()
+
+	status, body := doJSON(t, srv, http.MethodPost, "/v1/fine_tuning/jobs", map[string]string{
+		"model":         "mock-gpt-3.5-turbo",
+		"training_file": uploadTrainingFile(t, srv, 10),
+	}, nil)
+	if status != http.StatusOK {
+		t.Fatalf("create job: status %d, body %v", status, body)
+	}
+	jobID, _ := body["id"].(string)
+
+	time.Sleep(100 * time.Millisecond)
+	_, body = doJSON(t, srv, http.MethodGet, "/v1/models", nil, nil)
+	data, _ := body["data"].([]interface{})
+	for _, item := range data {
+		model, _ := item.(map[string]interface{})
+		if id, _ := model["id"].(string); strings.HasPrefix(id, "ft:mock-gpt-3.5-turbo:mock-org::"+jobID[len("ftjob-"):len("ftjob-")+8]) {
+			return
+		}
+	}
+	t.Fatalf("fine-tuned model of %s not listed: %v", jobID, data)
+}
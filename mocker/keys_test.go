@@ -0,0 +1,160 @@
+package mocker
+
+import (
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"RobinPenn974/OpenAI-mocker/apikeys"
+)
+
+// bearer 返回使用指定密钥的请求头
+func bearer(secret string) http.Header {
+	return http.Header{"Authorization": {"Bearer " + secret}}
+}
+
+// errorCode 返回错误响应中的错误码
+func errorCode(body map[string]interface{}) interface{} {
+	errObj, _ := body["error"].(map[string]interface{})
+	return errObj["code"]
+}
+
+// errorMessage 返回错误响应中的 message
+func errorMessage(body map[string]interface{}) string {
+	errObj, _ := body["error"].(map[string]interface{})
+	message, _ := errObj["message"].(string)
+	return message
+}
+
+func TestKeyScopes(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+
+	_, secret, err := srv.CreateKey(apikeys.KeyOptions{Name: "chat-only", Scopes: []string{apikeys.ScopeChatWrite}})
+	if err != nil {
+		t.Fatalf("create key: %v", err)
+	}
+
+	status, body := doJSON(t, srv, http.MethodPost, "/v1/chat/completions", chatRequest("mock-gpt-3.5-turbo"), bearer(secret))
+	if status != http.StatusOK {
+		t.Fatalf("chat with chat:write: status %d, body %v", status, body)
+	}
+
+	embedding := map[string]interface{}{"model": "mock-embedding-ada-002", "input": "hello"}
+	status, body = doJSON(t, srv, http.MethodPost, "/v1/embeddings", embedding, bearer(secret))
+	if status != http.StatusForbidden || errorCode(body) != "insufficient_permissions" {
+		t.Fatalf("embeddings without embeddings:write: status %d, body %v", status, body)
+	}
+
+	status, body = doJSON(t, srv, http.MethodGet, "/v1/models", nil, bearer(secret))
+	if status != http.StatusForbidden || errorCode(body) != "insufficient_permissions" {
+		t.Fatalf("models without models:read: status %d, body %v", status, body)
+	}
+
+	status, body = doJSON(t, srv, http.MethodPost, "/v1/chat/completions", chatRequest("mock-gpt-3.5-turbo"), nil)
+	if status != http.StatusUnauthorized {
+		t.Fatalf("request without key: status %d, body %v", status, body)
+	}
+}
+
+func TestKeyAllowedModels(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+
+	if err := srv.SetAlias("friendly-name", "deepseek-reasoner"); err != nil {
+		t.Fatalf("set alias: %v", err)
+	}
+	_, gptSecret, err := srv.CreateKey(apikeys.KeyOptions{Name: "gpt-only", AllowedModels: []string{"mock-gpt-*"}})
+	if err != nil {
+		t.Fatalf("create key: %v", err)
+	}
+	_, reasonerSecret, err := srv.CreateKey(apikeys.KeyOptions{Name: "reasoner-only", AllowedModels: []string{"deepseek-reasoner"}})
+	if err != nil {
+		t.Fatalf("create key: %v", err)
+	}
+
+	cases := []struct {
+		secret string
+		model  string
+		status int
+	}{
+		{gptSecret, "mock-gpt-3.5-turbo", http.StatusOK},
+		{gptSecret, "deepseek-reasoner", http.StatusForbidden},
+		// 白名单按别名解析后的模型检查
+		{gptSecret, "friendly-name", http.StatusForbidden},
+		{reasonerSecret, "friendly-name", http.StatusOK},
+		// 省略模型名时按默认模型检查，不能绕过白名单
+		{gptSecret, "", http.StatusOK},
+		{reasonerSecret, "", http.StatusForbidden},
+	}
+	for _, tc := range cases {
+		status, body := doJSON(t, srv, http.MethodPost, "/v1/chat/completions", chatRequest(tc.model), bearer(tc.secret))
+		if status != tc.status {
+			t.Errorf("model %q: status %d, want %d, body %v", tc.model, status, tc.status, body)
+		}
+	}
+}
+
+func TestRevokedAndExpiredKeys(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+
+	revoked, revokedSecret, err := srv.CreateKey(apikeys.KeyOptions{Name: "revoked"})
+	if err != nil {
+		t.Fatalf("create key: %v", err)
+	}
+	if _, err := srv.instance.Keys.RevokeKey(revoked.ID); err != nil {
+		t.Fatalf("revoke key: %v", err)
+	}
+	past := time.Now().Add(-time.Minute)
+	_, expiredSecret, err := srv.CreateKey(apikeys.KeyOptions{Name: "expired", ExpiresAt: &past})
+	if err != nil {
+		t.Fatalf("create key: %v", err)
+	}
+
+	cases := []struct {
+		secret string
+		code   string
+	}{
+		{revokedSecret, "api_key_revoked"},
+		{expiredSecret, "api_key_expired"},
+		{"sk-mock-unknown-key-value", "invalid_api_key"},
+	}
+	for _, tc := range cases {
+		status, body := doJSON(t, srv, http.MethodPost, "/v1/chat/completions", chatRequest("mock-gpt-3.5-turbo"), bearer(tc.secret))
+		if status != http.StatusUnauthorized || errorCode(body) != tc.code {
+			t.Errorf("want 401 %s, got status %d, body %v", tc.code, status, body)
+		}
+	}
+}
+
+func TestKeyUsageIsFlushed(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+
+	key, secret, err := srv.CreateKey(apikeys.KeyOptions{Name: "counted"})
+	if err != nil {
+		t.Fatalf("create key: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		if status, body := doJSON(t, srv, http.MethodPost, "/v1/chat/completions", chatRequest("mock-gpt-3.5-turbo"), bearer(secret)); status != http.StatusOK {
+			t.Fatalf("status %d, body %v", status, body)
+		}
+	}
+	if err := srv.instance.Keys.Flush(); err != nil {
+		t.Fatalf("flush: %v", err)
+	}
+
+	// 从文件重新加载，确认使用情况已写入
+	reloaded := apikeys.NewKeyStore(filepath.Join(srv.tempDir, "auth_data"), "")
+	for _, k := range reloaded.ListKeys() {
+		if k.ID == key.ID {
+			if k.UsageCount != 3 || k.LastUsedAt == nil {
+				t.Fatalf("usage not flushed: count %d, last used %v", k.UsageCount, k.LastUsedAt)
+			}
+			return
+		}
+	}
+	t.Fatalf("key %s not found after reload", key.ID)
+}
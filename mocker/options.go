@@ -0,0 +1,42 @@
+package mocker
+
+import "RobinPenn974/OpenAI-mocker/config"
+
+// Option 配置模拟服务器的选项
+type Option func(*options)
+
+// options 模拟服务器的配置
+type options struct {
+	config      *config.Config
+	stateDir    string
+	credentials []config.AdminCredential
+	adminRoutes bool
+}
+
+// WithConfig 使用启动配置，可预置模型、模板、密钥、故障注入规则和延迟配置，监听地址和TLS设置会被忽略
+func WithConfig(cfg *config.Config) Option {
+	return func(o *options) {
+		o.config = cfg
+	}
+}
+
+// WithStateDir 指定状态目录，未指定时使用临时目录并在Close时删除
+func WithStateDir(dir string) Option {
+	return func(o *options) {
+		o.stateDir = dir
+	}
+}
+
+// WithAdminCredentials 为管理API设置凭据，未设置时管理API无需鉴权
+func WithAdminCredentials(credentials ...config.AdminCredential) Option {
+	return func(o *options) {
+		o.credentials = append(o.credentials, credentials...)
+	}
+}
+
+// WithoutAdminRoutes 不注册 /admin 路由，只能通过Go方法修改状态
+func WithoutAdminRoutes() Option {
+	return func(o *options) {
+		o.adminRoutes = false
+	}
+}
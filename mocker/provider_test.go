@@ -0,0 +1,81 @@
+package mocker
+
+import (
+	"net/http"
+	"testing"
+
+	"RobinPenn974/OpenAI-mocker/apikeys"
+	"RobinPenn974/OpenAI-mocker/config"
+	"RobinPenn974/OpenAI-mocker/models"
+	"RobinPenn974/OpenAI-mocker/provider"
+)
+
+func TestProviderProfilePrecedence(t *testing.T) {
+	cfg := config.Default()
+	cfg.Features.ProviderProfile = provider.ProfileDeepSeek
+	srv := NewServer(WithConfig(cfg))
+	defer srv.Close()
+	if err := srv.RegisterModel(models.ModelInfo{ID: "together-model", ModelType: models.ModelTypeLLM, ProviderProfile: provider.ProfileTogether}); err != nil {
+		t.Fatalf("register model: %v", err)
+	}
+	_, groqSecret, err := srv.CreateKey(apikeys.KeyOptions{Name: "groq", ProviderProfile: provider.ProfileGroq})
+	if err != nil {
+		t.Fatalf("create key: %v", err)
+	}
+	_, plainSecret, err := srv.CreateKey(apikeys.KeyOptions{Name: "plain"})
+	if err != nil {
+		t.Fatalf("create key: %v", err)
+	}
+
+	// 依次使用API密钥、请求的模型和配置中的服务商兼容配置
+	cases := []struct {
+		name   string
+		model  string
+		header http.Header
+		check  func(header http.Header, body map[string]interface{}) bool
+	}{
+		{"config", "mock-gpt-3.5-turbo", bearer(plainSecret), func(_ http.Header, body map[string]interface{}) bool {
+			return body["system_fingerprint"] == "fp_mock_deepseek"
+		}},
+		{"model", "together-model", bearer(plainSecret), func(_ http.Header, body map[string]interface{}) bool {
+			choice := body["choices"].([]interface{})[0].(map[string]interface{})
+			_, hasPrompt := body["prompt"]
+			return choice["finish_reason"] == "eos" && hasPrompt
+		}},
+		{"key", "together-model", bearer(groqSecret), func(header http.Header, body map[string]interface{}) bool {
+			_, hasGroq := body["x_groq"]
+			return hasGroq && header.Get("x-groq-region") == "mock"
+		}},
+	}
+	for _, tc := range cases {
+		status, header, body := doJSONWithHeader(t, srv, http.MethodPost, "/v1/chat/completions", chatRequest(tc.model), tc.header)
+		if status != http.StatusOK || !tc.check(header, body) {
+			t.Errorf("%s: status %d, body %v", tc.name, status, body)
+		}
+	}
+}
+
+func TestProviderProfileErrors(t *testing.T) {
+	for _, tc := range []struct {
+		profile string
+		check   func(body map[string]interface{}) bool
+	}{
+		{provider.ProfileOpenAI, func(body map[string]interface{}) bool { return errorCode(body) == "model_not_found" }},
+		{provider.ProfileVLLM, func(body map[string]interface{}) bool {
+			return body["object"] == "error" && body["type"] == "NotFoundError" && body["code"] == float64(http.StatusNotFound)
+		}},
+		{provider.ProfileOpenRouter, func(body map[string]interface{}) bool {
+			errBody, _ := body["error"].(map[string]interface{})
+			return errBody != nil && errBody["code"] == float64(http.StatusNotFound)
+		}},
+	} {
+		cfg := config.Default()
+		cfg.Features.ProviderProfile = tc.profile
+		srv := NewServer(WithConfig(cfg))
+		status, body := doJSON(t, srv, http.MethodPost, "/v1/chat/completions", chatRequest("missing-model"), nil)
+		srv.Close()
+		if status != http.StatusNotFound || !tc.check(body) {
+			t.Errorf("%s: status %d, body %v", tc.profile, status, body)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package mocker
+
+import (
+	"net/http"
+	"testing"
+)
+
+// resultIndexes 返回重排序结果中各文档的原始下标
+func resultIndexes(body map[string]interface{}) []int {
+	results, _ := body["results"].([]interface{})
+	indexes := make([]int, len(results))
+	for i, item := range results {
+		result, _ := item.(map[string]interface{})
+		index, _ := result["index"].(float64)
+		indexes[i] = int(index)
+	}
+	return indexes
+}
+
+func TestRerankTopN(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+
+	documents := []string{
+		"Our office is closed on public holidays.",
+		"To reset a forgotten password, open the login page and click reset password.",
+		"",
+		"You can reset your password from the account settings.",
+	}
+	cases := []struct {
+		topN int
+		want []int
+	}{
+		// 分数相同时保持原始顺序，空文档得分为0
+		{0, []int{1, 3, 0, 2}},
+		{2, []int{1, 3}},
+		{10, []int{1, 3, 0, 2}},
+	}
+	for _, tc := range cases {
+		status, body := doJSON(t, srv, http.MethodPost, "/v1/rerank", map[string]interface{}{
+			"model":     "mock-rerank-v1",
+			"query":     "how to reset a forgotten password",
+			"documents": documents,
+			"top_n":     tc.topN,
+		}, nil)
+		if status != http.StatusOK {
+			t.Fatalf("top_n %d: status %d, body %v", tc.topN, status, body)
+		}
+		got := resultIndexes(body)
+		if len(got) != len(tc.want) {
+			t.Fatalf("top_n %d: indexes %v, want %v", tc.topN, got, tc.want)
+		}
+		for i := range got {
+			if got[i] != tc.want[i] {
+				t.Fatalf("top_n %d: indexes %v, want %v", tc.topN, got, tc.want)
+			}
+		}
+	}
+
+	if status, body := doJSON(t, srv, http.MethodPost, "/v1/rerank", map[string]interface{}{
+		"model": "mock-rerank-v1", "query": "", "documents": documents,
+	}, nil); status != http.StatusBadRequest {
+		t.Fatalf("empty query: status %d, body %v", status, body)
+	}
+}
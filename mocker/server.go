@@ -0,0 +1,221 @@
+// Package mocker 在进程内启动模拟服务器，用于在 go test 中代替Docker容器
+//
+//	srv := mocker.NewServer()
+//	defer srv.Close()
+//	client := openai.NewClient(option.WithBaseURL(srv.URL + "/v1"))
+//
+// 每个服务器拥有独立的状态，同一个测试进程中可以并发运行多个服务器
+package mocker
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+
+	"RobinPenn974/OpenAI-mocker/api"
+	"RobinPenn974/OpenAI-mocker/apikeys"
+	"RobinPenn974/OpenAI-mocker/azure"
+	"RobinPenn974/OpenAI-mocker/config"
+	"RobinPenn974/OpenAI-mocker/faults"
+	"RobinPenn974/OpenAI-mocker/instance"
+	"RobinPenn974/OpenAI-mocker/models"
+	"RobinPenn974/OpenAI-mocker/routes"
+	"RobinPenn974/OpenAI-mocker/templates"
+	"RobinPenn974/OpenAI-mocker/workspace"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Server 进程内的模拟服务器，Go方法修改的是默认工作区
+type Server struct {
+	// URL 服务器的基础地址，如 http://127.0.0.1:51234，OpenAI接口位于 URL + "/v1"
+	URL string
+
+	httpServer *httptest.Server
+	instance   *instance.Instance
+	tempDir    string // 自动创建的临时状态目录，Close时删除
+}
+
+// NewServer 创建并启动模拟服务器，与 httptest.NewServer 一样在失败时panic
+func NewServer(opts ...Option) *Server {
+	o := options{adminRoutes: true}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	cfg := config.Default()
+	if o.config != nil {
+		cfg = copyConfig(o.config)
+	}
+	if o.stateDir != "" {
+		cfg.StateDir = o.stateDir
+	}
+	cfg.Admin.Credentials = append(append([]config.AdminCredential(nil), cfg.Admin.Credentials...), o.credentials...)
+	if err := cfg.Validate(); err != nil {
+		panic("mocker: invalid config: " + err.Error())
+	}
+
+	tempDir := ""
+	if cfg.StateDir == "" {
+		dir, err := os.MkdirTemp("", "openai-mocker-*")
+		if err != nil {
+			panic("mocker: failed to create state directory: " + err.Error())
+		}
+		tempDir = dir
+		cfg.StateDir = dir
+	}
+
+	inst, err := instance.New(cfg)
+	if err != nil {
+		if tempDir != "" {
+			os.RemoveAll(tempDir)
+		}
+		panic("mocker: failed to create instance: " + err.Error())
+	}
+	engine := gin.New()
+	engine.Use(gin.Recovery())
+	routes.SetupRoutes(engine, inst)
+	if o.adminRoutes {
+		routes.SetupAdminRoutes(engine, inst)
+	}
+
+	httpServer := httptest.NewServer(engine)
+	return &Server{
+		URL:        httpServer.URL,
+		httpServer: httpServer,
+		instance:   inst,
+		tempDir:    tempDir,
+	}
+}
+
+// copyConfig 复制调用方的配置，Validate 会修改配置中的切片元素，共享同一配置的服务器之间不能互相影响
+func copyConfig(cfg *config.Config) *config.Config {
+	copied := *cfg
+	copied.Models = append([]models.ModelInfo(nil), cfg.Models...)
+	copied.Templates = append([]templates.ResponseTemplate(nil), cfg.Templates...)
+	copied.Keys = append([]api.CreateKeyRequest(nil), cfg.Keys...)
+	copied.Faults = append([]faults.Rule(nil), cfg.Faults...)
+	copied.Latency = append([]config.LatencyProfile(nil), cfg.Latency...)
+	return &copied
+}
+
+// Close 关闭服务器，并删除自动创建的临时状态目录
+func (s *Server) Close() {
+	s.httpServer.Close()
+	s.instance.Close()
+	if s.tempDir != "" {
+		os.RemoveAll(s.tempDir)
+	}
+}
+
+// Client 返回访问该服务器的HTTP客户端
+func (s *Server) Client() *http.Client {
+	return s.httpServer.Client()
+}
+
+// Workspace 返回默认工作区，可直接操作其中的各项状态
+func (s *Server) Workspace() *workspace.Workspace {
+	return s.instance.DefaultWorkspace()
+}
+
+// CreateWorkspace 创建工作区，请求通过 X-Mock-Workspace 头或绑定的API密钥选择工作区
+func (s *Server) CreateWorkspace(id, base string) (*workspace.Workspace, error) {
+	return s.instance.Workspaces.Create(id, base)
+}
+
+// DeleteWorkspace 删除工作区及其存储目录，仍有未吊销的API密钥绑定到该工作区时返回错误
+func (s *Server) DeleteWorkspace(id string) error {
+	return s.instance.DeleteWorkspace(id)
+}
+
+// RegisterModel 校验并注册模型，未设置模型类型时按LLM处理，未设置的能力元数据使用默认值补全
+func (s *Server) RegisterModel(model models.ModelInfo) error {
+	if model.ID == "" {
+		return errors.New("model id is required")
+	}
+	if model.ModelType == "" {
+		model.ModelType = models.ModelTypeLLM
+	}
+	if err := models.ValidateModel(model); err != nil {
+		return err
+	}
+	return s.Workspace().Models.RegisterModel(model)
+}
+
+// UnloadModel 卸载模型，并清理其关联的模板、别名、部署和规则
+func (s *Server) UnloadModel(modelID string) error {
+	ws := s.Workspace()
+	if err := ws.Models.UnloadModel(modelID); err != nil {
+		return err
+	}
+	ws.CleanupModel(modelID)
+	return nil
+}
+
+// RegisterTemplate 注册或更新模型的响应模板
+func (s *Server) RegisterTemplate(template templates.ResponseTemplate) error {
+	return s.Workspace().Templates.RegisterTemplate(template)
+}
+
+// SetAlias 设置模型别名，请求别名时响应中回显别名
+func (s *Server) SetAlias(alias, target string) error {
+	return s.Workspace().Router.SetAlias(alias, target)
+}
+
+// AddModelPattern 添加模型名匹配模式
+func (s *Server) AddModelPattern(pattern models.ModelPattern) (models.ModelPattern, error) {
+	return s.Workspace().Router.AddPattern(pattern)
+}
+
+// RegisterDeployment 注册Azure部署
+func (s *Server) RegisterDeployment(deployment models.Deployment) error {
+	return s.Workspace().Deployments.RegisterDeployment(deployment)
+}
+
+// AddContentFilterRule 添加Azure内容过滤规则
+func (s *Server) AddContentFilterRule(rule azure.ContentFilterRule) (azure.ContentFilterRule, error) {
+	return s.Workspace().ContentFilter.AddRule(rule)
+}
+
+// AddFault 添加故障注入规则
+func (s *Server) AddFault(rule faults.Rule) (faults.Rule, error) {
+	return s.Workspace().Faults.AddRule(rule)
+}
+
+// RemoveFault 删除故障注入规则
+func (s *Server) RemoveFault(id string) error {
+	return s.Workspace().Faults.DeleteRule(id)
+}
+
+// ClearFaults 删除所有故障注入规则
+func (s *Server) ClearFaults() {
+	s.Workspace().Faults.RemoveAllRules()
+}
+
+// CreateKey 创建API密钥，返回密钥记录和完整密钥，创建第一个密钥后所有API请求都需要鉴权
+func (s *Server) CreateKey(opts apikeys.KeyOptions) (apikeys.Key, string, error) {
+	return s.instance.Keys.CreateKey(opts)
+}
+
+// Reload 重新加载所有工作区的模板文件和 WithConfig 指定的配置文件，在测试中直接修改文件后调用
+func (s *Server) Reload() error {
+	return s.instance.Reload()
+}
+
+// Journal 返回默认工作区最近的API请求日志
+func (s *Server) Journal() []workspace.JournalEntry {
+	return s.Workspace().Journal.List()
+}
+
+// Usage 返回默认工作区的用量计数
+func (s *Server) Usage() workspace.UsageSummary {
+	return s.Workspace().Usage.Summary()
+}
+
+// ResetJournal 清空默认工作区的请求日志和用量计数
+func (s *Server) ResetJournal() {
+	ws := s.Workspace()
+	ws.Journal.Clear()
+	ws.Usage.Reset()
+}
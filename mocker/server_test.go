@@ -0,0 +1,187 @@
+package mocker
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"os"
+	"sync"
+	"testing"
+
+	"RobinPenn974/OpenAI-mocker/config"
+	"RobinPenn974/OpenAI-mocker/models"
+	"RobinPenn974/OpenAI-mocker/workspace"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestMain(m *testing.M) {
+	gin.SetMode(gin.TestMode)
+	os.Exit(m.Run())
+}
+
+// doJSON 发送JSON请求并解析响应，返回状态码和响应体
+func doJSON(t *testing.T, srv *Server, method, path string, body interface{}, header http.Header) (int, map[string]interface{}) {
+	t.Helper()
+	status, _, result := doJSONWithHeader(t, srv, method, path, body, header)
+	return status, result
+}
+
+// doJSONWithHeader 与 doJSON 相同，同时返回响应头
+func doJSONWithHeader(t *testing.T, srv *Server, method, path string, body interface{}, header http.Header) (int, http.Header, map[string]interface{}) {
+	t.Helper()
+
+	var reader *bytes.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			t.Fatalf("encode request: %v", err)
+		}
+		reader = bytes.NewReader(data)
+	} else {
+		reader = bytes.NewReader(nil)
+	}
+	req, err := http.NewRequest(method, srv.URL+path, reader)
+	if err != nil {
+		t.Fatalf("new request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for name, values := range header {
+		req.Header[name] = values
+	}
+
+	resp, err := srv.Client().Do(req)
+	if err != nil {
+		t.Fatalf("%s %s: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+
+	var result map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		t.Fatalf("%s %s: decode response: %v", method, path, err)
+	}
+	return resp.StatusCode, resp.Header, result
+}
+
+// chatRequest 构造一个最简单的Chat请求
+func chatRequest(model string) map[string]interface{} {
+	return map[string]interface{}{
+		"model":    model,
+		"messages": []map[string]string{{"role": "user", "content": "hello"}},
+	}
+}
+
+func TestNewServerAndClose(t *testing.T) {
+	srv := NewServer()
+	if srv.tempDir == "" {
+		t.Fatal("expected a temporary state directory")
+	}
+	if _, err := os.Stat(srv.tempDir); err != nil {
+		t.Fatalf("state directory: %v", err)
+	}
+
+	status, body := doJSON(t, srv, http.MethodGet, "/v1/models", nil, nil)
+	if status != http.StatusOK {
+		t.Fatalf("GET /v1/models: status %d, body %v", status, body)
+	}
+	if data, _ := body["data"].([]interface{}); len(data) == 0 {
+		t.Fatalf("GET /v1/models: expected built-in models, got %v", body)
+	}
+
+	srv.Close()
+	if _, err := os.Stat(srv.tempDir); !os.IsNotExist(err) {
+		t.Fatalf("state directory not removed after Close: %v", err)
+	}
+}
+
+func TestChatCompletion(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+
+	status, body := doJSON(t, srv, http.MethodPost, "/v1/chat/completions", chatRequest("mock-gpt-3.5-turbo"), nil)
+	if status != http.StatusOK {
+		t.Fatalf("status %d, body %v", status, body)
+	}
+	if body["object"] != "chat.completion" || body["model"] != "mock-gpt-3.5-turbo" {
+		t.Fatalf("unexpected response: %v", body)
+	}
+	choices, _ := body["choices"].([]interface{})
+	if len(choices) != 1 {
+		t.Fatalf("expected one choice, got %v", body["choices"])
+	}
+	message, _ := choices[0].(map[string]interface{})["message"].(map[string]interface{})
+	if content, _ := message["content"].(string); content == "" {
+		t.Fatalf("expected non-empty content, got %v", message)
+	}
+
+	status, body = doJSON(t, srv, http.MethodPost, "/v1/chat/completions", chatRequest("no-such-model"), nil)
+	if status != http.StatusNotFound {
+		t.Fatalf("unknown model: status %d, body %v", status, body)
+	}
+}
+
+func TestWorkspaceIsolation(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+
+	ws, err := srv.CreateWorkspace("isolated", workspace.DefaultID)
+	if err != nil {
+		t.Fatalf("create workspace: %v", err)
+	}
+	if err := ws.Models.RegisterModel(models.ModelInfo{ID: "only-in-workspace", ModelType: models.ModelTypeLLM}); err != nil {
+		t.Fatalf("register model in workspace: %v", err)
+	}
+	if err := srv.RegisterModel(models.ModelInfo{ID: "only-in-default"}); err != nil {
+		t.Fatalf("register model in default workspace: %v", err)
+	}
+
+	inWorkspace := http.Header{"X-Mock-Workspace": {"isolated"}}
+	cases := []struct {
+		model  string
+		header http.Header
+		status int
+	}{
+		{"only-in-workspace", inWorkspace, http.StatusOK},
+		{"only-in-workspace", nil, http.StatusNotFound},
+		{"only-in-default", nil, http.StatusOK},
+		{"only-in-default", inWorkspace, http.StatusNotFound},
+		// 创建时复制的基础工作区模型在两个工作区中都可用
+		{"mock-gpt-3.5-turbo", inWorkspace, http.StatusOK},
+	}
+	for _, tc := range cases {
+		status, body := doJSON(t, srv, http.MethodPost, "/v1/chat/completions", chatRequest(tc.model), tc.header)
+		if status != tc.status {
+			t.Errorf("model %s, header %v: status %d, want %d, body %v", tc.model, tc.header, status, tc.status, body)
+		}
+	}
+
+	if got := len(ws.Journal.List()); got != 3 {
+		t.Errorf("workspace journal has %d entries, want 3", got)
+	}
+	if got := len(srv.Journal()); got != 2 {
+		t.Errorf("default journal has %d entries, want 2", got)
+	}
+}
+
+func TestSharedConfigIsNotModified(t *testing.T) {
+	cfg := config.Default()
+	cfg.Models = []models.ModelInfo{{ID: "seeded-model"}}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			srv := NewServer(WithConfig(cfg))
+			defer srv.Close()
+			if _, err := srv.Workspace().Models.GetModel("seeded-model"); err != nil {
+				t.Errorf("seeded model: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if cfg.Models[0].ModelType != "" {
+		t.Fatalf("NewServer modified the caller's config: model_type %q", cfg.Models[0].ModelType)
+	}
+}
@@ -0,0 +1,96 @@
+package mocker
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"RobinPenn974/OpenAI-mocker/templates"
+)
+
+// streamContents 发送流式Chat请求，返回每个数据块的content
+func streamContents(t *testing.T, srv *Server, input string) []string {
+	t.Helper()
+	request := map[string]interface{}{
+		"model":    "mock-gpt-3.5-turbo",
+		"stream":   true,
+		"messages": []map[string]string{{"role": "user", "content": input}},
+	}
+	data, _ := json.Marshal(request)
+	resp, err := srv.Client().Post(srv.URL+"/v1/chat/completions", "application/json", bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("stream request: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("stream request: status %d", resp.StatusCode)
+	}
+
+	var contents []string
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		payload, ok := strings.CutPrefix(scanner.Text(), "data:")
+		payload = strings.TrimSpace(payload)
+		if !ok || payload == "" || payload == "[DONE]" {
+			continue
+		}
+		var chunk struct {
+			Choices []struct {
+				Delta struct {
+					Content *string `json:"content"`
+				} `json:"delta"`
+			} `json:"choices"`
+		}
+		if err := json.Unmarshal([]byte(payload), &chunk); err != nil {
+			t.Fatalf("decode chunk %q: %v", payload, err)
+		}
+		if len(chunk.Choices) > 0 && chunk.Choices[0].Delta.Content != nil {
+			contents = append(contents, *chunk.Choices[0].Delta.Content)
+		}
+	}
+	return contents
+}
+
+func TestStreamChunksByLanguage(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+	template := templates.ResponseTemplate{
+		ModelID:  "mock-gpt-3.5-turbo",
+		Prefix:   " ",
+		Greeting: "Hello there, this reply is streamed two words at a time.",
+		Localized: map[string]string{
+			"greeting.zh": "你好，这段回复按每五个字符切分成数据块发送。",
+			"greeting.ja": "こんにちは、この返信は五文字ずつ送信されます。",
+		},
+	}
+	if err := srv.RegisterTemplate(template); err != nil {
+		t.Fatalf("register template: %v", err)
+	}
+
+	// 中文和日文每块5个字符，其他语言每块2个词，拼接后与完整回复一致
+	cases := []struct {
+		input   string
+		want    string
+		measure func(piece string) int
+		size    int
+	}{
+		{"hello", template.Prefix + template.Greeting, func(piece string) int { return len(strings.Fields(piece)) }, 2},
+		{"你好", template.Prefix + template.Localized["greeting.zh"], utf8.RuneCountInString, 5},
+		{"こんにちは", template.Prefix + template.Localized["greeting.ja"], utf8.RuneCountInString, 5},
+	}
+	for _, tc := range cases {
+		contents := streamContents(t, srv, tc.input)
+		if joined := strings.Join(contents, ""); joined != tc.want || len(contents) < 2 {
+			t.Fatalf("%s: chunks %q, want %q", tc.input, contents, tc.want)
+		}
+		for _, piece := range contents[:len(contents)-1] {
+			if tc.measure(piece) != tc.size {
+				t.Fatalf("%s: chunk %q has size %d, want %d", tc.input, piece, tc.measure(piece), tc.size)
+			}
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+)
+
+// 模型可用的接口
+const (
+	EndpointChatCompletions = "chat.completions"
+	EndpointCompletions     = "completions"
+	EndpointEmbeddings      = "embeddings"
+	EndpointRerank          = "rerank"
+	EndpointScore           = "score"
+)
+
+// 输入模态
+const (
+	ModalityText  = "text"
+	ModalityImage = "image"
+	ModalityAudio = "audio"
+)
+
+// 各模型类型允许使用的接口
+var endpointsByType = map[string][]string{
+	ModelTypeLLM:       {EndpointChatCompletions, EndpointCompletions},
+	ModelTypeEmbedding: {EndpointEmbeddings},
+	ModelTypeRerank:    {EndpointRerank, EndpointScore},
+}
+
+// 各模型类型的默认上下文窗口
+var contextWindowByType = map[string]int{
+	ModelTypeLLM:       8192,
+	ModelTypeEmbedding: 8192,
+	ModelTypeRerank:    8192,
+}
+
+// ModelTypeForEndpoint 返回默认可以使用指定接口的模型类型
+func ModelTypeForEndpoint(endpoint string) (string, bool) {
+	for _, modelType := range []string{ModelTypeLLM, ModelTypeEmbedding, ModelTypeRerank} {
+		for _, allowed := range endpointsByType[modelType] {
+			if allowed == endpoint {
+				return modelType, true
+			}
+		}
+	}
+	return "", false
+}
+
+// NormalizeModelType 规范化模型类型，"chat" 作为 "llm" 的别名
+func NormalizeModelType(modelType string) string {
+	if modelType == "chat" {
+		return ModelTypeLLM
+	}
+	return modelType
+}
+
+// ApplyDefaults 按模型类型为未设置的能力元数据补全默认值
+func ApplyDefaults(model *ModelInfo) {
+	model.ModelType = NormalizeModelType(model.ModelType)
+	if model.Object == "" {
+		model.Object = "model"
+	}
+
+	if len(model.Endpoints) == 0 {
+		model.Endpoints = append([]string(nil), endpointsByType[model.ModelType]...)
+	}
+	if len(model.Modalities) == 0 {
+		model.Modalities = []string{ModalityText}
+	}
+
+	if model.ModelType == ModelTypeEmbedding {
+		spec := knownEmbeddingSpec(model.ID)
+		if model.EmbeddingDimensions == 0 {
+			model.EmbeddingDimensions = spec.Dimensions
+			model.SupportsDimensions = model.SupportsDimensions || spec.SupportsDimensions
+		}
+		if model.ContextWindow == 0 {
+			model.ContextWindow = spec.MaxInputTokens
+		}
+	}
+
+	if model.ContextWindow == 0 {
+		model.ContextWindow = contextWindowByType[model.ModelType]
+	}
+	if model.ModelType == ModelTypeLLM && model.MaxOutputTokens == 0 {
+		model.MaxOutputTokens = min(4096, model.ContextWindow)
+	}
+}
+
+// ValidateModel 校验模型类型和能力元数据是否合法
+func ValidateModel(model ModelInfo) error {
+	modelType := NormalizeModelType(model.ModelType)
+	allowedEndpoints, ok := endpointsByType[modelType]
+	if !ok {
+		return fmt.Errorf("invalid model_type '%s': must be one of llm, embedding, rerank", model.ModelType)
+	}
+
+	for _, endpoint := range model.Endpoints {
+		if !containsString(allowedEndpoints, endpoint) {
+			return fmt.Errorf("endpoint '%s' is not available for %s models (allowed: %s)", endpoint, modelType, strings.Join(allowedEndpoints, ", "))
+		}
+	}
+
+	for _, modality := range model.Modalities {
+		switch modality {
+		case ModalityText, ModalityImage, ModalityAudio:
+		default:
+			return fmt.Errorf("invalid modality '%s': must be one of text, image, audio", modality)
+		}
+	}
+
+	if model.ContextWindow < 0 || model.MaxOutputTokens < 0 || model.EmbeddingDimensions < 0 {
+		return fmt.Errorf("context_window, max_output_tokens and embedding_dimensions must not be negative")
+	}
+	if model.ContextWindow > 0 && model.MaxOutputTokens > model.ContextWindow {
+		return fmt.Errorf("max_output_tokens (%d) must not exceed context_window (%d)", model.MaxOutputTokens, model.ContextWindow)
+	}
+
+	if modelType != ModelTypeLLM && (model.SupportsTools || model.SupportsJSONSchema || model.SupportsReasoning || model.MaxOutputTokens > 0) {
+		return fmt.Errorf("supports_tools, supports_json_schema, supports_reasoning and max_output_tokens only apply to llm models")
+	}
+	if modelType != ModelTypeEmbedding && (model.EmbeddingDimensions > 0 || model.SupportsDimensions) {
+		return fmt.Errorf("embedding_dimensions and supports_dimensions only apply to embedding models")
+	}
+
+	return nil
+}
+
+// SupportsEndpoint 检查模型是否可以在指定接口上使用
+func (m ModelInfo) SupportsEndpoint(endpoint string) bool {
+	return containsString(m.Endpoints, endpoint)
+}
+
+// SupportsModality 检查模型是否支持指定的输入模态
+func (m ModelInfo) SupportsModality(modality string) bool {
+	return containsString(m.Modalities, modality)
+}
+
+// containsString 检查字符串切片是否包含指定值
+func containsString(values []string, target string) bool {
+	for _, value := range values {
+		if value == target {
+			return true
+		}
+	}
+	return false
+}
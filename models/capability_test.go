@@ -0,0 +1,74 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestApplyDefaults(t *testing.T) {
+	cases := []struct {
+		model         ModelInfo
+		endpoints     []string
+		contextWindow int
+		maxOutput     int
+		dimensions    int
+	}{
+		{ModelInfo{ID: "custom-llm", ModelType: "chat"}, []string{EndpointChatCompletions, EndpointCompletions}, 8192, 4096, 0},
+		{ModelInfo{ID: "small-llm", ModelType: ModelTypeLLM, ContextWindow: 2048}, []string{EndpointChatCompletions, EndpointCompletions}, 2048, 2048, 0},
+		{ModelInfo{ID: "my-text-embedding-3-large", ModelType: ModelTypeEmbedding}, []string{EndpointEmbeddings}, 8192, 0, 3072},
+		{ModelInfo{ID: "custom-embedding", ModelType: ModelTypeEmbedding, EmbeddingDimensions: 384}, []string{EndpointEmbeddings}, 8192, 0, 384},
+		{ModelInfo{ID: "custom-rerank", ModelType: ModelTypeRerank}, []string{EndpointRerank, EndpointScore}, 8192, 0, 0},
+		{ModelInfo{ID: "chat-only", ModelType: ModelTypeLLM, Endpoints: []string{EndpointChatCompletions}}, []string{EndpointChatCompletions}, 8192, 4096, 0},
+	}
+	for _, tc := range cases {
+		model := tc.model
+		ApplyDefaults(&model)
+		if !reflect.DeepEqual(model.Endpoints, tc.endpoints) || model.ContextWindow != tc.contextWindow ||
+			model.MaxOutputTokens != tc.maxOutput || model.EmbeddingDimensions != tc.dimensions {
+			t.Errorf("%s: %+v", tc.model.ID, model)
+		}
+		if !model.SupportsModality(ModalityText) || model.SupportsModality(ModalityImage) {
+			t.Errorf("%s: modalities %v, want text only", tc.model.ID, model.Modalities)
+		}
+	}
+}
+
+func TestValidateModel(t *testing.T) {
+	cases := []struct {
+		name    string
+		model   ModelInfo
+		wantErr bool
+	}{
+		{"llm", ModelInfo{ModelType: ModelTypeLLM, SupportsTools: true, Modalities: []string{ModalityText, ModalityImage}}, false},
+		{"chat alias", ModelInfo{ModelType: "chat"}, false},
+		{"embedding", ModelInfo{ModelType: ModelTypeEmbedding, EmbeddingDimensions: 256, SupportsDimensions: true}, false},
+		{"unknown type", ModelInfo{ModelType: "vision"}, true},
+		{"endpoint of another type", ModelInfo{ModelType: ModelTypeEmbedding, Endpoints: []string{EndpointChatCompletions}}, true},
+		{"unknown modality", ModelInfo{ModelType: ModelTypeLLM, Modalities: []string{"video"}}, true},
+		{"negative context window", ModelInfo{ModelType: ModelTypeLLM, ContextWindow: -1}, true},
+		{"output exceeds context", ModelInfo{ModelType: ModelTypeLLM, ContextWindow: 1024, MaxOutputTokens: 2048}, true},
+		{"tools on rerank", ModelInfo{ModelType: ModelTypeRerank, SupportsTools: true}, true},
+		{"dimensions on llm", ModelInfo{ModelType: ModelTypeLLM, EmbeddingDimensions: 256}, true},
+	}
+	for _, tc := range cases {
+		if err := ValidateModel(tc.model); (err != nil) != tc.wantErr {
+			t.Errorf("%s: error %v, want error %v", tc.name, err, tc.wantErr)
+		}
+	}
+}
+
+func TestGetEmbeddingSpec(t *testing.T) {
+	cases := []struct {
+		model ModelInfo
+		want  EmbeddingSpec
+	}{
+		{ModelInfo{ID: "mock-text-embedding-3-small"}, EmbeddingSpec{Dimensions: 1536, SupportsDimensions: true, MaxInputTokens: 8192}},
+		{ModelInfo{ID: "mock-embedding-ada-002"}, EmbeddingSpec{Dimensions: 1536, MaxInputTokens: 8192}},
+		{ModelInfo{ID: "custom", EmbeddingDimensions: 768, SupportsDimensions: true, ContextWindow: 512}, EmbeddingSpec{Dimensions: 768, SupportsDimensions: true, MaxInputTokens: 512}},
+	}
+	for _, tc := range cases {
+		if got := GetEmbeddingSpec(tc.model); got != tc.want {
+			t.Errorf("%s: %+v, want %+v", tc.model.ID, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"RobinPenn974/OpenAI-mocker/atomicfile"
+)
+
+// 默认部署文件名
+const defaultDeploymentFile = "deployments.json"
+
+// Deployment 表示Azure OpenAI风格的部署，将部署名映射到已注册的模型
+type Deployment struct {
+	Name    string `json:"name"`     // 部署名称，对应URL中的 {deployment}
+	ModelID string `json:"model_id"` // 实际使用的模型ID
+}
+
+// DeploymentRegistry 保存Azure部署，部署持久化到文件
+type DeploymentRegistry struct {
+	deployments map[string]Deployment
+	mu          sync.RWMutex
+	storageDir  string
+	filename    string
+}
+
+// NewDeploymentRegistry 创建一个新的部署存储
+func NewDeploymentRegistry(storageDir, filename string) *DeploymentRegistry {
+	if storageDir == "" {
+		storageDir = defaultModelDir
+	}
+	if filename == "" {
+		filename = defaultDeploymentFile
+	}
+
+	registry := &DeploymentRegistry{
+		deployments: make(map[string]Deployment),
+		storageDir:  storageDir,
+		filename:    filename,
+	}
+
+	// 加载部署
+	if err := registry.loadDeployments(); err != nil {
+		fmt.Printf("Error loading deployments: %v\n", err)
+	}
+
+	return registry
+}
+
+// RegisterDeployment 注册或更新一个部署
+func (d *DeploymentRegistry) RegisterDeployment(deployment Deployment) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	deployments := d.copyDeployments()
+	deployments[deployment.Name] = deployment
+	return d.saveDeployments(deployments)
+}
+
+// GetDeployment 获取指定名称的部署
+func (d *DeploymentRegistry) GetDeployment(name string) (Deployment, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	deployment, exists := d.deployments[name]
+	if !exists {
+		return Deployment{}, errors.New("deployment not found")
+	}
+	return deployment, nil
+}
+
+// ResolveDeployment 将部署名解析为模型，部署指向的模型名按别名和匹配模式解析
+// 未注册的部署名如果能解析为某个模型，则直接使用该模型
+func (d *DeploymentRegistry) ResolveDeployment(router *Router, name string) (Resolution, error) {
+	modelID := name
+	if deployment, err := d.GetDeployment(name); err == nil {
+		modelID = deployment.ModelID
+	}
+	return router.Lookup(modelID)
+}
+
+// ListDeployments 列出所有已注册的部署
+func (d *DeploymentRegistry) ListDeployments() []Deployment {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	result := make([]Deployment, 0, len(d.deployments))
+	for _, deployment := range d.deployments {
+		result = append(result, deployment)
+	}
+	return result
+}
+
+// DeleteDeployment 删除指定名称的部署
+func (d *DeploymentRegistry) DeleteDeployment(name string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if _, exists := d.deployments[name]; !exists {
+		return errors.New("deployment not found")
+	}
+
+	deployments := d.copyDeployments()
+	delete(deployments, name)
+	return d.saveDeployments(deployments)
+}
+
+// DeleteDeploymentsForModel 删除指向指定模型的所有部署
+func (d *DeploymentRegistry) DeleteDeploymentsForModel(modelID string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	deployments := d.copyDeployments()
+	for name, deployment := range deployments {
+		if deployment.ModelID == modelID {
+			delete(deployments, name)
+		}
+	}
+	return d.saveDeployments(deployments)
+}
+
+// copyDeployments 复制当前部署，调用方需持有锁
+func (d *DeploymentRegistry) copyDeployments() map[string]Deployment {
+	deployments := make(map[string]Deployment, len(d.deployments))
+	for name, deployment := range d.deployments {
+		deployments[name] = deployment
+	}
+	return deployments
+}
+
+// saveDeployments 将部署写入文件，成功后更新内存，调用方需持有写锁
+func (d *DeploymentRegistry) saveDeployments(deployments map[string]Deployment) error {
+	data, err := json.MarshalIndent(deployments, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding deployments: %v", err)
+	}
+
+	if err := os.MkdirAll(d.storageDir, 0755); err != nil {
+		return fmt.Errorf("error creating model directory: %v", err)
+	}
+	if err := atomicfile.WriteFile(filepath.Join(d.storageDir, d.filename), data, 0644); err != nil {
+		return fmt.Errorf("error writing deployment file: %v", err)
+	}
+
+	d.deployments = deployments
+	return nil
+}
+
+// loadDeployments 从文件加载部署，文件不存在时没有部署
+func (d *DeploymentRegistry) loadDeployments() error {
+	data, err := os.ReadFile(filepath.Join(d.storageDir, d.filename))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error reading deployment file: %v", err)
+	}
+
+	deployments := make(map[string]Deployment)
+	if err := json.Unmarshal(data, &deployments); err != nil {
+		return fmt.Errorf("error parsing deployment file: %v", err)
+	}
+	d.deployments = deployments
+	return nil
+}
@@ -0,0 +1,46 @@
+package models
+
+import "strings"
+
+// EmbeddingSpec 描述Embedding模型的向量规格
+type EmbeddingSpec struct {
+	Dimensions         int  // 默认维度，同时也是允许的最大维度
+	SupportsDimensions bool // 是否支持通过dimensions参数缩减维度
+	MaxInputTokens     int  // 单条输入允许的最大token数
+}
+
+// 已知Embedding模型的规格，按模型ID中包含的名称匹配
+var knownEmbeddingSpecs = []struct {
+	name string
+	spec EmbeddingSpec
+}{
+	{"text-embedding-3-large", EmbeddingSpec{Dimensions: 3072, SupportsDimensions: true, MaxInputTokens: 8192}},
+	{"text-embedding-3-small", EmbeddingSpec{Dimensions: 1536, SupportsDimensions: true, MaxInputTokens: 8192}},
+	{"ada-002", EmbeddingSpec{Dimensions: 1536, SupportsDimensions: false, MaxInputTokens: 8192}},
+}
+
+// defaultEmbeddingSpec 未知Embedding模型使用的规格
+var defaultEmbeddingSpec = EmbeddingSpec{Dimensions: 1536, SupportsDimensions: false, MaxInputTokens: 8192}
+
+// GetEmbeddingSpec 获取模型的向量规格
+func GetEmbeddingSpec(model ModelInfo) EmbeddingSpec {
+	spec := knownEmbeddingSpec(model.ID)
+	if model.EmbeddingDimensions > 0 {
+		spec.Dimensions = model.EmbeddingDimensions
+		spec.SupportsDimensions = model.SupportsDimensions
+	}
+	if model.ContextWindow > 0 {
+		spec.MaxInputTokens = model.ContextWindow
+	}
+	return spec
+}
+
+// knownEmbeddingSpec 按模型ID匹配已知模型的向量规格
+func knownEmbeddingSpec(modelID string) EmbeddingSpec {
+	for _, known := range knownEmbeddingSpecs {
+		if strings.Contains(modelID, known.name) {
+			return known.spec
+		}
+	}
+	return defaultEmbeddingSpec
+}
@@ -1,10 +1,5 @@
 package models
 
-import (
-	"errors"
-	"sync"
-)
-
 // 模型类型常量
 const (
 	ModelTypeLLM       = "llm"
@@ -19,111 +14,24 @@ type ModelInfo struct {
 	Created   int64  `json:"created"`
 	OwnedBy   string `json:"owned_by"`
 	ModelType string `json:"model_type"` // llm, embedding, rerank
-}
-
-// 全局模型存储
-var (
-	models     = make(map[string]ModelInfo)
-	modelMutex sync.RWMutex
-)
-
-// RegisterModel 注册一个模型到系统中
-func RegisterModel(model ModelInfo) {
-	modelMutex.Lock()
-	defer modelMutex.Unlock()
-	models[model.ID] = model
-}
-
-// GetModel 获取指定ID的模型信息
-func GetModel(id string) (ModelInfo, error) {
-	modelMutex.RLock()
-	defer modelMutex.RUnlock()
-
-	model, exists := models[id]
-	if !exists {
-		return ModelInfo{}, errors.New("model not found")
-	}
-	return model, nil
-}
-
-// ListModels 列出所有已注册的模型
-func ListModels() []ModelInfo {
-	modelMutex.RLock()
-	defer modelMutex.RUnlock()
-
-	result := make([]ModelInfo, 0, len(models))
-	for _, model := range models {
-		result = append(result, model)
-	}
-	return result
-}
-
-// UnloadModel 卸载指定ID的模型
-func UnloadModel(id string) error {
-	modelMutex.Lock()
-	defer modelMutex.Unlock()
-
-	// 检查模型是否存在
-	if _, exists := models[id]; !exists {
-		return errors.New("model not found")
-	}
-
-	delete(models, id)
-	return nil
-}
-
-// UnloadAllModels 卸载所有模型
-func UnloadAllModels() {
-	modelMutex.Lock()
-	defer modelMutex.Unlock()
-
-	// 清空模型映射
-	models = make(map[string]ModelInfo)
-}
-
-// InitDefaultModels 初始化默认内置模型
-func InitDefaultModels() {
-	// 注册LLM模型
-	RegisterModel(ModelInfo{
-		ID:        "mock-gpt-3.5-turbo",
-		Object:    "model",
-		Created:   1677610602,
-		OwnedBy:   "openai-mocker",
-		ModelType: ModelTypeLLM,
-	})
-
-	RegisterModel(ModelInfo{
-		ID:        "mock-davinci-002",
-		Object:    "model",
-		Created:   1649880484,
-		OwnedBy:   "openai-mocker",
-		ModelType: ModelTypeLLM,
-	})
-
-	// 注册推理模型
-	RegisterModel(ModelInfo{
-		ID:        "deepseek-reasoner",
-		Object:    "model",
-		Created:   1714207996,
-		OwnedBy:   "openai-mocker",
-		ModelType: ModelTypeLLM,
-	})
-
-	// 注册Embedding模型
-	RegisterModel(ModelInfo{
-		ID:        "mock-embedding-ada-002",
-		Object:    "model",
-		Created:   1671217299,
-		OwnedBy:   "openai-mocker",
-		ModelType: ModelTypeEmbedding,
-	})
 
-	// 注册Rerank模型
-	RegisterModel(ModelInfo{
-		ID:        "mock-rerank-v1",
-		Object:    "model",
-		Created:   1709486145,
-		OwnedBy:   "openai-mocker",
-		ModelType: ModelTypeRerank,
-	})
+	// 能力元数据，未设置的字段在注册时按模型类型和已知模型补全
+	ContextWindow       int      `json:"context_window,omitempty"`       // 上下文窗口（token数）
+	MaxOutputTokens     int      `json:"max_output_tokens,omitempty"`    // 单次最多生成的token数
+	Endpoints           []string `json:"endpoints,omitempty"`            // 支持的接口
+	Modalities          []string `json:"modalities,omitempty"`           // 支持的输入模态：text, image, audio
+	SupportsTools       bool     `json:"supports_tools,omitempty"`       // 是否支持工具调用
+	SupportsJSONSchema  bool     `json:"supports_json_schema,omitempty"` // 是否支持 json_schema 结构化输出
+	SupportsReasoning   bool     `json:"supports_reasoning,omitempty"`   // 是否为推理模型
+	EmbeddingDimensions int      `json:"embedding_dimensions,omitempty"` // Embedding向量维度
+	SupportsDimensions  bool     `json:"supports_dimensions,omitempty"`  // 是否支持通过dimensions参数缩减维度
+
+	// Embedding模型使用的向量生成算法，为空时使用默认算法
+	EmbeddingAlgorithm string `json:"embedding_algorithm,omitempty"`
+
+	// Rerank模型的响应格式：default, cohere, jina, vllm
+	RerankProfile string `json:"rerank_profile,omitempty"`
+
+	// Chat和文本补全模仿的服务商：openai, deepseek, openrouter, vllm, groq, together, azure，为空时使用配置的默认值
+	ProviderProfile string `json:"provider_profile,omitempty"`
 }
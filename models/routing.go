@@ -0,0 +1,461 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// 默认路由规则文件名
+	defaultRoutingFile = "routing.json"
+
+	// 别名链的最大解析深度，防止配置错误导致的无限解析
+	maxAliasDepth = 8
+)
+
+// 模型名匹配模式的类型
+const (
+	PatternTypeGlob  = "glob"
+	PatternTypeRegex = "regex"
+)
+
+// ModelPattern 将匹配的模型名路由到一个已注册的基础模型
+type ModelPattern struct {
+	ID      string `json:"id"`
+	Pattern string `json:"pattern"`
+	Type    string `json:"type"`   // glob 或 regex
+	Target  string `json:"target"` // 基础模型ID
+
+	compiled *regexp.Regexp
+}
+
+// AutoRegisterConfig 自动注册未知模型的配置
+type AutoRegisterConfig struct {
+	Enabled   bool   `json:"enabled"`
+	BaseModel string `json:"base_model,omitempty"` // 新模型复制该模型的元数据，为空时按LLM默认值创建
+}
+
+// RoutingRules 模型路由规则，包括别名、匹配模式和自动注册配置
+type RoutingRules struct {
+	Aliases      map[string]string  `json:"aliases"`
+	Patterns     []ModelPattern     `json:"patterns"`
+	AutoRegister AutoRegisterConfig `json:"auto_register"`
+}
+
+// Resolution 模型名的解析结果
+type Resolution struct {
+	Model ModelInfo // 提供行为和能力元数据的模型
+	Name  string    // 在响应的model字段中回显的名称
+}
+
+// Router 将请求中的模型名解析为已注册的模型，规则持久化到文件
+type Router struct {
+	manager    *ModelManager
+	rules      RoutingRules
+	mu         sync.RWMutex
+	storageDir string
+	filename   string
+}
+
+// NewRouter 创建一个新的模型路由器
+func NewRouter(manager *ModelManager, storageDir, filename string) *Router {
+	if storageDir == "" {
+		storageDir = defaultModelDir
+	}
+	if filename == "" {
+		filename = defaultRoutingFile
+	}
+
+	router := &Router{
+		manager:    manager,
+		rules:      RoutingRules{Aliases: make(map[string]string)},
+		storageDir: storageDir,
+		filename:   filename,
+	}
+
+	// 加载路由规则
+	if err := router.loadRules(); err != nil {
+		fmt.Printf("Error loading model routing rules: %v\n", err)
+	}
+
+	return router
+}
+
+// Resolve 解析模型名：精确匹配已注册模型，然后依次尝试别名和匹配模式，开启自动注册时注册未知模型
+// endpoint 为请求的接口，自动注册的模型总是支持该接口：base_model 不支持该接口时按接口对应的模型类型创建
+func (r *Router) Resolve(name, endpoint string) (Resolution, error) {
+	resolution, err := r.Lookup(name)
+	if err == nil {
+		return resolution, nil
+	}
+
+	r.mu.RLock()
+	autoRegister := r.rules.AutoRegister
+	r.mu.RUnlock()
+	if !autoRegister.Enabled || name == "" {
+		return Resolution{}, err
+	}
+
+	// 自动注册未知模型，别名解析后的名称作为新模型ID
+	modelID := r.followAliases(name)
+	modelType, ok := ModelTypeForEndpoint(endpoint)
+	if !ok {
+		return Resolution{}, err
+	}
+	model := ModelInfo{
+		ID:        modelID,
+		Object:    "model",
+		Created:   time.Now().Unix(),
+		OwnedBy:   "openai-mocker",
+		ModelType: modelType,
+	}
+	if base, baseErr := r.manager.GetModel(autoRegister.BaseModel); baseErr == nil && base.SupportsEndpoint(endpoint) {
+		base.ID = modelID
+		base.Created = model.Created
+		model = base
+	}
+	if regErr := r.manager.RegisterModel(model); regErr != nil {
+		return Resolution{}, regErr
+	}
+
+	model, err = r.manager.GetModel(modelID)
+	if err != nil {
+		return Resolution{}, err
+	}
+	return Resolution{Model: model, Name: modelID}, nil
+}
+
+// Lookup 按别名和匹配模式解析模型名，不会自动注册未知模型
+func (r *Router) Lookup(name string) (Resolution, error) {
+	if model, err := r.manager.GetModel(name); err == nil {
+		return Resolution{Model: model, Name: name}, nil
+	}
+
+	resolved := r.followAliases(name)
+	if model, err := r.manager.GetModel(resolved); err == nil {
+		return Resolution{Model: model, Name: resolved}, nil
+	}
+
+	if pattern, ok := r.matchPattern(resolved); ok {
+		if model, err := r.manager.GetModel(pattern.Target); err == nil {
+			return Resolution{Model: model, Name: resolved}, nil
+		}
+	}
+
+	return Resolution{}, errors.New("model not found")
+}
+
+// Candidates 返回解析过程中依次经过的模型名，用于查找关联的资源（如响应模板）
+func (r *Router) Candidates(name string) []string {
+	candidates := []string{name}
+
+	r.mu.RLock()
+	current := name
+	for i := 0; i < maxAliasDepth; i++ {
+		target, exists := r.rules.Aliases[current]
+		if !exists {
+			break
+		}
+		candidates = append(candidates, target)
+		current = target
+	}
+	r.mu.RUnlock()
+
+	if _, err := r.manager.GetModel(current); err != nil {
+		if pattern, ok := r.matchPattern(current); ok {
+			candidates = append(candidates, pattern.Target)
+		}
+	}
+	return candidates
+}
+
+// Rules 返回当前路由规则的副本
+func (r *Router) Rules() RoutingRules {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.copyRules()
+}
+
+// SetAlias 设置别名，别名必须能解析到一个已注册的模型
+func (r *Router) SetAlias(alias, target string) error {
+	if alias == "" || target == "" {
+		return errors.New("alias and target are required")
+	}
+	if alias == target {
+		return errors.New("alias cannot point to itself")
+	}
+	if _, err := r.manager.GetModel(alias); err == nil {
+		return fmt.Errorf("alias '%s' conflicts with a registered model", alias)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	rules := r.copyRules()
+	rules.Aliases[alias] = target
+
+	// 检查别名链是否形成循环
+	current := target
+	for i := 0; i < maxAliasDepth; i++ {
+		next, exists := rules.Aliases[current]
+		if !exists {
+			break
+		}
+		if next == alias {
+			return errors.New("alias creates a cycle")
+		}
+		current = next
+	}
+	if _, err := r.manager.GetModel(current); err != nil {
+		if _, ok := matchPatterns(rules.Patterns, current); !ok {
+			return fmt.Errorf("target '%s' does not resolve to a registered model", target)
+		}
+	}
+
+	return r.saveRules(rules)
+}
+
+// DeleteAlias 删除别名
+func (r *Router) DeleteAlias(alias string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.rules.Aliases[alias]; !exists {
+		return errors.New("alias not found")
+	}
+
+	rules := r.copyRules()
+	delete(rules.Aliases, alias)
+	return r.saveRules(rules)
+}
+
+// AddPattern 添加匹配模式，按添加顺序匹配，先添加的优先
+func (r *Router) AddPattern(pattern ModelPattern) (ModelPattern, error) {
+	if pattern.Pattern == "" || pattern.Target == "" {
+		return ModelPattern{}, errors.New("pattern and target are required")
+	}
+	if pattern.Type == "" {
+		pattern.Type = PatternTypeGlob
+	}
+	if err := pattern.compile(); err != nil {
+		return ModelPattern{}, err
+	}
+	if _, err := r.manager.GetModel(pattern.Target); err != nil {
+		return ModelPattern{}, fmt.Errorf("target model '%s' not found", pattern.Target)
+	}
+	pattern.ID = "mp-" + uuid.New().String()[:8]
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	rules := r.copyRules()
+	rules.Patterns = append(rules.Patterns, pattern)
+	if err := r.saveRules(rules); err != nil {
+		return ModelPattern{}, err
+	}
+	return pattern, nil
+}
+
+// DeletePattern 删除匹配模式
+func (r *Router) DeletePattern(id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	rules := r.copyRules()
+	for i, pattern := range rules.Patterns {
+		if pattern.ID == id {
+			rules.Patterns = append(rules.Patterns[:i], rules.Patterns[i+1:]...)
+			return r.saveRules(rules)
+		}
+	}
+	return errors.New("pattern not found")
+}
+
+// SetAutoRegister 设置自动注册未知模型的配置
+func (r *Router) SetAutoRegister(config AutoRegisterConfig) error {
+	if config.BaseModel != "" {
+		if _, err := r.manager.GetModel(config.BaseModel); err != nil {
+			return fmt.Errorf("base model '%s' not found", config.BaseModel)
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	rules := r.copyRules()
+	rules.AutoRegister = config
+	return r.saveRules(rules)
+}
+
+// ReplaceRules 用给定的规则替换全部路由规则
+func (r *Router) ReplaceRules(rules RoutingRules) error {
+	replaced := RoutingRules{
+		Aliases:      make(map[string]string, len(rules.Aliases)),
+		Patterns:     append([]ModelPattern(nil), rules.Patterns...),
+		AutoRegister: rules.AutoRegister,
+	}
+	for alias, target := range rules.Aliases {
+		replaced.Aliases[alias] = target
+	}
+	for i := range replaced.Patterns {
+		if err := replaced.Patterns[i].compile(); err != nil {
+			return err
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.saveRules(replaced)
+}
+
+// DeleteRulesForModel 删除指向指定模型的别名和匹配模式
+func (r *Router) DeleteRulesForModel(modelID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	rules := r.copyRules()
+	for alias, target := range rules.Aliases {
+		if target == modelID {
+			delete(rules.Aliases, alias)
+		}
+	}
+	patterns := rules.Patterns[:0]
+	for _, pattern := range rules.Patterns {
+		if pattern.Target != modelID {
+			patterns = append(patterns, pattern)
+		}
+	}
+	rules.Patterns = patterns
+	if rules.AutoRegister.BaseModel == modelID {
+		rules.AutoRegister.BaseModel = ""
+	}
+	return r.saveRules(rules)
+}
+
+// followAliases 沿别名链解析模型名
+func (r *Router) followAliases(name string) string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	current := name
+	for i := 0; i < maxAliasDepth; i++ {
+		target, exists := r.rules.Aliases[current]
+		if !exists {
+			break
+		}
+		current = target
+	}
+	return current
+}
+
+// matchPattern 返回第一个匹配模型名的模式
+func (r *Router) matchPattern(name string) (ModelPattern, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return matchPatterns(r.rules.Patterns, name)
+}
+
+// copyRules 复制当前路由规则，调用方需持有锁
+func (r *Router) copyRules() RoutingRules {
+	rules := RoutingRules{
+		Aliases:      make(map[string]string, len(r.rules.Aliases)),
+		Patterns:     append([]ModelPattern(nil), r.rules.Patterns...),
+		AutoRegister: r.rules.AutoRegister,
+	}
+	for alias, target := range r.rules.Aliases {
+		rules.Aliases[alias] = target
+	}
+	return rules
+}
+
+// saveRules 将路由规则写入文件，成功后更新内存，调用方需持有写锁
+func (r *Router) saveRules(rules RoutingRules) error {
+	data, err := json.MarshalIndent(rules, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding routing rules: %v", err)
+	}
+
+	if err := os.MkdirAll(r.storageDir, 0755); err != nil {
+		return fmt.Errorf("error creating model directory: %v", err)
+	}
+	filePath := filepath.Join(r.storageDir, r.filename)
+	if err := os.WriteFile(filePath, data, 0644); err != nil {
+		return fmt.Errorf("error writing routing file: %v", err)
+	}
+
+	r.rules = rules
+	return nil
+}
+
+// loadRules 从文件加载路由规则，文件不存在时使用空规则
+func (r *Router) loadRules() error {
+	filePath := filepath.Join(r.storageDir, r.filename)
+	data, err := os.ReadFile(filePath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error reading routing file: %v", err)
+	}
+
+	var rules RoutingRules
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return fmt.Errorf("error parsing routing file: %v", err)
+	}
+	if rules.Aliases == nil {
+		rules.Aliases = make(map[string]string)
+	}
+	for i := range rules.Patterns {
+		if err := rules.Patterns[i].compile(); err != nil {
+			return err
+		}
+	}
+
+	r.rules = rules
+	return nil
+}
+
+// compile 校验并编译匹配模式
+func (p *ModelPattern) compile() error {
+	switch p.Type {
+	case PatternTypeGlob:
+		// glob中的*匹配任意字符（包括斜杠），?匹配单个字符
+		expr := regexp.QuoteMeta(p.Pattern)
+		expr = strings.ReplaceAll(expr, `\*`, ".*")
+		expr = strings.ReplaceAll(expr, `\?`, ".")
+		p.compiled = regexp.MustCompile("^" + expr + "$")
+	case PatternTypeRegex:
+		compiled, err := regexp.Compile("^(?:" + p.Pattern + ")$")
+		if err != nil {
+			return fmt.Errorf("invalid regex pattern '%s': %v", p.Pattern, err)
+		}
+		p.compiled = compiled
+	default:
+		return fmt.Errorf("invalid pattern type '%s', must be one of: glob, regex", p.Type)
+	}
+	return nil
+}
+
+// matches 判断模型名是否匹配该模式
+func (p ModelPattern) matches(name string) bool {
+	return p.compiled != nil && p.compiled.MatchString(name)
+}
+
+// matchPatterns 返回第一个匹配模型名的模式
+func matchPatterns(patterns []ModelPattern, name string) (ModelPattern, bool) {
+	for _, pattern := range patterns {
+		if pattern.matches(name) {
+			return pattern, true
+		}
+	}
+	return ModelPattern{}, false
+}
@@ -0,0 +1,133 @@
+package models
+
+import (
+	"testing"
+)
+
+// newTestRouter 在临时目录中创建包含内置模型的路由器
+func newTestRouter(t *testing.T) (*ModelManager, *Router) {
+	t.Helper()
+	dir := t.TempDir()
+	manager := NewModelManager(dir, "")
+	return manager, NewRouter(manager, dir, "")
+}
+
+func TestLookupAliasesAndPatterns(t *testing.T) {
+	_, router := newTestRouter(t)
+	if err := router.SetAlias("gpt-3.5", "mock-gpt-3.5-turbo"); err != nil {
+		t.Fatalf("set alias: %v", err)
+	}
+	if err := router.SetAlias("latest", "gpt-3.5"); err != nil {
+		t.Fatalf("set chained alias: %v", err)
+	}
+	if _, err := router.AddPattern(ModelPattern{Pattern: "gpt-4o-*", Target: "mock-gpt-3.5-turbo"}); err != nil {
+		t.Fatalf("add glob pattern: %v", err)
+	}
+	if _, err := router.AddPattern(ModelPattern{Pattern: `claude-\d+`, Type: PatternTypeRegex, Target: "mock-davinci-002"}); err != nil {
+		t.Fatalf("add regex pattern: %v", err)
+	}
+
+	cases := []struct {
+		name  string
+		model string
+		echo  string
+	}{
+		{"mock-gpt-3.5-turbo", "mock-gpt-3.5-turbo", "mock-gpt-3.5-turbo"},
+		{"gpt-3.5", "mock-gpt-3.5-turbo", "mock-gpt-3.5-turbo"},
+		{"latest", "mock-gpt-3.5-turbo", "mock-gpt-3.5-turbo"},
+		{"gpt-4o-2024-08-06", "mock-gpt-3.5-turbo", "gpt-4o-2024-08-06"},
+		{"claude-3", "mock-davinci-002", "claude-3"},
+	}
+	for _, tc := range cases {
+		resolution, err := router.Lookup(tc.name)
+		if err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+			continue
+		}
+		if resolution.Model.ID != tc.model || resolution.Name != tc.echo {
+			t.Errorf("%s: resolved to %s echoing %s, want %s echoing %s", tc.name, resolution.Model.ID, resolution.Name, tc.model, tc.echo)
+		}
+	}
+
+	for _, name := range []string{"gpt-4o", "claude-x", "unknown"} {
+		if _, err := router.Lookup(name); err == nil {
+			t.Errorf("%s: expected model not found", name)
+		}
+	}
+}
+
+func TestRoutingRuleValidation(t *testing.T) {
+	_, router := newTestRouter(t)
+	if err := router.SetAlias("a", "b"); err == nil {
+		t.Error("expected an alias to an unknown model to fail")
+	}
+	if err := router.SetAlias("mock-gpt-3.5-turbo", "mock-davinci-002"); err == nil {
+		t.Error("expected an alias shadowing a registered model to fail")
+	}
+	if err := router.SetAlias("a", "mock-gpt-3.5-turbo"); err != nil {
+		t.Fatalf("set alias: %v", err)
+	}
+	if err := router.SetAlias("chat-alias", "a"); err != nil {
+		t.Fatalf("set chained alias: %v", err)
+	}
+	if err := router.SetAlias("a", "chat-alias"); err == nil {
+		t.Error("expected an alias cycle to fail")
+	}
+
+	patterns := []ModelPattern{
+		{Pattern: "(", Type: PatternTypeRegex, Target: "mock-gpt-3.5-turbo"},
+		{Pattern: "x-*", Type: "prefix", Target: "mock-gpt-3.5-turbo"},
+		{Pattern: "x-*", Target: "missing-model"},
+		{Target: "mock-gpt-3.5-turbo"},
+	}
+	for _, pattern := range patterns {
+		if _, err := router.AddPattern(pattern); err == nil {
+			t.Errorf("expected pattern %+v to fail", pattern)
+		}
+	}
+}
+
+func TestAutoRegisterByEndpoint(t *testing.T) {
+	manager, router := newTestRouter(t)
+	if _, err := router.Resolve("unknown-chat", EndpointChatCompletions); err == nil {
+		t.Fatal("expected unknown model to fail while auto-registration is disabled")
+	}
+	if err := router.SetAutoRegister(AutoRegisterConfig{Enabled: true, BaseModel: "mock-gpt-3.5-turbo"}); err != nil {
+		t.Fatalf("enable auto-registration: %v", err)
+	}
+
+	// base_model 不支持请求的接口时按接口对应的模型类型创建
+	cases := []struct {
+		name      string
+		endpoint  string
+		modelType string
+	}{
+		{"new-chat-model", EndpointChatCompletions, ModelTypeLLM},
+		{"new-embedding-model", EndpointEmbeddings, ModelTypeEmbedding},
+		{"new-rerank-model", EndpointRerank, ModelTypeRerank},
+		{"new-score-model", EndpointScore, ModelTypeRerank},
+	}
+	for _, tc := range cases {
+		resolution, err := router.Resolve(tc.name, tc.endpoint)
+		if err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+			continue
+		}
+		model, err := manager.GetModel(tc.name)
+		if err != nil || model.ModelType != tc.modelType || !model.SupportsEndpoint(tc.endpoint) || resolution.Name != tc.name {
+			t.Errorf("%s: registered %+v, %v", tc.name, model, err)
+		}
+	}
+
+	// 复制 base_model 的元数据
+	if model, _ := manager.GetModel("new-chat-model"); model.ContextWindow == 0 || !model.SupportsTools {
+		t.Errorf("auto-registered model did not copy the base model: %+v", model)
+	}
+
+	if _, err := router.Resolve("new-unknown-endpoint", "images"); err == nil {
+		t.Error("expected an endpoint without a model type to fail")
+	}
+	if err := router.SetAutoRegister(AutoRegisterConfig{Enabled: true, BaseModel: "missing"}); err == nil {
+		t.Error("expected an unknown base model to fail")
+	}
+}
@@ -0,0 +1,394 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+const (
+	// 默认模型存储目录
+	defaultModelDir = "model_data"
+	// 默认模型注册表文件名
+	defaultModelFile = "models.json"
+	// 默认模型清单文件，注册表文件不存在时从此文件初始化
+	defaultModelManifestFile = "default_models.json"
+)
+
+// ModelManager 管理模型注册表，提供持久化存储能力
+type ModelManager struct {
+	models     map[string]ModelInfo // 模型存储
+	mu         sync.RWMutex         // 读写锁
+	storageDir string               // 存储目录
+	filename   string               // 存储文件名
+}
+
+// NewModelManager 创建一个新的模型管理器
+func NewModelManager(storageDir, filename string) *ModelManager {
+	// 如果未指定存储目录，使用默认目录
+	if storageDir == "" {
+		storageDir = defaultModelDir
+	}
+
+	// 如果未指定文件名，使用默认文件名
+	if filename == "" {
+		filename = defaultModelFile
+	}
+
+	manager := &ModelManager{
+		models:     make(map[string]ModelInfo),
+		storageDir: storageDir,
+		filename:   filename,
+	}
+
+	// 确保存储目录存在
+	if err := os.MkdirAll(storageDir, 0755); err != nil {
+		fmt.Printf("Error creating model directory: %v\n", err)
+	}
+
+	// 加载模型数据
+	if err := manager.loadModels(); err != nil {
+		fmt.Printf("Error loading models: %v\n", err)
+	}
+
+	return manager
+}
+
+// RegisterModel 注册一个模型，未设置的能力元数据使用默认值补全
+func (mm *ModelManager) RegisterModel(model ModelInfo) error {
+	ApplyDefaults(&model)
+
+	mm.mu.Lock()
+	defer mm.mu.Unlock()
+
+	models := mm.copyModels()
+	models[model.ID] = model
+
+	// 先写入文件，成功后再更新内存
+	if err := mm.writeModelsToFile(models); err != nil {
+		return err
+	}
+	mm.models = models
+
+	return nil
+}
+
+// GetModel 获取指定ID的模型信息
+func (mm *ModelManager) GetModel(id string) (ModelInfo, error) {
+	mm.mu.RLock()
+	defer mm.mu.RUnlock()
+
+	model, exists := mm.models[id]
+	if !exists {
+		return ModelInfo{}, errors.New("model not found")
+	}
+	return model, nil
+}
+
+// ListModels 按模型ID顺序列出所有已注册的模型
+func (mm *ModelManager) ListModels() []ModelInfo {
+	mm.mu.RLock()
+	defer mm.mu.RUnlock()
+
+	result := make([]ModelInfo, 0, len(mm.models))
+	for _, model := range mm.models {
+		result = append(result, model)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].ID < result[j].ID
+	})
+	return result
+}
+
+// UnloadModel 卸载指定ID的模型
+func (mm *ModelManager) UnloadModel(id string) error {
+	mm.mu.Lock()
+	defer mm.mu.Unlock()
+
+	// 检查模型是否存在
+	if _, exists := mm.models[id]; !exists {
+		return errors.New("model not found")
+	}
+
+	models := mm.copyModels()
+	delete(models, id)
+
+	if err := mm.writeModelsToFile(models); err != nil {
+		return err
+	}
+	mm.models = models
+
+	return nil
+}
+
+// UnloadAllModels 卸载所有模型
+func (mm *ModelManager) UnloadAllModels() error {
+	mm.mu.Lock()
+	defer mm.mu.Unlock()
+
+	models := make(map[string]ModelInfo)
+	if err := mm.writeModelsToFile(models); err != nil {
+		return err
+	}
+	mm.models = models
+
+	return nil
+}
+
+// RegisterModels 一次性注册多个模型，只写入一次文件，未设置的能力元数据使用默认值补全
+func (mm *ModelManager) RegisterModels(modelList []ModelInfo) error {
+	mm.mu.Lock()
+	defer mm.mu.Unlock()
+
+	models := mm.copyModels()
+	for _, model := range modelList {
+		ApplyDefaults(&model)
+		models[model.ID] = model
+	}
+
+	if err := mm.writeModelsToFile(models); err != nil {
+		return err
+	}
+	mm.models = models
+	return nil
+}
+
+// ReplaceModels 用给定的模型替换整个注册表
+func (mm *ModelManager) ReplaceModels(modelList []ModelInfo) error {
+	models := make(map[string]ModelInfo, len(modelList))
+	for _, model := range modelList {
+		ApplyDefaults(&model)
+		models[model.ID] = model
+	}
+
+	mm.mu.Lock()
+	defer mm.mu.Unlock()
+
+	if err := mm.writeModelsToFile(models); err != nil {
+		return err
+	}
+	mm.models = models
+
+	return nil
+}
+
+// LoadDefaultModels 从默认模型清单重新注册所有默认模型，保留其他已注册的模型
+func (mm *ModelManager) LoadDefaultModels() error {
+	defaults, err := mm.readManifest()
+	if err != nil {
+		return err
+	}
+
+	mm.mu.Lock()
+	defer mm.mu.Unlock()
+
+	models := mm.copyModels()
+	for id, model := range defaults {
+		models[id] = model
+	}
+
+	if err := mm.writeModelsToFile(models); err != nil {
+		return err
+	}
+	mm.models = models
+
+	return nil
+}
+
+// copyModels 复制当前的模型映射，调用方需持有锁
+func (mm *ModelManager) copyModels() map[string]ModelInfo {
+	models := make(map[string]ModelInfo, len(mm.models))
+	for id, model := range mm.models {
+		models[id] = model
+	}
+	return models
+}
+
+// loadModels 从文件加载模型注册表，文件不存在时从默认模型清单初始化
+func (mm *ModelManager) loadModels() error {
+	filePath := filepath.Join(mm.storageDir, mm.filename)
+
+	// 检查文件是否存在
+	if _, err := os.Stat(filePath); os.IsNotExist(err) {
+		defaults, err := mm.readManifest()
+		if err != nil {
+			return err
+		}
+		mm.models = defaults
+
+		// 将默认模型复制到注册表文件
+		return mm.writeModelsToFile(defaults)
+	}
+
+	// 读取文件内容
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return fmt.Errorf("error reading model file: %v", err)
+	}
+
+	models, err := parseModels(data)
+	if err != nil {
+		return fmt.Errorf("error parsing model file: %v", err)
+	}
+
+	mm.models = models
+	return nil
+}
+
+// readManifest 读取默认模型清单，清单文件不存在时创建
+func (mm *ModelManager) readManifest() (map[string]ModelInfo, error) {
+	manifestPath := filepath.Join(mm.storageDir, defaultModelManifestFile)
+
+	// 检查默认模型清单是否存在
+	if _, err := os.Stat(manifestPath); os.IsNotExist(err) {
+		if err := createDefaultManifestFile(manifestPath); err != nil {
+			return nil, fmt.Errorf("error creating default model manifest: %v", err)
+		}
+	}
+
+	data, err := os.ReadFile(manifestPath)
+	if err != nil {
+		return nil, fmt.Errorf("error reading default model manifest: %v", err)
+	}
+
+	models, err := parseModels(data)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing default model manifest: %v", err)
+	}
+	return models, nil
+}
+
+// writeModelsToFile 将模型注册表写入文件
+func (mm *ModelManager) writeModelsToFile(models map[string]ModelInfo) error {
+	// 将模型数据编码为JSON
+	data, err := json.MarshalIndent(models, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding models: %v", err)
+	}
+
+	// 保存到文件
+	filePath := filepath.Join(mm.storageDir, mm.filename)
+	if err := os.WriteFile(filePath, data, 0644); err != nil {
+		return fmt.Errorf("error writing model file: %v", err)
+	}
+
+	return nil
+}
+
+// parseModels 解析模型JSON，校验每个模型并补全默认元数据
+func parseModels(data []byte) (map[string]ModelInfo, error) {
+	var models map[string]ModelInfo
+	if err := json.Unmarshal(data, &models); err != nil {
+		return nil, err
+	}
+
+	for id, model := range models {
+		if model.ID == "" {
+			model.ID = id
+		}
+		if err := ValidateModel(model); err != nil {
+			return nil, fmt.Errorf("model %s: %v", id, err)
+		}
+		ApplyDefaults(&model)
+		models[id] = model
+	}
+	return models, nil
+}
+
+// createDefaultManifestFile 创建包含内置模型的默认模型清单
+func createDefaultManifestFile(filePath string) error {
+	models := make(map[string]ModelInfo)
+	for _, model := range builtinModels() {
+		models[model.ID] = model
+	}
+
+	// 将模型数据编码为JSON
+	data, err := json.MarshalIndent(models, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding default models: %v", err)
+	}
+
+	// 保存到文件
+	dir := filepath.Dir(filePath)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("error creating directory for default models: %v", err)
+	}
+
+	if err := os.WriteFile(filePath, data, 0644); err != nil {
+		return fmt.Errorf("error writing default model manifest: %v", err)
+	}
+
+	fmt.Printf("Created default model manifest: %s\n", filePath)
+	return nil
+}
+
+// builtinModels 返回内置的默认模型
+func builtinModels() []ModelInfo {
+	return []ModelInfo{
+		// LLM模型
+		{
+			ID:              "mock-gpt-3.5-turbo",
+			Object:          "model",
+			Created:         1677610602,
+			OwnedBy:         "openai-mocker",
+			ModelType:       ModelTypeLLM,
+			ContextWindow:   16385,
+			MaxOutputTokens: 4096,
+			SupportsTools:   true,
+		},
+		{
+			ID:              "mock-davinci-002",
+			Object:          "model",
+			Created:         1649880484,
+			OwnedBy:         "openai-mocker",
+			ModelType:       ModelTypeLLM,
+			ContextWindow:   16384,
+			MaxOutputTokens: 4096,
+		},
+		// 推理模型
+		{
+			ID:                "deepseek-reasoner",
+			Object:            "model",
+			Created:           1714207996,
+			OwnedBy:           "openai-mocker",
+			ModelType:         ModelTypeLLM,
+			ContextWindow:     65536,
+			MaxOutputTokens:   8192,
+			SupportsReasoning: true,
+		},
+		// Embedding模型
+		{
+			ID:        "mock-embedding-ada-002",
+			Object:    "model",
+			Created:   1671217299,
+			OwnedBy:   "openai-mocker",
+			ModelType: ModelTypeEmbedding,
+		},
+		{
+			ID:        "mock-text-embedding-3-small",
+			Object:    "model",
+			Created:   1705948997,
+			OwnedBy:   "openai-mocker",
+			ModelType: ModelTypeEmbedding,
+		},
+		{
+			ID:        "mock-text-embedding-3-large",
+			Object:    "model",
+			Created:   1705953180,
+			OwnedBy:   "openai-mocker",
+			ModelType: ModelTypeEmbedding,
+		},
+		// Rerank模型
+		{
+			ID:        "mock-rerank-v1",
+			Object:    "model",
+			Created:   1709486145,
+			OwnedBy:   "openai-mocker",
+			ModelType: ModelTypeRerank,
+		},
+	}
+}
@@ -0,0 +1,55 @@
+package provider
+
+import (
+	"net/http"
+	"strings"
+
+	"RobinPenn974/OpenAI-mocker/api"
+)
+
+// FormatError 将OpenAI格式的错误响应转换为服务商的错误格式
+func (p Profile) FormatError(status int, errResp api.ErrorResponse) interface{} {
+	detail := errResp.Error
+	switch p.ErrorFormat {
+	case ErrorFormatAzure:
+		// Azure的错误码优先使用OpenAI的code，没有时使用type
+		code := detail.Type
+		if detail.Code != nil {
+			code = *detail.Code
+		}
+		var response api.AzureErrorResponse
+		response.Error.Message = detail.Message
+		response.Error.Code = code
+		response.Error.Param = detail.Param
+		return response
+	case ErrorFormatVLLM:
+		return map[string]interface{}{
+			"object":  "error",
+			"message": detail.Message,
+			"type":    vllmErrorType(status),
+			"param":   detail.Param,
+			"code":    status,
+		}
+	case ErrorFormatOpenRouter:
+		return map[string]interface{}{
+			"error": map[string]interface{}{
+				"code":    status,
+				"message": detail.Message,
+			},
+		}
+	default:
+		return errResp
+	}
+}
+
+// vllmErrorType 按HTTP状态码返回vLLM的错误类型，如 BadRequestError、NotFoundError
+func vllmErrorType(status int) string {
+	name := strings.ReplaceAll(http.StatusText(status), " ", "")
+	if name == "" {
+		name = "InternalServer"
+	}
+	if !strings.HasSuffix(name, "Error") {
+		name += "Error"
+	}
+	return name
+}
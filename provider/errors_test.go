@@ -0,0 +1,64 @@
+package provider
+
+import (
+	"testing"
+
+	"RobinPenn974/OpenAI-mocker/api"
+)
+
+func TestFormatError(t *testing.T) {
+	errResp := api.NewErrorResponse("The model 'x' does not exist", "invalid_request_error", "model", "model_not_found")
+
+	cases := []struct {
+		profile string
+		status  int
+		want    string
+	}{
+		{ProfileOpenAI, 404, `{"error":{"message":"The model 'x' does not exist","type":"invalid_request_error","param":"model","code":"model_not_found"}}`},
+		{ProfileGroq, 404, `{"error":{"message":"The model 'x' does not exist","type":"invalid_request_error","param":"model","code":"model_not_found"}}`},
+		{ProfileAzure, 404, `{"error":{"message":"The model 'x' does not exist","type":null,"param":"model","code":"model_not_found"}}`},
+		{ProfileVLLM, 404, `{"code":404,"message":"The model 'x' does not exist","object":"error","param":"model","type":"NotFoundError"}`},
+		{ProfileOpenRouter, 404, `{"error":{"code":404,"message":"The model 'x' does not exist"}}`},
+	}
+	for _, tc := range cases {
+		if got := mustJSON(Get(tc.profile).FormatError(tc.status, errResp)); got != tc.want {
+			t.Errorf("%s:\n got %s\nwant %s", tc.profile, got, tc.want)
+		}
+	}
+
+	// Azure 没有code时使用type
+	noCode := api.NewErrorResponse("Invalid request", "invalid_request_error", "", "")
+	if got, _ := field(toObject(Get(ProfileAzure).FormatError(400, noCode)), "error", "code"); got != "invalid_request_error" {
+		t.Errorf("azure error without code: code %v", got)
+	}
+}
+
+func TestVLLMErrorType(t *testing.T) {
+	cases := map[int]string{
+		400: "BadRequestError",
+		401: "UnauthorizedError",
+		429: "TooManyRequestsError",
+		500: "InternalServerError",
+		599: "InternalServerError",
+	}
+	for status, want := range cases {
+		if got := vllmErrorType(status); got != want {
+			t.Errorf("vllmErrorType(%d) = %s, want %s", status, got, want)
+		}
+	}
+}
+
+func TestGetProfile(t *testing.T) {
+	if Get("").Name != ProfileOpenAI || Get("unknown").Name != ProfileOpenAI {
+		t.Error("empty or unknown profile does not fall back to openai")
+	}
+	if IsSupportedProfile("unknown") || !IsSupportedProfile(ProfileTogether) {
+		t.Error("unexpected IsSupportedProfile result")
+	}
+	list := List()
+	for i := 1; i < len(list); i++ {
+		if list[i-1].Name >= list[i].Name {
+			t.Fatalf("profiles not sorted: %s before %s", list[i-1].Name, list[i].Name)
+		}
+	}
+}
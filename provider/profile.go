@@ -0,0 +1,108 @@
+package provider
+
+import (
+	"sort"
+
+	"RobinPenn974/OpenAI-mocker/templates"
+)
+
+// 服务商兼容配置
+const (
+	// ProfileOpenAI OpenAI 官方的响应格式，默认使用
+	ProfileOpenAI = "openai"
+	// ProfileDeepSeek DeepSeek 的响应格式：reasoning_content 字段和提示词缓存的token统计
+	ProfileDeepSeek = "deepseek"
+	// ProfileOpenRouter OpenRouter 的响应格式：reasoning 字段、provider 和 native_finish_reason
+	ProfileOpenRouter = "openrouter"
+	// ProfileVLLM vLLM 的响应格式：stop_reason、prompt_logprobs 和扁平的错误格式
+	ProfileVLLM = "vllm"
+	// ProfileGroq Groq 的响应格式：x_groq 字段和带耗时的token统计
+	ProfileGroq = "groq"
+	// ProfileTogether Together AI 的响应格式：eos 结束原因、prompt 字段和流式数据块中的 text
+	ProfileTogether = "together"
+	// ProfileAzure Azure OpenAI 的响应格式：内容过滤结果和Azure错误格式
+	ProfileAzure = "azure"
+)
+
+// 错误响应格式
+const (
+	ErrorFormatOpenAI     = "openai"     // {"error": {"message", "type", "param", "code"}}
+	ErrorFormatAzure      = "azure"      // {"error": {"code", "message"}}
+	ErrorFormatVLLM       = "vllm"       // {"object": "error", "message", "type", "param", "code": HTTP状态码}
+	ErrorFormatOpenRouter = "openrouter" // {"error": {"code": HTTP状态码, "message"}}
+)
+
+// Profile 服务商兼容配置，决定Chat和文本补全的响应结构、流式数据块、响应头和错误格式
+type Profile struct {
+	Name            string            `json:"name"`
+	ReasoningFormat string            `json:"reasoning_format,omitempty"`  // 推理内容的返回格式，为空时由模板和 features.reasoning_field 决定
+	Headers         map[string]string `json:"headers,omitempty"`           // 每个响应附带的响应头
+	RequestIDHeader string            `json:"request_id_header,omitempty"` // 返回请求ID的响应头
+	ErrorFormat     string            `json:"error_format"`
+}
+
+// profiles 内置的服务商兼容配置
+var profiles = map[string]Profile{
+	ProfileOpenAI: {
+		Name:            ProfileOpenAI,
+		Headers:         map[string]string{"openai-version": "2020-10-01", "openai-organization": "user-mock"},
+		RequestIDHeader: "x-request-id",
+		ErrorFormat:     ErrorFormatOpenAI,
+	},
+	ProfileDeepSeek: {
+		Name:            ProfileDeepSeek,
+		ReasoningFormat: templates.ReasoningFormatContent,
+		ErrorFormat:     ErrorFormatOpenAI,
+	},
+	ProfileOpenRouter: {
+		Name:            ProfileOpenRouter,
+		ReasoningFormat: templates.ReasoningFormatReasoning,
+		ErrorFormat:     ErrorFormatOpenRouter,
+	},
+	ProfileVLLM: {
+		Name:            ProfileVLLM,
+		ReasoningFormat: templates.ReasoningFormatContent,
+		ErrorFormat:     ErrorFormatVLLM,
+	},
+	ProfileGroq: {
+		Name:            ProfileGroq,
+		ReasoningFormat: templates.ReasoningFormatReasoning,
+		Headers:         map[string]string{"x-groq-region": "mock"},
+		RequestIDHeader: "x-request-id",
+		ErrorFormat:     ErrorFormatOpenAI,
+	},
+	ProfileTogether: {
+		Name:        ProfileTogether,
+		ErrorFormat: ErrorFormatOpenAI,
+	},
+	ProfileAzure: {
+		Name:            ProfileAzure,
+		Headers:         map[string]string{"x-ms-region": "East US"},
+		RequestIDHeader: "apim-request-id",
+		ErrorFormat:     ErrorFormatAzure,
+	},
+}
+
+// IsSupportedProfile 检查服务商兼容配置名称是否受支持
+func IsSupportedProfile(name string) bool {
+	_, ok := profiles[name]
+	return ok
+}
+
+// Get 返回指定名称的服务商兼容配置，名称为空或不受支持时返回 openai
+func Get(name string) Profile {
+	if profile, ok := profiles[name]; ok {
+		return profile
+	}
+	return profiles[ProfileOpenAI]
+}
+
+// List 按名称顺序返回所有服务商兼容配置
+func List() []Profile {
+	list := make([]Profile, 0, len(profiles))
+	for _, profile := range profiles {
+		list = append(list, profile)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
@@ -0,0 +1,191 @@
+package provider
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+)
+
+// Kind 响应对象的类型
+type Kind int
+
+const (
+	KindChat            Kind = iota // Chat回复
+	KindChatChunk                   // Chat流式数据块
+	KindCompletion                  // 文本补全回复
+	KindCompletionChunk             // 文本补全流式数据块
+)
+
+// position 数据块在流式响应中的位置，非流式响应既是第一块也是最后一块
+type position struct {
+	first bool
+	last  bool
+}
+
+// shaper 将OpenAI格式的响应对象原地转换为服务商的格式，usage 为响应的token使用量
+type shaper func(object map[string]interface{}, kind Kind, pos position, usage map[string]interface{})
+
+// shapers 各服务商兼容配置的响应转换，openai 和 azure 不需要转换，Azure的内容过滤结果由处理器添加
+var shapers = map[string]shaper{
+	ProfileDeepSeek:   shapeDeepSeek,
+	ProfileOpenRouter: shapeOpenRouter,
+	ProfileVLLM:       shapeVLLM,
+	ProfileGroq:       shapeGroq,
+	ProfileTogether:   shapeTogether,
+}
+
+// Shape 将OpenAI格式的非流式响应转换为服务商的格式
+func (p Profile) Shape(response interface{}, kind Kind) interface{} {
+	shape, ok := shapers[p.Name]
+	if !ok {
+		return response
+	}
+	object := toObject(response)
+	usage, _ := object["usage"].(map[string]interface{})
+	shape(object, kind, position{first: true, last: true}, usage)
+	return object
+}
+
+// ShapeStream 将OpenAI格式的流式数据块依次转换为服务商的格式，usage 为整个响应的token使用量，
+// 由需要在最后一块返回token使用量的服务商使用
+func (p Profile) ShapeStream(chunks []interface{}, kind Kind, usage interface{}) []interface{} {
+	shape, ok := shapers[p.Name]
+	if !ok {
+		return chunks
+	}
+	var usageObject map[string]interface{}
+	if usage != nil {
+		usageObject = toObject(usage)
+	}
+	shaped := make([]interface{}, len(chunks))
+	for i, chunk := range chunks {
+		object := toObject(chunk)
+		shape(object, kind, position{first: i == 0, last: i == len(chunks)-1}, usageObject)
+		shaped[i] = object
+	}
+	return shaped
+}
+
+// toObject 将响应结构体转换为可以修改的JSON对象
+func toObject(v interface{}) map[string]interface{} {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return map[string]interface{}{}
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var object map[string]interface{}
+	if err := decoder.Decode(&object); err != nil || object == nil {
+		return map[string]interface{}{}
+	}
+	return object
+}
+
+// choices 返回响应对象中的所有选项
+func choices(object map[string]interface{}) []map[string]interface{} {
+	list, _ := object["choices"].([]interface{})
+	result := make([]map[string]interface{}, 0, len(list))
+	for _, item := range list {
+		if choice, ok := item.(map[string]interface{}); ok {
+			result = append(result, choice)
+		}
+	}
+	return result
+}
+
+// number 返回JSON数字的值，不是数字时返回0
+func number(v interface{}) float64 {
+	n, _ := v.(json.Number)
+	value, _ := n.Float64()
+	return value
+}
+
+// isChunk 判断是否为流式数据块
+func (k Kind) isChunk() bool {
+	return k == KindChatChunk || k == KindCompletionChunk
+}
+
+// shapeDeepSeek 添加 system_fingerprint 以及提示词缓存命中和未命中的token数
+func shapeDeepSeek(object map[string]interface{}, kind Kind, pos position, usage map[string]interface{}) {
+	object["system_fingerprint"] = "fp_mock_deepseek"
+	if kind.isChunk() || usage == nil {
+		return
+	}
+	usage["prompt_cache_hit_tokens"] = 0
+	usage["prompt_cache_miss_tokens"] = usage["prompt_tokens"]
+}
+
+// shapeOpenRouter 添加实际提供服务的 provider，选项中添加 native_finish_reason 和 logprobs
+func shapeOpenRouter(object map[string]interface{}, kind Kind, pos position, usage map[string]interface{}) {
+	object["provider"] = "Mock"
+	for _, choice := range choices(object) {
+		choice["native_finish_reason"] = choice["finish_reason"]
+		choice["logprobs"] = nil
+	}
+}
+
+// shapeVLLM 选项中添加 stop_reason 和 logprobs，非流式响应添加 prompt_logprobs
+func shapeVLLM(object map[string]interface{}, kind Kind, pos position, usage map[string]interface{}) {
+	if kind == KindChat {
+		object["prompt_logprobs"] = nil
+	}
+	for _, choice := range choices(object) {
+		choice["stop_reason"] = nil
+		if _, ok := choice["logprobs"]; !ok {
+			choice["logprobs"] = nil
+		}
+		if kind == KindCompletion {
+			choice["prompt_logprobs"] = nil
+		}
+	}
+}
+
+// shapeGroq 添加 x_groq 和 system_fingerprint，token使用量中加入各阶段耗时，
+// 流式响应在第一块返回请求ID，在最后一块返回token使用量
+func shapeGroq(object map[string]interface{}, kind Kind, pos position, usage map[string]interface{}) {
+	id, _ := object["id"].(string)
+	xGroq := map[string]interface{}{"id": "req_" + id[strings.Index(id, "-")+1:]}
+	object["system_fingerprint"] = "fp_mock_groq"
+
+	if usage != nil {
+		promptTokens, completionTokens := number(usage["prompt_tokens"]), number(usage["completion_tokens"])
+		usage["queue_time"] = 0.0002
+		usage["prompt_time"] = promptTokens * 0.00005
+		usage["completion_time"] = completionTokens * 0.002
+		usage["total_time"] = promptTokens*0.00005 + completionTokens*0.002
+	}
+
+	switch {
+	case !kind.isChunk():
+		object["x_groq"] = xGroq
+	case pos.last:
+		xGroq["usage"] = usage
+		object["x_groq"] = xGroq
+	case pos.first:
+		object["x_groq"] = xGroq
+	}
+}
+
+// shapeTogether 结束原因 stop 改为 eos，非流式响应添加 prompt，流式数据块的选项中添加 text，
+// 只有最后一块返回token使用量
+func shapeTogether(object map[string]interface{}, kind Kind, pos position, usage map[string]interface{}) {
+	if !kind.isChunk() {
+		object["prompt"] = []interface{}{}
+	} else if pos.last {
+		object["usage"] = usage
+	} else {
+		object["usage"] = nil
+	}
+
+	for _, choice := range choices(object) {
+		if choice["finish_reason"] == "stop" {
+			choice["finish_reason"] = "eos"
+		}
+		choice["logprobs"] = nil
+		if kind == KindChatChunk {
+			delta, _ := choice["delta"].(map[string]interface{})
+			text, _ := delta["content"].(string)
+			choice["text"] = text
+		}
+	}
+}
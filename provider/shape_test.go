@@ -0,0 +1,173 @@
+package provider
+
+import (
+	"encoding/json"
+	"testing"
+
+	"RobinPenn974/OpenAI-mocker/api"
+)
+
+// chatResponse 返回OpenAI格式的Chat回复
+func chatResponse() api.ChatCompletionResponse {
+	return api.ChatCompletionResponse{
+		ID:      "chatcmpl-abc123",
+		Object:  "chat.completion",
+		Created: 1700000000,
+		Model:   "mock-gpt-3.5-turbo",
+		Choices: []api.ChatCompletionChoice{{Message: api.ChatCompletionMessage{Role: "assistant", Content: "hi"}, FinishReason: "stop"}},
+		Usage:   api.ChatCompletionUsage{PromptTokens: 10, CompletionTokens: 5, TotalTokens: 15},
+	}
+}
+
+// chatChunks 返回OpenAI格式的Chat流式数据块
+func chatChunks() []interface{} {
+	stop := "stop"
+	chunks := make([]interface{}, 3)
+	for i, content := range []string{"a", "b", "c"} {
+		content := content
+		chunk := api.ChatCompletionChunkResponse{
+			ID:      "chatcmpl-abc123",
+			Object:  "chat.completion.chunk",
+			Choices: []api.ChatCompletionChunkChoice{{Delta: api.ChatCompletionChunkDelta{Content: &content}}},
+		}
+		if i == 2 {
+			chunk.Choices[0].FinishReason = &stop
+		}
+		chunks[i] = chunk
+	}
+	return chunks
+}
+
+// field 按路径返回JSON对象中的字段，路径中的数字为数组下标
+func field(object interface{}, path ...interface{}) (interface{}, bool) {
+	current := object
+	for _, key := range path {
+		switch k := key.(type) {
+		case string:
+			m, ok := current.(map[string]interface{})
+			if !ok {
+				return nil, false
+			}
+			if current, ok = m[k]; !ok {
+				return nil, false
+			}
+		case int:
+			list, ok := current.([]interface{})
+			if !ok || k >= len(list) {
+				return nil, false
+			}
+			current = list[k]
+		}
+	}
+	return current, true
+}
+
+func TestShape(t *testing.T) {
+	cases := []struct {
+		profile string
+		present [][]interface{}
+		absent  [][]interface{}
+		values  map[string]interface{} // 字段路径以点分隔，值按JSON编码比较
+	}{
+		{ProfileOpenAI, nil, [][]interface{}{{"system_fingerprint"}, {"provider"}, {"x_groq"}}, nil},
+		{ProfileAzure, nil, [][]interface{}{{"system_fingerprint"}}, nil},
+		{ProfileDeepSeek, nil, nil, map[string]interface{}{
+			"system_fingerprint":             "fp_mock_deepseek",
+			"usage.prompt_cache_hit_tokens":  0,
+			"usage.prompt_cache_miss_tokens": 10,
+		}},
+		{ProfileOpenRouter, [][]interface{}{{"choices", 0, "logprobs"}}, nil, map[string]interface{}{
+			"provider":                       "Mock",
+			"choices.0.native_finish_reason": "stop",
+		}},
+		{ProfileVLLM, [][]interface{}{{"prompt_logprobs"}, {"choices", 0, "stop_reason"}, {"choices", 0, "logprobs"}}, nil, nil},
+		{ProfileGroq, [][]interface{}{{"usage", "total_time"}, {"usage", "queue_time"}}, nil, map[string]interface{}{
+			"x_groq.id":          "req_abc123",
+			"system_fingerprint": "fp_mock_groq",
+		}},
+		{ProfileTogether, [][]interface{}{{"prompt"}}, nil, map[string]interface{}{
+			"choices.0.finish_reason": "eos",
+		}},
+	}
+	for _, tc := range cases {
+		shaped := Get(tc.profile).Shape(chatResponse(), KindChat)
+		object := toObject(shaped)
+		for _, path := range tc.present {
+			if _, ok := field(object, path...); !ok {
+				t.Errorf("%s: missing %v", tc.profile, path)
+			}
+		}
+		for _, path := range tc.absent {
+			if _, ok := field(object, path...); ok {
+				t.Errorf("%s: unexpected %v", tc.profile, path)
+			}
+		}
+		for path, want := range tc.values {
+			got, ok := field(object, splitPath(path)...)
+			if gotJSON, wantJSON := mustJSON(got), mustJSON(want); !ok || gotJSON != wantJSON {
+				t.Errorf("%s: %s = %s, want %s", tc.profile, path, gotJSON, wantJSON)
+			}
+		}
+	}
+}
+
+func TestShapeStream(t *testing.T) {
+	usage := api.ChatCompletionUsage{PromptTokens: 10, CompletionTokens: 5, TotalTokens: 15}
+
+	// openai 不转换数据块
+	if shaped := Get(ProfileOpenAI).ShapeStream(chatChunks(), KindChatChunk, usage); len(shaped) != 3 {
+		t.Errorf("openai profile returned %d chunks", len(shaped))
+	} else if _, ok := shaped[0].(api.ChatCompletionChunkResponse); !ok {
+		t.Errorf("openai profile converted the chunks to %T", shaped[0])
+	}
+
+	// groq 在第一块返回请求ID，在最后一块返回token使用量
+	groq := Get(ProfileGroq).ShapeStream(chatChunks(), KindChatChunk, usage)
+	if id, _ := field(groq[0], "x_groq", "id"); id != "req_abc123" {
+		t.Errorf("groq first chunk: x_groq.id %v", id)
+	}
+	if _, ok := field(groq[1], "x_groq"); ok {
+		t.Error("groq middle chunk has x_groq")
+	}
+	if total, _ := field(groq[2], "x_groq", "usage", "total_tokens"); mustJSON(total) != "15" {
+		t.Errorf("groq last chunk: usage.total_tokens %v", total)
+	}
+
+	// together 在数据块的选项中返回 text，只有最后一块返回token使用量
+	together := Get(ProfileTogether).ShapeStream(chatChunks(), KindChatChunk, usage)
+	for i, chunk := range together {
+		text, _ := field(chunk, "choices", 0, "text")
+		chunkUsage, _ := field(chunk, "usage")
+		if text != []string{"a", "b", "c"}[i] || (chunkUsage != nil) != (i == 2) {
+			t.Errorf("together chunk %d: text %v, usage %v", i, text, chunkUsage)
+		}
+	}
+	if reason, _ := field(together[2], "choices", 0, "finish_reason"); reason != "eos" {
+		t.Errorf("together last chunk: finish_reason %v", reason)
+	}
+}
+
+// splitPath 将点分隔的路径拆分为字段名和数组下标
+func splitPath(path string) []interface{} {
+	var keys []interface{}
+	start := 0
+	for i := 0; i <= len(path); i++ {
+		if i < len(path) && path[i] != '.' {
+			continue
+		}
+		key := path[start:i]
+		if n, err := json.Number(key).Int64(); err == nil {
+			keys = append(keys, int(n))
+		} else {
+			keys = append(keys, key)
+		}
+		start = i + 1
+	}
+	return keys
+}
+
+// mustJSON 返回值的JSON编码
+func mustJSON(v interface{}) string {
+	data, _ := json.Marshal(v)
+	return string(data)
+}
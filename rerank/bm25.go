@@ -0,0 +1,143 @@
+package rerank
+
+import (
+	"math"
+	"strings"
+	"unicode"
+)
+
+// BM25 参数
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// 文档分块时每块包含的词元数
+const chunkSize = 512
+
+// Score 使用BM25计算每个文档与查询的相关性，返回校准到0到1之间的分数
+// 超长文档按块切分，取前maxChunksPerDoc个块中的最高分，maxChunksPerDoc<=0表示不限制
+func Score(query string, documents []string, maxChunksPerDoc int) []float64 {
+	queryTerms := Tokenize(query)
+
+	// 切分文档块，记录每个块所属的文档
+	var chunks [][]string
+	var owners []int
+	for i, doc := range documents {
+		terms := Tokenize(doc)
+		for start, n := 0, 1; ; start, n = start+chunkSize, n+1 {
+			end := min(start+chunkSize, len(terms))
+			chunks = append(chunks, terms[start:end])
+			owners = append(owners, i)
+			if end == len(terms) || (maxChunksPerDoc > 0 && n >= maxChunksPerDoc) {
+				break
+			}
+		}
+	}
+
+	// 统计文档频率和平均长度
+	docFreq := make(map[string]int)
+	totalLength := 0
+	for _, chunk := range chunks {
+		totalLength += len(chunk)
+		seen := make(map[string]bool)
+		for _, term := range chunk {
+			if !seen[term] {
+				seen[term] = true
+				docFreq[term]++
+			}
+		}
+	}
+	avgLength := 1.0
+	if len(chunks) > 0 && totalLength > 0 {
+		avgLength = float64(totalLength) / float64(len(chunks))
+	}
+
+	// 查询词的IDF以及参考分数：每个查询词在平均长度文档中出现一次时的得分，用于把BM25分数校准到0到1
+	n := float64(len(chunks))
+	idf := make(map[string]float64)
+	upperBound := 0.0
+	for _, term := range queryTerms {
+		df := float64(docFreq[term])
+		value := math.Log(1 + (n-df+0.5)/(df+0.5))
+		idf[term] = value
+		upperBound += value
+	}
+
+	scores := make([]float64, len(documents))
+	for i, chunk := range chunks {
+		termFreq := make(map[string]int)
+		for _, term := range chunk {
+			termFreq[term]++
+		}
+
+		raw := 0.0
+		lengthNorm := 1 - bm25B + bm25B*float64(len(chunk))/avgLength
+		for _, term := range queryTerms {
+			tf := float64(termFreq[term])
+			if tf == 0 {
+				continue
+			}
+			raw += idf[term] * tf * (bm25K1 + 1) / (tf + bm25K1*lengthNorm)
+		}
+
+		score := 0.0
+		if upperBound > 0 {
+			score = math.Min(raw/upperBound, 1)
+		}
+		if score > scores[owners[i]] {
+			scores[owners[i]] = score
+		}
+	}
+
+	return scores
+}
+
+// Tokenize 将文本切分为检索词元
+// 拉丁字母和数字按词切分；中日韩文字没有空格，连续片段同时产生单字和相邻二元组
+func Tokenize(text string) []string {
+	var terms []string
+	var word []rune
+	var cjk []rune
+
+	flushWord := func() {
+		if len(word) > 0 {
+			terms = append(terms, string(word))
+			word = word[:0]
+		}
+	}
+	flushCJK := func() {
+		for i, r := range cjk {
+			terms = append(terms, string(r))
+			if i > 0 {
+				terms = append(terms, string(cjk[i-1:i+1]))
+			}
+		}
+		cjk = cjk[:0]
+	}
+
+	for _, r := range strings.ToLower(text) {
+		switch {
+		case isCJK(r):
+			flushWord()
+			cjk = append(cjk, r)
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			flushCJK()
+			word = append(word, r)
+		default:
+			flushWord()
+			flushCJK()
+		}
+	}
+	flushWord()
+	flushCJK()
+
+	return terms
+}
+
+// isCJK 判断字符是否为中日韩文字，长音符号ー属于通用字符，需要单独判断才不会把片假名词切开
+func isCJK(r rune) bool {
+	return unicode.Is(unicode.Han, r) || unicode.Is(unicode.Hiragana, r) ||
+		unicode.Is(unicode.Katakana, r) || unicode.Is(unicode.Hangul, r) ||
+		r == 'ー' || r == 'ｰ'
+}
@@ -0,0 +1,93 @@
+package rerank
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestScoreRanking(t *testing.T) {
+	query := "how to reset a forgotten password"
+	documents := []string{
+		"Our office is closed on public holidays.",
+		"To reset a forgotten password, open the login page and click reset password.",
+		"Passwords must contain at least eight characters.",
+		"You can reset your password from the account settings.",
+	}
+	scores := Score(query, documents, 0)
+	if len(scores) != len(documents) {
+		t.Fatalf("%d scores for %d documents", len(scores), len(documents))
+	}
+
+	// 包含全部查询词的文档最相关，没有任何查询词的文档得分为0
+	if !(scores[1] > scores[3] && scores[3] > scores[2] && scores[2] >= scores[0]) {
+		t.Fatalf("unexpected ranking: %v", scores)
+	}
+	if scores[0] != 0 {
+		t.Fatalf("unrelated document scored %v", scores[0])
+	}
+	for i, score := range scores {
+		if score < 0 || score > 1 {
+			t.Errorf("score %d out of range: %v", i, score)
+		}
+	}
+
+	if again := Score(query, documents, 0); !reflect.DeepEqual(scores, again) {
+		t.Fatalf("scores not deterministic: %v, %v", scores, again)
+	}
+}
+
+func TestScoreEmptyDocuments(t *testing.T) {
+	if scores := Score("query", nil, 0); len(scores) != 0 {
+		t.Fatalf("scores for no documents: %v", scores)
+	}
+
+	scores := Score("reset password", []string{"", "reset password", "   "}, 0)
+	if scores[0] != 0 || scores[2] != 0 || scores[1] <= 0 {
+		t.Fatalf("unexpected scores with empty documents: %v", scores)
+	}
+
+	// 查询没有可检索的词元时所有文档得分为0
+	if scores := Score("?!", []string{"reset password"}, 0); scores[0] != 0 {
+		t.Fatalf("punctuation-only query scored %v", scores[0])
+	}
+}
+
+func TestScoreChunks(t *testing.T) {
+	// 相关内容位于第二个块中，只取第一个块时得分为0
+	long := strings.Repeat("filler ", chunkSize) + "reset password"
+	documents := []string{long, "unrelated text"}
+	if scores := Score("reset password", documents, 0); scores[0] <= 0 {
+		t.Fatalf("relevant chunk ignored: %v", scores)
+	}
+	if scores := Score("reset password", documents, 1); scores[0] != 0 {
+		t.Fatalf("max_chunks_per_doc=1 still scored the second chunk: %v", scores)
+	}
+}
+
+func TestTokenize(t *testing.T) {
+	cases := []struct {
+		text string
+		want []string
+	}{
+		{"Hello, World 42!", []string{"hello", "world", "42"}},
+		{"重置密码", []string{"重", "置", "重置", "密", "置密", "码", "密码"}},
+		{"reset密码now", []string{"reset", "密", "码", "密码", "now"}},
+		{"パスワード", []string{"パ", "ス", "パス", "ワ", "スワ", "ー", "ワー", "ド", "ード"}},
+		{"비밀 번호", []string{"비", "밀", "비밀", "번", "호", "번호"}},
+		{"", nil},
+	}
+	for _, tc := range cases {
+		if got := Tokenize(tc.text); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("Tokenize(%q) = %v, want %v", tc.text, got, tc.want)
+		}
+	}
+}
+
+func TestScoreCJK(t *testing.T) {
+	// 中文没有空格，按单字和二元组匹配
+	scores := Score("如何重置密码", []string{"今天天气很好", "忘记密码时可以在登录页面重置密码", "密码长度至少八位"}, 0)
+	if !(scores[1] > scores[2] && scores[2] > scores[0]) {
+		t.Fatalf("unexpected CJK ranking: %v", scores)
+	}
+}
@@ -0,0 +1,27 @@
+package rerank
+
+// 重排序响应格式
+const (
+	// ProfileDefault 本服务原有的响应格式
+	ProfileDefault = "default"
+	// ProfileCohere Cohere /v2/rerank 的响应格式
+	ProfileCohere = "cohere"
+	// ProfileJina Jina /v1/rerank 的响应格式
+	ProfileJina = "jina"
+	// ProfileVLLM vLLM /v1/rerank 的响应格式，/score 接口始终使用vLLM格式
+	ProfileVLLM = "vllm"
+)
+
+// IsSupportedProfile 检查响应格式名称是否受支持
+func IsSupportedProfile(profile string) bool {
+	switch profile {
+	case ProfileDefault, ProfileCohere, ProfileJina, ProfileVLLM:
+		return true
+	}
+	return false
+}
+
+// DefaultReturnDocuments 返回各响应格式在未指定 return_documents 时的默认行为
+func DefaultReturnDocuments(profile string) bool {
+	return profile != ProfileCohere
+}
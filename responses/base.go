@@ -6,6 +6,7 @@ import (
 
 	"RobinPenn974/OpenAI-mocker/api"
 	"RobinPenn974/OpenAI-mocker/templates"
+	"RobinPenn974/OpenAI-mocker/tokenizer"
 )
 
 // ResponseGenerator 定义了响应生成器的通用接口
@@ -19,15 +20,68 @@ type ReasoningSupport interface {
 	// GenerateReasoningContent 生成推理内容
 	GenerateReasoningContent(input string, modelID string) string
 
-	// ShouldUseReasoningField 判断是否应该使用专门的reasoning_content字段
-	ShouldUseReasoningField() bool
+	// ReasoningFormat 返回推理内容的返回格式：reasoning_content、reasoning 或 think
+	ReasoningFormat() string
 }
 
 // ResponseContent 包含生成的响应内容
 type ResponseContent struct {
 	Content          string  // 主要内容
 	ReasoningContent *string // 可选的推理内容，如果不支持则为nil
+	ReasoningField   string  // 推理内容返回的字段，reasoning_content 或 reasoning
+	ReasoningTokens  int     // 推理内容的token数，包括以<think>标签合并到回复中的推理内容
 	FinishReason     string  // 结束原因
+	Rule             string  // 命中的模板规则，即使用的模板字段
+	Language         string  // 从输入检测到的语言
+}
+
+// 模板规则，按顺序匹配输入内容，关键词包含常见语言的说法
+const (
+	RuleGreeting    = "greeting"     // 输入包含 hello、hi、你好 等问候语
+	RuleHelpRequest = "help_request" // 输入包含 help、帮助 等求助用语
+	RuleQuestion    = "question"     // 输入包含问号
+	RuleDefault     = "default"      // 其他输入
+)
+
+// SelectResponse 根据输入内容选择模板规则，返回加上前缀的回复、命中的规则和结束原因。
+// 按输入的语言依次使用该语言的候选回复、该语言的本地化文本、候选回复和字段本身，
+// 生成的文本超过 opts.MaxTokens 时截断并返回 length
+func SelectResponse(template templates.ResponseTemplate, input string, opts Options) (string, string, string) {
+	rule := matchRule(input)
+	language := DetectLanguage(input)
+
+	// 前缀也计入最大生成token数
+	if opts.MaxTokens > 0 {
+		opts.MaxTokens = max(opts.MaxTokens-tokenizer.CountTokens(template.Prefix), 1)
+	}
+
+	localized := rule + "." + language
+	switch {
+	case language != "" && len(template.Variants[localized]) > 0:
+		text, finishReason := renderVariant(template.Variants[localized], input, opts)
+		return template.Prefix + text, rule, finishReason
+	case language != "" && template.Localized[localized] != "":
+		return template.Prefix + template.Localized[localized], rule, "stop"
+	case len(template.Variants[rule]) > 0:
+		text, finishReason := renderVariant(template.Variants[rule], input, opts)
+		return template.Prefix + text, rule, finishReason
+	default:
+		return template.Prefix + templateField(template, rule), rule, "stop"
+	}
+}
+
+// templateField 返回规则对应的模板字段
+func templateField(template templates.ResponseTemplate, rule string) string {
+	switch rule {
+	case RuleGreeting:
+		return template.Greeting
+	case RuleHelpRequest:
+		return template.HelpRequest
+	case RuleQuestion:
+		return template.Question
+	default:
+		return template.Default
+	}
 }
 
 // GenerateID 生成唯一的响应ID
@@ -40,26 +94,39 @@ func GetCurrentTimestamp() int64 {
 	return time.Now().Unix()
 }
 
-// ModelFactory 根据模型ID和类型返回合适的响应生成器
-func ModelFactory(modelID string) ResponseGenerator {
-	// 特殊处理推理模型
-	if modelID == "deepseek-reasoner" {
-		return NewReasoningGenerator()
-	}
+// Options 响应生成的功能选项
+type Options struct {
+	ReasoningField  bool   // 推理内容使用 reasoning_content 字段返回，否则以<think>标签合并到content中
+	ReasoningFormat string // 服务商兼容配置指定的推理内容返回格式，优先于模板的 reasoning_format
 
-	// 获取模型模板
-	template := templates.GetTemplate(modelID)
+	Tokens    templates.TokenDistribution // 生成器未设置分布时使用的目标token数分布
+	CorpusDir string                      // 马尔可夫链语料文件所在目录
 
-	// 如果模板中设置了支持推理，返回推理生成器
-	if template.SupportReasoning {
-		return NewReasoningGenerator()
+	Seed      *int64 // 请求的seed，设置后相同输入的候选回复和生成的文本保持一致
+	MaxTokens int    // 请求的最大生成token数，为0时不限制
+
+	Thinking        *bool  // 请求开启或关闭推理，为nil时由模板决定
+	ReasoningEffort string // 请求的推理强度，调整推理内容的长度，为空时使用模板的推理内容
+	ReasoningBudget int    // 推理内容的最大token数，为0时不限制
+}
+
+// ModelFactory 根据模型ID、模型的响应模板和功能选项返回合适的响应生成器
+func ModelFactory(modelID string, template templates.ResponseTemplate, opts Options) ResponseGenerator {
+	// 模板中设置了支持推理时使用推理生成器，deepseek-reasoner 除非模板显式关闭推理
+	reasoning := template.ReasoningEnabled() || (modelID == "deepseek-reasoner" && template.SupportReasoning == nil)
+	// 请求可以开启或关闭推理
+	if opts.Thinking != nil {
+		reasoning = *opts.Thinking
+	}
+	if reasoning {
+		return NewReasoningGenerator(template, opts)
 	}
 
 	// 处理文本补全模型
 	if strings.Contains(modelID, "davinci") {
-		return NewCompletionGenerator()
+		return NewCompletionGenerator(template, opts)
 	}
 
 	// 默认使用普通聊天模型
-	return NewChatGenerator()
+	return NewChatGenerator(template, opts)
 }
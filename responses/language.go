@@ -0,0 +1,85 @@
+package responses
+
+import (
+	"strings"
+	"unicode"
+)
+
+// 检测到的语言，拉丁字母统一视为英文
+const (
+	LanguageEnglish  = "en"
+	LanguageChinese  = "zh"
+	LanguageJapanese = "ja"
+	LanguageKorean   = "ko"
+	LanguageRussian  = "ru"
+)
+
+// DetectLanguage 按文字类型检测文本的语言：包含假名为日文，否则取汉字、谚文、西里尔字母和拉丁字母中占比最多的一种，
+// 一个汉字、假名或谚文音节按两个字母计算。文本中没有文字时返回空字符串
+func DetectLanguage(text string) string {
+	var han, kana, hangul, cyrillic, latin int
+	for _, r := range text {
+		switch {
+		case unicode.Is(unicode.Hiragana, r) || unicode.Is(unicode.Katakana, r):
+			kana++
+		case unicode.Is(unicode.Han, r):
+			han++
+		case unicode.Is(unicode.Hangul, r):
+			hangul++
+		case unicode.Is(unicode.Cyrillic, r):
+			cyrillic++
+		case unicode.Is(unicode.Latin, r):
+			latin++
+		}
+	}
+
+	scores := []struct {
+		language string
+		score    int
+	}{
+		{LanguageJapanese, (kana + han) * 2},
+		{LanguageChinese, han * 2},
+		{LanguageKorean, hangul * 2},
+		{LanguageRussian, cyrillic},
+		{LanguageEnglish, latin},
+	}
+	if kana == 0 {
+		scores[0].score = 0
+	}
+
+	language, best := "", 0
+	for _, s := range scores {
+		if s.score > best {
+			language, best = s.language, s.score
+		}
+	}
+	return language
+}
+
+// IsCJKLanguage 判断语言的文字是否不以空格分词
+func IsCJKLanguage(language string) bool {
+	return language == LanguageChinese || language == LanguageJapanese
+}
+
+// ruleTriggers 各模板规则的关键词，包含常见语言的问候语和求助用语
+var ruleTriggers = []struct {
+	rule     string
+	keywords []string
+}{
+	{RuleGreeting, []string{"hello", "hi", "你好", "您好", "嗨", "哈喽", "こんにちは", "こんばんは", "おはよう", "안녕", "привет", "здравствуй"}},
+	{RuleHelpRequest, []string{"help", "帮助", "帮忙", "帮我", "助けて", "手伝", "도와", "도움", "помоги", "помощь"}},
+	{RuleQuestion, []string{"?", "？", "吗", "呢"}},
+}
+
+// matchRule 按顺序返回输入命中的第一个规则，都未命中时返回 default
+func matchRule(input string) string {
+	lower := strings.ToLower(input)
+	for _, trigger := range ruleTriggers {
+		for _, keyword := range trigger.keywords {
+			if strings.Contains(lower, keyword) {
+				return trigger.rule
+			}
+		}
+	}
+	return RuleDefault
+}
@@ -0,0 +1,73 @@
+package responses
+
+import (
+	"testing"
+
+	"RobinPenn974/OpenAI-mocker/templates"
+)
+
+func TestDetectLanguage(t *testing.T) {
+	cases := []struct {
+		text string
+		want string
+	}{
+		{"How do I reset my password?", LanguageEnglish},
+		{"Bonjour, ça va ?", LanguageEnglish},
+		{"你好，请问怎么重置密码？", LanguageChinese},
+		{"パスワードをリセットするには？", LanguageJapanese},
+		{"東京に行きたい", LanguageJapanese},
+		{"東京", LanguageChinese},
+		{"비밀번호를 재설정하려면?", LanguageKorean},
+		{"Как сбросить пароль?", LanguageRussian},
+		{"请帮我修复这个 bug", LanguageChinese},
+		{"请帮我 fix this bug", LanguageEnglish},
+		{"Please translate 你好 into English", LanguageEnglish},
+		{"12345 !?", ""},
+		{"", ""},
+	}
+	for _, tc := range cases {
+		if got := DetectLanguage(tc.text); got != tc.want {
+			t.Errorf("DetectLanguage(%q) = %q, want %q", tc.text, got, tc.want)
+		}
+	}
+}
+
+func TestSelectResponseLocalized(t *testing.T) {
+	template := templates.ResponseTemplate{
+		Prefix:   "> ",
+		Greeting: "Hello!",
+		Question: "Good question.",
+		Default:  "OK.",
+		Localized: map[string]string{
+			"greeting.zh": "你好！",
+			"greeting.ja": "こんにちは！",
+			"question.ru": "Хороший вопрос.",
+		},
+		Variants: map[string][]templates.Variant{
+			"greeting.ko": {{Text: "안녕하세요!"}},
+		},
+	}
+
+	// 依次使用该语言的候选回复、本地化文本和字段本身
+	cases := []struct {
+		input string
+		rule  string
+		want  string
+	}{
+		{"hello there", RuleGreeting, "> Hello!"},
+		{"你好", RuleGreeting, "> 你好！"},
+		{"こんにちは", RuleGreeting, "> こんにちは！"},
+		{"안녕", RuleGreeting, "> 안녕하세요!"},
+		{"привет", RuleGreeting, "> Hello!"},
+		{"Как дела?", RuleQuestion, "> Хороший вопрос."},
+		{"这是什么？", RuleQuestion, "> Good question."},
+		{"帮我看看", RuleHelpRequest, "> "},
+		{"12345", RuleDefault, "> OK."},
+	}
+	for _, tc := range cases {
+		content, rule, finishReason := SelectResponse(template, tc.input, Options{})
+		if content != tc.want || rule != tc.rule || finishReason != "stop" {
+			t.Errorf("%q: %q, %s, %s, want %q, %s, stop", tc.input, content, rule, finishReason, tc.want, tc.rule)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package responses
+
+import (
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"RobinPenn974/OpenAI-mocker/watch"
+)
+
+// markovChain 二阶词级马尔可夫链
+type markovChain struct {
+	starts [][2]string            // 句首的两个词
+	next   map[[2]string][]string // 两个词之后可能出现的词
+}
+
+// cachedChain 缓存的马尔可夫链及训练时语料文件的状态
+type cachedChain struct {
+	stamp watch.Stamp
+	chain *markovChain
+}
+
+var (
+	chainMu    sync.Mutex
+	chainCache = map[string]cachedChain{}
+)
+
+// loadMarkovChain 加载语料文件训练的马尔可夫链，语料文件修改后重新训练，name为空时使用内置语料
+func loadMarkovChain(corpusDir, name string) (*markovChain, error) {
+	chainMu.Lock()
+	defer chainMu.Unlock()
+
+	if name == "" {
+		if cached, exists := chainCache[""]; exists {
+			return cached.chain, nil
+		}
+		chain, err := trainMarkovChain(builtinCorpus)
+		if err != nil {
+			return nil, err
+		}
+		chainCache[""] = cachedChain{chain: chain}
+		return chain, nil
+	}
+
+	path := filepath.Join(corpusDir, name)
+	stamp := watch.StampOf(path)
+	if cached, exists := chainCache[path]; exists && cached.stamp == stamp {
+		return cached.chain, nil
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading corpus: %v", err)
+	}
+	chain, err := trainMarkovChain(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("corpus %s: %v", name, err)
+	}
+	chainCache[path] = cachedChain{stamp: stamp, chain: chain}
+	return chain, nil
+}
+
+// trainMarkovChain 在语料上训练马尔可夫链，以句末标点结尾的词之后为新句子的开始
+func trainMarkovChain(corpus string) (*markovChain, error) {
+	words := strings.Fields(corpus)
+	if len(words) < 3 {
+		return nil, fmt.Errorf("corpus must contain at least 3 words")
+	}
+
+	chain := &markovChain{next: make(map[[2]string][]string)}
+	for i := 0; i+2 < len(words); i++ {
+		key := [2]string{words[i], words[i+1]}
+		chain.next[key] = append(chain.next[key], words[i+2])
+		if i == 0 || endsSentence(words[i-1]) {
+			chain.starts = append(chain.starts, key)
+		}
+	}
+	if len(chain.starts) == 0 {
+		chain.starts = append(chain.starts, [2]string{words[0], words[1]})
+	}
+	return chain, nil
+}
+
+// source 返回逐词生成文本的片段来源，每隔几句另起一段
+func (m *markovChain) source(rng *rand.Rand) func() string {
+	var pending []string
+	var state [2]string
+	first := true
+	sentences := 0
+	paragraphBreak := false
+	return func() string {
+		if len(pending) == 0 {
+			followers := m.next[state]
+			if len(followers) == 0 {
+				state = m.starts[rng.Intn(len(m.starts))]
+				pending = []string{state[0], state[1]}
+			} else {
+				word := followers[rng.Intn(len(followers))]
+				state = [2]string{state[1], word}
+				pending = []string{word}
+			}
+		}
+
+		word := pending[0]
+		pending = pending[1:]
+		prefix := " "
+		switch {
+		case first:
+			prefix = ""
+			first = false
+		case paragraphBreak:
+			prefix = "\n\n"
+			paragraphBreak = false
+		}
+		if endsSentence(word) {
+			if sentences++; sentences%(3+rng.Intn(3)) == 0 {
+				paragraphBreak = true
+			}
+		}
+		return prefix + word
+	}
+}
+
+// endsSentence 判断词是否以句末标点结尾
+func endsSentence(word string) bool {
+	return strings.HasSuffix(word, ".") || strings.HasSuffix(word, "!") || strings.HasSuffix(word, "?") ||
+		strings.HasSuffix(word, "。") || strings.HasSuffix(word, "！") || strings.HasSuffix(word, "？")
+}
+
+// builtinCorpus 未指定语料文件时使用的内置语料
+const builtinCorpus = `A mock server is only as useful as the situations it can reproduce. When a client is tested against
+responses that never change, the tests pass for the wrong reasons. Real models answer with short replies, long
+explanations, lists, tables and code. A good mock should do the same, so that the client handles every shape of
+answer it will meet in production.
+
+Streaming makes this even more important. The client has to render partial text, keep the scroll position stable
+and handle the final chunk correctly. Short answers finish before the interface has time to update. Long answers
+reveal layout problems, slow rendering and memory growth. Testing both is the only way to know that the client
+behaves well.
+
+The server keeps its behaviour predictable. When a request carries a seed, the same input produces the same
+answer, which makes failures easy to reproduce. Without a seed, every request may pick a different variant and a
+different length. This helps to find problems that only appear with certain answers. Developers can write the
+templates they need, combine fixed replies with generated text and tune the length of every answer.
+
+Good tests describe what the user sees. They check that the first token arrives quickly, that the text is
+complete and that nothing is lost when the connection closes early. They also check how the client reacts when
+the answer is cut off because it reached the token limit. With varied responses, these checks become meaningful,
+and the team can trust the results.`
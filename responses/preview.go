@@ -0,0 +1,71 @@
+package responses
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"RobinPenn974/OpenAI-mocker/templates"
+)
+
+// placeholderPattern 匹配模板中的 {name} 占位符
+var placeholderPattern = regexp.MustCompile(`\{[A-Za-z_][A-Za-z0-9_]*\}`)
+
+// GeneratorName 返回响应生成器的类型名：chat、completion 或 reasoning
+func GeneratorName(generator ResponseGenerator) string {
+	switch generator.(type) {
+	case *ReasoningGenerator:
+		return "reasoning"
+	case *CompletionGenerator:
+		return "completion"
+	default:
+		return "chat"
+	}
+}
+
+// TemplateWarnings 检查模板渲染中可能不符合预期的地方，如命中的字段为空、占位符不会被替换
+func TemplateWarnings(template templates.ResponseTemplate, generator ResponseGenerator, content ResponseContent) []string {
+	var warnings []string
+
+	fields := map[string]string{
+		RuleGreeting:    template.Greeting,
+		RuleHelpRequest: template.HelpRequest,
+		RuleQuestion:    template.Question,
+		RuleDefault:     template.Default,
+	}
+	localizedKey := content.Rule + "." + content.Language
+	if strings.TrimSpace(fields[content.Rule]) == "" && len(template.Variants[content.Rule]) == 0 &&
+		len(template.Variants[localizedKey]) == 0 && template.Localized[localizedKey] == "" {
+		warnings = append(warnings, fmt.Sprintf("matched field '%s' is empty, the response only contains the prefix", content.Rule))
+	}
+
+	// 只有推理模板中的 {question} 会被替换
+	for _, name := range []string{RuleGreeting, RuleHelpRequest, RuleQuestion, RuleDefault} {
+		for _, placeholder := range placeholderPattern.FindAllString(fields[name], -1) {
+			warnings = append(warnings, fmt.Sprintf("field '%s' contains placeholder %s which is not substituted", name, placeholder))
+		}
+		for i, variant := range template.Variants[name] {
+			for _, placeholder := range placeholderPattern.FindAllString(variant.Text, -1) {
+				warnings = append(warnings, fmt.Sprintf("variants.%s[%d] contains placeholder %s which is not substituted", name, i, placeholder))
+			}
+		}
+	}
+	for key, text := range template.Localized {
+		if strings.HasPrefix(key, "reasoning_template.") {
+			continue
+		}
+		for _, placeholder := range placeholderPattern.FindAllString(text, -1) {
+			warnings = append(warnings, fmt.Sprintf("localized.%s contains placeholder %s which is not substituted", key, placeholder))
+		}
+	}
+	for _, placeholder := range placeholderPattern.FindAllString(template.ReasoningTemplate, -1) {
+		if placeholder != "{question}" {
+			warnings = append(warnings, fmt.Sprintf("reasoning_template contains unsupported placeholder %s, only {question} is substituted", placeholder))
+		}
+	}
+
+	if _, reasoning := generator.(*ReasoningGenerator); !reasoning && (template.ReasoningTemplate != "" || template.ReasoningPrefix != "" || template.ReasoningFormat != "") {
+		warnings = append(warnings, "reasoning_template, reasoning_prefix and reasoning_format are ignored because reasoning is disabled by the template or the request")
+	}
+	return warnings
+}
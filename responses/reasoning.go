@@ -1,70 +1,84 @@
 package responses
 
 import (
-	"os"
+	"fmt"
 	"strings"
 
 	"RobinPenn974/OpenAI-mocker/templates"
+	"RobinPenn974/OpenAI-mocker/tokenizer"
+)
+
+// 推理强度
+const (
+	ReasoningEffortLow    = "low"    // 只保留推理内容的第一段和最后一段
+	ReasoningEffortMedium = "medium" // 使用模板的推理内容
+	ReasoningEffortHigh   = "high"   // 在结论之前追加约两倍长度的分析
 )
 
 // ReasoningGenerator 推理模型响应生成器
-type ReasoningGenerator struct{}
+type ReasoningGenerator struct {
+	template templates.ResponseTemplate // 模型的响应模板
+	opts     Options                    // 响应生成选项
+}
 
-// NewReasoningGenerator 创建一个新的推理响应生成器
-func NewReasoningGenerator() *ReasoningGenerator {
-	return &ReasoningGenerator{}
+// NewReasoningGenerator 创建一个新的推理响应生成器，推理内容的返回格式见 ReasoningFormat
+func NewReasoningGenerator(template templates.ResponseTemplate, opts Options) *ReasoningGenerator {
+	return &ReasoningGenerator{template: template, opts: opts}
 }
 
 // GenerateResponse 根据输入生成推理模型的响应
 func (g *ReasoningGenerator) GenerateResponse(input string, modelID string) ResponseContent {
-	// 获取模型的响应模板
-	template := templates.GetTemplate(modelID)
+	template := g.template
 
-	// 生成推理内容
+	// 生成推理内容，超过请求的思考预算时截断
 	reasoningContent := g.GenerateReasoningContent(input, modelID)
 	reasoningContent = template.Prefix + template.ReasoningPrefix + reasoningContent
+	if g.opts.ReasoningBudget > 0 {
+		reasoningContent = truncateTokens(reasoningContent, g.opts.ReasoningBudget)
+	}
 
 	// 生成常规回复内容
-	var responseText string
-	if strings.Contains(strings.ToLower(input), "hello") || strings.Contains(strings.ToLower(input), "hi") {
-		responseText = template.Prefix + template.Greeting
-	} else if strings.Contains(strings.ToLower(input), "help") {
-		responseText = template.Prefix + template.HelpRequest
-	} else if strings.Contains(strings.ToLower(input), "?") {
-		responseText = template.Prefix + template.Question
-	} else {
-		responseText = template.Prefix + template.Default
-	}
+	responseText, rule, finishReason := SelectResponse(template, input, g.opts)
 
-	// 根据环境变量决定如何处理推理内容
-	if g.ShouldUseReasoningField() {
-		// 启用了推理功能，单独返回推理内容和回复内容
-		reasoningPtr := &reasoningContent
-		return ResponseContent{
-			Content:          responseText,
-			ReasoningContent: reasoningPtr,
-			FinishReason:     "stop",
-		}
+	content := ResponseContent{
+		Content:         responseText,
+		FinishReason:    finishReason,
+		Rule:            rule,
+		Language:        DetectLanguage(input),
+		ReasoningTokens: tokenizer.CountTokens(reasoningContent),
+	}
+	if format := g.ReasoningFormat(); format == templates.ReasoningFormatThink {
+		// 将推理内容合并到回复内容中
+		content.Content = "<think>" + reasoningContent + "</think>\n\n" + responseText
 	} else {
-		// 未启用推理功能，将推理内容合并到回复内容中
-		combinedContent := "<think>" + reasoningContent + "</think>\n\n" + responseText
-		return ResponseContent{
-			Content:          combinedContent,
-			ReasoningContent: nil,
-			FinishReason:     "stop",
-		}
+		// 单独返回推理内容和回复内容
+		content.ReasoningContent = &reasoningContent
+		content.ReasoningField = format
 	}
+	return content
 }
 
-// GenerateReasoningContent 生成推理内容
+// GenerateReasoningContent 生成推理内容，按请求的推理强度调整长度
 func (g *ReasoningGenerator) GenerateReasoningContent(question string, modelID string) string {
-	// 获取模板
-	template := templates.GetTemplate(modelID)
+	template := g.template
 
-	// 使用模板中的推理模板，如果为空则使用默认值
-	reasoningTemplate := template.ReasoningTemplate
-
-	// 如果模板中未定义推理模板，则使用默认内容
+	// 优先使用输入语言的推理模板，其次是模板中的推理模板，都为空时使用默认内容
+	language := DetectLanguage(question)
+	reasoningTemplate := template.Localized["reasoning_template."+language]
+	if reasoningTemplate == "" {
+		reasoningTemplate = template.ReasoningTemplate
+	}
+	if reasoningTemplate == "" && language == LanguageChinese {
+		reasoningTemplate = "让我一步一步地思考这个问题。\n\n" +
+			"首先，我需要理解问题的含义：\n" +
+			"用户的问题是：{question}\n\n" +
+			"接下来，我将分解这个问题进行分析：\n" +
+			"1. 找出关键信息\n" +
+			"2. 运用相关知识\n" +
+			"3. 从不同角度考虑\n" +
+			"4. 得出合理的结论\n\n" +
+			"根据以上分析，我现在可以给出完整的回答。"
+	}
 	if reasoningTemplate == "" {
 		reasoningTemplate = "Let me think step by step about this question.\n\n" +
 			"First, I need to understand what is being asked:\n" +
@@ -80,11 +94,65 @@ func (g *ReasoningGenerator) GenerateReasoningContent(question string, modelID s
 	// 替换内容中的占位符
 	reasoningTemplate = strings.Replace(reasoningTemplate, "{question}", question, -1)
 
-	return reasoningTemplate
+	return g.scaleReasoning(reasoningTemplate, question, language)
+}
+
+// ReasoningFormat 返回推理内容的返回格式，依次使用服务商兼容配置和模板的设置，
+// 都未设置时 opts.ReasoningField 为true使用 reasoning_content 字段，否则以<think>标签合并到回复中
+func (g *ReasoningGenerator) ReasoningFormat() string {
+	if g.opts.ReasoningFormat != "" {
+		return g.opts.ReasoningFormat
+	}
+	if g.template.ReasoningFormat != "" {
+		return g.template.ReasoningFormat
+	}
+	if g.opts.ReasoningField {
+		return templates.ReasoningFormatContent
+	}
+	return templates.ReasoningFormatThink
+}
+
+// scaleReasoning 按推理强度调整推理内容的长度，追加的分析在设置了seed时保持一致
+func (g *ReasoningGenerator) scaleReasoning(reasoning, question, language string) string {
+	paragraphs := strings.Split(reasoning, "\n\n")
+	last := len(paragraphs) - 1
+
+	switch g.opts.ReasoningEffort {
+	case ReasoningEffortLow:
+		if last < 2 {
+			return reasoning
+		}
+		return paragraphs[0] + "\n\n" + paragraphs[last]
+	case ReasoningEffortHigh:
+		spec := templates.GeneratorSpec{Type: templates.GeneratorMarkov}
+		if language == LanguageChinese {
+			spec.Type = templates.GeneratorLoremZh
+		}
+		analysis, err := GenerateText(spec, 2*tokenizer.CountTokens(reasoning), true, newRand(g.opts.Seed, question), g.opts.CorpusDir)
+		if err != nil {
+			fmt.Printf("Error generating reasoning text: %v\n", err)
+			return reasoning
+		}
+		if last == 0 {
+			return reasoning + "\n\n" + analysis
+		}
+		return strings.Join(paragraphs[:last], "\n\n") + "\n\n" + analysis + "\n\n" + paragraphs[last]
+	default:
+		return reasoning
+	}
 }
 
-// ShouldUseReasoningField 判断是否应该使用专门的reasoning_content字段
-func (g *ReasoningGenerator) ShouldUseReasoningField() bool {
-	val := os.Getenv("ENABLE_REASONING")
-	return strings.ToLower(val) == "true" || val == "1"
+// truncateTokens 按预分词片段截断文本，使其不超过limit个token
+func truncateTokens(text string, limit int) string {
+	var builder strings.Builder
+	count := 0
+	for _, piece := range tokenizer.Pieces(text) {
+		tokens := tokenizer.CountTokens(piece)
+		if count+tokens > limit {
+			break
+		}
+		count += tokens
+		builder.WriteString(piece)
+	}
+	return strings.TrimRight(builder.String(), " \t\r\n")
 }
@@ -0,0 +1,267 @@
+package responses
+
+import (
+	"fmt"
+	"math/rand"
+	"regexp"
+	"strings"
+
+	"RobinPenn974/OpenAI-mocker/templates"
+	"RobinPenn974/OpenAI-mocker/tokenizer"
+)
+
+// piecePattern 将文本块切分为带前导空白的片段，代码缩进和换行随片段保留
+var piecePattern = regexp.MustCompile(`\s*\S+`)
+
+// GenerateText 使用生成器生成约target个token的文本，closeBlocks为true时补全被截断的代码块
+func GenerateText(spec templates.GeneratorSpec, target int, closeBlocks bool, rng *rand.Rand, corpusDir string) (string, error) {
+	var next func() string
+	switch spec.Type {
+	case templates.GeneratorLorem:
+		next = sentenceSource(rng, loremWords, " ", ".", ",")
+	case templates.GeneratorLoremZh:
+		next = sentenceSource(rng, loremZhPhrases, "", "。", "，")
+	case templates.GeneratorMarkov:
+		chain, err := loadMarkovChain(corpusDir, spec.Corpus)
+		if err != nil {
+			return "", err
+		}
+		next = chain.source(rng)
+	case templates.GeneratorMarkdown:
+		next = blockSource(func(first bool) string { return markdownBlock(rng, first) })
+	case templates.GeneratorCode:
+		next = blockSource(func(first bool) string { return codeBlock(rng) })
+	default:
+		return "", fmt.Errorf("unknown generator '%s'", spec.Type)
+	}
+
+	var builder strings.Builder
+	for tokens := 0; tokens < target; {
+		piece := next()
+		builder.WriteString(piece)
+		tokens += tokenizer.CountTokens(piece)
+	}
+	text := strings.TrimSpace(builder.String())
+	if closeBlocks && strings.Count(text, "```")%2 == 1 {
+		text += "\n```"
+	}
+	return text, nil
+}
+
+// sentenceSource 从词表中随机组成句子和段落，separator为词之间的分隔符，
+// 中文假文的词之间没有空格，句子之间按句号和逗号分隔
+func sentenceSource(rng *rand.Rand, words []string, separator, period, comma string) func() string {
+	wordsLeft, sentencesLeft := 0, 0
+	first := true
+	return func() string {
+		prefix := separator
+		startSentence := wordsLeft == 0
+		if startSentence {
+			wordsLeft = 6 + rng.Intn(9)
+			if sentencesLeft == 0 {
+				sentencesLeft = 3 + rng.Intn(4)
+				if !first {
+					prefix = "\n\n"
+				}
+			}
+		}
+		if first {
+			prefix = ""
+			first = false
+		}
+
+		word := words[rng.Intn(len(words))]
+		if startSentence && separator != "" {
+			word = strings.ToUpper(word[:1]) + word[1:]
+		}
+		wordsLeft--
+		switch {
+		case wordsLeft == 0:
+			word += period
+			sentencesLeft--
+		case rng.Intn(8) == 0:
+			word += comma
+		}
+		return prefix + word
+	}
+}
+
+// blockSource 将生成的文本块逐片段返回，块之间以空行分隔
+func blockSource(block func(first bool) string) func() string {
+	var pieces []string
+	first := true
+	return func() string {
+		if len(pieces) == 0 {
+			pieces = piecePattern.FindAllString(block(first), -1)
+			if !first {
+				pieces[0] = "\n\n" + strings.TrimLeft(pieces[0], " \t\n")
+			}
+			first = false
+		}
+		piece := pieces[0]
+		pieces = pieces[1:]
+		return piece
+	}
+}
+
+// loremSentence 返回一个随机的 lorem ipsum 句子
+func loremSentence(rng *rand.Rand, minWords, maxWords int) string {
+	count := minWords + rng.Intn(maxWords-minWords+1)
+	words := make([]string, count)
+	for i := range words {
+		words[i] = loremWords[rng.Intn(len(loremWords))]
+	}
+	words[0] = strings.ToUpper(words[0][:1]) + words[0][1:]
+	return strings.Join(words, " ")
+}
+
+// markdownBlock 返回一个随机的Markdown片段，第一个片段总是标题
+func markdownBlock(rng *rand.Rand, first bool) string {
+	kind := 0
+	if !first {
+		kind = 1 + rng.Intn(7)
+	}
+
+	switch kind {
+	case 0:
+		return "## " + loremSentence(rng, 2, 5)
+	case 1:
+		return loremSentence(rng, 4, 8) + " **" + loremSentence(rng, 2, 3) + "** " +
+			strings.ToLower(loremSentence(rng, 3, 6)) + " `" + loremWords[rng.Intn(len(loremWords))] + "()` " +
+			strings.ToLower(loremSentence(rng, 4, 8)) + "."
+	case 2:
+		items := make([]string, 3+rng.Intn(3))
+		for i := range items {
+			items[i] = "- " + loremSentence(rng, 3, 8)
+		}
+		return strings.Join(items, "\n")
+	case 3:
+		items := make([]string, 3+rng.Intn(3))
+		for i := range items {
+			items[i] = fmt.Sprintf("%d. %s", i+1, loremSentence(rng, 3, 8))
+		}
+		return strings.Join(items, "\n")
+	case 4:
+		rows := []string{"| Name | Status | Notes |", "| --- | --- | --- |"}
+		for i := 1 + rng.Intn(4); i >= 0; i-- {
+			rows = append(rows, fmt.Sprintf("| %s | %s | %s |",
+				loremSentence(rng, 1, 2), markdownStatuses[rng.Intn(len(markdownStatuses))], loremSentence(rng, 2, 5)))
+		}
+		return strings.Join(rows, "\n")
+	case 5:
+		return "> " + loremSentence(rng, 8, 16) + "."
+	case 6:
+		return "### " + loremSentence(rng, 2, 4)
+	default:
+		return codeBlock(rng)
+	}
+}
+
+// codeBlock 返回一段说明和一个随机语言的代码块
+func codeBlock(rng *rand.Rand) string {
+	fixture := codeFixtures[rng.Intn(len(codeFixtures))]
+	return fixture.intro + "\n\n```" + fixture.lang + "\n" + fixture.code + "\n```"
+}
+
+// markdownStatuses 表格中的状态列
+var markdownStatuses = []string{"✅ Done", "🚧 In progress", "⏳ Pending", "❌ Blocked"}
+
+// loremWords lorem ipsum 词表
+var loremWords = strings.Fields(`lorem ipsum dolor sit amet consectetur adipiscing elit sed do eiusmod tempor
+incididunt ut labore et dolore magna aliqua enim ad minim veniam quis nostrud exercitation ullamco laboris
+nisi aliquip ex ea commodo consequat duis aute irure in reprehenderit voluptate velit esse cillum fugiat
+nulla pariatur excepteur sint occaecat cupidatat non proident sunt culpa qui officia deserunt mollit anim
+id est laborum curabitur pretium tincidunt lacus nulla gravida orci a odio nullam varius turpis et commodo
+pharetra est eros bibendum elit nec luctus magna felis sollicitudin mauris integer vitae justo eget magna
+fermentum iaculis`)
+
+// loremZhPhrases 中文假文的词组
+var loremZhPhrases = strings.Fields(`我们 今天 讨论 这个 问题 从 不同 角度 进行 分析 首先 需要 明确 目标
+然后 收集 相关 信息 整理 数据 得出 结论 在 实际 应用 中 应该 注意 细节 保持 耐心 逐步 推进 工作 系统
+设计 性能 稳定 用户 体验 团队 协作 持续 改进 方案 实施 过程 可能 遇到 困难 但是 只要 坚持 就能 找到
+合适 的 方法 总的来说 这是 一个 值得 思考 的 话题 同时 也 需要 结合 具体 场景 灵活 调整 策略`)
+
+// codeFixture 代码块素材
+type codeFixture struct {
+	lang  string
+	intro string
+	code  string
+}
+
+// codeFixtures 多种语言的代码块素材
+var codeFixtures = []codeFixture{
+	{"go", "Here is a minimal HTTP handler in Go:", `package main
+
+import (
+	"fmt"
+	"net/http"
+)
+
+func hello(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("name")
+	if name == "" {
+		name = "world"
+	}
+	fmt.Fprintf(w, "Hello, %s!\n", name)
+}
+
+func main() {
+	http.HandleFunc("/hello", hello)
+	http.ListenAndServe(":8080", nil)
+}`},
+	{"python", "The following Python function retries a flaky call with exponential backoff:", `import time
+
+
+def retry(func, attempts=5, base_delay=0.1):
+    for attempt in range(attempts):
+        try:
+            return func()
+        except Exception as exc:
+            if attempt == attempts - 1:
+                raise
+            delay = base_delay * (2 ** attempt)
+            print(f"attempt {attempt + 1} failed: {exc}, retrying in {delay:.1f}s")
+            time.sleep(delay)`},
+	{"typescript", "A small TypeScript helper that debounces a callback:", `export function debounce<T extends (...args: unknown[]) => void>(fn: T, waitMs: number) {
+  let timer: ReturnType<typeof setTimeout> | undefined;
+  return (...args: Parameters<T>) => {
+    if (timer !== undefined) {
+      clearTimeout(timer);
+    }
+    timer = setTimeout(() => fn(...args), waitMs);
+  };
+}`},
+	{"sql", "This query returns the ten most active users in the last week:", `SELECT u.id,
+       u.name,
+       COUNT(e.id) AS events
+FROM users u
+JOIN events e ON e.user_id = u.id
+WHERE e.created_at >= NOW() - INTERVAL '7 days'
+GROUP BY u.id, u.name
+ORDER BY events DESC
+LIMIT 10;`},
+	{"bash", "You can run the service locally with the following commands:", `git clone https://github.com/example/service.git
+cd service
+export PORT=8080
+go build -o bin/service ./cmd/service
+./bin/service --log-level=debug`},
+	{"json", "An example configuration file looks like this:", `{
+  "listen": ":8080",
+  "timeout_ms": 3000,
+  "features": {
+    "streaming": true,
+    "retries": 3
+  },
+  "tags": ["mock", "test"]
+}`},
+	{"rust", "In Rust, the same logic can be written with an iterator chain:", `fn top_words(text: &str, n: usize) -> Vec<(String, usize)> {
+    let mut counts = std::collections::HashMap::new();
+    for word in text.split_whitespace() {
+        *counts.entry(word.to_lowercase()).or_insert(0) += 1;
+    }
+    let mut pairs: Vec<_> = counts.into_iter().collect();
+    pairs.sort_by(|a, b| b.1.cmp(&a.1));
+    pairs.truncate(n);
+    pairs
+}`},
+}
@@ -0,0 +1,100 @@
+package responses
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math"
+	"math/rand"
+	"time"
+
+	"RobinPenn974/OpenAI-mocker/templates"
+)
+
+// defaultTokens 未配置任何分布时生成器的目标token数
+const defaultTokens = 150
+
+// renderVariant 按权重选择一个候选回复，设置了生成器时生成目标长度的文本，返回文本和结束原因
+func renderVariant(variants []templates.Variant, input string, opts Options) (string, string) {
+	rng := newRand(opts.Seed, input)
+
+	total := 0
+	for _, variant := range variants {
+		total += variantWeight(variant)
+	}
+	chosen := variants[len(variants)-1]
+	n := rng.Intn(total)
+	for _, variant := range variants {
+		if n -= variantWeight(variant); n < 0 {
+			chosen = variant
+			break
+		}
+	}
+
+	if chosen.Generator == nil {
+		return chosen.Text, "stop"
+	}
+
+	distribution := opts.Tokens
+	if chosen.Generator.Tokens != nil {
+		distribution = *chosen.Generator.Tokens
+	}
+	target := sampleTokens(distribution, rng)
+	truncated := opts.MaxTokens > 0 && target > opts.MaxTokens
+	if truncated {
+		target = opts.MaxTokens
+	}
+
+	text, err := GenerateText(*chosen.Generator, target, !truncated, rng, opts.CorpusDir)
+	if err != nil {
+		fmt.Printf("Error generating %s text: %v\n", chosen.Generator.Type, err)
+		return chosen.Text, "stop"
+	}
+	if truncated {
+		return text, "length"
+	}
+	return text, "stop"
+}
+
+// variantWeight 返回候选回复的权重，未设置时为1
+func variantWeight(variant templates.Variant) int {
+	if variant.Weight == 0 {
+		return 1
+	}
+	return variant.Weight
+}
+
+// newRand 创建随机数生成器，设置了seed时由seed和输入内容决定，相同的请求得到相同的结果
+func newRand(seed *int64, input string) *rand.Rand {
+	if seed == nil {
+		return rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+	hash := fnv.New64a()
+	hash.Write([]byte(input))
+	return rand.New(rand.NewSource(*seed ^ int64(hash.Sum64())))
+}
+
+// sampleTokens 从分布中抽取目标token数，结果至少为1
+func sampleTokens(d templates.TokenDistribution, rng *rand.Rand) int {
+	var value float64
+	switch d.Type {
+	case templates.DistributionFixed:
+		value = float64(d.Tokens)
+	case templates.DistributionUniform:
+		value = float64(d.Min + rng.Intn(d.Max-d.Min+1))
+	case templates.DistributionNormal:
+		value = d.Mean + rng.NormFloat64()*d.Stddev
+	case templates.DistributionLognormal:
+		value = d.Mean * math.Exp(rng.NormFloat64()*d.Stddev)
+	default:
+		value = defaultTokens
+	}
+
+	tokens := int(math.Round(value))
+	if d.Max > 0 && tokens > d.Max {
+		tokens = d.Max
+	}
+	if tokens < d.Min {
+		tokens = d.Min
+	}
+	return max(tokens, 1)
+}
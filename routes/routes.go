@@ -2,13 +2,24 @@ package routes
 
 import (
 	"RobinPenn974/OpenAI-mocker/controller"
+	"RobinPenn974/OpenAI-mocker/instance"
 	"RobinPenn974/OpenAI-mocker/middleware"
+	"RobinPenn974/OpenAI-mocker/provider"
 
 	"github.com/gin-gonic/gin"
 )
 
-// SetupRoutes 设置所有路由
-func SetupRoutes(r *gin.Engine) {
+// SetupRoutes 设置所有API路由，请求使用inst中的状态
+func SetupRoutes(r *gin.Engine, inst *instance.Instance) {
+	// 选择实例、按服务商兼容配置调整响应头和错误格式、鉴权、选择工作区并注入故障
+	apiMiddleware := []gin.HandlerFunc{
+		middleware.WithInstance(inst),
+		middleware.ProviderProfile(),
+		middleware.AuthRequired(),
+		middleware.WorkspaceRequired(),
+		middleware.InjectFaults(),
+	}
+
 	// 健康检查路由 - 不需要认证
 	r.GET("/v1/healthz", func(c *gin.Context) {
 		c.JSON(200, gin.H{
@@ -18,7 +29,7 @@ func SetupRoutes(r *gin.Engine) {
 
 	// API v1 路由组 - 需要认证
 	v1 := r.Group("/v1")
-	v1.Use(middleware.AuthRequired())
+	v1.Use(apiMiddleware...)
 	{
 		// Chat Completions API
 		v1.POST("/chat/completions", controller.HandleChatCompletions)
@@ -31,32 +42,173 @@ func SetupRoutes(r *gin.Engine) {
 
 		// Rerank API
 		v1.POST("/rerank", controller.HandleRerank)
+		v1.POST("/score", controller.HandleScore)
 
 		// Models API
 		v1.GET("/models", controller.HandleListModels)
+		v1.GET("/models/*model_id", controller.HandleRetrieveModel)
+		v1.DELETE("/models/*model_id", controller.HandleDeleteModel)
+
+		// Files API
+		v1.POST("/files", controller.HandleUploadFile)
+		v1.GET("/files", controller.HandleListFiles)
+		v1.GET("/files/:file_id", controller.HandleGetFile)
+		v1.DELETE("/files/:file_id", controller.HandleDeleteFile)
+		v1.GET("/files/:file_id/content", controller.HandleGetFileContent)
+
+		// Batch API
+		v1.POST("/batches", controller.HandleCreateBatch)
+		v1.GET("/batches", controller.HandleListBatches)
+		v1.GET("/batches/:batch_id", controller.HandleGetBatch)
+		v1.POST("/batches/:batch_id/cancel", controller.HandleCancelBatch)
+
+		// Fine-tuning API
+		v1.POST("/fine_tuning/jobs", controller.HandleCreateFineTuningJob)
+		v1.GET("/fine_tuning/jobs", controller.HandleListFineTuningJobs)
+		v1.GET("/fine_tuning/jobs/:job_id", controller.HandleGetFineTuningJob)
+		v1.POST("/fine_tuning/jobs/:job_id/cancel", controller.HandleCancelFineTuningJob)
+		v1.GET("/fine_tuning/jobs/:job_id/events", controller.HandleListFineTuningEvents)
+		v1.GET("/fine_tuning/jobs/:job_id/checkpoints", controller.HandleListFineTuningCheckpoints)
+
+		// Assistants API v2 - 需要 OpenAI-Beta: assistants=v2 头
+		beta := v1.Group("", middleware.AssistantsBetaRequired())
+		beta.POST("/assistants", controller.HandleCreateAssistant)
+		beta.GET("/assistants", controller.HandleListAssistants)
+		beta.GET("/assistants/:assistant_id", controller.HandleGetAssistant)
+		beta.POST("/assistants/:assistant_id", controller.HandleModifyAssistant)
+		beta.DELETE("/assistants/:assistant_id", controller.HandleDeleteAssistant)
+
+		beta.POST("/threads", controller.HandleCreateThread)
+		beta.POST("/threads/runs", controller.HandleCreateThreadAndRun)
+		beta.GET("/threads/:thread_id", controller.HandleGetThread)
+		beta.POST("/threads/:thread_id", controller.HandleModifyThread)
+		beta.DELETE("/threads/:thread_id", controller.HandleDeleteThread)
+
+		beta.POST("/threads/:thread_id/messages", controller.HandleCreateMessage)
+		beta.GET("/threads/:thread_id/messages", controller.HandleListMessages)
+		beta.GET("/threads/:thread_id/messages/:message_id", controller.HandleGetMessage)
+		beta.POST("/threads/:thread_id/messages/:message_id", controller.HandleModifyMessage)
+		beta.DELETE("/threads/:thread_id/messages/:message_id", controller.HandleDeleteMessage)
+
+		beta.POST("/threads/:thread_id/runs", controller.HandleCreateRun)
+		beta.GET("/threads/:thread_id/runs", controller.HandleListRuns)
+		beta.GET("/threads/:thread_id/runs/:run_id", controller.HandleGetRun)
+		beta.POST("/threads/:thread_id/runs/:run_id", controller.HandleModifyRun)
+		beta.POST("/threads/:thread_id/runs/:run_id/cancel", controller.HandleCancelRun)
+		beta.POST("/threads/:thread_id/runs/:run_id/submit_tool_outputs", controller.HandleSubmitToolOutputs)
+		beta.GET("/threads/:thread_id/runs/:run_id/steps", controller.HandleListRunSteps)
+		beta.GET("/threads/:thread_id/runs/:run_id/steps/:step_id", controller.HandleGetRunStep)
+	}
+
+	// API v2 路由组 - Cohere 风格的重排序接口
+	v2 := r.Group("/v2")
+	v2.Use(apiMiddleware...)
+	{
+		v2.POST("/rerank", controller.HandleCohereRerank)
+	}
+
+	// vLLM 风格的打分接口
+	r.Group("", apiMiddleware...).POST("/score", controller.HandleScore)
+
+	// Azure OpenAI 部署风格路由组 - 需要 api-version 和Azure凭据
+	deploymentsGroup := r.Group("/openai/deployments/:deployment")
+	deploymentsGroup.Use(middleware.WithInstance(inst), middleware.FixedProviderProfile(provider.ProfileAzure), middleware.AzureAPIVersionRequired(), middleware.AzureAuthRequired(), middleware.WorkspaceRequired(), middleware.InjectFaults())
+	{
+		deploymentsGroup.POST("/chat/completions", controller.HandleAzureChatCompletions)
+		deploymentsGroup.POST("/completions", controller.HandleAzureCompletions)
+		deploymentsGroup.POST("/embeddings", controller.HandleAzureEmbeddings)
 	}
+}
 
-	// 管理员API路由组
+// SetupAdminRoutes 设置管理API路由，使用实例当前配置中的管理员凭据，所有修改操作记录到inst的审计日志
+func SetupAdminRoutes(r *gin.Engine, inst *instance.Instance) {
+	// 管理员API路由组 - 需要管理员凭据
 	admin := r.Group("/admin")
+	admin.Use(middleware.WithInstance(inst), middleware.AdminAuthRequired(), middleware.AdminWorkspace())
 	{
+		// 审计日志
+		admin.GET("/audit", controller.HandleListAuditLog)
+
+		// 服务状态和重新加载配置文件、模板文件
+		admin.GET("/status", controller.HandleGetStatus)
+		admin.POST("/reload", middleware.AuditMutations(controller.SnapshotReloadStatus), controller.HandleReload)
+
+		// 工作区管理
+		workspaces := admin.Group("/workspaces")
+		workspaces.Use(middleware.AuditMutations(controller.SnapshotWorkspaces))
+		workspaces.GET("", controller.HandleListWorkspaces)
+		workspaces.POST("", controller.HandleCreateWorkspace)
+		workspaces.GET("/:workspace_id", controller.HandleGetWorkspace)
+		workspaces.DELETE("/:workspace_id", controller.HandleDeleteWorkspace)
+		workspaces.GET("/:workspace_id/journal", controller.HandleGetWorkspaceJournal)
+		workspaces.DELETE("/:workspace_id/journal", controller.HandleResetWorkspaceJournal)
+
 		// 模型管理
 		models := admin.Group("/models")
+		models.Use(middleware.AuditMutations(controller.SnapshotModels))
 		models.POST("/load", controller.HandleLoadModel)
 		models.POST("/unload", controller.HandleUnloadModel)
 		models.POST("/unload_all", controller.HandleUnloadAllModels)
 
+		// 服务商兼容配置
+		admin.GET("/provider_profiles", controller.HandleListProviderProfiles)
+
+		// 模型别名、匹配模式和自动注册
+		models.GET("/routing", controller.HandleGetModelRouting)
+		models.POST("/aliases", controller.HandleCreateModelAlias)
+		models.DELETE("/aliases/*alias", controller.HandleDeleteModelAlias)
+		models.POST("/patterns", controller.HandleCreateModelPattern)
+		models.DELETE("/patterns/:pattern_id", controller.HandleDeleteModelPattern)
+		models.PUT("/auto_register", controller.HandleSetAutoRegister)
+
 		// 模板管理
 		templates := admin.Group("/templates")
+		templates.Use(middleware.AuditMutations(controller.SnapshotTemplates))
 		templates.GET("", controller.HandleListTemplates)
 		templates.GET("/:model_id", controller.HandleGetTemplate)
 		templates.PUT("/:model_id", controller.HandleUpdateTemplate)
+		templates.PATCH("/:model_id", controller.HandlePatchTemplate)
 		templates.DELETE("/:model_id", controller.HandleDeleteTemplate)
+		templates.GET("/:model_id/history", controller.HandleGetTemplateHistory)
+		templates.POST("/:model_id/rollback/:version", controller.HandleRollbackTemplate)
+
+		// 模板预览不修改任何状态，不记录审计日志
+		admin.POST("/templates/:model_id/preview", controller.HandlePreviewTemplate)
 
 		// 认证管理
 		auth := admin.Group("/auth")
+		auth.Use(middleware.AuditMutations(controller.SnapshotApiKeys))
 		auth.GET("/keys", controller.HandleListApiKeys)
 		auth.POST("/keys", controller.HandleCreateApiKey)
 		auth.DELETE("/keys/:key_id", controller.HandleDeleteApiKey)
+		auth.POST("/keys/:key_id/revoke", controller.HandleRevokeApiKey)
 		auth.DELETE("/keys", controller.HandleDeleteAllApiKeys)
+
+		// Embedding 固定向量管理
+		embeddingPins := admin.Group("/embeddings/pins")
+		embeddingPins.Use(middleware.AuditMutations(controller.SnapshotEmbeddingPins))
+		embeddingPins.GET("", controller.HandleListEmbeddingPins)
+		embeddingPins.POST("", controller.HandleCreateEmbeddingPin)
+		embeddingPins.DELETE("/:pin_id", controller.HandleDeleteEmbeddingPin)
+		embeddingPins.DELETE("", controller.HandleDeleteAllEmbeddingPins)
+
+		// 故障注入
+		faults := admin.Group("/faults")
+		faults.Use(middleware.AuditMutations(controller.SnapshotFaults))
+		faults.GET("", controller.HandleListFaults)
+		faults.POST("", controller.HandleCreateFault)
+		faults.DELETE("/:fault_id", controller.HandleDeleteFault)
+		faults.DELETE("", controller.HandleDeleteAllFaults)
+
+		// Azure 部署与内容过滤管理
+		azure := admin.Group("/azure")
+		azure.Use(middleware.AuditMutations(controller.SnapshotAzure))
+		azure.GET("/deployments", controller.HandleListDeployments)
+		azure.POST("/deployments", controller.HandleCreateDeployment)
+		azure.DELETE("/deployments/:name", controller.HandleDeleteDeployment)
+		azure.GET("/content_filter/rules", controller.HandleListContentFilterRules)
+		azure.POST("/content_filter/rules", controller.HandleCreateContentFilterRule)
+		azure.DELETE("/content_filter/rules/:rule_id", controller.HandleDeleteContentFilterRule)
+		azure.DELETE("/content_filter/rules", controller.HandleDeleteAllContentFilterRules)
 	}
 }
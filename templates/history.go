@@ -0,0 +1,350 @@
+package templates
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"RobinPenn974/OpenAI-mocker/atomicfile"
+)
+
+const (
+	// 模板历史版本文件名
+	historyFile = "template_history.json"
+	// 每个模板保留的历史版本数
+	maxVersionsPerTemplate = 50
+)
+
+// 历史版本的修改者
+const (
+	AuthorSystem = "system" // 启动配置、模型加载等内部修改
+	AuthorFile   = "file"   // 直接修改模板文件后重新加载
+)
+
+// 历史版本的操作类型
+const (
+	ActionCreate   = "create"
+	ActionUpdate   = "update"
+	ActionDelete   = "delete"
+	ActionRollback = "rollback"
+	ActionReload   = "reload"
+)
+
+var (
+	// ErrPreconditionFailed If-Match 与模板的当前版本不一致
+	ErrPreconditionFailed = errors.New("template has been modified since it was read")
+	// ErrVersionNotFound 历史版本不存在
+	ErrVersionNotFound = errors.New("template version not found")
+)
+
+// Version 模板的一个历史版本
+type Version struct {
+	Version    int               `json:"version"`
+	Action     string            `json:"action"`
+	Author     string            `json:"author"` // 管理员名称、API密钥或 system、file
+	Timestamp  time.Time         `json:"timestamp"`
+	Template   *ResponseTemplate `json:"template,omitempty"` // 该版本的模板，删除时为空
+	Diff       []FieldChange     `json:"diff,omitempty"`     // 与上一版本相比变化的字段
+	RollbackTo int               `json:"rollback_to,omitempty"`
+}
+
+// FieldChange 一个字段的变化，新增或删除的字段对应的值为空
+type FieldChange struct {
+	Field string          `json:"field"`
+	Old   json.RawMessage `json:"old,omitempty"`
+	New   json.RawMessage `json:"new,omitempty"`
+}
+
+// ETag 返回模板当前版本的实体标签，尚无历史版本的模板为 "v0"
+func (tm *TemplateManager) ETag(modelID string) string {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+
+	return tm.etag(modelID)
+}
+
+// History 返回模板的历史版本，按版本号从旧到新排列
+func (tm *TemplateManager) History(modelID string) []Version {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+
+	return append([]Version(nil), tm.history[modelID]...)
+}
+
+// UpdateTemplate 创建或替换模板并记录历史版本，ifMatch不为空时必须与模板当前的ETag一致
+func (tm *TemplateManager) UpdateTemplate(template ResponseTemplate, author, ifMatch string) (string, error) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	if err := tm.checkIfMatch(template.ModelID, ifMatch); err != nil {
+		return "", err
+	}
+
+	templates := tm.copyTemplates()
+	templates[template.ModelID] = template
+	if err := tm.commit(templates, change{author: author}); err != nil {
+		return "", err
+	}
+	return tm.etag(template.ModelID), nil
+}
+
+// RemoveTemplate 删除模板并记录历史版本，ifMatch不为空时必须与模板当前的ETag一致
+func (tm *TemplateManager) RemoveTemplate(modelID, author, ifMatch string) error {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	if _, exists := tm.templates[modelID]; !exists {
+		return fmt.Errorf("%w: %s", ErrTemplateNotFound, modelID)
+	}
+	if err := tm.checkIfMatch(modelID, ifMatch); err != nil {
+		return err
+	}
+	var children []string
+	for childID, template := range tm.templates {
+		if template.Extends == modelID {
+			children = append(children, childID)
+		}
+	}
+	if len(children) > 0 {
+		sort.Strings(children)
+		return fmt.Errorf("%w: template %s is extended by %s", ErrInvalidTemplate, modelID, strings.Join(children, ", "))
+	}
+
+	templates := tm.copyTemplates()
+	delete(templates, modelID)
+	return tm.commit(templates, change{author: author})
+}
+
+// Rollback 将模板恢复为指定历史版本的内容，恢复本身记录为一个新版本
+func (tm *TemplateManager) Rollback(modelID string, version int, author, ifMatch string) (ResponseTemplate, string, error) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	var target *ResponseTemplate
+	for _, v := range tm.history[modelID] {
+		if v.Version == version {
+			target = v.Template
+			break
+		}
+	}
+	if target == nil {
+		return ResponseTemplate{}, "", ErrVersionNotFound
+	}
+	if err := tm.checkIfMatch(modelID, ifMatch); err != nil {
+		return ResponseTemplate{}, "", err
+	}
+
+	templates := tm.copyTemplates()
+	templates[modelID] = *target
+	if err := tm.commit(templates, change{author: author, action: ActionRollback, rollbackTo: version}); err != nil {
+		return ResponseTemplate{}, "", err
+	}
+	return *target, tm.etag(modelID), nil
+}
+
+// etag 返回模板当前版本的实体标签，调用方需持有锁
+func (tm *TemplateManager) etag(modelID string) string {
+	version := 0
+	if versions := tm.history[modelID]; len(versions) > 0 {
+		version = versions[len(versions)-1].Version
+	}
+	return `"v` + strconv.Itoa(version) + `"`
+}
+
+// checkIfMatch 检查 If-Match 请求头，"*" 要求模板已存在，调用方需持有锁
+func (tm *TemplateManager) checkIfMatch(modelID, ifMatch string) error {
+	ifMatch = strings.TrimSpace(ifMatch)
+	if ifMatch == "" {
+		return nil
+	}
+	if ifMatch == "*" {
+		if _, exists := tm.templates[modelID]; exists {
+			return nil
+		}
+		return ErrPreconditionFailed
+	}
+
+	current := tm.etag(modelID)
+	for _, tag := range strings.Split(ifMatch, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(tag), "W/") == current {
+			return nil
+		}
+	}
+	return ErrPreconditionFailed
+}
+
+// copyTemplates 复制当前的模板，调用方需持有锁
+func (tm *TemplateManager) copyTemplates() map[string]ResponseTemplate {
+	templates := make(map[string]ResponseTemplate, len(tm.templates))
+	for modelID, template := range tm.templates {
+		templates[modelID] = template
+	}
+	return templates
+}
+
+// change 一次修改的来源，action为空时根据变化推断为创建、更新或删除
+type change struct {
+	author     string
+	action     string
+	rollbackTo int
+}
+
+// commit 校验并保存新的模板集合，记录与当前模板相比发生变化的模板的历史版本，调用方需持有锁
+func (tm *TemplateManager) commit(templates map[string]ResponseTemplate, c change) error {
+	if err := validateTemplates(templates); err != nil {
+		return err
+	}
+	if err := tm.writeTemplatesToFile(templates); err != nil {
+		return err
+	}
+	tm.recordHistory(tm.templates, templates, c)
+	tm.templates = templates
+	return nil
+}
+
+// recordHistory 记录从previous到next发生变化的模板的历史版本并保存，调用方需持有锁
+func (tm *TemplateManager) recordHistory(previous, next map[string]ResponseTemplate, c change) {
+	modelIDs := make([]string, 0, len(next))
+	for modelID := range previous {
+		modelIDs = append(modelIDs, modelID)
+	}
+	for modelID := range next {
+		if _, exists := previous[modelID]; !exists {
+			modelIDs = append(modelIDs, modelID)
+		}
+	}
+	sort.Strings(modelIDs)
+
+	changed := false
+	now := time.Now().UTC()
+	for _, modelID := range modelIDs {
+		old, hadOld := previous[modelID]
+		template, hasNew := next[modelID]
+		if hadOld == hasNew && reflect.DeepEqual(old, template) {
+			continue
+		}
+
+		version := Version{
+			Version:    1,
+			Action:     c.action,
+			Author:     c.author,
+			Timestamp:  now,
+			RollbackTo: c.rollbackTo,
+		}
+		if versions := tm.history[modelID]; len(versions) > 0 {
+			version.Version = versions[len(versions)-1].Version + 1
+		}
+		var oldPtr, newPtr *ResponseTemplate
+		if hadOld {
+			oldPtr = &old
+		}
+		if hasNew {
+			newPtr = &template
+			version.Template = newPtr
+		}
+		version.Diff = diffTemplates(oldPtr, newPtr)
+		if version.Action == "" {
+			switch {
+			case !hadOld:
+				version.Action = ActionCreate
+			case !hasNew:
+				version.Action = ActionDelete
+			default:
+				version.Action = ActionUpdate
+			}
+		}
+
+		versions := append(tm.history[modelID], version)
+		if len(versions) > maxVersionsPerTemplate {
+			versions = versions[len(versions)-maxVersionsPerTemplate:]
+		}
+		tm.history[modelID] = versions
+		changed = true
+	}
+
+	if changed {
+		if err := tm.saveHistory(); err != nil {
+			fmt.Printf("Error saving template history: %v\n", err)
+		}
+	}
+}
+
+// diffTemplates 按JSON字段比较两个模板，返回变化的字段
+func diffTemplates(old, new *ResponseTemplate) []FieldChange {
+	oldFields := templateFields(old)
+	newFields := templateFields(new)
+
+	names := make([]string, 0, len(oldFields)+len(newFields))
+	for name := range oldFields {
+		names = append(names, name)
+	}
+	for name := range newFields {
+		if _, exists := oldFields[name]; !exists {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var changes []FieldChange
+	for _, name := range names {
+		if name == "model_id" {
+			continue
+		}
+		if string(oldFields[name]) != string(newFields[name]) {
+			changes = append(changes, FieldChange{Field: name, Old: oldFields[name], New: newFields[name]})
+		}
+	}
+	return changes
+}
+
+// templateFields 将模板转换为JSON字段，空值字段视为不存在，显式设置的false保留
+func templateFields(template *ResponseTemplate) map[string]json.RawMessage {
+	fields := map[string]json.RawMessage{}
+	if template == nil {
+		return fields
+	}
+	data, err := json.Marshal(template)
+	if err != nil {
+		return fields
+	}
+	json.Unmarshal(data, &fields)
+	for name, value := range fields {
+		switch string(value) {
+		case `""`, "null", "0", "[]", "{}":
+			delete(fields, name)
+		}
+	}
+	return fields
+}
+
+// loadHistory 加载历史版本文件
+func (tm *TemplateManager) loadHistory() error {
+	data, err := ioutil.ReadFile(filepath.Join(tm.storageDir, historyFile))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error reading template history: %v", err)
+	}
+	if err := json.Unmarshal(data, &tm.history); err != nil {
+		return fmt.Errorf("error parsing template history: %v", err)
+	}
+	return nil
+}
+
+// saveHistory 保存历史版本文件
+func (tm *TemplateManager) saveHistory() error {
+	data, err := json.MarshalIndent(tm.history, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding template history: %v", err)
+	}
+	return atomicfile.WriteFile(filepath.Join(tm.storageDir, historyFile), data, 0644)
+}
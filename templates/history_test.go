@@ -0,0 +1,89 @@
+package templates
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestUpdateTemplateIfMatch(t *testing.T) {
+	tm := NewTemplateManager(t.TempDir(), "")
+
+	// 模板不存在时 "*" 不满足
+	if _, err := tm.UpdateTemplate(ResponseTemplate{ModelID: "m", Greeting: "v1"}, "alice", "*"); !errors.Is(err, ErrPreconditionFailed) {
+		t.Fatalf("If-Match * on a missing template: got %v", err)
+	}
+	etag, err := tm.UpdateTemplate(ResponseTemplate{ModelID: "m", Greeting: "v1"}, "alice", "")
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	// 使用读取时的ETag修改成功，之后旧的ETag失效
+	next, err := tm.UpdateTemplate(ResponseTemplate{ModelID: "m", Greeting: "v2"}, "bob", etag)
+	if err != nil {
+		t.Fatalf("update with current ETag: %v", err)
+	}
+	if next == etag {
+		t.Fatalf("ETag did not change after update: %s", next)
+	}
+	if _, err := tm.UpdateTemplate(ResponseTemplate{ModelID: "m", Greeting: "lost update"}, "carol", etag); !errors.Is(err, ErrPreconditionFailed) {
+		t.Fatalf("update with stale ETag: got %v", err)
+	}
+	if err := tm.RemoveTemplate("m", "carol", etag); !errors.Is(err, ErrPreconditionFailed) {
+		t.Fatalf("delete with stale ETag: got %v", err)
+	}
+	if _, err := tm.UpdateTemplate(ResponseTemplate{ModelID: "m", Greeting: "v3"}, "carol", `W/"other", `+next); err != nil {
+		t.Fatalf("update with a matching ETag in a list: %v", err)
+	}
+
+	if template, _ := tm.StoredTemplate("m"); template.Greeting != "v3" {
+		t.Fatalf("stored greeting %q, want v3", template.Greeting)
+	}
+}
+
+func TestRollback(t *testing.T) {
+	tm := NewTemplateManager(t.TempDir(), "")
+
+	for _, greeting := range []string{"v1", "v2", "v3"} {
+		if _, err := tm.UpdateTemplate(ResponseTemplate{ModelID: "m", Greeting: greeting}, "alice", ""); err != nil {
+			t.Fatalf("update %s: %v", greeting, err)
+		}
+	}
+	history := tm.History("m")
+	if len(history) != 3 || history[0].Action != ActionCreate || history[2].Action != ActionUpdate {
+		t.Fatalf("unexpected history: %+v", history)
+	}
+
+	if _, _, err := tm.Rollback("m", history[0].Version, "bob", `"v0"`); !errors.Is(err, ErrPreconditionFailed) {
+		t.Fatalf("rollback with stale ETag: got %v", err)
+	}
+	if _, _, err := tm.Rollback("m", 99, "bob", ""); !errors.Is(err, ErrVersionNotFound) {
+		t.Fatalf("rollback to a missing version: got %v", err)
+	}
+
+	template, etag, err := tm.Rollback("m", history[0].Version, "bob", tm.ETag("m"))
+	if err != nil {
+		t.Fatalf("rollback: %v", err)
+	}
+	if template.Greeting != "v1" {
+		t.Fatalf("rolled back greeting %q, want v1", template.Greeting)
+	}
+	if stored, _ := tm.StoredTemplate("m"); stored.Greeting != "v1" {
+		t.Fatalf("stored greeting %q after rollback, want v1", stored.Greeting)
+	}
+
+	// 回滚记录为新版本，ETag随之变化
+	history = tm.History("m")
+	last := history[len(history)-1]
+	if len(history) != 4 || last.Action != ActionRollback || last.RollbackTo != history[0].Version || last.Author != "bob" {
+		t.Fatalf("unexpected rollback version: %+v", last)
+	}
+	if etag != tm.ETag("m") || etag == `"v3"` {
+		t.Fatalf("unexpected ETag after rollback: %s", etag)
+	}
+
+	// 重新加载后历史版本仍然保留
+	reloaded := NewTemplateManager(tm.storageDir, "")
+	if got := len(reloaded.History("m")); got != 4 {
+		t.Fatalf("history has %d versions after reload, want 4", got)
+	}
+}
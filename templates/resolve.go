@@ -0,0 +1,350 @@
+package templates
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// 继承链的最大深度
+const maxExtendsDepth = 16
+
+var (
+	// ErrTemplateNotFound 模板不存在
+	ErrTemplateNotFound = errors.New("template not found")
+	// ErrInvalidTemplate 模板的模式或继承关系无效
+	ErrInvalidTemplate = errors.New("invalid template")
+)
+
+// IsPattern 判断模型ID是否为匹配模型名的模式，如 "*-reasoner"
+func IsPattern(modelID string) bool {
+	return strings.ContainsAny(modelID, "*?[")
+}
+
+// Resolve 返回候选模型ID中第一个已注册模板的生效模板，都未注册时返回第一个候选模型的默认模板。
+// 生效模板依次合并内置默认模板、匹配任一候选模型名的模式模板（越具体越优先）、extends 继承链和模板自身，
+// 后者中非空的字段覆盖前者
+func (tm *TemplateManager) Resolve(modelIDs []string) ResponseTemplate {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+
+	for _, modelID := range modelIDs {
+		if template, exists := tm.templates[modelID]; exists && !IsPattern(modelID) {
+			return tm.resolve(template, modelIDs)
+		}
+	}
+	var modelID string
+	if len(modelIDs) > 0 {
+		modelID = modelIDs[0]
+	}
+	return tm.resolve(ResponseTemplate{ModelID: modelID}, modelIDs)
+}
+
+// ResolveDraft 按 Resolve 的规则返回未保存的模板草稿的生效模板
+func (tm *TemplateManager) ResolveDraft(draft ResponseTemplate, modelIDs []string) ResponseTemplate {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+
+	return tm.resolve(draft, modelIDs)
+}
+
+// StoredTemplate 返回保存的模板本身，不合并默认模板和父模板
+func (tm *TemplateManager) StoredTemplate(modelID string) (ResponseTemplate, bool) {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+
+	template, exists := tm.templates[modelID]
+	return template, exists
+}
+
+// PatchTemplate 按 JSON Merge Patch 的规则修改保存的模板：patch中的字段替换原值，值为null的字段恢复为未设置，
+// ifMatch不为空时必须与模板当前的ETag一致
+func (tm *TemplateManager) PatchTemplate(modelID string, patch map[string]json.RawMessage, author, ifMatch string) (ResponseTemplate, string, error) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	current, exists := tm.templates[modelID]
+	if !exists {
+		return ResponseTemplate{}, "", ErrTemplateNotFound
+	}
+	if err := tm.checkIfMatch(modelID, ifMatch); err != nil {
+		return ResponseTemplate{}, "", err
+	}
+
+	template, err := applyPatch(current, patch)
+	if err != nil {
+		return ResponseTemplate{}, "", err
+	}
+	template.ModelID = modelID
+
+	templates := tm.copyTemplates()
+	templates[modelID] = template
+	if err := tm.commit(templates, change{author: author}); err != nil {
+		return ResponseTemplate{}, "", err
+	}
+	return template, tm.etag(modelID), nil
+}
+
+// applyPatch 将patch应用到模板上，嵌套的对象（如 variants）逐层合并，patch中包含模板没有的字段时返回错误
+func applyPatch(template ResponseTemplate, patch map[string]json.RawMessage) (ResponseTemplate, error) {
+	data, err := json.Marshal(template)
+	if err != nil {
+		return ResponseTemplate{}, err
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return ResponseTemplate{}, err
+	}
+	data, err = json.Marshal(patch)
+	if err != nil {
+		return ResponseTemplate{}, err
+	}
+	var changes map[string]interface{}
+	if err := json.Unmarshal(data, &changes); err != nil {
+		return ResponseTemplate{}, err
+	}
+
+	data, err = json.Marshal(mergePatch(fields, changes))
+	if err != nil {
+		return ResponseTemplate{}, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	var patched ResponseTemplate
+	if err := decoder.Decode(&patched); err != nil {
+		return ResponseTemplate{}, fmt.Errorf("%w: %v", ErrInvalidTemplate, err)
+	}
+	return patched, nil
+}
+
+// mergePatch 按 RFC 7396 将patch合并到target中，值为null的字段被删除
+func mergePatch(target, patch map[string]interface{}) map[string]interface{} {
+	if target == nil {
+		target = map[string]interface{}{}
+	}
+	for name, value := range patch {
+		switch value := value.(type) {
+		case nil:
+			delete(target, name)
+		case map[string]interface{}:
+			current, _ := target[name].(map[string]interface{})
+			target[name] = mergePatch(current, value)
+		default:
+			target[name] = value
+		}
+	}
+	return target
+}
+
+// resolve 合并模板的默认模板、模式模板和继承链，调用方需持有锁
+func (tm *TemplateManager) resolve(template ResponseTemplate, modelIDs []string) ResponseTemplate {
+	resolved := getDefaultTemplate(template.ModelID)
+	for _, pattern := range tm.matchingPatterns(modelIDs) {
+		if pattern == template.ModelID {
+			continue
+		}
+		for _, layer := range tm.chain(tm.templates[pattern]) {
+			resolved = mergeTemplate(resolved, layer)
+		}
+	}
+	for _, layer := range tm.chain(template) {
+		resolved = mergeTemplate(resolved, layer)
+	}
+	resolved.ModelID = template.ModelID
+	resolved.Extends = ""
+	return resolved
+}
+
+// matchingPatterns 返回匹配任一候选模型名的模式模板，按具体程度从低到高排列，调用方需持有锁
+func (tm *TemplateManager) matchingPatterns(modelIDs []string) []string {
+	var patterns []string
+	for pattern := range tm.templates {
+		if !IsPattern(pattern) {
+			continue
+		}
+		for _, modelID := range modelIDs {
+			if matched, _ := path.Match(pattern, modelID); matched {
+				patterns = append(patterns, pattern)
+				break
+			}
+		}
+	}
+	sort.Slice(patterns, func(a, b int) bool {
+		if specificity(patterns[a]) != specificity(patterns[b]) {
+			return specificity(patterns[a]) < specificity(patterns[b])
+		}
+		return patterns[a] < patterns[b]
+	})
+	return patterns
+}
+
+// specificity 模式中非通配符的字符数，越多越具体
+func specificity(pattern string) int {
+	return len(pattern) - strings.Count(pattern, "*") - strings.Count(pattern, "?")
+}
+
+// chain 返回模板的继承链，从最上层的父模板到模板自身，缺失或循环的父模板被忽略，调用方需持有锁
+func (tm *TemplateManager) chain(template ResponseTemplate) []ResponseTemplate {
+	chain := []ResponseTemplate{template}
+	visited := map[string]bool{template.ModelID: true}
+	for current := template; current.Extends != "" && len(chain) < maxExtendsDepth; {
+		parent, exists := tm.templates[current.Extends]
+		if !exists || visited[current.Extends] {
+			break
+		}
+		visited[current.Extends] = true
+		chain = append([]ResponseTemplate{parent}, chain...)
+		current = parent
+	}
+	return chain
+}
+
+// mergeTemplate 用override中已设置的字段覆盖base
+func mergeTemplate(base, override ResponseTemplate) ResponseTemplate {
+	merged := base
+	for _, field := range []struct {
+		dst *string
+		src string
+	}{
+		{&merged.Prefix, override.Prefix},
+		{&merged.Greeting, override.Greeting},
+		{&merged.Question, override.Question},
+		{&merged.HelpRequest, override.HelpRequest},
+		{&merged.Default, override.Default},
+		{&merged.ReasoningPrefix, override.ReasoningPrefix},
+		{&merged.ReasoningTemplate, override.ReasoningTemplate},
+		{&merged.ReasoningFormat, override.ReasoningFormat},
+		{&merged.CompletionPrefix, override.CompletionPrefix},
+	} {
+		if field.src != "" {
+			*field.dst = field.src
+		}
+	}
+	if override.SupportReasoning != nil {
+		merged.SupportReasoning = override.SupportReasoning
+	}
+	// 覆盖了字段本身或其候选回复时，父模板中该字段的候选回复和本地化文本不再使用
+	for _, field := range localizedFields {
+		if fieldValue(override, field) == "" && len(override.Variants[field]) == 0 {
+			continue
+		}
+		merged.Variants = withoutField(merged.Variants, field)
+		merged.Localized = withoutField(merged.Localized, field)
+	}
+
+	// 候选回复和本地化文本按键覆盖，空的候选回复列表表示不使用父模板的候选回复
+	if len(override.Variants) > 0 {
+		variants := make(map[string][]Variant, len(merged.Variants)+len(override.Variants))
+		for key, list := range merged.Variants {
+			variants[key] = list
+		}
+		for key, list := range override.Variants {
+			if len(list) == 0 {
+				delete(variants, key)
+			} else {
+				variants[key] = list
+			}
+		}
+		merged.Variants = variants
+	}
+	if len(override.Localized) > 0 {
+		localized := make(map[string]string, len(merged.Localized)+len(override.Localized))
+		for key, text := range merged.Localized {
+			localized[key] = text
+		}
+		for key, text := range override.Localized {
+			localized[key] = text
+		}
+		merged.Localized = localized
+	}
+	return merged
+}
+
+// fieldValue 返回可以本地化的字段的值
+func fieldValue(template ResponseTemplate, field string) string {
+	switch field {
+	case "greeting":
+		return template.Greeting
+	case "question":
+		return template.Question
+	case "help_request":
+		return template.HelpRequest
+	case "default":
+		return template.Default
+	case "reasoning_template":
+		return template.ReasoningTemplate
+	}
+	return ""
+}
+
+// withoutField 返回删除了字段及其各语言键的副本
+func withoutField[V any](values map[string]V, field string) map[string]V {
+	if len(values) == 0 {
+		return values
+	}
+	copied := make(map[string]V, len(values))
+	for key, value := range values {
+		if key != field && !strings.HasPrefix(key, field+".") {
+			copied[key] = value
+		}
+	}
+	return copied
+}
+
+// validateTemplates 校验候选回复、模式模板的语法和继承关系：父模板必须存在、不能是模式模板且不能形成循环
+func validateTemplates(templates map[string]ResponseTemplate) error {
+	modelIDs := make([]string, 0, len(templates))
+	for modelID := range templates {
+		modelIDs = append(modelIDs, modelID)
+	}
+	sort.Strings(modelIDs)
+
+	var errs []error
+	for _, modelID := range modelIDs {
+		template := templates[modelID]
+		if err := validateVariants(template); err != nil {
+			errs = append(errs, fmt.Errorf("%w: template %s: %v", ErrInvalidTemplate, modelID, err))
+		}
+		switch template.ReasoningFormat {
+		case "", ReasoningFormatContent, ReasoningFormatReasoning, ReasoningFormatThink:
+		default:
+			errs = append(errs, fmt.Errorf("%w: template %s: invalid reasoning_format '%s', must be one of: %s, %s, %s", ErrInvalidTemplate, modelID, template.ReasoningFormat, ReasoningFormatContent, ReasoningFormatReasoning, ReasoningFormatThink))
+		}
+		if IsPattern(modelID) {
+			if _, err := path.Match(modelID, ""); err != nil {
+				errs = append(errs, fmt.Errorf("%w: invalid model pattern '%s'", ErrInvalidTemplate, modelID))
+			}
+		}
+		if template.Extends == "" {
+			continue
+		}
+		if _, exists := templates[template.Extends]; !exists {
+			errs = append(errs, fmt.Errorf("%w: template %s extends unknown template '%s'", ErrInvalidTemplate, modelID, template.Extends))
+			continue
+		}
+		if IsPattern(template.Extends) {
+			errs = append(errs, fmt.Errorf("%w: template %s cannot extend pattern template '%s'", ErrInvalidTemplate, modelID, template.Extends))
+			continue
+		}
+
+		// 缺失的祖先模板由其子模板的检查报告
+		visited := map[string]bool{modelID: true}
+		for current := template; current.Extends != ""; {
+			if visited[current.Extends] || len(visited) >= maxExtendsDepth {
+				errs = append(errs, fmt.Errorf("%w: template %s has a circular or too deep extends chain", ErrInvalidTemplate, modelID))
+				break
+			}
+			visited[current.Extends] = true
+			parent, exists := templates[current.Extends]
+			if !exists {
+				break
+			}
+			current = parent
+		}
+	}
+	return errors.Join(errs...)
+}
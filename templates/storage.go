@@ -6,7 +6,11 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"reflect"
 	"sync"
+
+	"RobinPenn974/OpenAI-mocker/atomicfile"
+	"RobinPenn974/OpenAI-mocker/watch"
 )
 
 const (
@@ -24,6 +28,11 @@ type TemplateManager struct {
 	mu         sync.RWMutex                // 读写锁
 	storageDir string                      // 存储目录
 	filename   string                      // 存储文件名
+
+	history       map[string][]Version        // 各模板的历史版本
+	defaults      map[string]ResponseTemplate // 最近一次应用的默认模板文件内容，用于合并默认模板的修改
+	fileStamp     watch.Stamp                 // 最近一次加载或写入时模板文件的状态
+	defaultsStamp watch.Stamp                 // 最近一次加载时默认模板文件的状态
 }
 
 // NewTemplateManager 创建一个新的模板管理器
@@ -40,6 +49,7 @@ func NewTemplateManager(storageDir, filename string) *TemplateManager {
 
 	manager := &TemplateManager{
 		templates:  make(map[string]ResponseTemplate),
+		history:    make(map[string][]Version),
 		storageDir: storageDir,
 		filename:   filename,
 	}
@@ -50,78 +60,200 @@ func NewTemplateManager(storageDir, filename string) *TemplateManager {
 	}
 
 	// 加载模板数据
-	manager.loadTemplates()
+	if err := manager.loadTemplates(); err != nil {
+		fmt.Printf("Error loading templates: %v\n", err)
+	}
+	manager.defaultsStamp = watch.StampOf(manager.defaultsPath())
+	if err := manager.loadHistory(); err != nil {
+		fmt.Printf("Error loading template history: %v\n", err)
+	}
 
 	return manager
 }
 
-// GetTemplate 获取指定模型的模板，如果不存在则返回默认模板
-func (tm *TemplateManager) GetTemplate(modelID string) ResponseTemplate {
+// Path 返回模板文件的路径
+func (tm *TemplateManager) Path() string {
+	return filepath.Join(tm.storageDir, tm.filename)
+}
+
+// defaultsPath 返回默认模板文件的路径
+func (tm *TemplateManager) defaultsPath() string {
+	return filepath.Join(tm.storageDir, defaultTemplateDataFile)
+}
+
+// Changed 判断模板文件或默认模板文件自上次加载后是否被修改
+func (tm *TemplateManager) Changed() bool {
 	tm.mu.RLock()
 	defer tm.mu.RUnlock()
 
-	template, exists := tm.templates[modelID]
-	if !exists {
-		// 返回默认模板
-		return getDefaultTemplate(modelID)
-	}
-	return template
+	return watch.StampOf(tm.Path()) != tm.fileStamp || watch.StampOf(tm.defaultsPath()) != tm.defaultsStamp
 }
 
-// RegisterTemplate 注册或更新模型的响应模板
-func (tm *TemplateManager) RegisterTemplate(template ResponseTemplate) error {
+// Reload 重新加载模板文件，并合并默认模板文件的修改：默认模板中新增或修改的模板覆盖当前模板，
+// 删除的模板在未被修改过时一并删除。文件无法解析或校验失败时返回错误，继续使用当前的模板
+func (tm *TemplateManager) Reload() error {
 	tm.mu.Lock()
 	defer tm.mu.Unlock()
 
-	// 从文件中读取当前所有模板
-	templates, err := tm.readTemplatesFromFile()
-	if err != nil {
-		return err
+	// 先记录文件状态，加载失败时不再重复尝试，直到文件再次被修改
+	fileStamp := watch.StampOf(tm.Path())
+	defaultsStamp := watch.StampOf(tm.defaultsPath())
+	tm.fileStamp = fileStamp
+	tm.defaultsStamp = defaultsStamp
+
+	var defaults map[string]ResponseTemplate
+	if defaultsStamp.Exists {
+		data, err := ioutil.ReadFile(tm.defaultsPath())
+		if err != nil {
+			return fmt.Errorf("error reading default template file: %v", err)
+		}
+		if defaults, err = parseTemplates(data); err != nil {
+			return fmt.Errorf("error parsing default template file: %v", err)
+		}
 	}
 
-	// 更新或添加模板
-	templates[template.ModelID] = template
+	// 模板文件被删除时从默认模板重新生成
+	var templates map[string]ResponseTemplate
+	if fileStamp.Exists {
+		current, err := tm.readTemplatesFromFile()
+		if err != nil {
+			return err
+		}
+		templates = mergeDefaults(current, tm.defaults, defaults)
+		if reflect.DeepEqual(templates, current) {
+			if err := validateTemplates(templates); err != nil {
+				return err
+			}
+			tm.recordHistory(tm.templates, templates, change{author: AuthorFile, action: ActionReload})
+			tm.templates = templates
+			tm.defaults = defaults
+			return nil
+		}
+	} else {
+		templates = mergeDefaults(map[string]ResponseTemplate{}, nil, defaults)
+	}
 
-	// 保存所有模板回文件
-	err = tm.writeTemplatesToFile(templates)
-	if err != nil {
+	if err := tm.commit(templates, change{author: AuthorFile, action: ActionReload}); err != nil {
 		return err
 	}
+	tm.defaults = defaults
+	return nil
+}
 
-	// 更新内存中的模板
-	tm.templates[template.ModelID] = template
+// mergeDefaults 将默认模板从previous到next的修改合并到templates中
+func mergeDefaults(templates, previous, next map[string]ResponseTemplate) map[string]ResponseTemplate {
+	merged := make(map[string]ResponseTemplate, len(templates))
+	for modelID, template := range templates {
+		merged[modelID] = template
+	}
 
-	return nil
+	for modelID, template := range next {
+		if old, exists := previous[modelID]; !exists || !reflect.DeepEqual(old, template) {
+			merged[modelID] = template
+		}
+	}
+	for modelID, old := range previous {
+		if _, exists := next[modelID]; exists {
+			continue
+		}
+		if current, exists := merged[modelID]; exists && reflect.DeepEqual(current, old) {
+			delete(merged, modelID)
+		}
+	}
+	return merged
 }
 
-// DeleteTemplate 删除模型的响应模板
-func (tm *TemplateManager) DeleteTemplate(modelID string) error {
+// parseTemplates 解析模板文件内容，未设置model_id的模板使用键名，键名与model_id不一致时返回错误
+func parseTemplates(data []byte) (map[string]ResponseTemplate, error) {
+	var templates map[string]ResponseTemplate
+	if err := json.Unmarshal(data, &templates); err != nil {
+		return nil, err
+	}
+
+	for modelID, template := range templates {
+		if template.ModelID == "" {
+			template.ModelID = modelID
+			templates[modelID] = template
+		} else if template.ModelID != modelID {
+			return nil, fmt.Errorf("template key '%s' does not match model_id '%s'", modelID, template.ModelID)
+		}
+	}
+	return templates, nil
+}
+
+// GetTemplate 获取指定模型的生效模板，未注册模板时返回默认模板和匹配的模式模板合并后的结果
+func (tm *TemplateManager) GetTemplate(modelID string) ResponseTemplate {
+	return tm.Resolve([]string{modelID})
+}
+
+// RegisterTemplate 注册或更新模型的响应模板，历史版本记录为系统修改
+func (tm *TemplateManager) RegisterTemplate(template ResponseTemplate) error {
+	_, err := tm.UpdateTemplate(template, AuthorSystem, "")
+	return err
+}
+
+// RegisterTemplates 一次性创建或替换多个模板，任一模板无效时不做任何修改，历史版本记录为系统修改
+func (tm *TemplateManager) RegisterTemplates(templateList []ResponseTemplate) error {
 	tm.mu.Lock()
 	defer tm.mu.Unlock()
 
-	// 从文件中读取当前所有模板
-	templates, err := tm.readTemplatesFromFile()
-	if err != nil {
-		return err
+	templates := tm.copyTemplates()
+	for _, template := range templateList {
+		templates[template.ModelID] = template
 	}
+	return tm.commit(templates, change{author: AuthorSystem})
+}
+
+// CheckTemplates 校验在当前模板的基础上写入给定模板后的模板集合，不修改任何状态
+func (tm *TemplateManager) CheckTemplates(templateList []ResponseTemplate) error {
+	tm.mu.RLock()
+	templates := tm.copyTemplates()
+	tm.mu.RUnlock()
 
-	// 检查模板是否存在
-	if _, exists := templates[modelID]; !exists {
-		return fmt.Errorf("template for model %s not found", modelID)
+	for _, template := range templateList {
+		templates[template.ModelID] = template
 	}
+	return validateTemplates(templates)
+}
 
-	// 删除模板
-	delete(templates, modelID)
+// DeleteTemplate 删除模型的响应模板，历史版本记录为系统修改
+func (tm *TemplateManager) DeleteTemplate(modelID string) error {
+	return tm.RemoveTemplate(modelID, AuthorSystem, "")
+}
 
-	// 保存更改回文件
-	err = tm.writeTemplatesToFile(templates)
-	if err != nil {
-		return err
-	}
+// CopyFrom 用source的全部模板替换当前模板，并复制source最近一次应用的默认模板，
+// 之后修改默认模板文件时以source的默认模板为基准合并，而不是内置的默认模板
+func (tm *TemplateManager) CopyFrom(source *TemplateManager) error {
+	source.mu.RLock()
+	templates := source.copyTemplates()
+	defaults := mergeDefaults(source.defaults, nil, nil)
+	sourceHasDefaults := source.defaults != nil
+	source.mu.RUnlock()
 
-	// 更新内存中的模板
-	delete(tm.templates, modelID)
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	// source没有默认模板文件时删除新建的内置默认模板文件，避免重新加载时合并内置默认模板
+	if sourceHasDefaults {
+		data, err := json.MarshalIndent(defaults, "", "  ")
+		if err != nil {
+			return fmt.Errorf("error encoding default templates: %v", err)
+		}
+		if err := atomicfile.WriteFile(tm.defaultsPath(), data, 0644); err != nil {
+			return fmt.Errorf("error writing default template file: %v", err)
+		}
+	} else {
+		if err := os.Remove(tm.defaultsPath()); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("error removing default template file: %v", err)
+		}
+		defaults = nil
+	}
+	tm.defaultsStamp = watch.StampOf(tm.defaultsPath())
 
+	if err := tm.commit(templates, change{author: AuthorSystem}); err != nil {
+		return err
+	}
+	tm.defaults = defaults
 	return nil
 }
 
@@ -149,6 +281,7 @@ func (tm *TemplateManager) loadTemplates() error {
 	}
 
 	// 读取文件内容
+	tm.fileStamp = watch.StampOf(filePath)
 	templates, err := tm.readTemplatesFromFile()
 	if err != nil {
 		return err
@@ -157,6 +290,13 @@ func (tm *TemplateManager) loadTemplates() error {
 	// 更新模板存储
 	tm.templates = templates
 
+	// 记录默认模板文件的内容，之后对默认模板的修改会合并到模板中
+	if data, err := ioutil.ReadFile(tm.defaultsPath()); err == nil {
+		if defaults, err := parseTemplates(data); err == nil {
+			tm.defaults = defaults
+		}
+	}
+
 	return nil
 }
 
@@ -171,8 +311,8 @@ func (tm *TemplateManager) readTemplatesFromFile() (map[string]ResponseTemplate,
 	}
 
 	// 解析JSON
-	var templates map[string]ResponseTemplate
-	if err := json.Unmarshal(data, &templates); err != nil {
+	templates, err := parseTemplates(data)
+	if err != nil {
 		return nil, fmt.Errorf("error parsing template file: %v", err)
 	}
 
@@ -189,9 +329,10 @@ func (tm *TemplateManager) writeTemplatesToFile(templates map[string]ResponseTem
 
 	// 保存到文件
 	filePath := filepath.Join(tm.storageDir, tm.filename)
-	if err := ioutil.WriteFile(filePath, data, 0644); err != nil {
+	if err := atomicfile.WriteFile(filePath, data, 0644); err != nil {
 		return fmt.Errorf("error writing template file: %v", err)
 	}
+	tm.fileStamp = watch.StampOf(filePath)
 
 	return nil
 }
@@ -215,13 +356,15 @@ func (tm *TemplateManager) loadDefaultTemplates() error {
 	}
 
 	// 解析JSON
-	var templates map[string]ResponseTemplate
-	if err := json.Unmarshal(data, &templates); err != nil {
+	templates, err := parseTemplates(data)
+	if err != nil {
 		return fmt.Errorf("error parsing default template file: %v", err)
 	}
 
 	// 更新模板存储
 	tm.templates = templates
+	// 单独保存一份，修改模板时不影响记录的默认模板
+	tm.defaults = mergeDefaults(templates, nil, nil)
 
 	// 将默认模板复制到主模板文件
 	return tm.writeTemplatesToFile(templates)
@@ -238,6 +381,12 @@ func createDefaultTemplatesFile(filePath string) error {
 			Question:    "That's an interesting question. As a mock model, I'll respond with this simulated answer. In a real OpenAI API, you would get a more contextual response.",
 			HelpRequest: "I'm here to help! Although I'm just a mock model, I can simulate responses. What do you need assistance with?",
 			Default:     "I understand. As a mock GPT model, I'm providing this simulated response to your message. In a real OpenAI API, the response would be generated based on the trained model.",
+			Localized: map[string]string{
+				"greeting.zh":     "你好！我是一个模拟的GPT模型，有什么可以帮你的吗？",
+				"question.zh":     "这是一个很有意思的问题。作为模拟模型，我提供这个模拟的回答。在真实的OpenAI API中，你会得到更贴合上下文的回复。",
+				"help_request.zh": "很乐意帮忙！虽然我只是一个模拟模型，但我可以模拟回复。你需要什么帮助？",
+				"default.zh":      "明白了。作为模拟的GPT模型，我提供这个模拟回复。在真实的OpenAI API中，回复会由训练好的模型生成。",
+			},
 		},
 		"mock-davinci-002": {
 			ModelID:          "mock-davinci-002",
@@ -247,17 +396,36 @@ func createDefaultTemplatesFile(filePath string) error {
 			HelpRequest:      "is on the way! This is a simulated response from the mock completions API.",
 			Default:          "As a mock AI model, I'm continuing your text with this simulated response. In a real OpenAI API, this would be generated based on the trained model.",
 			CompletionPrefix: "",
+			Localized: map[string]string{
+				"greeting.zh":     "！我是一个模拟的AI模型，有什么可以帮你的吗？",
+				"question.zh":     "这是一个很有意思的问题。作为模拟模型，我提供这个模拟的回答。",
+				"help_request.zh": "马上就来！这是模拟补全接口返回的回复。",
+				"default.zh":      "作为模拟的AI模型，我用这段模拟回复续写你的文本。在真实的OpenAI API中，续写内容会由训练好的模型生成。",
+			},
 		},
 		"deepseek-reasoner": {
-			ModelID:           "deepseek-reasoner",
-			Prefix:            "[DEEPSEEK] ",
-			Greeting:          "Hello! I'm a mock reasoning model. How can I assist you today?",
-			Question:          "That's an interesting question. After analyzing the problem, I've arrived at this answer. In a real reasoning model, the response would be more contextual.",
-			HelpRequest:       "I'm here to help! After careful reasoning, I can provide this simulated response. What do you need assistance with?",
-			Default:           "I understand. After careful reasoning, I'm providing this simulated response. In a real reasoning model, both the reasoning process and final answer would be more sophisticated.",
-			SupportReasoning:  true,
+			ModelID:     "deepseek-reasoner",
+			Prefix:      "[DEEPSEEK] ",
+			Greeting:    "Hello! I'm a mock reasoning model. How can I assist you today?",
+			Question:    "That's an interesting question. After analyzing the problem, I've arrived at this answer. In a real reasoning model, the response would be more contextual.",
+			HelpRequest: "I'm here to help! After careful reasoning, I can provide this simulated response. What do you need assistance with?",
+			Default:     "I understand. After careful reasoning, I'm providing this simulated response. In a real reasoning model, both the reasoning process and final answer would be more sophisticated.",
+			Localized: map[string]string{
+				"greeting.zh":     "你好！我是一个模拟的推理模型，有什么可以帮你的吗？",
+				"question.zh":     "这是一个很有意思的问题。经过分析，我得出了这个答案。在真实的推理模型中，回答会更贴合上下文。",
+				"help_request.zh": "很乐意帮忙！经过仔细推理，我可以提供这个模拟回复。你需要什么帮助？",
+				"default.zh":      "明白了。经过仔细推理，我提供这个模拟回复。在真实的推理模型中，推理过程和最终回答都会更加完善。",
+			},
+		},
+		// 所有 *-reasoner 模型共用的推理配置
+		"*-reasoner": {
+			ModelID:           "*-reasoner",
+			SupportReasoning:  Bool(true),
 			ReasoningPrefix:   "REASONING: ",
 			ReasoningTemplate: "Let me think step by step about this question.\n\nFirst, I need to understand what is being asked:\nThe user asked about: {question}\n\nNow I will analyze this by breaking it down:\n1. Identify key information\n2. Apply relevant knowledge\n3. Consider different angles\n4. Form a logical conclusion\n\nBased on my analysis, I can now provide a comprehensive response.",
+			Localized: map[string]string{
+				"reasoning_template.zh": "让我一步一步地思考这个问题。\n\n首先，我需要理解问题的含义：\n用户的问题是：{question}\n\n接下来，我将分解这个问题进行分析：\n1. 找出关键信息\n2. 运用相关知识\n3. 从不同角度考虑\n4. 得出合理的结论\n\n根据以上分析，我现在可以给出完整的回答。",
+			},
 		},
 	}
 
@@ -273,7 +441,7 @@ func createDefaultTemplatesFile(filePath string) error {
 		return fmt.Errorf("error creating directory for default templates: %v", err)
 	}
 
-	if err := ioutil.WriteFile(filePath, data, 0644); err != nil {
+	if err := atomicfile.WriteFile(filePath, data, 0644); err != nil {
 		return fmt.Errorf("error writing default template file: %v", err)
 	}
 
@@ -281,15 +449,20 @@ func createDefaultTemplatesFile(filePath string) error {
 	return nil
 }
 
-// getDefaultTemplate 获取默认模板
+// getDefaultTemplate 获取内置默认模板，所有模板未设置的字段最终使用其中的值
 func getDefaultTemplate(modelID string) ResponseTemplate {
 	return ResponseTemplate{
-		ModelID:           modelID,
-		Prefix:            "[MOCK] ",
-		Greeting:          "Hello! I'm a mock AI model. How can I assist you today?",
-		Question:          "That's an interesting question. As a mock model, I'll provide this simulated answer.",
-		HelpRequest:       "I'm here to help! As a mock model, I can simulate responses.",
-		Default:           "I understand. I'm providing this simulated response to your message.",
-		ReasoningTemplate: "",
+		ModelID:     modelID,
+		Prefix:      "[MOCK] ",
+		Greeting:    "Hello! I'm a mock AI model. How can I assist you today?",
+		Question:    "That's an interesting question. As a mock model, I'll provide this simulated answer.",
+		HelpRequest: "I'm here to help! As a mock model, I can simulate responses.",
+		Default:     "I understand. I'm providing this simulated response to your message.",
+		Localized: map[string]string{
+			"greeting.zh":     "你好！我是一个模拟的AI模型，有什么可以帮你的吗？",
+			"question.zh":     "这是一个很有意思的问题。作为模拟模型，我提供这个模拟的回答。",
+			"help_request.zh": "很乐意帮忙！作为模拟模型，我可以提供模拟的回复。",
+			"default.zh":      "明白了。这是针对你的消息的模拟回复。",
+		},
 	}
 }
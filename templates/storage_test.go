@@ -0,0 +1,89 @@
+package templates
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeDefaults 写入默认模板文件
+func writeDefaults(t *testing.T, tm *TemplateManager, defaults map[string]ResponseTemplate) {
+	t.Helper()
+	data, err := json.Marshal(defaults)
+	if err != nil {
+		t.Fatalf("encode default templates: %v", err)
+	}
+	if err := os.WriteFile(tm.defaultsPath(), data, 0644); err != nil {
+		t.Fatalf("write default templates: %v", err)
+	}
+}
+
+func TestCopyFromKeepsSourceDefaults(t *testing.T) {
+	source := NewTemplateManager(t.TempDir(), "")
+	sourceDefaults := map[string]ResponseTemplate{
+		"custom-model": {ModelID: "custom-model", Greeting: "custom default"},
+	}
+	writeDefaults(t, source, sourceDefaults)
+	if err := source.Reload(); err != nil {
+		t.Fatalf("reload source: %v", err)
+	}
+
+	target := NewTemplateManager(t.TempDir(), "")
+	if err := target.CopyFrom(source); err != nil {
+		t.Fatalf("copy: %v", err)
+	}
+	if _, exists := target.StoredTemplate("custom-model"); !exists {
+		t.Fatal("template of the source workspace not copied")
+	}
+
+	// 默认模板文件和合并基准与source一致，而不是内置的默认模板
+	data, err := os.ReadFile(filepath.Join(target.storageDir, defaultTemplateDataFile))
+	if err != nil {
+		t.Fatalf("read copied default templates: %v", err)
+	}
+	copied, err := parseTemplates(data)
+	if err != nil || len(copied) != 1 || copied["custom-model"].Greeting != "custom default" {
+		t.Fatalf("copied default templates: %v, %v", copied, err)
+	}
+	if target.Changed() {
+		t.Fatal("copied workspace reports changed template files")
+	}
+
+	// 修改默认模板文件后重新加载，未变化的默认模板不覆盖管理员的修改
+	if err := target.RegisterTemplate(ResponseTemplate{ModelID: "custom-model", Greeting: "edited by admin"}); err != nil {
+		t.Fatalf("edit template: %v", err)
+	}
+	sourceDefaults["another-model"] = ResponseTemplate{ModelID: "another-model", Greeting: "another default"}
+	writeDefaults(t, target, sourceDefaults)
+	if err := target.Reload(); err != nil {
+		t.Fatalf("reload target: %v", err)
+	}
+	if template, _ := target.StoredTemplate("custom-model"); template.Greeting != "edited by admin" {
+		t.Fatalf("unchanged default overwrote the admin edit: %q", template.Greeting)
+	}
+	if _, exists := target.StoredTemplate("another-model"); !exists {
+		t.Fatal("new default template not merged")
+	}
+}
+
+func TestCopyFromWithoutSourceDefaults(t *testing.T) {
+	source := NewTemplateManager(t.TempDir(), "")
+	if err := os.Remove(source.defaultsPath()); err != nil {
+		t.Fatalf("remove default templates: %v", err)
+	}
+	if err := source.Reload(); err != nil {
+		t.Fatalf("reload source: %v", err)
+	}
+
+	target := NewTemplateManager(t.TempDir(), "")
+	if err := target.CopyFrom(source); err != nil {
+		t.Fatalf("copy: %v", err)
+	}
+	if _, err := os.Stat(target.defaultsPath()); !os.IsNotExist(err) {
+		t.Fatalf("built-in default templates kept in the copied workspace: %v", err)
+	}
+	if len(target.ListTemplates()) != len(source.ListTemplates()) {
+		t.Fatalf("copied %d templates, want %d", len(target.ListTemplates()), len(source.ListTemplates()))
+	}
+}
@@ -3,8 +3,9 @@ package templates
 // ResponseTemplate 定义了一个模型的响应模板
 type ResponseTemplate struct {
 	// 基本信息
-	ModelID string `json:"model_id"` // 模型ID
-	Prefix  string `json:"prefix"`   // 模型前缀标识
+	ModelID string `json:"model_id"`          // 模型ID，包含 * ? [ 时为匹配模型名的模式模板
+	Extends string `json:"extends,omitempty"` // 继承的父模板的模型ID，未设置的字段使用父模板的值
+	Prefix  string `json:"prefix"`            // 模型前缀标识
 
 	// 通用响应模板
 	Greeting    string `json:"greeting"`     // 问候语模板
@@ -12,11 +13,36 @@ type ResponseTemplate struct {
 	HelpRequest string `json:"help_request"` // 帮助请求模板
 	Default     string `json:"default"`      // 默认回复模板
 
+	// 各字段的候选回复，键为 greeting、question、help_request 或 default，设置后替代对应字段，
+	// 键加上语言后缀（如 greeting.zh）时只用于该语言的输入
+	Variants map[string][]Variant `json:"variants,omitempty"`
+	// 各字段的本地化文本，键为字段名加语言后缀，如 greeting.zh、reasoning_template.ja
+	Localized map[string]string `json:"localized,omitempty"`
+
 	// 推理模型配置
-	SupportReasoning  bool   `json:"support_reasoning"`  // 是否支持推理功能
+	SupportReasoning  *bool  `json:"support_reasoning"`  // 是否支持推理功能，未设置时使用父模板的值
 	ReasoningPrefix   string `json:"reasoning_prefix"`   // 推理内容前缀
 	ReasoningTemplate string `json:"reasoning_template"` // 推理内容模板
+	// 推理内容的返回格式，未设置时由 features.reasoning_field 决定使用 reasoning_content 还是 think
+	ReasoningFormat string `json:"reasoning_format,omitempty"`
 
 	// 文本补全模型配置
 	CompletionPrefix string `json:"completion_prefix"` // 补全前缀
 }
+
+// 推理内容的返回格式
+const (
+	ReasoningFormatContent   = "reasoning_content" // 使用 reasoning_content 字段，与 DeepSeek 一致
+	ReasoningFormatReasoning = "reasoning"         // 使用 reasoning 字段，与 OpenRouter、vLLM 新版本一致
+	ReasoningFormatThink     = "think"             // 以<think>标签合并到回复内容中
+)
+
+// ReasoningEnabled 判断模板是否支持推理功能
+func (t ResponseTemplate) ReasoningEnabled() bool {
+	return t.SupportReasoning != nil && *t.SupportReasoning
+}
+
+// Bool 返回指向b的指针，用于设置 SupportReasoning
+func Bool(b bool) *bool {
+	return &b
+}
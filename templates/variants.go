@@ -0,0 +1,147 @@
+package templates
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// 文本生成器类型
+const (
+	GeneratorLorem    = "lorem"    // 英文 lorem ipsum
+	GeneratorLoremZh  = "lorem_zh" // 中文假文
+	GeneratorMarkov   = "markov"   // 在语料文件上训练的马尔可夫链
+	GeneratorMarkdown = "markdown" // 标题、列表、表格等Markdown片段
+	GeneratorCode     = "code"     // 多种语言的代码块
+)
+
+// token数分布类型
+const (
+	DistributionFixed     = "fixed"
+	DistributionUniform   = "uniform"
+	DistributionNormal    = "normal"
+	DistributionLognormal = "lognormal"
+)
+
+// Variant 模板字段的一个候选回复，按权重随机选择，设置了 generator 时生成指定长度的文本
+type Variant struct {
+	Text      string         `json:"text,omitempty"`
+	Weight    int            `json:"weight,omitempty"` // 为0时按1计算
+	Generator *GeneratorSpec `json:"generator,omitempty"`
+}
+
+// GeneratorSpec 文本生成器配置
+type GeneratorSpec struct {
+	Type   string             `json:"type"`             // lorem、lorem_zh、markov、markdown 或 code
+	Corpus string             `json:"corpus,omitempty"` // markov 使用的语料文件名，位于语料目录下，为空时使用内置语料
+	Tokens *TokenDistribution `json:"tokens,omitempty"` // 目标token数的分布，为空时使用配置中的默认分布
+}
+
+// TokenDistribution 目标token数的分布，结果限制在 [min, max] 之间
+type TokenDistribution struct {
+	Type   string  `json:"type"`             // fixed、uniform、normal 或 lognormal
+	Tokens int     `json:"tokens,omitempty"` // fixed 的token数
+	Min    int     `json:"min,omitempty"`
+	Max    int     `json:"max,omitempty"`
+	Mean   float64 `json:"mean,omitempty"`   // normal 的均值，lognormal 的中位数
+	Stddev float64 `json:"stddev,omitempty"` // normal 的标准差，lognormal 的对数标准差
+}
+
+// variantRules 可以设置候选回复的模板字段
+var variantRules = []string{"greeting", "question", "help_request", "default"}
+
+// localizedFields 可以设置本地化文本的模板字段
+var localizedFields = append([]string{"reasoning_template"}, variantRules...)
+
+// supportedLanguages 本地化文本支持的语言后缀，与 responses.DetectLanguage 检测的语言一致
+var supportedLanguages = []string{"en", "zh", "ja", "ko", "ru"}
+
+// Validate 校验分布的参数
+func (d TokenDistribution) Validate() error {
+	if d.Min < 0 || d.Max < 0 || (d.Max > 0 && d.Min > d.Max) {
+		return fmt.Errorf("min and max must not be negative and min must not exceed max")
+	}
+	switch d.Type {
+	case DistributionFixed:
+		if d.Tokens <= 0 {
+			return fmt.Errorf("fixed distribution requires tokens > 0")
+		}
+	case DistributionUniform:
+		if d.Max <= 0 {
+			return fmt.Errorf("uniform distribution requires max > 0")
+		}
+	case DistributionNormal, DistributionLognormal:
+		if d.Mean <= 0 || d.Stddev < 0 {
+			return fmt.Errorf("%s distribution requires mean > 0 and stddev >= 0", d.Type)
+		}
+	default:
+		return fmt.Errorf("invalid distribution type '%s', must be one of: fixed, uniform, normal, lognormal", d.Type)
+	}
+	return nil
+}
+
+// validateVariants 校验模板的候选回复
+func validateVariants(template ResponseTemplate) error {
+	for key, text := range template.Localized {
+		if err := checkLocalizedKey(key, localizedFields, false); err != nil {
+			return fmt.Errorf("localized: %v", err)
+		}
+		if text == "" {
+			return fmt.Errorf("localized.%s: text must not be empty", key)
+		}
+	}
+	for rule, variants := range template.Variants {
+		if err := checkLocalizedKey(rule, variantRules, true); err != nil {
+			return fmt.Errorf("variants: %v", err)
+		}
+		for i, variant := range variants {
+			if variant.Weight < 0 {
+				return fmt.Errorf("variants.%s[%d]: weight must not be negative", rule, i)
+			}
+			spec := variant.Generator
+			if spec == nil {
+				continue
+			}
+			switch spec.Type {
+			case GeneratorLorem, GeneratorLoremZh, GeneratorMarkdown, GeneratorCode:
+			case GeneratorMarkov:
+				if spec.Corpus != "" && (filepath.Base(spec.Corpus) != spec.Corpus || strings.HasPrefix(spec.Corpus, ".")) {
+					return fmt.Errorf("variants.%s[%d]: corpus must be a file name in the corpus directory", rule, i)
+				}
+			default:
+				return fmt.Errorf("variants.%s[%d]: invalid generator '%s', must be one of: lorem, lorem_zh, markov, markdown, code", rule, i, spec.Type)
+			}
+			if spec.Tokens != nil {
+				if err := spec.Tokens.Validate(); err != nil {
+					return fmt.Errorf("variants.%s[%d].generator.tokens: %v", rule, i, err)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// checkLocalizedKey 校验 字段名.语言 形式的键，optional为true时语言后缀可以省略
+func checkLocalizedKey(key string, fields []string, optional bool) error {
+	field, language, hasLanguage := strings.Cut(key, ".")
+	if !contains(fields, field) {
+		return fmt.Errorf("invalid field '%s', must be one of: %s", field, strings.Join(fields, ", "))
+	}
+	if !hasLanguage && !optional {
+		return fmt.Errorf("key '%s' must have a language suffix, e.g. %s.zh", key, field)
+	}
+	if hasLanguage && !contains(supportedLanguages, language) {
+		return fmt.Errorf("invalid language '%s' in '%s', must be one of: %s", language, key, strings.Join(supportedLanguages, ", "))
+	}
+	return nil
+}
+
+// contains 判断列表中是否包含value
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
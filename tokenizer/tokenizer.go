@@ -0,0 +1,79 @@
+package tokenizer
+
+import (
+	"regexp"
+	"unicode"
+)
+
+// pretokenizePattern 与cl100k_base预分词规则相近的切分正则（Go的regexp不支持前瞻，省略了尾随空白的特殊处理）
+var pretokenizePattern = regexp.MustCompile(`'(?i:[sdmt]|ll|ve|re)|[^\r\n\p{L}\p{N}]?\p{L}+|\p{N}{1,3}| ?[^\s\p{L}\p{N}]+[\r\n]*|\s+`)
+
+// Pieces 按预分词规则将文本切分为片段
+func Pieces(text string) []string {
+	return pretokenizePattern.FindAllString(text, -1)
+}
+
+// CountTokens 估算文本的token数量
+// 模拟服务不携带BPE词表，按片段类型估算，结果与cl100k_base的计数接近
+func CountTokens(text string) int {
+	count := 0
+	for _, piece := range Pieces(text) {
+		count += pieceTokens(piece)
+	}
+	return count
+}
+
+// CountMessagesTokens 估算Chat消息列表的token数量，包含每条消息的格式开销
+func CountMessagesTokens(contents []string) int {
+	// 每条消息有3个格式token，回复以3个token引导
+	count := 3
+	for _, content := range contents {
+		count += 3 + CountTokens(content)
+	}
+	return count
+}
+
+// pieceTokens 估算单个片段的token数量
+func pieceTokens(piece string) int {
+	letters := 0
+	cjk := 0
+	other := 0
+	for _, r := range piece {
+		switch {
+		case isCJK(r):
+			cjk++
+		case unicode.IsLetter(r):
+			letters++
+		case unicode.IsSpace(r):
+		default:
+			other++
+		}
+	}
+
+	// 纯空白片段计为1个token
+	if letters == 0 && cjk == 0 && other == 0 {
+		return 1
+	}
+
+	count := cjk
+	if letters > 0 {
+		// 常见单词通常是1个token，长单词大约每6个字母增加1个token
+		count += 1 + (letters-1)/6
+	}
+	if other > 0 {
+		if letters > 0 || cjk > 0 {
+			// 单词前的一个标点会与单词合并
+			other--
+		}
+		count += (other + 2) / 3
+	}
+	return count
+}
+
+// isCJK 判断字符是否为中日韩文字
+func isCJK(r rune) bool {
+	return unicode.Is(unicode.Han, r) ||
+		unicode.Is(unicode.Hiragana, r) ||
+		unicode.Is(unicode.Katakana, r) ||
+		unicode.Is(unicode.Hangul, r)
+}
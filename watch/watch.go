@@ -0,0 +1,46 @@
+// Package watch 通过轮询文件的修改时间和大小检测文件变化，不依赖平台相关的文件通知机制
+package watch
+
+import (
+	"os"
+	"sync"
+	"time"
+)
+
+// Stamp 文件在某一时刻的状态，两个Stamp不相等表示文件发生了变化
+type Stamp struct {
+	Exists  bool
+	ModTime time.Time
+	Size    int64
+}
+
+// StampOf 返回文件当前的状态，文件不存在时返回零值
+func StampOf(path string) Stamp {
+	info, err := os.Stat(path)
+	if err != nil {
+		return Stamp{}
+	}
+	return Stamp{Exists: true, ModTime: info.ModTime(), Size: info.Size()}
+}
+
+// Poll 每隔interval调用一次check，直到调用返回的停止函数
+func Poll(interval time.Duration, check func()) (stop func()) {
+	ticker := time.NewTicker(interval)
+	done := make(chan struct{})
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				check()
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() { close(done) })
+	}
+}
@@ -0,0 +1,68 @@
+package workspace
+
+import (
+	"sync"
+	"time"
+)
+
+// 每个工作区保留的最近请求记录数
+const maxJournalEntries = 1000
+
+// JournalEntry 工作区收到的一次API请求的记录
+type JournalEntry struct {
+	Time       time.Time    `json:"time"`
+	Method     string       `json:"method"`
+	Path       string       `json:"path"`
+	Model      string       `json:"model,omitempty"`
+	Status     int          `json:"status"`
+	DurationMs int64        `json:"duration_ms"`
+	APIKeyID   string       `json:"api_key_id,omitempty"`
+	File       *JournalFile `json:"file,omitempty"` // 上传的文件
+}
+
+// JournalFile 请求上传的文件
+type JournalFile struct {
+	ID       string `json:"id"`
+	Filename string `json:"filename"`
+	Purpose  string `json:"purpose"`
+	Bytes    int64  `json:"bytes"`
+}
+
+// Journal 工作区的请求记录，只保留最近的记录
+type Journal struct {
+	entries []JournalEntry
+	mu      sync.RWMutex
+}
+
+// NewJournal 创建一个新的请求记录
+func NewJournal() *Journal {
+	return &Journal{}
+}
+
+// Record 追加一条请求记录
+func (j *Journal) Record(entry JournalEntry) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.entries = append(j.entries, entry)
+	if len(j.entries) > maxJournalEntries {
+		j.entries = j.entries[len(j.entries)-maxJournalEntries:]
+	}
+}
+
+// List 按时间顺序返回所有请求记录
+func (j *Journal) List() []JournalEntry {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+
+	result := make([]JournalEntry, len(j.entries))
+	copy(result, j.entries)
+	return result
+}
+
+// Clear 清空请求记录
+func (j *Journal) Clear() {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.entries = nil
+}
@@ -0,0 +1,184 @@
+package workspace
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"sync"
+
+	"RobinPenn974/OpenAI-mocker/atomicfile"
+)
+
+const (
+	// 默认工作区存储目录，每个工作区一个子目录
+	defaultWorkspaceDir = "workspace_data"
+	// 工作区元数据文件名
+	workspaceMetaFile = "workspace.json"
+)
+
+// 工作区ID只允许字母、数字、点、下划线和连字符，用作目录名
+var workspaceIDPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,63}$`)
+
+// Manager 管理所有工作区
+type Manager struct {
+	workspaces map[string]*Workspace
+	mu         sync.RWMutex
+	storageDir string
+}
+
+// NewManager 创建工作区管理器，defaultWorkspace为默认工作区，并从存储目录加载已创建的工作区
+func NewManager(storageDir string, defaultWorkspace *Workspace) *Manager {
+	if storageDir == "" {
+		storageDir = defaultWorkspaceDir
+	}
+
+	manager := &Manager{
+		workspaces: map[string]*Workspace{DefaultID: defaultWorkspace},
+		storageDir: storageDir,
+	}
+
+	// 加载已创建的工作区
+	if err := manager.loadWorkspaces(); err != nil {
+		fmt.Printf("Error loading workspaces: %v\n", err)
+	}
+
+	return manager
+}
+
+// Get 获取指定ID的工作区
+func (m *Manager) Get(id string) (*Workspace, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	ws, exists := m.workspaces[id]
+	if !exists {
+		return nil, errors.New("workspace not found")
+	}
+	return ws, nil
+}
+
+// Default 返回默认工作区
+func (m *Manager) Default() *Workspace {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.workspaces[DefaultID]
+}
+
+// List 按ID顺序列出所有工作区
+func (m *Manager) List() []*Workspace {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	result := make([]*Workspace, 0, len(m.workspaces))
+	for _, ws := range m.workspaces {
+		result = append(result, ws)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].ID < result[j].ID
+	})
+	return result
+}
+
+// Create 创建工作区，复制基础工作区的状态，base为空时使用默认工作区
+func (m *Manager) Create(id, base string) (*Workspace, error) {
+	if !workspaceIDPattern.MatchString(id) {
+		return nil, fmt.Errorf("invalid workspace id '%s'", id)
+	}
+	if base == "" {
+		base = DefaultID
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, exists := m.workspaces[id]; exists {
+		return nil, fmt.Errorf("workspace '%s' already exists", id)
+	}
+	baseWorkspace, exists := m.workspaces[base]
+	if !exists {
+		return nil, fmt.Errorf("base workspace '%s' not found", base)
+	}
+
+	dir := filepath.Join(m.storageDir, id)
+	ws := newWorkspace(id, dir)
+	if err := ws.copyFrom(baseWorkspace); err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+	if err := writeMeta(dir, ws); err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+
+	m.workspaces[id] = ws
+	return ws, nil
+}
+
+// Delete 删除工作区及其存储目录，默认工作区不能删除
+func (m *Manager) Delete(id string) error {
+	if id == DefaultID {
+		return errors.New("the default workspace cannot be deleted")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, exists := m.workspaces[id]; !exists {
+		return errors.New("workspace not found")
+	}
+	if err := os.RemoveAll(filepath.Join(m.storageDir, id)); err != nil {
+		return fmt.Errorf("error removing workspace directory: %v", err)
+	}
+
+	delete(m.workspaces, id)
+	return nil
+}
+
+// loadWorkspaces 加载存储目录中已创建的工作区
+func (m *Manager) loadWorkspaces() error {
+	entries, err := os.ReadDir(m.storageDir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error reading workspace directory: %v", err)
+	}
+
+	for _, entry := range entries {
+		if !entry.IsDir() || entry.Name() == DefaultID {
+			continue
+		}
+		dir := filepath.Join(m.storageDir, entry.Name())
+		data, err := os.ReadFile(filepath.Join(dir, workspaceMetaFile))
+		if err != nil {
+			continue
+		}
+
+		var meta Workspace
+		if err := json.Unmarshal(data, &meta); err != nil {
+			return fmt.Errorf("error parsing workspace %s: %v", entry.Name(), err)
+		}
+
+		ws := newWorkspace(entry.Name(), dir)
+		ws.Base = meta.Base
+		ws.CreatedAt = meta.CreatedAt
+		m.workspaces[ws.ID] = ws
+	}
+	return nil
+}
+
+// writeMeta 写入工作区元数据
+func writeMeta(dir string, ws *Workspace) error {
+	data, err := json.MarshalIndent(ws, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding workspace: %v", err)
+	}
+	if err := atomicfile.WriteFile(filepath.Join(dir, workspaceMetaFile), data, 0644); err != nil {
+		return fmt.Errorf("error writing workspace file: %v", err)
+	}
+	return nil
+}
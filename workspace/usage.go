@@ -0,0 +1,59 @@
+package workspace
+
+import "sync"
+
+// Usage 工作区的用量计数
+type Usage struct {
+	requests map[string]int64 // 按模型统计的请求数
+	errors   int64
+	mu       sync.RWMutex
+}
+
+// UsageSummary 用量计数的快照
+type UsageSummary struct {
+	TotalRequests    int64            `json:"total_requests"`
+	ErrorRequests    int64            `json:"error_requests"`
+	RequestsPerModel map[string]int64 `json:"requests_per_model"`
+}
+
+// NewUsage 创建一个新的用量计数
+func NewUsage() *Usage {
+	return &Usage{requests: make(map[string]int64)}
+}
+
+// Record 记录一次请求
+func (u *Usage) Record(model string, status int) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	u.requests[model]++
+	if status >= 400 {
+		u.errors++
+	}
+}
+
+// Summary 返回用量计数的快照
+func (u *Usage) Summary() UsageSummary {
+	u.mu.RLock()
+	defer u.mu.RUnlock()
+
+	summary := UsageSummary{
+		ErrorRequests:    u.errors,
+		RequestsPerModel: make(map[string]int64, len(u.requests)),
+	}
+	for model, count := range u.requests {
+		summary.TotalRequests += count
+		if model != "" {
+			summary.RequestsPerModel[model] = count
+		}
+	}
+	return summary
+}
+
+// Reset 清零用量计数
+func (u *Usage) Reset() {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.requests = make(map[string]int64)
+	u.errors = 0
+}
@@ -0,0 +1,196 @@
+package workspace
+
+import (
+	"path/filepath"
+	"time"
+
+	"RobinPenn974/OpenAI-mocker/assistants"
+	"RobinPenn974/OpenAI-mocker/azure"
+	"RobinPenn974/OpenAI-mocker/batches"
+	"RobinPenn974/OpenAI-mocker/embeddings"
+	"RobinPenn974/OpenAI-mocker/faults"
+	"RobinPenn974/OpenAI-mocker/files"
+	"RobinPenn974/OpenAI-mocker/finetuning"
+	"RobinPenn974/OpenAI-mocker/models"
+	"RobinPenn974/OpenAI-mocker/templates"
+)
+
+// DefaultID 默认工作区ID，未指定工作区的请求使用默认工作区
+const DefaultID = "default"
+
+// Workspace 工作区，包含一组相互隔离的模型、模板、规则、请求记录、文件、批处理、微调任务和Assistants API的对象
+type Workspace struct {
+	ID        string    `json:"id"`
+	Base      string    `json:"base,omitempty"` // 创建时复制的基础工作区
+	CreatedAt time.Time `json:"created_at"`
+
+	Models        *models.ModelManager       `json:"-"`
+	Router        *models.Router             `json:"-"`
+	Deployments   *models.DeploymentRegistry `json:"-"`
+	Templates     *templates.TemplateManager `json:"-"`
+	ContentFilter *azure.ContentFilter       `json:"-"`
+	Pins          *embeddings.PinStore       `json:"-"`
+	Faults        *faults.Injector           `json:"-"`
+	Journal       *Journal                   `json:"-"`
+	Usage         *Usage                     `json:"-"`
+	Assistants    *assistants.Store          `json:"-"`
+	Files         *files.Store               `json:"-"`
+	Batches       *batches.Store             `json:"-"`
+	FineTuning    *finetuning.Store          `json:"-"`
+}
+
+// Info 工作区的概要信息
+type Info struct {
+	ID         string       `json:"id"`
+	Base       string       `json:"base"`
+	CreatedAt  time.Time    `json:"created_at"`
+	ModelCount int          `json:"model_count"`
+	Usage      UsageSummary `json:"usage"`
+}
+
+// NewDefault 创建默认工作区，模型、路由规则和部署存储在dir下的 model_data 目录，
+// 模板、文件、内容过滤规则和固定向量分别存储在 template_data、file_data、azure_data 和 embedding_data 目录
+// 故障注入规则、请求记录、用量、Assistants API对象、批处理和微调任务只保存在内存中
+func NewDefault(dir string) *Workspace {
+	return newWorkspace(DefaultID, dir)
+}
+
+// newWorkspace 在指定目录下创建工作区的存储
+func newWorkspace(id, dir string) *Workspace {
+	modelManager := models.NewModelManager(filepath.Join(dir, "model_data"), "")
+	fileStore := files.NewStore(filepath.Join(dir, "file_data"))
+	ws := &Workspace{
+		ID:            id,
+		CreatedAt:     time.Now().UTC(),
+		Models:        modelManager,
+		Router:        models.NewRouter(modelManager, filepath.Join(dir, "model_data"), ""),
+		Deployments:   models.NewDeploymentRegistry(filepath.Join(dir, "model_data"), ""),
+		Templates:     templates.NewTemplateManager(filepath.Join(dir, "template_data"), ""),
+		ContentFilter: azure.NewContentFilter(filepath.Join(dir, "azure_data"), ""),
+		Pins:          embeddings.NewPinStore(filepath.Join(dir, "embedding_data"), ""),
+		Faults:        faults.NewInjector(),
+		Journal:       NewJournal(),
+		Usage:         NewUsage(),
+		Assistants:    assistants.NewStore(),
+		Files:         fileStore,
+		Batches:       batches.NewStore(fileStore),
+	}
+	ws.FineTuning = finetuning.NewStore(fileStore, ws.registerFineTunedModel)
+	return ws
+}
+
+// copyFrom 复制基础工作区的模型、路由规则、部署、模板、内容过滤规则、固定向量和故障注入规则
+func (w *Workspace) copyFrom(base *Workspace) error {
+	if err := w.Models.ReplaceModels(base.Models.ListModels()); err != nil {
+		return err
+	}
+	if err := w.Router.ReplaceRules(base.Router.Rules()); err != nil {
+		return err
+	}
+	if err := w.Templates.CopyFrom(base.Templates); err != nil {
+		return err
+	}
+	for _, deployment := range base.Deployments.ListDeployments() {
+		if err := w.Deployments.RegisterDeployment(deployment); err != nil {
+			return err
+		}
+	}
+	for _, rule := range base.ContentFilter.ListRules() {
+		if _, err := w.ContentFilter.AddRule(rule); err != nil {
+			return err
+		}
+	}
+	for _, pin := range base.Pins.ListPins() {
+		if _, err := w.Pins.AddPin(pin, len(pin.Embedding)); err != nil {
+			return err
+		}
+	}
+	for _, rule := range base.Faults.ListRules() {
+		if _, err := w.Faults.AddRule(rule); err != nil {
+			return err
+		}
+	}
+	w.Base = base.ID
+	return nil
+}
+
+// ResolveModel 解析请求中的模型名，开启自动注册时按请求的接口注册未知模型
+func (w *Workspace) ResolveModel(name, endpoint string) (models.Resolution, error) {
+	w.FineTuning.AdvanceAll()
+	return w.Router.Resolve(name, endpoint)
+}
+
+// ListModels 列出工作区的所有模型，包括已经完成的微调任务生成的模型
+func (w *Workspace) ListModels() []models.ModelInfo {
+	w.FineTuning.AdvanceAll()
+	return w.Models.ListModels()
+}
+
+// Info 返回工作区的概要信息和用量计数
+func (w *Workspace) Info() Info {
+	return Info{
+		ID:         w.ID,
+		Base:       w.Base,
+		CreatedAt:  w.CreatedAt,
+		ModelCount: len(w.ListModels()),
+		Usage:      w.Usage.Summary(),
+	}
+}
+
+// LookupModel 按别名和匹配模式解析模型名，不会自动注册未知模型，查找前推进微调任务以注册已经完成的微调模型
+func (w *Workspace) LookupModel(name string) (models.Resolution, error) {
+	w.FineTuning.AdvanceAll()
+	return w.Router.Lookup(name)
+}
+
+// ResolveDeployment 将Azure部署名解析为模型
+func (w *Workspace) ResolveDeployment(name string) (models.Resolution, error) {
+	w.FineTuning.AdvanceAll()
+	return w.Deployments.ResolveDeployment(w.Router, name)
+}
+
+// Template 获取模型的生效模板，模型名按别名和匹配模式解析后查找，并合并父模板、模式模板和默认模板
+func (w *Workspace) Template(modelID string) templates.ResponseTemplate {
+	return w.Templates.Resolve(w.Router.Candidates(modelID))
+}
+
+// DraftTemplate 按与 Template 相同的规则返回未保存的模板草稿的生效模板
+func (w *Workspace) DraftTemplate(modelID string, draft templates.ResponseTemplate) templates.ResponseTemplate {
+	draft.ModelID = modelID
+	return w.Templates.ResolveDraft(draft, w.Router.Candidates(modelID))
+}
+
+// CleanupModel 清理模型关联的模板、别名、部署、内容过滤规则、固定向量和故障注入规则
+func (w *Workspace) CleanupModel(modelID string) {
+	w.Templates.DeleteTemplate(modelID)
+	w.Router.DeleteRulesForModel(modelID)
+	w.Deployments.DeleteDeploymentsForModel(modelID)
+	w.ContentFilter.DeleteRulesForModel(modelID)
+	w.Pins.DeletePinsForModel(modelID)
+	w.Faults.DeleteRulesForModel(modelID)
+}
+
+// registerFineTunedModel 注册微调任务生成的模型，模型复制基础模型的元数据，响应模板继承基础模型的模板
+func (w *Workspace) registerFineTunedModel(job finetuning.Job) error {
+	base, err := w.Models.GetModel(job.Model)
+	if err != nil {
+		return err
+	}
+
+	// 基础模型没有保存的模板时复制其生效模板
+	template := templates.ResponseTemplate{ModelID: *job.FineTunedModel, Extends: job.Model}
+	if _, exists := w.Templates.StoredTemplate(job.Model); !exists {
+		template = w.Template(job.Model)
+		template.ModelID = *job.FineTunedModel
+		template.Extends = ""
+	}
+	if err := w.Templates.RegisterTemplate(template); err != nil {
+		return err
+	}
+
+	model := base
+	model.ID = *job.FineTunedModel
+	model.Created = time.Now().Unix()
+	model.OwnedBy = job.OrganizationID
+	return w.Models.RegisterModel(model)
+}